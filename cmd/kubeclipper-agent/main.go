@@ -23,6 +23,7 @@ import (
 	"os"
 
 	"github.com/kubeclipper/kubeclipper/cmd/kubeclipper-agent/app"
+	_ "github.com/kubeclipper/kubeclipper/pkg/component/bundle"
 	_ "github.com/kubeclipper/kubeclipper/pkg/component/nfs"
 	_ "github.com/kubeclipper/kubeclipper/pkg/component/nfscsi"
 	_ "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/cri"
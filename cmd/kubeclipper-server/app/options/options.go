@@ -57,6 +57,10 @@ func (s *ServerOptions) Flags() (fss cliflag.NamedFlagSets) {
 	s.LogOptions.AddFlags(fss.FlagSet("log"))
 	s.AuthenticationOptions.AddFlags(fss.FlagSet("authentication"))
 	s.AuditOptions.AddFlags(fss.FlagSet("audit"))
+	s.CertRenewalOptions.AddFlags(fss.FlagSet("cert renewal"))
+	s.OperationGCOptions.AddFlags(fss.FlagSet("operation retention"))
+	s.BackupVerifyOptions.AddFlags(fss.FlagSet("backup verify"))
+	s.BackupPointOptions.AddFlags(fss.FlagSet("backup point"))
 	return fss
 }
 
@@ -68,6 +72,10 @@ func (s *ServerOptions) Validate() []error {
 	errors = append(errors, s.LogOptions.Validate()...)
 	errors = append(errors, s.AuthenticationOptions.Validate()...)
 	errors = append(errors, s.AuditOptions.Validate()...)
+	errors = append(errors, s.CertRenewalOptions.Validate()...)
+	errors = append(errors, s.OperationGCOptions.Validate()...)
+	errors = append(errors, s.BackupVerifyOptions.Validate()...)
+	errors = append(errors, s.BackupPointOptions.Validate()...)
 	return errors
 }
 
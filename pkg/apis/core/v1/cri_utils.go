@@ -146,6 +146,12 @@ func criRegistryUpdateStep(cluster *v1.Cluster, registries []v1.RegistrySpec, no
 			// TODO: get from config
 			ConfigDir: cri.ContainerdDefaultRegistryConfigDir,
 		}
+	case v1.CRICrio:
+		identity = cri.CrioRegistryConfigureIdentity
+		step = &cri.CrioRegistryConfigure{
+			Registries: cri.ToCrioRegistryConfig(registries),
+			ConfigDir:  cri.CrioDefaultRegistryConfigDir,
+		}
 	default:
 		return nil, fmt.Errorf("unknown CRI type:%s", cluster.ContainerRuntime.Type)
 	}
@@ -27,6 +27,7 @@ import (
 	"math/rand"
 	"net/http"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -42,9 +43,13 @@ import (
 	"k8s.io/apimachinery/pkg/util/json"
 	r "k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/sets"
+	utilvalidation "k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	k8sversion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/retry"
 
 	"github.com/kubeclipper/kubeclipper/cmd/kcctl/app/options"
 	"github.com/kubeclipper/kubeclipper/pkg/models"
@@ -56,6 +61,8 @@ import (
 	"github.com/kubeclipper/kubeclipper/pkg/component"
 	"github.com/kubeclipper/kubeclipper/pkg/controller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/client"
+	"github.com/kubeclipper/kubeclipper/pkg/controller/backuppointcontroller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller/backupverifycontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/cloudprovidercontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/cronbackupcontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/logger"
@@ -67,8 +74,10 @@ import (
 	"github.com/kubeclipper/kubeclipper/pkg/models/tenant"
 	"github.com/kubeclipper/kubeclipper/pkg/oplog"
 	"github.com/kubeclipper/kubeclipper/pkg/query"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme"
 	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
 	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/cni"
 	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/k8s"
 	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/validation"
 	apirequest "github.com/kubeclipper/kubeclipper/pkg/server/request"
@@ -77,6 +86,7 @@ import (
 	"github.com/kubeclipper/kubeclipper/pkg/service"
 	bs "github.com/kubeclipper/kubeclipper/pkg/simple/backupstore"
 	"github.com/kubeclipper/kubeclipper/pkg/simple/generic"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/staticserver"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/certs"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/netutil"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
@@ -98,6 +108,8 @@ type handler struct {
 	delivery         service.IDelivery
 	tenantOperator   tenant.Operator
 	tokenOperator    auth.TokenManagementInterface
+	// staticServerOptions the static server holds the offline packages
+	staticServerOptions *staticserver.Options
 }
 
 const (
@@ -109,12 +121,13 @@ const (
 )
 
 var (
-	ErrNodesRegionDifferent = errors.New("nodes belongs to different region")
+	ErrNodesRegionDifferent    = errors.New("nodes belongs to different region")
+	ErrOperationNotCancellable = errors.New("operation can not be cancelled")
 )
 
 func newHandler(conf *generic.ServerRunOptions, clusterOperator cluster.Operator, op operation.Operator, leaseOperator lease.Operator,
 	platform platform.Operator, coreOperator core.Operator, delivery service.IDelivery,
-	tokenOperator auth.TokenManagementInterface, tenantOperator tenant.Operator) *handler {
+	tokenOperator auth.TokenManagementInterface, tenantOperator tenant.Operator, staticServerOptions *staticserver.Options) *handler {
 	return &handler{
 		genericConfig:    conf,
 		clusterOperator:  clusterOperator,
@@ -125,6 +138,8 @@ func newHandler(conf *generic.ServerRunOptions, clusterOperator cluster.Operator
 		coreOperator:     coreOperator,
 		tenantOperator:   tenantOperator,
 		tokenOperator:    tokenOperator,
+
+		staticServerOptions: staticServerOptions,
 	}
 }
 
@@ -147,6 +162,12 @@ func (h *handler) ListClusters(request *restful.Request, response *restful.Respo
 			restplus.HandleInternalError(response, request, err)
 			return
 		}
+		if redactResponse(request) {
+			if err = redactList(result, redactCluster); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+		}
 		_ = response.WriteHeaderAndEntity(http.StatusOK, result)
 	} else {
 		result, err := h.clusterOperator.ListClusterEx(request.Request.Context(), q)
@@ -154,6 +175,7 @@ func (h *handler) ListClusters(request *restful.Request, response *restful.Respo
 			restplus.HandleInternalError(response, request, err)
 			return
 		}
+		redactPageableResponse(result, redactCluster)
 		_ = response.WriteHeaderAndEntity(http.StatusOK, result)
 	}
 }
@@ -179,7 +201,7 @@ func (h *handler) DescribeCluster(request *restful.Request, response *restful.Re
 			return
 		}
 	}
-	_ = response.WriteHeaderAndEntity(http.StatusOK, c)
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactCluster(c))
 }
 
 func (h *handler) AddOrRemoveNodes(request *restful.Request, response *restful.Response) {
@@ -196,6 +218,7 @@ func (h *handler) AddOrRemoveNodes(request *restful.Request, response *restful.R
 	}
 
 	dryRun := query.GetBoolValueWithDefault(request, query.ParamDryRun, false)
+	pn.Rollback = query.GetBoolValueWithDefault(request, query.ParamRollback, pn.Rollback)
 	ctx := request.Request.Context()
 	c, err := h.clusterOperator.GetClusterEx(ctx, clu, "0")
 	if err != nil {
@@ -222,18 +245,33 @@ func (h *handler) AddOrRemoveNodes(request *restful.Request, response *restful.R
 	if pn.Operation == clusteroperation.NodesOperationRemove {
 		operationType = v1.OperationRemoveNodes
 	}
-	executable, err := clusteroperation.Executable(ctx, operationType, clu, h.opOperator)
+	priority, err := getPriority(request)
 	if err != nil {
 		restplus.HandleBadRequest(response, request, err)
 		return
 	}
-	if !executable {
-		restplus.HandleBadRequest(response, request, fmt.Errorf("%s does not support concurrent execution", operationType))
+	// the operation waits in the queue of cluster if it can not run along with the running operations,
+	// or other operations are already waiting.
+	queued, err := h.mustQueueNodesOperation(ctx, c, operationType, pn.Role)
+	if err != nil {
+		restplus.HandleInternalError(response, request, err)
 		return
 	}
 
-	if err = pn.MakeCompare(c); err != nil {
-		if errors.Is(err, clusteroperation.ErrInvalidNodesOperation) || errors.Is(err, clusteroperation.ErrInvalidNodesRole) {
+	// the queued operation changes the nodes of cluster when it starts, so it is validated against the cluster
+	// changed by the operations queued before it, and the cluster is not changed now.
+	target := c
+	if queued {
+		target = c.DeepCopy()
+		if err = clusteroperation.ApplyQueuedNodes(target); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		nodeSet = target.GetAllNodes()
+	}
+	if err = pn.MakeCompare(target); err != nil {
+		if errors.Is(err, clusteroperation.ErrInvalidNodesOperation) || errors.Is(err, clusteroperation.ErrInvalidNodesRole) ||
+			errors.Is(err, clusteroperation.ErrEtcdQuorum) {
 			restplus.HandleBadRequest(response, request, err)
 			return
 		}
@@ -282,6 +320,27 @@ func (h *handler) AddOrRemoveNodes(request *restful.Request, response *restful.R
 		}
 	}
 
+	if query.IsDryRunPlan(request) {
+		pendingOperation, err := buildPendingOperation(operationType, timeoutSecs, c.ResourceVersion, pn)
+		if err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		// the nodes of cluster have been changed by pn.MakeCompare.
+		origin, err := h.clusterOperator.GetClusterEx(ctx, clu, "0")
+		if err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		op, err := h.planPendingOperation(ctx, origin, pendingOperation)
+		if err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		writeOperationPlan(response, op)
+		return
+	}
+
 	if !dryRun {
 		for _, node := range pn.Nodes {
 			_, err = MarkToOriginNode(ctx, h.clusterOperator, node.ID)
@@ -296,15 +355,19 @@ func (h *handler) AddOrRemoveNodes(request *restful.Request, response *restful.R
 			return
 		}
 
-		c.Status.Phase = v1.ClusterUpdating
-		c.PendingOperations = append(c.PendingOperations, pendingOperation)
+		if queued {
+			clusteroperation.Enqueue(c, pendingOperation, priority, time.Now())
+		} else {
+			c.Status.Phase = v1.ClusterUpdating
+			c.PendingOperations = append(c.PendingOperations, pendingOperation)
+		}
 		if c, err = h.clusterOperator.UpdateCluster(ctx, c); err != nil {
 			restplus.HandleInternalError(response, request, err)
 			return
 		}
 	}
 
-	_ = response.WriteHeaderAndEntity(http.StatusOK, c)
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactCluster(c))
 }
 
 func (h *handler) watchCluster(req *restful.Request, resp *restful.Response, q *query.Query) {
@@ -321,6 +384,9 @@ func (h *handler) watchCluster(req *restful.Request, resp *restful.Response, q *
 		restplus.HandleInternalError(resp, req, err)
 		return
 	}
+	if redactResponse(req) {
+		watcher = redactWatch(watcher, redactCluster)
+	}
 	restplus.ServeWatch(watcher, v1.SchemeGroupVersion.WithKind("Cluster"), req, resp, timeout)
 }
 
@@ -398,7 +464,7 @@ func (h *handler) DeleteCluster(request *restful.Request, response *restful.Resp
 	}
 
 	extraMeta.OperationType = v1.OperationDeleteCluster
-	op, err := h.parseOperationFromCluster(extraMeta, c, v1.ActionUninstall)
+	op, err := h.parseOperationFromCluster(extraMeta, c, v1.ActionUninstall, false)
 	if err != nil {
 		restplus.HandleInternalError(response, request, err)
 		return
@@ -434,6 +500,16 @@ func (h *handler) CreateClusters(request *restful.Request, response *restful.Res
 		return
 	}
 	ctx := request.Request.Context()
+	if c.TemplateRef != nil {
+		if err := h.renderClusterTemplate(ctx, &c); err != nil {
+			if apimachineryErrors.IsNotFound(err) || errors.Is(err, ErrInvalidTemplate) || errors.Is(err, ErrInvalidTemplateValue) {
+				restplus.HandleBadRequest(response, request, err)
+				return
+			}
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+	}
 	info, _ := reqpkg.InfoFrom(ctx)
 
 	// create cluster in manager platform,front will set a project in label
@@ -503,6 +579,10 @@ func (h *handler) CreateClusters(request *restful.Request, response *restful.Res
 		restplus.HandleBadRequest(response, request, err)
 		return
 	}
+	// kube-apiserver accesses the managed etcd through all the etcd nodes.
+	if c.Etcd.IsManaged() {
+		c.Etcd.External.Endpoints = k8s.EtcdEndpoints(extraMeta.EtcdNodes)
+	}
 	// TODO: This logic has been implemented in the clusterController
 	c.Status.Registries, err = h.getClusterCRIRegistries(request.Request.Context(), &c)
 	if err != nil {
@@ -511,11 +591,19 @@ func (h *handler) CreateClusters(request *restful.Request, response *restful.Res
 	}
 
 	extraMeta.OperationType = v1.OperationCreateCluster
-	op, err := h.parseOperationFromCluster(extraMeta, &c, v1.ActionInstall)
+	rollback := query.GetBoolValueWithDefault(request, query.ParamRollback, false)
+	op, err := h.parseOperationFromCluster(extraMeta, &c, v1.ActionInstall, rollback)
 	if err != nil {
 		restplus.HandleInternalError(response, request, err)
 		return
 	}
+	op.Labels[common.LabelTimeoutSeconds] = timeoutSecs
+	op.Labels[common.LabelOperationAction] = v1.OperationCreateCluster
+	op.Status.Status = v1.OperationStatusRunning
+	if query.IsDryRunPlan(request) {
+		writeOperationPlan(response, op)
+		return
+	}
 
 	// TODO: make dry run path to etcd
 	if !dryRun {
@@ -527,9 +615,6 @@ func (h *handler) CreateClusters(request *restful.Request, response *restful.Res
 		}
 	}
 
-	op.Labels[common.LabelTimeoutSeconds] = timeoutSecs
-	op.Labels[common.LabelOperationAction] = v1.OperationCreateCluster
-	op.Status.Status = v1.OperationStatusRunning
 	if !dryRun {
 		op, err = h.opOperator.CreateOperation(context.TODO(), op)
 		if err != nil {
@@ -539,7 +624,7 @@ func (h *handler) CreateClusters(request *restful.Request, response *restful.Res
 	}
 
 	go h.doOperation(context.TODO(), op, &service.Options{DryRun: dryRun})
-	_ = response.WriteHeaderAndEntity(http.StatusOK, c)
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactCluster(&c))
 }
 
 func (h *handler) UpdateClusters(request *restful.Request, response *restful.Response) {
@@ -576,6 +661,12 @@ func (h *handler) UpdateClusters(request *restful.Request, response *restful.Res
 			return
 		}
 	}
+	if c.MaintenanceWindow != nil {
+		if err := c.MaintenanceWindow.Validate(); err != nil {
+			restplus.HandleBadRequest(response, request, err)
+			return
+		}
+	}
 
 	if !dryRun {
 		clu, err := h.clusterOperator.GetCluster(context.TODO(), name)
@@ -598,6 +689,7 @@ func (h *handler) UpdateClusters(request *restful.Request, response *restful.Res
 		clu.Labels = c.Labels
 		clu.Annotations = c.Annotations
 		clu.ContainerRuntime.Registries = c.ContainerRuntime.Registries
+		clu.MaintenanceWindow = c.MaintenanceWindow
 		_, err = h.clusterOperator.UpdateCluster(context.TODO(), clu)
 		if err != nil {
 			restplus.HandleInternalError(response, request, err)
@@ -671,6 +763,11 @@ func (h *handler) UpdateClusterCertification(request *restful.Request, response
 	cluName := request.PathParameter(query.ParameterName)
 	ctx := request.Request.Context()
 	dryRun := query.GetBoolValueWithDefault(request, query.ParamDryRun, false)
+	priority, err := getPriority(request)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
 	c, err := h.clusterOperator.GetCluster(ctx, cluName)
 	if err != nil {
 		restplus.HandleBadRequest(response, request, err)
@@ -703,6 +800,27 @@ func (h *handler) UpdateClusterCertification(request *restful.Request, response
 		common.LabelOperationAction: v1.OperationUpdateCertification,
 	}
 	op.Status.Status = v1.OperationStatusRunning
+	if !dryRun {
+		queued, err := h.mustQueueOperation(ctx, c)
+		if err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		if queued {
+			// the certificates are renewed on the control plane nodes of the time the operation starts.
+			pendingOperation, err := buildPendingOperation(v1.OperationUpdateCertification, v1.DefaultOperationTimeoutSecs, c.ResourceVersion, nil)
+			if err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+			if c, err = h.enqueueOperation(ctx, c, pendingOperation, priority); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+			_ = response.WriteHeaderAndEntity(http.StatusOK, redactCluster(c))
+			return
+		}
+	}
 	c.Status.Phase = v1.ClusterUpdating
 	if !dryRun {
 		op, err = h.opOperator.CreateOperation(ctx, op)
@@ -718,7 +836,7 @@ func (h *handler) UpdateClusterCertification(request *restful.Request, response
 	}
 
 	go h.doOperation(context.TODO(), op, &service.Options{DryRun: dryRun})
-	_ = response.WriteHeaderAndEntity(http.StatusOK, c)
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactCluster(c))
 }
 
 func (h *handler) GetKubeConfig(request *restful.Request, response *restful.Response) {
@@ -761,7 +879,7 @@ func (h *handler) GetKubeConfig(request *restful.Request, response *restful.Resp
 			extraMeta.ClusterName = clu.Annotations[common.AnnotationActualName]
 		}
 
-		kubeConfig, err := k8s.GetKubeConfig(context.TODO(), extraMeta.ClusterName, extraMeta.Masters[0], h.delivery)
+		kubeConfig, err := k8s.GetKubeConfig(context.TODO(), extraMeta.ClusterName, extraMeta.Masters[0], clu.ControlPlaneVIP, h.delivery)
 		if err != nil {
 			restplus.HandleInternalError(response, request, err)
 			return
@@ -1026,7 +1144,101 @@ func (h *handler) DescribeOperation(request *restful.Request, response *restful.
 		restplus.HandleInternalError(response, request, err)
 		return
 	}
-	_ = response.WriteHeaderAndEntity(http.StatusOK, c)
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactOperation(c))
+}
+
+func (h *handler) PauseOperation(request *restful.Request, response *restful.Response) {
+	h.transitOperation(request, response, v1.OperationStatusRunning, v1.OperationStatusPaused)
+}
+
+func (h *handler) ResumeOperation(request *restful.Request, response *restful.Response) {
+	h.transitOperation(request, response, v1.OperationStatusPaused, v1.OperationStatusRunning)
+}
+
+// CancelOperation stops the pending, running or paused operation, the running commands on nodes are killed
+// and the cluster is set to the failed phase of the operation.
+func (h *handler) CancelOperation(request *restful.Request, response *restful.Response) {
+	op, ok := h.getRequestOperation(request, response)
+	if !ok {
+		return
+	}
+	ctx := request.Request.Context()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := h.opOperator.GetOperation(ctx, op.Name)
+		if err != nil {
+			return err
+		}
+		switch latest.Status.Status {
+		case v1.OperationStatusPending, v1.OperationStatusRunning, v1.OperationStatusPaused:
+		default:
+			return fmt.Errorf("%w: operation %s is %s, only pending, running or paused operation can be cancelled",
+				ErrOperationNotCancellable, latest.Name, latest.Status.Status)
+		}
+		latest.Status.Status = v1.OperationStatusCancelled
+		op, err = h.opOperator.UpdateOperation(ctx, latest)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, ErrOperationNotCancellable) {
+			restplus.HandleBadRequest(response, request, err)
+			return
+		}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	if err = h.delivery.CancelOperation(ctx, op); err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactOperation(op))
+}
+
+// getRequestOperation gets the operation in path, the project scoped request can only get the operations of its project.
+func (h *handler) getRequestOperation(request *restful.Request, response *restful.Response) (*v1.Operation, bool) {
+	name := request.PathParameter(query.ParameterName)
+	op, err := h.opOperator.GetOperationEx(request.Request.Context(), name, "0")
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(response, request, err)
+			return nil, false
+		}
+		restplus.HandleInternalError(response, request, err)
+		return nil, false
+	}
+
+	info, _ := reqpkg.InfoFrom(request.Request.Context())
+	if info.IsProjectScope() {
+		clu, err := h.clusterOperator.GetClusterEx(request.Request.Context(), op.Labels[common.LabelClusterName], "0")
+		if err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return nil, false
+		}
+		if project := request.PathParameter("project"); clu.Labels[common.LabelProject] != project {
+			restplus.HandleBadRequest(response, request, fmt.Errorf("operation %s not belong to project %s", name, project))
+			return nil, false
+		}
+	}
+	return op, true
+}
+
+// transitOperation changes the status of operation, the running steps are not interrupted,
+// the operation stops before its next step when it is paused.
+func (h *handler) transitOperation(request *restful.Request, response *restful.Response, from, to v1.OperationStatusType) {
+	op, ok := h.getRequestOperation(request, response)
+	if !ok {
+		return
+	}
+	if op.Status.Status != from {
+		restplus.HandleBadRequest(response, request, fmt.Errorf("operation %s is %s, only %s operation can be %s", op.Name, op.Status.Status, from, to))
+		return
+	}
+	op.Status.Status = to
+	op, err := h.opOperator.UpdateOperation(request.Request.Context(), op)
+	if err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactOperation(op))
 }
 
 func (h *handler) ListOperations(request *restful.Request, response *restful.Response) {
@@ -1041,6 +1253,12 @@ func (h *handler) ListOperations(request *restful.Request, response *restful.Res
 			restplus.HandleInternalError(response, request, err)
 			return
 		}
+		if redactResponse(request) {
+			if err = redactList(result, redactOperationObject); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+		}
 		_ = response.WriteHeaderAndEntity(http.StatusOK, result)
 	} else {
 		result, err := h.opOperator.ListOperationsEx(request.Request.Context(), q)
@@ -1048,6 +1266,7 @@ func (h *handler) ListOperations(request *restful.Request, response *restful.Res
 			restplus.HandleInternalError(response, request, err)
 			return
 		}
+		redactPageableResponse(result, redactOperationObject)
 		_ = response.WriteHeaderAndEntity(http.StatusOK, result)
 	}
 }
@@ -1062,6 +1281,9 @@ func (h *handler) watchOperations(req *restful.Request, resp *restful.Response,
 		restplus.HandleInternalError(resp, req, err)
 		return
 	}
+	if redactResponse(req) {
+		watcher = redactWatch(watcher, redactOperationObject)
+	}
 	restplus.ServeWatch(watcher, v1.SchemeGroupVersion.WithKind("Operation"), req, resp, timeout)
 }
 
@@ -1091,7 +1313,14 @@ func (h *handler) getClusterMetadata(ctx context.Context, c *v1.Cluster, skipNod
 		return nil, err
 	}
 	meta.Workers = append(meta.Workers, workers...)
-	err = h.regionCheck(meta.Masters, meta.Workers)
+	if c.Etcd.IsManaged() {
+		etcdNodes, err := h.getNodeInfo(ctx, c.Etcd.External.Nodes, skipNodeNotFound)
+		if err != nil {
+			return nil, err
+		}
+		meta.EtcdNodes = append(meta.EtcdNodes, etcdNodes...)
+	}
+	err = h.regionCheck(meta.Masters, append(meta.Workers, meta.EtcdNodes...))
 	if err != nil {
 		return nil, err
 	}
@@ -1324,6 +1553,29 @@ func (h *handler) createClusterCheck(ctx context.Context, c *v1.Cluster) error {
 	if len(c.Masters) == 0 {
 		return fmt.Errorf("cluster must have one master node")
 	}
+	if !v1.AllowedCNI.Has(c.CNI.Type) {
+		return fmt.Errorf("unsupported cni %s, support %v now", c.CNI.Type, v1.AllowedCNI.List())
+	}
+	if c.Networking.ProxyMode == v1.ProxyModeEBPF && !cni.SupportKubeProxyReplacement(c.CNI.Type) {
+		return fmt.Errorf("proxy mode ebpf is not supported by cni %s", c.CNI.Type)
+	}
+	if err := validateExternalEtcd(c); err != nil {
+		return err
+	}
+	if c.MaintenanceWindow != nil {
+		if err := c.MaintenanceWindow.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.ControlPlaneVIP != nil {
+		if err := c.ControlPlaneVIP.Validate(); err != nil {
+			return err
+		}
+	}
+	config := c.Config()
+	if err := config.Validate(); err != nil {
+		return err
+	}
 
 	cluInfo, err := h.clusterOperator.GetClusterEx(ctx, c.Name, "0")
 	if err != nil && !apimachineryErrors.IsNotFound(err) {
@@ -1349,10 +1601,7 @@ func (h *handler) createClusterCheck(ctx context.Context, c *v1.Cluster) error {
 	for _, node := range nodeList.Items {
 		freeNodes.Insert(node.Name)
 	}
-	cluNodes := sets.NewString()
-	for _, node := range append(c.Masters, c.Workers...) {
-		cluNodes.Insert(node.ID)
-	}
+	cluNodes := c.GetAllNodes()
 
 	if freeNodes.HasAll(cluNodes.List()...) {
 		return nil
@@ -1450,6 +1699,11 @@ func (h *handler) CreateBackup(request *restful.Request, response *restful.Respo
 	}
 
 	dryRun := query.GetBoolValueWithDefault(request, query.ParamDryRun, false)
+	priority, err := getPriority(request)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
 	// cluster name in path
 	clusterName := request.PathParameter("name")
 	ctx := request.Request.Context()
@@ -1472,7 +1726,15 @@ func (h *handler) CreateBackup(request *restful.Request, response *restful.Respo
 		}
 	}
 
-	if c.Status.Phase != v1.ClusterRunning {
+	// the backup waits in the queue while other operations are running.
+	queued := false
+	if !dryRun {
+		if queued, err = h.mustQueueOperation(ctx, c); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+	}
+	if !queued && c.Status.Phase != v1.ClusterRunning {
 		restplus.HandleInternalError(response, request, fmt.Errorf("cluster %s current is %s, can't back up",
 			c.Name, c.Status.Phase))
 		return
@@ -1567,6 +1829,19 @@ func (h *handler) CreateBackup(request *restful.Request, response *restful.Respo
 			restplus.HandleInternalError(response, request, err)
 			return
 		}
+		if queued {
+			// the backup is kept while its operation is queued.
+			if _, err = h.enqueuePrebuiltOperation(ctx, c, op, priority); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+			if backup, err = h.clusterOperator.CreateBackup(ctx, backup); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+			_ = response.WriteHeaderAndEntity(http.StatusOK, backup)
+			return
+		}
 		if op, err = h.opOperator.CreateOperation(context.TODO(), op); err != nil {
 			restplus.HandleInternalError(response, request, err)
 			return
@@ -1591,6 +1866,11 @@ func (h *handler) DeleteBackup(request *restful.Request, response *restful.Respo
 	clusterName := request.PathParameter("cluster")
 	ctx := request.Request.Context()
 	dryRun := query.GetBoolValueWithDefault(request, query.ParamDryRun, false)
+	priority, err := getPriority(request)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
 	c, err := h.clusterOperator.GetClusterEx(ctx, clusterName, "0")
 	if err != nil {
 		if apimachineryErrors.IsNotFound(err) {
@@ -1644,6 +1924,11 @@ func (h *handler) DeleteBackup(request *restful.Request, response *restful.Respo
 		restplus.HandleBadRequest(response, request, fmt.Errorf("backup is %s now, can't delete", b.Status.ClusterBackupStatus))
 		return
 	}
+	if clusteroperation.IsQueued(c, b.Labels[common.LabelOperationName]) {
+		restplus.HandleBadRequest(response, request, fmt.Errorf("operation %s of backup is queued, remove it from the queue before deleting the backup",
+			b.Labels[common.LabelOperationName]))
+		return
+	}
 
 	if !dryRun {
 		c, err = h.clusterOperator.GetCluster(ctx, clusterName)
@@ -1658,6 +1943,24 @@ func (h *handler) DeleteBackup(request *restful.Request, response *restful.Respo
 			restplus.HandleInternalError(response, request, err)
 			return
 		}
+		queued, err := h.mustQueueOperation(ctx, c)
+		if err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		if queued {
+			// the backup is deleted at once, its files are removed when the operation starts.
+			if _, err = h.enqueuePrebuiltOperation(ctx, c, op, priority); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+			if err = h.clusterOperator.DeleteBackup(ctx, backupName); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+			response.WriteHeader(http.StatusOK)
+			return
+		}
 		if op, err = h.opOperator.CreateOperation(context.TODO(), op); err != nil {
 			restplus.HandleInternalError(response, request, err)
 			return
@@ -1723,6 +2026,61 @@ func (h *handler) UpdateBackup(request *restful.Request, response *restful.Respo
 	_ = response.WriteHeaderAndEntity(http.StatusOK, backup)
 }
 
+// VerifyBackup downloads the backup and checks it against the size and md5 recorded when it was created,
+// the backup is marked corrupted or missing if it is not intact.
+func (h *handler) VerifyBackup(request *restful.Request, response *restful.Response) {
+	clusterName := request.PathParameter("cluster")
+	ctx := request.Request.Context()
+	clu, err := h.clusterOperator.GetClusterEx(ctx, clusterName, "0")
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(response, request, err)
+			return
+		}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+
+	info, _ := reqpkg.InfoFrom(ctx)
+	if info.IsProjectScope() {
+		project := request.PathParameter("project")
+		if clu.Labels[common.LabelProject] != project {
+			restplus.HandleBadRequest(response, request, fmt.Errorf("cluster %s not belong to project %s", clusterName, project))
+			return
+		}
+	}
+
+	backup, err := h.clusterOperator.GetBackupEx(ctx, clusterName, request.PathParameter("backup"))
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(response, request, err)
+			return
+		}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	bp, err := h.clusterOperator.GetBackupPointEx(ctx, backup.BackupPointName, "0")
+	if err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	if err = backupverifycontroller.Verifiable(bp, backup); err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	if err = backupverifycontroller.VerifyBackup(ctx, h.coreOperator, bp, backup, time.Now()); err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+
+	backup, err = h.clusterOperator.UpdateBackup(ctx, backup)
+	if err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, backup)
+}
+
 func (h *handler) RetryCluster(request *restful.Request, response *restful.Response) {
 	dryRun := query.GetBoolValueWithDefault(request, query.ParamDryRun, false)
 	name := request.PathParameter(query.ParameterName)
@@ -1733,15 +2091,16 @@ func (h *handler) RetryCluster(request *restful.Request, response *restful.Respo
 		return
 	}
 
-	// backup/recovery/upgrade does not support retries
-	switch op.Labels[common.LabelOperationAction] {
-	case v1.OperationBackupCluster, v1.OperationRecoverCluster, v1.OperationUpgradeCluster:
-		restplus.HandleBadRequest(response, request, fmt.Errorf("backup/recovery/upgrade operation-action does not support retries"))
-		return
-	case "":
+	// the manual retry follows the same rule as the automatic retry
+	action := op.Labels[common.LabelOperationAction]
+	if action == "" {
 		restplus.HandleBadRequest(response, request, fmt.Errorf("operation %s action is empty", name))
 		return
 	}
+	if !clusteroperation.IsRetry(action) {
+		restplus.HandleBadRequest(response, request, fmt.Errorf("%s operation-action does not support retries", action))
+		return
+	}
 
 	// only the last retry is supported
 	q := query.New()
@@ -1760,18 +2119,30 @@ func (h *handler) RetryCluster(request *restful.Request, response *restful.Respo
 	}
 
 	op = opList.Items[0].(*v1.Operation)
-	if op.Status.Status == v1.OperationStatusSuccessful || op.Status.Status == v1.OperationStatusRunning || op.Name != name {
+	if op.Status.Status == v1.OperationStatusSuccessful || op.Status.Status == v1.OperationStatusRunning ||
+		op.Status.Status == v1.OperationStatusPaused || op.Name != name {
 		restplus.HandleBadRequest(response, request, fmt.Errorf("only the latest faild operation can do a retry"))
 		return
 	}
+	if op.IsRolledBack() {
+		restplus.HandleBadRequest(response, request, clusteroperation.ErrOperationRolledBack)
+		return
+	}
+	if op.IsDropped() {
+		restplus.HandleBadRequest(response, request, clusteroperation.ErrOperationDropped)
+		return
+	}
 
 	// error step index
 	failedIndex := len(op.Status.Conditions) - 1
 	ctx := component.WithRetry(context.TODO(), true)
+	graph := op.HasStepDependencies()
 
 	// if there is an uninstall error, continue directly from the current step
 	var continueSteps []v1.Step
-	if op.Steps[0].Action == v1.ActionInstall {
+	if graph {
+		continueSteps = clusteroperation.RetryStepGraph(op)
+	} else if op.Steps[0].Action == v1.ActionInstall {
 		findStepNode := func(nodes []v1.StepNode, nodeID string) v1.StepNode {
 			for _, v := range nodes {
 				if v.ID == nodeID {
@@ -1815,7 +2186,7 @@ func (h *handler) RetryCluster(request *restful.Request, response *restful.Respo
 	}
 
 	// if there is an uninstall error, start from the beginning
-	if op.Steps[0].Action == v1.ActionUninstall {
+	if !graph && op.Steps[0].Action == v1.ActionUninstall {
 		continueSteps = op.Steps
 		op.Status.Conditions = make([]v1.OperationCondition, 0)
 	}
@@ -1854,6 +2225,11 @@ func (h *handler) CreateRecovery(request *restful.Request, response *restful.Res
 	}
 
 	dryRun := query.GetBoolValueWithDefault(request, query.ParamDryRun, false)
+	priority, err := getPriority(request)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
 	// cluster name in path
 	clusterName := request.PathParameter("cluster")
 	ctx := request.Request.Context()
@@ -1895,6 +2271,20 @@ func (h *handler) CreateRecovery(request *restful.Request, response *restful.Res
 		return
 	}
 
+	switch b.Status.ClusterBackupStatus {
+	case v1.ClusterBackupCorrupted, v1.ClusterBackupMissing:
+		restplus.HandleBadRequest(response, request,
+			fmt.Errorf("backup %s is %s, it can't be used for recovery: %s", b.Name, b.Status.ClusterBackupStatus, b.Status.VerifyMessage))
+		return
+	}
+	// the etcd snapshot only matches the kubernetes version it was taken at.
+	if b.Status.KubernetesVersion != c.KubernetesVersion {
+		restplus.HandleBadRequest(response, request,
+			fmt.Errorf("backup %s was taken at kubernetes version %q, it can't be used to recover cluster %s of version %s",
+				b.Name, b.Status.KubernetesVersion, c.Name, c.KubernetesVersion))
+		return
+	}
+
 	for _, node := range nodeList.Items {
 		val, ok := b.ClusterNodes[node.Status.Ipv4DefaultIP]
 		if !ok {
@@ -1911,20 +2301,33 @@ func (h *handler) CreateRecovery(request *restful.Request, response *restful.Res
 		}
 	}
 
-	switch c.Status.Phase {
-	case v1.ClusterRestoring, v1.ClusterBackingUp,
-		v1.ClusterTerminating, v1.ClusterUpdating, v1.ClusterInstalling:
-		restplus.HandleInternalError(response, request,
-			fmt.Errorf("cluster %s current is %s, can't recovery",
-				c.Name, c.Status.ComponentConditions[0].Status))
+	if c.Etcd.IsExternal() && !c.Etcd.IsManaged() {
+		restplus.HandleBadRequest(response, request,
+			fmt.Errorf("cluster %s uses an external etcd which is not managed by kubeclipper, it can't be recovered", c.Name))
 		return
 	}
 
-	rName := uuid.New().String()
-	oName := uuid.New().String()
-
-	// update cluster status to recovering
-	c.Status.Phase = v1.ClusterRestoring
+	// the recovery waits in the queue while other operations are running.
+	queued := false
+	if !dryRun {
+		if queued, err = h.mustQueueOperation(ctx, c); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+	}
+	if !queued {
+		switch c.Status.Phase {
+		case v1.ClusterRestoring, v1.ClusterBackingUp,
+			v1.ClusterTerminating, v1.ClusterUpdating, v1.ClusterInstalling:
+			restplus.HandleInternalError(response, request,
+				fmt.Errorf("cluster %s current is %s, can't recovery",
+					c.Name, c.Status.ComponentConditions[0].Status))
+			return
+		}
+	}
+
+	rName := uuid.New().String()
+	oName := uuid.New().String()
 
 	// create operation
 	o := &v1.Operation{}
@@ -1943,6 +2346,10 @@ func (h *handler) CreateRecovery(request *restful.Request, response *restful.Res
 		return
 	}
 	o.Steps = steps
+	if query.IsDryRunPlan(request) {
+		writeOperationPlan(response, o)
+		return
+	}
 
 	// add recovery
 	r.Name = rName
@@ -1951,6 +2358,23 @@ func (h *handler) CreateRecovery(request *restful.Request, response *restful.Res
 	r.Labels[common.LabelOperationName] = oName
 	r.Labels[common.LabelTimeoutSeconds] = strconv.Itoa(v1.DefaultBackupTimeoutSec)
 
+	if queued {
+		if _, err = h.enqueuePrebuiltOperation(ctx, c, o, priority); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		// the backup is kept for the recovery while its operation is queued.
+		b.Labels[common.LabelOperationName] = oName
+		if _, err = h.clusterOperator.UpdateBackup(ctx, b); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusOK, r)
+		return
+	}
+
+	// update cluster status to recovering
+	c.Status.Phase = v1.ClusterRestoring
 	if !dryRun {
 		if c, err = h.clusterOperator.UpdateCluster(ctx, c); err != nil {
 			restplus.HandleInternalError(response, request, err)
@@ -1989,6 +2413,11 @@ func (h *handler) InstallOrUninstallPlugins(request *restful.Request, response *
 	if v := request.QueryParameter("timeout"); v != "" {
 		timeoutSecs = v
 	}
+	priority, err := getPriority(request)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
 
 	ctx := request.Request.Context()
 	clu, err := h.clusterOperator.GetClusterEx(ctx, clusterName, "0")
@@ -2023,12 +2452,22 @@ func (h *handler) InstallOrUninstallPlugins(request *restful.Request, response *
 		restplus.HandleBadRequest(response, request, err)
 		return
 	}
+	if pcs.Uninstall {
+		err = pcs.checkRemoval(clu)
+	} else {
+		err = pcs.resolveDependencies(clu, query.GetBoolValueWithDefault(request, query.ParamWithDependencies, false))
+	}
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
 	action, operationAction := v1.ActionInstall, v1.OperationInstallComponents
 	if pcs.Uninstall {
 		action = v1.ActionUninstall
 		operationAction = v1.OperationUninstallComponents
 	}
-	op, err := h.parseOperationFromComponent(ctx, extraMeta, pcs.Addons, clu, action)
+	rollback := query.GetBoolValueWithDefault(request, query.ParamRollback, false)
+	op, err := h.parseOperationFromComponent(ctx, extraMeta, pcs.Addons, clu, action, rollback)
 	if err != nil {
 		restplus.HandleInternalError(response, request, err)
 		return
@@ -2042,25 +2481,39 @@ func (h *handler) InstallOrUninstallPlugins(request *restful.Request, response *
 	op.Labels[common.LabelTimeoutSeconds] = timeoutSecs
 	op.Labels[common.LabelOperationAction] = operationAction
 	op.Status.Status = v1.OperationStatusRunning
+	// the addons of cluster are changed when the operation is done.
+	if err = clusteroperation.SetComponents(op, pcs.Addons); err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
 
-	if !dryRun {
-		// cluster.Status.Registries will be updated
-		var statusRegistry []v1.RegistrySpec
-		statusRegistry, err = h.getClusterCRIRegistries(ctx, clu)
-		if err != nil {
+	plan := query.IsDryRunPlan(request)
+	if !dryRun || plan {
+		if err = h.prependCRIRegistriesStep(ctx, clu, op); err != nil {
 			restplus.HandleInternalError(response, request, err)
 			return
 		}
-		criStep, err := h.getCRIRegistriesStep(ctx, clu, statusRegistry)
+	}
+	if plan {
+		writeOperationPlan(response, op)
+		return
+	}
+
+	if !dryRun {
+		queued, err := h.mustQueueOperation(ctx, clu)
 		if err != nil {
 			restplus.HandleInternalError(response, request, err)
 			return
 		}
-		if criStep != nil {
-			op.Steps = append(op.Steps[:1], op.Steps...)
-			op.Steps[0] = *criStep
-			clu.Status.Registries = statusRegistry
+		if queued {
+			if clu, err = h.enqueuePrebuiltOperation(ctx, clu, op, priority); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+			_ = response.WriteHeaderAndEntity(http.StatusOK, clu)
+			return
 		}
+
 		clu.Status.Phase = v1.ClusterUpdating
 		_, err = h.clusterOperator.UpdateCluster(context.TODO(), clu)
 		if err != nil {
@@ -2075,34 +2528,153 @@ func (h *handler) InstallOrUninstallPlugins(request *restful.Request, response *
 		}
 
 	}
-	go func(o *v1.Operation, opts *service.Options, oPcs *PatchComponents) {
-		if err := h.delivery.DeliverTaskOperation(context.TODO(), o, opts); err != nil {
-			logger.Error("delivery task error", zap.Error(err))
+	go h.doOperation(context.TODO(), op, &service.Options{DryRun: dryRun})
+
+	_ = response.WriteHeaderAndEntity(http.StatusOK, clu)
+}
+
+// UpgradePlugins upgrades the installed plugins to other versions in place, the cluster keeps the plugins
+// which are not upgraded successfully.
+func (h *handler) UpgradePlugins(request *restful.Request, response *restful.Response) {
+	ucs := &UpgradeComponents{}
+	if err := request.ReadEntity(ucs); err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	if len(ucs.Addons) == 0 {
+		restplus.HandleBadRequest(response, request, errors.New("no plugin to be upgraded"))
+		return
+	}
+	clusterName := request.PathParameter("cluster")
+	dryRun := query.GetBoolValueWithDefault(request, query.ParamDryRun, false)
+	timeoutSecs := v1.DefaultOperationTimeoutSecs
+	if v := request.QueryParameter("timeout"); v != "" {
+		timeoutSecs = v
+	}
+	priority, err := getPriority(request)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+
+	ctx := request.Request.Context()
+	clu, err := h.clusterOperator.GetClusterEx(ctx, clusterName, "0")
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(response, request, err)
 			return
 		}
-		logger.Debugf("the install or uninstall plugins message was delivered successfully")
-		// the database is not updated until the message is delivered successfully
-		if !opts.DryRun {
-			latestCluster, err := h.clusterOperator.GetClusterEx(ctx, clusterName, "0")
-			if err != nil {
-				logger.Error("get the latest cluster info error", zap.Error(err))
-				return
-			}
-			newCluster, err := oPcs.addOrRemoveComponentFromCluster(latestCluster)
-			if err != nil {
-				logger.Error("add or remove component from cluster", zap.Error(err))
-				return
-			}
-			_, err = h.clusterOperator.UpdateCluster(context.TODO(), newCluster)
-			if err != nil {
-				logger.Error("update cluster metadata error", zap.Error(err))
-			}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	info, _ := reqpkg.InfoFrom(ctx)
+	if info.IsProjectScope() {
+		project := request.PathParameter("project")
+		if clu.Labels[common.LabelProject] != project {
+			restplus.HandleBadRequest(response, request, fmt.Errorf("cluster %s not belong to project %s", clusterName, project))
+			return
+		}
+	}
+	// the plugins are upgraded after the running operations if they wait in the queue.
+	queued := false
+	if !dryRun {
+		if queued, err = h.mustQueueOperation(ctx, clu); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+	}
+	if !queued && clu.Status.Phase != v1.ClusterRunning {
+		restplus.HandleBadRequest(response, request, fmt.Errorf("plugins can not be upgraded while cluster is %s", clu.Status.Phase))
+		return
+	}
+
+	extraMeta, err := h.getClusterMetadata(ctx, clu, false)
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) || err == ErrNodesRegionDifferent {
+			restplus.HandleBadRequest(response, request, err)
+			return
+		}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	addons, err := ucs.makeUpgradedAddons(clu)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	op, err := h.parseOperationFromComponent(ctx, extraMeta, addons, clu, v1.ActionUpgrade, false)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	if len(op.Steps) == 0 {
+		restplus.HandleBadRequest(response, request, errors.New("the plugins do not support upgrade"))
+		return
+	}
+	op.Labels[common.LabelTimeoutSeconds] = timeoutSecs
+	op.Labels[common.LabelOperationAction] = v1.OperationUpgradeComponents
+	op.Status.Status = v1.OperationStatusRunning
+	// the installed addons of cluster are replaced when the operation is done.
+	if err = clusteroperation.SetComponents(op, addons); err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+
+	plan := query.IsDryRunPlan(request)
+	if !dryRun || plan {
+		if err = h.prependCRIRegistriesStep(ctx, clu, op); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+	}
+	if plan {
+		writeOperationPlan(response, op)
+		return
+	}
+
+	if queued {
+		if clu, err = h.enqueuePrebuiltOperation(ctx, clu, op, priority); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		_ = response.WriteHeaderAndEntity(http.StatusOK, clu)
+		return
+	}
+	if !dryRun {
+		clu.Status.Phase = v1.ClusterUpdating
+		if clu, err = h.clusterOperator.UpdateCluster(ctx, clu); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		if op, err = h.opOperator.CreateOperation(ctx, op); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
 		}
-	}(op, &service.Options{DryRun: dryRun}, pcs)
+	}
+	go h.doOperation(context.TODO(), op, &service.Options{DryRun: dryRun})
 
 	_ = response.WriteHeaderAndEntity(http.StatusOK, clu)
 }
 
+// prependCRIRegistriesStep runs the step which updates the registries of container runtime at first,
+// if the registries of plugins are not configured yet.
+func (h *handler) prependCRIRegistriesStep(ctx context.Context, clu *v1.Cluster, op *v1.Operation) error {
+	// cluster.Status.Registries will be updated
+	statusRegistry, err := h.getClusterCRIRegistries(ctx, clu)
+	if err != nil {
+		return err
+	}
+	criStep, err := h.getCRIRegistriesStep(ctx, clu, statusRegistry)
+	if err != nil {
+		return err
+	}
+	if criStep != nil {
+		op.Steps = append([]v1.Step{*criStep}, op.Steps...)
+		clu.Status.Registries = statusRegistry
+	}
+	return nil
+}
+
 func (h *handler) UpgradeCluster(request *restful.Request, response *restful.Response) {
 	name := request.PathParameter(query.ParameterName)
 	body := &ClusterUpgrade{}
@@ -2135,6 +2707,11 @@ func (h *handler) UpgradeCluster(request *restful.Request, response *restful.Res
 	if v := request.QueryParameter("timeout"); v != "" {
 		timeoutSecs = v
 	}
+	priority, err := getPriority(request)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
 	extraMeta, err := h.getClusterMetadata(request.Request.Context(), clu, false)
 	if err != nil {
 		if apimachineryErrors.IsNotFound(err) || err == ErrNodesRegionDifferent {
@@ -2145,30 +2722,51 @@ func (h *handler) UpgradeCluster(request *restful.Request, response *restful.Res
 		return
 	}
 	extraMeta.Offline = body.Offline
-	extraMeta.KubeVersion = body.Version
 	extraMeta.LocalRegistry = body.LocalRegistry
-	upgradeComp := &k8s.Upgrade{}
-	upgradeComp.InitStepper(extraMeta, clu)
-	if err := upgradeComp.Validate(); err != nil {
+	available, err := h.availableKubeVersions(request.Request.Context(), clu, !body.Offline)
+	if err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	path, err := k8s.UpgradePath(clu.KubernetesVersion, body.Version, available)
+	if err != nil {
 		restplus.HandleBadRequest(response, request, err)
 		return
 	}
-
-	if err := upgradeComp.InitSteps(component.WithExtraMetadata(context.TODO(), *extraMeta)); err != nil {
+	op, err := makeUpgradeOperation(*extraMeta, clu, path, body.Strategy)
+	if err != nil {
 		restplus.HandleBadRequest(response, request, err)
 		return
 	}
-
-	op := &v1.Operation{}
 	op.Name = uuid.New().String()
 	op.Labels = map[string]string{
 		common.LabelClusterName:    clu.Name,
 		common.LabelTopologyRegion: extraMeta.Masters[0].Region,
 	}
-	op.Steps = upgradeComp.GetInstallSteps()
+	op.Labels[common.LabelTimeoutSeconds] = timeoutSecs
+	op.Labels[common.LabelOperationAction] = v1.OperationUpgradeCluster
+	op.Labels[common.LabelUpgradeVersion] = body.Version
+	op.Status.Status = v1.OperationStatusRunning
+	if query.IsDryRunPlan(request) {
+		writeOperationPlan(response, op)
+		return
+	}
 
 	// TODO: make dry run path to etcd
 	if !dryRun {
+		queued, err := h.mustQueueOperation(request.Request.Context(), clu)
+		if err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		if queued {
+			if _, err = h.enqueuePrebuiltOperation(request.Request.Context(), clu, op, priority); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+			response.WriteHeader(http.StatusOK)
+			return
+		}
 		clu.Status.Phase = v1.ClusterUpgrading
 		_, err = h.clusterOperator.UpdateCluster(request.Request.Context(), clu)
 		if err != nil {
@@ -2177,10 +2775,6 @@ func (h *handler) UpgradeCluster(request *restful.Request, response *restful.Res
 		}
 	}
 
-	op.Labels[common.LabelTimeoutSeconds] = timeoutSecs
-	op.Labels[common.LabelOperationAction] = v1.OperationUpgradeCluster
-	op.Labels[common.LabelUpgradeVersion] = body.Version
-	op.Status.Status = v1.OperationStatusRunning
 	if !dryRun {
 		op, err = h.opOperator.CreateOperation(context.TODO(), op)
 		if err != nil {
@@ -2192,6 +2786,197 @@ func (h *handler) UpgradeCluster(request *restful.Request, response *restful.Res
 	response.WriteHeader(http.StatusOK)
 }
 
+// availableKubeVersions returns the kubernetes versions whose packages are available for all architectures of the cluster nodes.
+func (h *handler) availableKubeVersions(ctx context.Context, c *v1.Cluster, online bool) ([]string, error) {
+	arches := sets.NewString()
+	for _, id := range append(c.Masters.GetNodeIDs(), c.Workers.GetNodeIDs()...) {
+		node, err := h.clusterOperator.GetNodeEx(ctx, id, "0")
+		if err != nil {
+			return nil, err
+		}
+		arches.Insert(node.Status.NodeInfo.Arch)
+	}
+	metas := scheme.PackageMetadata{}
+	if err := metas.ReadMetadata(online, h.staticServerOptions.Path); err != nil {
+		return nil, err
+	}
+	var versions []string
+	for _, addon := range metas.Addons {
+		if addon.Name != k8s.K8s || addon.Arch != arches.List()[0] {
+			continue
+		}
+		exist := true
+		for _, arch := range arches.List() {
+			if !metas.AddonsExist(k8s.K8s, addon.Version, arch) {
+				exist = false
+				break
+			}
+		}
+		if exist {
+			versions = append(versions, addon.Version)
+		}
+	}
+	return versions, nil
+}
+
+func (h *handler) MigrateContainerRuntime(request *restful.Request, response *restful.Response) {
+	name := request.PathParameter(query.ParameterName)
+	body := &clusteroperation.MigrateRuntime{}
+	if err := request.ReadEntity(body); err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	timeoutSecs := v1.DefaultOperationTimeoutSecs
+	if v := request.QueryParameter("timeout"); v != "" {
+		timeoutSecs = v
+	}
+	dryRun := query.GetBoolValueWithDefault(request, query.ParamDryRun, false)
+	priority, err := getPriority(request)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	ctx := request.Request.Context()
+	c, err := h.clusterOperator.GetClusterEx(ctx, name, "0")
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(response, request, err)
+			return
+		}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+
+	info, _ := reqpkg.InfoFrom(ctx)
+	if info.IsProjectScope() {
+		project := request.PathParameter("project")
+		if c.Labels[common.LabelProject] != project {
+			restplus.HandleBadRequest(response, request, fmt.Errorf("cluster %s not belong to project %s", name, project))
+			return
+		}
+	}
+
+	// the nodes must not be changed while they are migrating one by one, so the migration waits in the queue
+	// while other operations are running.
+	queued, err := h.mustQueueOperation(ctx, c)
+	if err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	if !queued && c.Status.Phase != v1.ClusterRunning {
+		restplus.HandleBadRequest(response, request, fmt.Errorf("cluster %s is %s, only running cluster can migrate container runtime", name, c.Status.Phase))
+		return
+	}
+	if err = body.Validate(c); err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+
+	if !dryRun {
+		pendingOperation, err := buildPendingOperation(v1.OperationMigrateContainerRuntime, timeoutSecs, c.ResourceVersion, body)
+		if err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		if queued {
+			if c, err = h.enqueueOperation(ctx, c, pendingOperation, priority); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+			_ = response.WriteHeaderAndEntity(http.StatusOK, redactCluster(c))
+			return
+		}
+
+		c.Status.Phase = v1.ClusterUpdating
+		c.PendingOperations = append(c.PendingOperations, pendingOperation)
+		if c, err = h.clusterOperator.UpdateCluster(ctx, c); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+	}
+
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactCluster(c))
+}
+
+func (h *handler) UpdateClusterConfig(request *restful.Request, response *restful.Response) {
+	name := request.PathParameter(query.ParameterName)
+	body := &v1.ClusterConfig{}
+	if err := request.ReadEntity(body); err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	timeoutSecs := v1.DefaultOperationTimeoutSecs
+	if v := request.QueryParameter("timeout"); v != "" {
+		timeoutSecs = v
+	}
+	dryRun := query.GetBoolValueWithDefault(request, query.ParamDryRun, false)
+	priority, err := getPriority(request)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	ctx := request.Request.Context()
+	c, err := h.clusterOperator.GetClusterEx(ctx, name, "0")
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(response, request, err)
+			return
+		}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+
+	info, _ := reqpkg.InfoFrom(ctx)
+	if info.IsProjectScope() {
+		project := request.PathParameter("project")
+		if c.Labels[common.LabelProject] != project {
+			restplus.HandleBadRequest(response, request, fmt.Errorf("cluster %s not belong to project %s", name, project))
+			return
+		}
+	}
+
+	// the nodes must not be changed while they are restarted one by one, so the update waits in the queue
+	// while other operations are running.
+	queued, err := h.mustQueueOperation(ctx, c)
+	if err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	if !queued && c.Status.Phase != v1.ClusterRunning {
+		restplus.HandleBadRequest(response, request, fmt.Errorf("cluster %s is %s, only running cluster can update config", name, c.Status.Phase))
+		return
+	}
+	if err = body.Validate(); err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+
+	if !dryRun {
+		pendingOperation, err := buildPendingOperation(v1.OperationUpdateClusterConfig, timeoutSecs, c.ResourceVersion, body)
+		if err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		if queued {
+			if c, err = h.enqueueOperation(ctx, c, pendingOperation, priority); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+			_ = response.WriteHeaderAndEntity(http.StatusOK, redactCluster(c))
+			return
+		}
+
+		c.Status.Phase = v1.ClusterUpdating
+		c.PendingOperations = append(c.PendingOperations, pendingOperation)
+		if c, err = h.clusterOperator.UpdateCluster(ctx, c); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+	}
+
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactCluster(c))
+}
+
 func (h *handler) ResetClusterStatus(request *restful.Request, response *restful.Response) {
 	dryRun := query.GetBoolValueWithDefault(request, query.ParamDryRun, false)
 	cluName := request.PathParameter(query.ParameterName)
@@ -3102,8 +3887,14 @@ func (h *handler) CreateTemplate(request *restful.Request, response *restful.Res
 		restplus.HandleBadRequest(response, request, fmt.Errorf("template '%s' already exists", template.Annotations[common.AnnotationDisplayName]))
 		return
 	}
+	if err = validateTemplate(template); err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
 
 	template.ObjectMeta.GenerateName = "tmpl-"
+	template.Revision = 1
+	template.History = nil
 	template, err = h.clusterOperator.CreateTemplate(request.Request.Context(), template)
 	if err != nil {
 		restplus.HandleInternalError(response, request, err)
@@ -3124,6 +3915,20 @@ func (h *handler) UpdateTemplate(request *restful.Request, response *restful.Res
 		restplus.HandleBadRequest(response, request, fmt.Errorf("template name not match"))
 		return
 	}
+	if err = validateTemplate(template); err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	previous, err := h.clusterOperator.GetTemplate(request.Request.Context(), templateName)
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(response, request, err)
+			return
+		}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	nextTemplateRevision(template, previous)
 	template, err = h.clusterOperator.UpdateTemplate(request.Request.Context(), template)
 	if err != nil {
 		restplus.HandleInternalError(response, request, err)
@@ -3180,7 +3985,7 @@ func (h *handler) DescribeBackupPoint(request *restful.Request, response *restfu
 		restplus.HandleInternalError(response, request, err)
 		return
 	}
-	_ = response.WriteHeaderAndEntity(http.StatusOK, result)
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactBackupPoint(result))
 }
 
 func (h *handler) ListBackupPoints(request *restful.Request, response *restful.Response) {
@@ -3211,6 +4016,12 @@ func (h *handler) ListBackupPoints(request *restful.Request, response *restful.R
 			restplus.HandleInternalError(response, request, err)
 			return
 		}
+		if redactResponse(request) {
+			if err = redactList(result, redactBackupPoint); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+		}
 		_ = response.WriteHeaderAndEntity(http.StatusOK, result)
 	} else {
 		result, err := h.clusterOperator.ListBackupPointEx(ctx, q)
@@ -3218,6 +4029,7 @@ func (h *handler) ListBackupPoints(request *restful.Request, response *restful.R
 			restplus.HandleInternalError(response, request, err)
 			return
 		}
+		redactPageableResponse(result, redactBackupPoint)
 		_ = response.WriteHeaderAndEntity(http.StatusOK, result)
 	}
 }
@@ -3236,6 +4048,9 @@ func (h *handler) watchBackupPoints(req *restful.Request, resp *restful.Response
 		restplus.HandleInternalError(resp, req, err)
 		return
 	}
+	if redactResponse(req) {
+		watcher = redactWatch(watcher, redactBackupPoint)
+	}
 	restplus.ServeWatch(watcher, v1.SchemeGroupVersion.WithKind("BackupPoint"), req, resp, timeout)
 }
 
@@ -3278,6 +4093,7 @@ func (h *handler) ListBackupPointsProject(req *restful.Request, resp *restful.Re
 		restplus.HandleInternalError(resp, req, err)
 		return
 	}
+	redactPageableResponse(result, redactBackupPoint)
 	_ = resp.WriteHeaderAndEntity(http.StatusOK, result)
 }
 
@@ -3297,6 +4113,9 @@ func (h *handler) watchBackupPointsProject(req *restful.Request, resp *restful.R
 			restplus.HandleInternalError(resp, req, err)
 			return
 		}
+		if redactResponse(req) {
+			watcher = redactWatch(watcher, redactBackupPoint)
+		}
 		watchers = append(watchers, watcher)
 	}
 	multiWatcher := restplus.NewMultiWatcher(watchers)
@@ -3315,7 +4134,21 @@ func (h *handler) CreateBackupPoint(request *restful.Request, response *restful.
 		restplus.HandleBadRequest(response, request, fmt.Errorf("bucket name cannot be shorter than 3 characters"))
 		return
 	}
+	if bp.StorageType == bs.SFTPStorage {
+		if err := validateSftpConfig(bp.SftpConfig); err != nil {
+			restplus.HandleBadRequest(response, request, err)
+			return
+		}
+	}
+	if bp.Encryption != nil && bp.Encryption.Key != "" {
+		if _, err := core.ParseBackupKey(bp.Encryption.Key); err != nil {
+			restplus.HandleBadRequest(response, request, err)
+			return
+		}
+	}
 
+	// the status is updated by the server.
+	bp.Status = v1.BackupPointStatus{}
 	createdBp, err := h.clusterOperator.CreateBackupPoint(request.Request.Context(), bp)
 	if err != nil {
 		if apimachineryErrors.IsAlreadyExists(err) {
@@ -3325,7 +4158,7 @@ func (h *handler) CreateBackupPoint(request *restful.Request, response *restful.
 		restplus.HandleInternalError(response, request, err)
 		return
 	}
-	_ = response.WriteHeaderAndEntity(http.StatusOK, createdBp)
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactBackupPoint(createdBp))
 }
 
 func (h *handler) DeleteBackupPoint(request *restful.Request, response *restful.Response) {
@@ -3353,6 +4186,125 @@ func (h *handler) DeleteBackupPoint(request *restful.Request, response *restful.
 	response.WriteHeader(http.StatusOK)
 }
 
+// ImportBackup imports the backup file saved in the backup point as the backup of cluster, e.g. the file was created
+// by another kubeclipper. The file is downloaded to record its size and md5. The nodes of cluster are recorded as the
+// nodes of backup, which the recovery checks against, so the caller must confirm the backup was taken from them.
+func (h *handler) ImportBackup(request *restful.Request, response *restful.Response) {
+	ib := &ImportBackup{}
+	if err := request.ReadEntity(ib); err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	if ib.FileName == "" || ib.ClusterName == "" || ib.KubernetesVersion == "" {
+		restplus.HandleBadRequest(response, request, fmt.Errorf("file name, cluster name and kubernetes version must be provided"))
+		return
+	}
+	ver, err := k8sversion.ParseSemantic(ib.KubernetesVersion)
+	if err != nil {
+		restplus.HandleBadRequest(response, request, fmt.Errorf("kubernetes version %s is invalid: %w", ib.KubernetesVersion, err))
+		return
+	}
+	if errs := utilvalidation.IsDNS1123Subdomain(ib.FileName); len(errs) > 0 {
+		restplus.HandleBadRequest(response, request, fmt.Errorf("file name %s can't be used as backup name: %s", ib.FileName, strings.Join(errs, ", ")))
+		return
+	}
+	if !ib.ConfirmNodes {
+		restplus.HandleBadRequest(response, request, fmt.Errorf("the recovery can't check the nodes of imported backup, "+
+			"confirm that backup file %s was taken from the nodes of cluster %s", ib.FileName, ib.ClusterName))
+		return
+	}
+
+	ctx := request.Request.Context()
+	bp, err := h.clusterOperator.GetBackupPointEx(ctx, request.PathParameter(query.ParameterName), "0")
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(response, request, err)
+			return
+		}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	if err = backuppointcontroller.Listable(bp); err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	c, err := h.clusterOperator.GetClusterEx(ctx, ib.ClusterName, "0")
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(response, request, err)
+			return
+		}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+
+	backups, err := h.clusterOperator.ListBackups(ctx, query.New())
+	if err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	for _, b := range backups.Items {
+		if b.Name == ib.FileName || (b.BackupPointName == bp.Name && b.Status.FileName == ib.FileName) {
+			restplus.HandleBadRequest(response, request, fmt.Errorf("backup file %s is already imported by backup %s", ib.FileName, b.Name))
+			return
+		}
+	}
+
+	status, err := backuppointcontroller.InspectBackup(ctx, h.coreOperator, bp, ib.FileName)
+	if err != nil {
+		if errors.Is(err, bs.ErrNotFound) {
+			restplus.HandleNotFound(response, request, err)
+			return
+		}
+		if errors.Is(err, core.ErrBackupKeyNotFound) {
+			restplus.HandleBadRequest(response, request, fmt.Errorf("backup file %s can't be decrypted, "+
+				"set its key as the encryption key of backup point %s to import it: %w", ib.FileName, bp.Name, err))
+			return
+		}
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	q := query.New()
+	q.LabelSelector = fmt.Sprintf("%s=%s", common.LabelClusterName, c.Name)
+	nodeList, err := h.clusterOperator.ListNodes(ctx, q)
+	if err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+
+	backup := &v1.Backup{}
+	backup.Name = ib.FileName
+	backup.Labels = map[string]string{common.LabelClusterName: c.Name}
+	backup.Annotations = map[string]string{common.AnnotationDescription: ib.Description}
+	backup.BackupPointName = bp.Name
+	backup.PreferredNode = c.Masters[0].ID
+	backup.ClusterNodes = make(map[string]string)
+	for _, node := range nodeList.Items {
+		backup.ClusterNodes[node.Status.Ipv4DefaultIP] = node.Status.NodeInfo.Hostname
+	}
+	backup.Status = *status
+	// the version is formatted as the version of cluster is, e.g. v1.23.6.
+	backup.Status.KubernetesVersion = "v" + ver.String()
+	verified := metav1.Now()
+	backup.Status.VerifiedTime = &verified
+	if backup, err = h.clusterOperator.CreateBackup(ctx, backup); err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+
+	// the file is no longer an orphan, it is not offered again.
+	for i, f := range bp.Status.OrphanBackups {
+		if f.FileName == ib.FileName {
+			bp.Status.OrphanBackups = append(bp.Status.OrphanBackups[:i], bp.Status.OrphanBackups[i+1:]...)
+			if _, err = h.clusterOperator.UpdateBackupPoint(ctx, bp); err != nil {
+				logger.Warn("remove imported backup from orphans failed", zap.String("backupPoint", bp.Name), zap.Error(err))
+			}
+			break
+		}
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, backup)
+}
+
 func (h *handler) UpdateBackupPoint(req *restful.Request, resp *restful.Response) {
 	bp := &v1.BackupPoint{}
 	if err := req.ReadEntity(bp); err != nil {
@@ -3375,7 +4327,24 @@ func (h *handler) UpdateBackupPoint(req *restful.Request, resp *restful.Response
 
 	if bp.StorageType == bs.S3Storage && obp.StorageType == bs.S3Storage && bp.FsConfig == nil {
 		obp.S3Config.AccessKeyID = bp.S3Config.AccessKeyID
-		obp.S3Config.AccessKeySecret = bp.S3Config.AccessKeySecret
+		obp.S3Config.AccessKeySecret = unredactString(bp.S3Config.AccessKeySecret, obp.S3Config.AccessKeySecret)
+		obp.Description = bp.Description
+	}
+
+	if bp.StorageType == bs.SFTPStorage && obp.StorageType == bs.SFTPStorage && bp.SftpConfig != nil && obp.SftpConfig != nil {
+		// the login of sftp backup point can be modified, the backups are kept on the same host and directory.
+		bp.SftpConfig.Password = unredactString(bp.SftpConfig.Password, obp.SftpConfig.Password)
+		bp.SftpConfig.PrivateKey = unredactString(bp.SftpConfig.PrivateKey, obp.SftpConfig.PrivateKey)
+		bp.SftpConfig.PkPassword = unredactString(bp.SftpConfig.PkPassword, obp.SftpConfig.PkPassword)
+		if err = validateSftpConfig(bp.SftpConfig); err != nil {
+			restplus.HandleBadRequest(resp, req, err)
+			return
+		}
+		obp.SftpConfig.Port = bp.SftpConfig.Port
+		obp.SftpConfig.User = bp.SftpConfig.User
+		obp.SftpConfig.Password = bp.SftpConfig.Password
+		obp.SftpConfig.PrivateKey = bp.SftpConfig.PrivateKey
+		obp.SftpConfig.PkPassword = bp.SftpConfig.PkPassword
 		obp.Description = bp.Description
 	}
 
@@ -3385,7 +4354,7 @@ func (h *handler) UpdateBackupPoint(req *restful.Request, resp *restful.Response
 		return
 	}
 
-	_ = resp.WriteHeaderAndEntity(http.StatusOK, obp)
+	_ = resp.WriteHeaderAndEntity(http.StatusOK, redactBackupPoint(obp))
 }
 
 func (h *handler) DescribeCronBackup(request *restful.Request, response *restful.Response) {
@@ -3452,6 +4421,10 @@ func (h *handler) CreateCronBackup(request *restful.Request, response *restful.R
 			return
 		}
 	}
+	if errs := validation.ValidateCronBackupRetention(cb.Spec.Retention, field.NewPath("spec", "retention")); len(errs) > 0 {
+		restplus.HandleBadRequest(response, request, errs.ToAggregate())
+		return
+	}
 
 	ok, err := h.checkCronBackupExist(request.Request.Context(), cb.Name, cb.Spec.ClusterName)
 	if err != nil {
@@ -3513,6 +4486,10 @@ func (h *handler) UpdateCronBackup(req *restful.Request, resp *restful.Response)
 		restplus.HandleBadRequest(resp, req, err)
 		return
 	}
+	if errs := validation.ValidateCronBackupRetention(cb.Spec.Retention, field.NewPath("spec", "retention")); len(errs) > 0 {
+		restplus.HandleBadRequest(resp, req, errs.ToAggregate())
+		return
+	}
 	name := req.PathParameter(query.ParameterName)
 	resourceVersion := strutil.StringDefaultIfEmpty("0", req.QueryParameter(query.ParameterResourceVersion))
 	ocb, err := h.clusterOperator.GetCronBackupEx(req.Request.Context(), name, resourceVersion)
@@ -3546,6 +4523,7 @@ func (h *handler) UpdateCronBackup(req *restful.Request, resp *restful.Response)
 	}
 	ocb.Spec.Schedule = cb.Spec.Schedule
 	ocb.Spec.MaxBackupNum = cb.Spec.MaxBackupNum
+	ocb.Spec.Retention = cb.Spec.Retention
 	_, err = h.clusterOperator.UpdateCronBackup(req.Request.Context(), ocb)
 	if err != nil {
 		restplus.HandleInternalError(resp, req, err)
@@ -3648,6 +4626,45 @@ func (h *handler) ListBackupsWithCronBackup(req *restful.Request, resp *restful.
 	_ = resp.WriteHeaderAndEntity(http.StatusOK, result)
 }
 
+func (h *handler) ListCronBackupRetention(req *restful.Request, resp *restful.Response) {
+	cronBackupName := req.PathParameter(query.ParameterName)
+	ctx := req.Request.Context()
+	cronBackup, err := h.clusterOperator.GetCronBackupEx(ctx, cronBackupName, "0")
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(resp, req, err)
+			return
+		}
+		restplus.HandleInternalError(resp, req, err)
+		return
+	}
+	backupList, err := h.clusterOperator.ListBackups(ctx, query.New())
+	if err != nil {
+		restplus.HandleInternalError(resp, req, err)
+		return
+	}
+	var backups []*v1.Backup
+	for i := range backupList.Items {
+		if uid, ok := cronbackupcontroller.GetParentUIDFromBackup(&backupList.Items[i]); ok && uid == cronBackup.UID {
+			backups = append(backups, &backupList.Items[i])
+		}
+	}
+	buckets := cronbackupcontroller.RetentionBuckets(cronBackup, backups)
+	result := make([]BackupRetention, 0, len(backups))
+	for _, b := range backups {
+		result = append(result, BackupRetention{
+			Name:                b.Name,
+			CreationTimestamp:   b.CreationTimestamp,
+			ClusterBackupStatus: b.Status.ClusterBackupStatus,
+			Buckets:             buckets[b.Name],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[j].CreationTimestamp.Before(&result[i].CreationTimestamp)
+	})
+	_ = resp.WriteHeaderAndEntity(http.StatusOK, result)
+}
+
 func (h *handler) ListConfigMaps(req *restful.Request, resp *restful.Response) {
 	q := query.ParseQueryParameter(req)
 	if q.Watch {
@@ -0,0 +1,230 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/emicklei/go-restful"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/watch"
+
+	"github.com/kubeclipper/kubeclipper/pkg/authentication/request/internaltoken"
+	"github.com/kubeclipper/kubeclipper/pkg/clusteroperation"
+	"github.com/kubeclipper/kubeclipper/pkg/models"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	apirequest "github.com/kubeclipper/kubeclipper/pkg/server/request"
+)
+
+const redacted = "******"
+
+var (
+	// sensitiveField matches the field names of step commands whose values are redacted in the operation plan.
+	sensitiveField = regexp.MustCompile(`(?i)(password|passwd|token|secret|credential|privatekey|private_key|cakey|ca_key|certificatekey|accesskey|access_key|kubeconfig|key$)`)
+	// nonSensitiveKeyFields the field names ending in "key" whose values are not secrets, in lower case.
+	nonSensitiveKeyFields = sets.NewString("publickey", "instancenamekey")
+	// sensitiveFlag matches the shell arguments like "--password=xxx" or "token: xxx".
+	sensitiveFlag = regexp.MustCompile(`(?i)((?:password|passwd|token|secret)\S*?(?:=|:\s*|\s+))(\S+)`)
+	// sensitiveFlagName matches the shell arguments whose next argument is a secret, e.g. "--password xxx".
+	sensitiveFlagName = regexp.MustCompile(`(?i)^--?[a-z-]*(password|passwd|token|secret)$`)
+)
+
+// writeOperationPlan responds the operation which would be run, the secrets in its commands are redacted.
+func writeOperationPlan(response *restful.Response, op *v1.Operation) {
+	_ = response.WriteHeaderAndEntity(http.StatusOK, redactOperation(op))
+}
+
+// planPendingOperation builds the operation of pending operation as the cluster controller does.
+func (h *handler) planPendingOperation(ctx context.Context, c *v1.Cluster, pendingOperation v1.PendingOperation) (*v1.Operation, error) {
+	extraMeta, err := h.getClusterMetadata(ctx, c, false)
+	if err != nil {
+		return nil, err
+	}
+	extraMeta.OperationID = pendingOperation.OperationID
+	return clusteroperation.BuildOperationAdapter(c, pendingOperation, extraMeta, nil)
+}
+
+// redactResponse the secrets are redacted in the responses of users, only the internal user of server, e.g. its
+// informers, gets them as they are. The informer query header only selects the format of response.
+func redactResponse(request *restful.Request) bool {
+	u, ok := apirequest.UserFrom(request.Request.Context())
+	return !ok || !internaltoken.IsInternalUser(u)
+}
+
+// redactList redacts the items of raw list response in place.
+func redactList(list runtime.Object, redact func(runtime.Object) runtime.Object) error {
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		items[i] = redact(items[i])
+	}
+	return meta.SetList(list, items)
+}
+
+// redactPageableResponse redacts the items of list response in place.
+func redactPageableResponse(result *models.PageableResponse, redact func(runtime.Object) runtime.Object) {
+	for i, item := range result.Items {
+		if obj, ok := item.(runtime.Object); ok {
+			result.Items[i] = redact(obj)
+		}
+	}
+}
+
+// redactWatch redacts the objects sent by the watcher.
+func redactWatch(watcher watch.Interface, redact func(runtime.Object) runtime.Object) watch.Interface {
+	return watch.Filter(watcher, func(in watch.Event) (watch.Event, bool) {
+		if in.Type != watch.Error && in.Object != nil {
+			in.Object = redact(in.Object)
+		}
+		return in, true
+	})
+}
+
+func redactOperationObject(obj runtime.Object) runtime.Object {
+	if op, ok := obj.(*v1.Operation); ok {
+		return redactOperation(op)
+	}
+	return obj
+}
+
+// redactCluster masks the client key of external etcd, the key is never changed by updating the cluster.
+func redactCluster(obj runtime.Object) runtime.Object {
+	c, ok := obj.(*v1.Cluster)
+	if !ok || c.Etcd.External == nil || c.Etcd.External.ClientKey == "" {
+		return obj
+	}
+	out := c.DeepCopy()
+	out.Etcd.External.ClientKey = redacted
+	return out
+}
+
+// redactBackupPoint masks the credentials of storage and the encryption key of backup point, the masked values
+// sent back by updating the backup point keep the saved ones, and the key is never changed by updating.
+func redactBackupPoint(obj runtime.Object) runtime.Object {
+	bp, ok := obj.(*v1.BackupPoint)
+	if !ok {
+		return obj
+	}
+	out := bp.DeepCopy()
+	if out.S3Config != nil {
+		out.S3Config.AccessKeySecret = redactString(out.S3Config.AccessKeySecret)
+	}
+	if out.SftpConfig != nil {
+		out.SftpConfig.Password = redactString(out.SftpConfig.Password)
+		out.SftpConfig.PrivateKey = redactString(out.SftpConfig.PrivateKey)
+		out.SftpConfig.PkPassword = redactString(out.SftpConfig.PkPassword)
+	}
+	if out.Encryption != nil {
+		out.Encryption.Key = redactString(out.Encryption.Key)
+	}
+	return out
+}
+
+// redactString masks the secret unless it is empty.
+func redactString(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redacted
+}
+
+// unredactString returns the saved secret if the masked value is sent back.
+func unredactString(secret, saved string) string {
+	if secret == redacted {
+		return saved
+	}
+	return secret
+}
+
+func redactOperation(op *v1.Operation) *v1.Operation {
+	out := op.DeepCopy()
+	for i := range out.Steps {
+		step := &out.Steps[i]
+		for _, cmds := range [][]v1.Command{step.BeforeRunCommands, step.Commands, step.AfterRunCommands} {
+			for j := range cmds {
+				redactCommand(&cmds[j])
+			}
+		}
+	}
+	return out
+}
+
+func redactCommand(c *v1.Command) {
+	for i, arg := range c.ShellCommand {
+		if i > 0 && sensitiveFlagName.MatchString(c.ShellCommand[i-1]) {
+			c.ShellCommand[i] = redacted
+			continue
+		}
+		c.ShellCommand[i] = sensitiveFlag.ReplaceAllString(arg, "${1}"+redacted)
+	}
+	c.CustomCommand = redactJSON(c.CustomCommand)
+	if c.Template != nil {
+		c.Template.Data = redactJSON(c.Template.Data)
+	}
+}
+
+// redactJSON returns data as it is if data is not json.
+func redactJSON(data []byte) []byte {
+	if len(data) == 0 {
+		return data
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return data
+	}
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+// isSensitiveField any field ending in "key" is a secret, e.g. the client key of external etcd,
+// except the ones in nonSensitiveKeyFields.
+func isSensitiveField(name string) bool {
+	return sensitiveField.MatchString(name) && !nonSensitiveKeyFields.Has(strings.ToLower(name))
+}
+
+func redactValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if isSensitiveField(k) && item != nil && item != "" {
+				val[k] = redacted
+				continue
+			}
+			val[k] = redactValue(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactValue(item)
+		}
+	case string:
+		return sensitiveFlag.ReplaceAllString(val, "${1}"+redacted)
+	}
+	return v
+}
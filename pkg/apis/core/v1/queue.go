@@ -0,0 +1,278 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	apimachineryErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/kubeclipper/kubeclipper/pkg/clusteroperation"
+	"github.com/kubeclipper/kubeclipper/pkg/query"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	reqpkg "github.com/kubeclipper/kubeclipper/pkg/server/request"
+	"github.com/kubeclipper/kubeclipper/pkg/server/restplus"
+)
+
+// getPriority gets the priority of the queued operation in query, defaults to 0.
+func getPriority(request *restful.Request) (int32, error) {
+	v := request.QueryParameter(query.ParamPriority)
+	if v == "" {
+		return 0, nil
+	}
+	priority, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid priority %s: %w", v, err)
+	}
+	return int32(priority), nil
+}
+
+// mustQueueNodesOperation whether the nodes operation must wait in the queue of cluster.
+func (h *handler) mustQueueNodesOperation(ctx context.Context, c *v1.Cluster, opType string, role common.NodeRole) (bool, error) {
+	if len(c.QueuedOperations) > 0 {
+		return true, nil
+	}
+	executable, err := clusteroperation.Executable(ctx, opType, c.Name, h.opOperator)
+	if err != nil || !executable {
+		return err == nil, err
+	}
+	// the nodes joined during the migration would keep using the old container runtime.
+	migrating, err := clusteroperation.IsRunning(ctx, v1.OperationMigrateContainerRuntime, c.Name, h.opOperator)
+	if err != nil || migrating {
+		return migrating, err
+	}
+	// the control plane nodes must be changed one operation at a time, otherwise the etcd membership may be broken.
+	if role != common.NodeRoleMaster {
+		return false, nil
+	}
+	for _, t := range []string{v1.OperationAddNodes, v1.OperationRemoveNodes} {
+		running, err := clusteroperation.IsRunning(ctx, t, c.Name, h.opOperator)
+		if err != nil || running {
+			return running, err
+		}
+	}
+	return false, nil
+}
+
+// mustQueueOperation whether the operation must wait in the queue of cluster, because other operations
+// are running or already waiting.
+func (h *handler) mustQueueOperation(ctx context.Context, c *v1.Cluster) (bool, error) {
+	if len(c.QueuedOperations) > 0 || len(c.PendingOperations) > 0 {
+		return true, nil
+	}
+	return clusteroperation.HasRunning(ctx, c.Name, h.opOperator)
+}
+
+// enqueueOperation adds the pending operation to the queue of cluster.
+func (h *handler) enqueueOperation(ctx context.Context, c *v1.Cluster, pendingOp v1.PendingOperation, priority int32) (*v1.Cluster, error) {
+	clusteroperation.Enqueue(c, pendingOp, priority, time.Now())
+	return h.clusterOperator.UpdateCluster(ctx, c)
+}
+
+// enqueuePrebuiltOperation adds the operation built by the request to the queue of cluster,
+// it starts as it is unless the nodes of cluster are changed before.
+func (h *handler) enqueuePrebuiltOperation(ctx context.Context, c *v1.Cluster, op *v1.Operation, priority int32) (*v1.Cluster, error) {
+	pendingOp, err := clusteroperation.NewPrebuiltPendingOperation(c, op)
+	if err != nil {
+		return nil, err
+	}
+	return h.enqueueOperation(ctx, c, pendingOp, priority)
+}
+
+// getRequestCluster gets the cluster in path, the project scoped request can only get the clusters of its project.
+func (h *handler) getRequestCluster(request *restful.Request, response *restful.Response) (*v1.Cluster, bool) {
+	name := request.PathParameter(query.ParameterName)
+	c, err := h.clusterOperator.GetClusterEx(request.Request.Context(), name, "0")
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			restplus.HandleNotFound(response, request, err)
+			return nil, false
+		}
+		restplus.HandleInternalError(response, request, err)
+		return nil, false
+	}
+	info, _ := reqpkg.InfoFrom(request.Request.Context())
+	if project := request.PathParameter("project"); info.IsProjectScope() && c.Labels[common.LabelProject] != project {
+		restplus.HandleBadRequest(response, request, fmt.Errorf("cluster %s not belong to project %s", name, project))
+		return nil, false
+	}
+	return c, true
+}
+
+func (h *handler) DescribeOperationQueue(request *restful.Request, response *restful.Response) {
+	c, ok := h.getRequestCluster(request, response)
+	if !ok {
+		return
+	}
+	h.writeOperationQueue(request, response, c)
+}
+
+func (h *handler) writeOperationQueue(request *restful.Request, response *restful.Response, c *v1.Cluster) {
+	ops, err := clusteroperation.ListRunning(request.Request.Context(), c.Name, h.opOperator)
+	if err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	queue := OperationQueue{
+		Running: make([]RunningOperation, 0, len(ops)),
+		Pending: c.PendingOperations,
+		Queued:  c.QueuedOperations,
+	}
+	for _, op := range ops {
+		queue.Running = append(queue.Running, RunningOperation{
+			Name:          op.Name,
+			OperationType: op.Labels[common.LabelOperationAction],
+			Status:        op.Status.Status,
+		})
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusOK, queue)
+}
+
+// UpdateQueuedOperation changes the priority of the queued operation, which reorders the queue.
+func (h *handler) UpdateQueuedOperation(request *restful.Request, response *restful.Response) {
+	var p QueuedOperationPriority
+	if err := request.ReadEntity(&p); err != nil {
+		restplus.HandleBadRequest(response, request, err)
+		return
+	}
+	c, ok := h.updateOperationQueue(request, response, func(c *v1.Cluster, operationID string) error {
+		return clusteroperation.SetQueuedPriority(c, operationID, p.Priority)
+	})
+	if !ok {
+		return
+	}
+	h.writeOperationQueue(request, response, c)
+}
+
+// DeleteQueuedOperation removes the queued operation before it starts.
+func (h *handler) DeleteQueuedOperation(request *restful.Request, response *restful.Response) {
+	var removed v1.QueuedOperation
+	c, ok := h.updateOperationQueue(request, response, func(c *v1.Cluster, operationID string) (err error) {
+		removed, err = clusteroperation.RemoveQueued(c, operationID)
+		return err
+	})
+	if !ok {
+		return
+	}
+	var err error
+	switch removed.OperationType {
+	case v1.OperationAddNodes:
+		// the nodes to be added are not part of the cluster anymore.
+		var pn clusteroperation.PatchNodes
+		if err = json.Unmarshal(removed.ExtraData, &pn); err != nil {
+			restplus.HandleInternalError(response, request, err)
+			return
+		}
+		for _, n := range pn.Nodes {
+			if err = h.unmarkClusterNode(request.Request.Context(), c.Name, n.ID); err != nil {
+				restplus.HandleInternalError(response, request, err)
+				return
+			}
+		}
+	case v1.OperationBackupCluster, v1.OperationRecoverCluster:
+		err = h.releaseQueuedBackups(request.Request.Context(), removed)
+	}
+	if err != nil {
+		restplus.HandleInternalError(response, request, err)
+		return
+	}
+	h.writeOperationQueue(request, response, c)
+}
+
+// releaseQueuedBackups releases the backups of the removed operation, the backup to be created is deleted,
+// and the backup to be recovered from is not bound to the operation anymore.
+func (h *handler) releaseQueuedBackups(ctx context.Context, removed v1.QueuedOperation) error {
+	q := query.New()
+	q.LabelSelector = fmt.Sprintf("%s=%s", common.LabelOperationName, removed.OperationID)
+	backups, err := h.clusterOperator.ListBackups(ctx, q)
+	if err != nil {
+		return err
+	}
+	for i := range backups.Items {
+		b := &backups.Items[i]
+		if removed.OperationType == v1.OperationBackupCluster {
+			err = h.clusterOperator.DeleteBackup(ctx, b.Name)
+		} else {
+			delete(b.Labels, common.LabelOperationName)
+			_, err = h.clusterOperator.UpdateBackup(ctx, b)
+		}
+		if err != nil && !apimachineryErrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateOperationQueue updates the queue of cluster in path with the operation in path, it retries on conflict.
+func (h *handler) updateOperationQueue(request *restful.Request, response *restful.Response,
+	update func(c *v1.Cluster, operationID string) error) (*v1.Cluster, bool) {
+	c, ok := h.getRequestCluster(request, response)
+	if !ok {
+		return nil, false
+	}
+	ctx := request.Request.Context()
+	operationID := request.PathParameter("operation")
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := h.clusterOperator.GetClusterEx(ctx, c.Name, "0")
+		if err != nil {
+			return err
+		}
+		if err = update(latest, operationID); err != nil {
+			return err
+		}
+		c, err = h.clusterOperator.UpdateCluster(ctx, latest)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, clusteroperation.ErrQueuedOperationNotFound) {
+			restplus.HandleNotFound(response, request, err)
+			return nil, false
+		}
+		restplus.HandleInternalError(response, request, err)
+		return nil, false
+	}
+	return c, true
+}
+
+// unmarkClusterNode removes the role labels of the node which is marked by the cluster controller.
+func (h *handler) unmarkClusterNode(ctx context.Context, clusterName, nodeID string) error {
+	node, err := h.clusterOperator.GetNode(ctx, nodeID)
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if node.Labels[common.LabelClusterName] != clusterName {
+		return nil
+	}
+	delete(node.Labels, common.LabelNodeRole)
+	delete(node.Labels, common.LabelClusterName)
+	_, err = h.clusterOperator.UpdateNode(ctx, node)
+	return err
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/kubeclipper/kubeclipper/pkg/models/core"
 	"github.com/kubeclipper/kubeclipper/pkg/models/tenant"
 	"github.com/kubeclipper/kubeclipper/pkg/simple/generic"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/staticserver"
 
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
@@ -58,6 +59,8 @@ const (
 	CoreRegionTag  = "Core-Region"
 )
 
+const queuePriorityDesc = "the priority of the operation if it waits in the queue of cluster while other operations are running or queued, the operation of higher priority starts earlier"
+
 /*
 this is how set up web service route only
 in that case cli tool can simply call it to get api route by pass in a nil parameter
@@ -144,7 +147,9 @@ func SetupWebService(h *handler) *restful.WebService {
 		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
 		Doc("Create clusters.").
 		Reads(corev1.Cluster{}).
-		Param(webservice.QueryParameter(query.ParamDryRun, "dry run create clusters").
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run create clusters, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
+		Param(webservice.QueryParameter(query.ParamRollback, "uninstall the installed components of cluster if the creation fails").
 			Required(false).DataType("boolean")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}))
 	webservice.Route(webservice.POST("/projects/{project}/clusters").
@@ -153,7 +158,9 @@ func SetupWebService(h *handler) *restful.WebService {
 		Doc("Create clusters.").
 		Reads(corev1.Cluster{}).
 		Param(webservice.PathParameter("project", "project name")).
-		Param(webservice.QueryParameter(query.ParamDryRun, "dry run create clusters").
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run create clusters, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
+		Param(webservice.QueryParameter(query.ParamRollback, "uninstall the installed components of cluster if the creation fails").
 			Required(false).DataType("boolean")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}))
 
@@ -240,6 +247,8 @@ func SetupWebService(h *handler) *restful.WebService {
 		Param(webservice.PathParameter(query.ParameterName, "cluster name").
 			Required(true).
 			DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 	webservice.Route(webservice.POST("/projects/{project}/clusters/{name}/certification").
@@ -251,6 +260,8 @@ func SetupWebService(h *handler) *restful.WebService {
 		Param(webservice.PathParameter(query.ParameterName, "cluster name").
 			Required(true).
 			DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 
@@ -286,6 +297,12 @@ func SetupWebService(h *handler) *restful.WebService {
 		Param(webservice.PathParameter(query.ParameterName, "cluster name").
 			Required(true).
 			DataType("string")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run add or remove nodes, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
+		Param(webservice.QueryParameter(query.ParamRollback, "uninstall the added nodes if adding nodes fails").
+			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 	webservice.Route(webservice.PUT("/projects/{project}/clusters/{name}/nodes").
@@ -297,9 +314,89 @@ func SetupWebService(h *handler) *restful.WebService {
 		Param(webservice.PathParameter(query.ParameterName, "cluster name").
 			Required(true).
 			DataType("string")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run add or remove nodes, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
+		Param(webservice.QueryParameter(query.ParamRollback, "uninstall the added nodes if adding nodes fails").
+			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 
+	webservice.Route(webservice.GET("/clusters/{name}/operations/queue").
+		To(h.DescribeOperationQueue).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Describe the running, pending and queued operations of cluster.").
+		Param(webservice.PathParameter(query.ParameterName, "cluster name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), OperationQueue{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+	webservice.Route(webservice.GET("/projects/{project}/clusters/{name}/operations/queue").
+		To(h.DescribeOperationQueue).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Describe the running, pending and queued operations of cluster.").
+		Param(webservice.PathParameter("project", "project name")).
+		Param(webservice.PathParameter(query.ParameterName, "cluster name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), OperationQueue{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+
+	webservice.Route(webservice.PUT("/clusters/{name}/operations/queue/{operation}").
+		To(h.UpdateQueuedOperation).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Change the priority of queued operation, which reorders the queue.").
+		Reads(QueuedOperationPriority{}).
+		Param(webservice.PathParameter(query.ParameterName, "cluster name").
+			Required(true).
+			DataType("string")).
+		Param(webservice.PathParameter("operation", "queued operation id").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), OperationQueue{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+	webservice.Route(webservice.PUT("/projects/{project}/clusters/{name}/operations/queue/{operation}").
+		To(h.UpdateQueuedOperation).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Change the priority of queued operation, which reorders the queue.").
+		Reads(QueuedOperationPriority{}).
+		Param(webservice.PathParameter("project", "project name")).
+		Param(webservice.PathParameter(query.ParameterName, "cluster name").
+			Required(true).
+			DataType("string")).
+		Param(webservice.PathParameter("operation", "queued operation id").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), OperationQueue{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+
+	webservice.Route(webservice.DELETE("/clusters/{name}/operations/queue/{operation}").
+		To(h.DeleteQueuedOperation).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Remove queued operation before it starts.").
+		Param(webservice.PathParameter(query.ParameterName, "cluster name").
+			Required(true).
+			DataType("string")).
+		Param(webservice.PathParameter("operation", "queued operation id").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), OperationQueue{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+	webservice.Route(webservice.DELETE("/projects/{project}/clusters/{name}/operations/queue/{operation}").
+		To(h.DeleteQueuedOperation).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Remove queued operation before it starts.").
+		Param(webservice.PathParameter("project", "project name")).
+		Param(webservice.PathParameter(query.ParameterName, "cluster name").
+			Required(true).
+			DataType("string")).
+		Param(webservice.PathParameter("operation", "queued operation id").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), OperationQueue{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+
 	webservice.Route(webservice.GET("/clusters/{name}/backups").
 		To(h.ListBackupsWithCluster).
 		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
@@ -338,6 +435,8 @@ func SetupWebService(h *handler) *restful.WebService {
 			DataType("string")).
 		Param(webservice.QueryParameter(query.ParamDryRun, "dry run create clusters").
 			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Backup{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 	webservice.Route(webservice.POST("/projects/{project}/clusters/{name}/backups").
@@ -351,6 +450,8 @@ func SetupWebService(h *handler) *restful.WebService {
 			DataType("string")).
 		Param(webservice.QueryParameter(query.ParamDryRun, "dry run create clusters").
 			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Backup{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 
@@ -362,6 +463,8 @@ func SetupWebService(h *handler) *restful.WebService {
 		Param(webservice.PathParameter("backup", "backup name")).
 		Param(webservice.QueryParameter(query.ParamDryRun, "dry run create clusters").
 			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), nil).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 	webservice.Route(webservice.DELETE("/projects/{project}/clusters/{cluster}/backups/{backup}").
@@ -373,6 +476,8 @@ func SetupWebService(h *handler) *restful.WebService {
 		Param(webservice.PathParameter("backup", "backup name")).
 		Param(webservice.QueryParameter(query.ParamDryRun, "dry run create clusters").
 			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), nil).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 
@@ -394,12 +499,34 @@ func SetupWebService(h *handler) *restful.WebService {
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Backup{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 
+	webservice.Route(webservice.POST("/clusters/{cluster}/backups/{backup}/verify").
+		To(h.VerifyBackup).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Verify backup against its size and md5, the backup is marked corrupted or missing if it is not intact.").
+		Param(webservice.PathParameter("cluster", "cluster name")).
+		Param(webservice.PathParameter("backup", "backup name")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Backup{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+	webservice.Route(webservice.POST("/projects/{project}/clusters/{cluster}/backups/{backup}/verify").
+		To(h.VerifyBackup).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Verify backup against its size and md5, the backup is marked corrupted or missing if it is not intact.").
+		Param(webservice.PathParameter("project", "project name")).
+		Param(webservice.PathParameter("cluster", "cluster name")).
+		Param(webservice.PathParameter("backup", "backup name")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Backup{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+
 	webservice.Route(webservice.POST("/clusters/{cluster}/recovery").
 		To(h.CreateRecovery).
 		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
 		Doc("create recovery.").
 		Reads(corev1.Recovery{}).
 		Param(webservice.PathParameter("cluster", "cluster name")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run recovery, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Recovery{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 	webservice.Route(webservice.POST("/projects/{project}/clusters/{cluster}/recovery").
@@ -409,24 +536,70 @@ func SetupWebService(h *handler) *restful.WebService {
 		Reads(corev1.Recovery{}).
 		Param(webservice.PathParameter("project", "project name")).
 		Param(webservice.PathParameter("cluster", "cluster name")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run recovery, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Recovery{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 
 	webservice.Route(webservice.PATCH("/clusters/{cluster}/plugins").
 		To(h.InstallOrUninstallPlugins).
 		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
-		Doc("Install or uninstall plugins").
+		Doc("Install or uninstall plugins, the plugins are installed after their dependencies and uninstalled before them").
 		Reads(PatchComponents{}).
 		Param(webservice.PathParameter("cluster", "cluster name")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run install or uninstall plugins, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
+		Param(webservice.QueryParameter(query.ParamRollback, "uninstall the installed plugins if the installation fails").
+			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamWithDependencies, "install the missing dependencies of plugins with their default configurations").
+			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 	webservice.Route(webservice.PATCH("/projects/{project}/clusters/{cluster}/plugins").
 		To(h.InstallOrUninstallPlugins).
 		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
-		Doc("Install or uninstall plugins").
+		Doc("Install or uninstall plugins, the plugins are installed after their dependencies and uninstalled before them").
 		Reads(PatchComponents{}).
 		Param(webservice.PathParameter("project", "project name")).
 		Param(webservice.PathParameter("cluster", "cluster name")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run install or uninstall plugins, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
+		Param(webservice.QueryParameter(query.ParamRollback, "uninstall the installed plugins if the installation fails").
+			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamWithDependencies, "install the missing dependencies of plugins with their default configurations").
+			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+
+	webservice.Route(webservice.POST("/clusters/{cluster}/plugins/upgrade").
+		To(h.UpgradePlugins).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Upgrade installed plugins to other versions in place").
+		Reads(UpgradeComponents{}).
+		Param(webservice.PathParameter("cluster", "cluster name")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run upgrade plugins, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+	webservice.Route(webservice.POST("/projects/{project}/clusters/{cluster}/plugins/upgrade").
+		To(h.UpgradePlugins).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Upgrade installed plugins to other versions in place").
+		Reads(UpgradeComponents{}).
+		Param(webservice.PathParameter("project", "project name")).
+		Param(webservice.PathParameter("cluster", "cluster name")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run upgrade plugins, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 
@@ -747,30 +920,148 @@ func SetupWebService(h *handler) *restful.WebService {
 			DataType("string")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}))
 
+	webservice.Route(webservice.POST("/operations/{name}/pause").
+		To(h.PauseOperation).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("pause running operation before its next step.").
+		Param(webservice.PathParameter(query.ParameterName, "operation name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Operation{}))
+	webservice.Route(webservice.POST("/projects/{project}/operations/{name}/pause").
+		To(h.PauseOperation).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("pause running operation before its next step.").
+		Param(webservice.PathParameter("project", "project name")).
+		Param(webservice.PathParameter(query.ParameterName, "operation name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Operation{}))
+
+	webservice.Route(webservice.POST("/operations/{name}/resume").
+		To(h.ResumeOperation).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("resume paused operation.").
+		Param(webservice.PathParameter(query.ParameterName, "operation name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Operation{}))
+	webservice.Route(webservice.POST("/projects/{project}/operations/{name}/resume").
+		To(h.ResumeOperation).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("resume paused operation.").
+		Param(webservice.PathParameter("project", "project name")).
+		Param(webservice.PathParameter(query.ParameterName, "operation name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Operation{}))
+
+	webservice.Route(webservice.POST("/operations/{name}/cancel").
+		To(h.CancelOperation).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("cancel pending, running or paused operation, the running commands are killed.").
+		Param(webservice.PathParameter(query.ParameterName, "operation name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Operation{}))
+	webservice.Route(webservice.POST("/projects/{project}/operations/{name}/cancel").
+		To(h.CancelOperation).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("cancel pending, running or paused operation, the running commands are killed.").
+		Param(webservice.PathParameter("project", "project name")).
+		Param(webservice.PathParameter(query.ParameterName, "operation name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Operation{}))
+
 	webservice.Route(webservice.POST("/clusters/{name}/upgrade").
 		To(h.UpgradeCluster).
 		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
-		Doc("upgrade cluster.").
+		Doc("upgrade cluster, the cluster is upgraded through every intermediate minor version.").
 		Reads(ClusterUpgrade{}).
-		Param(webservice.QueryParameter(query.ParamDryRun, "dry run upgrade cluster.").
-			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run upgrade cluster, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
 		Param(webservice.PathParameter(query.ParameterName, "cluster name").
 			Required(true).
 			DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), nil))
 	webservice.Route(webservice.POST("projects/{project}/clusters/{name}/upgrade").
 		To(h.UpgradeCluster).
 		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
-		Doc("upgrade cluster.").
+		Doc("upgrade cluster, the cluster is upgraded through every intermediate minor version.").
 		Reads(ClusterUpgrade{}).
 		Param(webservice.PathParameter("project", "project name")).
-		Param(webservice.QueryParameter(query.ParamDryRun, "dry run upgrade cluster.").
-			Required(false).DataType("boolean")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run upgrade cluster, \"plan\" returns the operation to be run without running it").
+			Required(false).DataType("string")).
 		Param(webservice.PathParameter(query.ParameterName, "cluster name").
 			Required(true).
 			DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), nil))
 
+	webservice.Route(webservice.PUT("/clusters/{name}/config").
+		To(h.UpdateClusterConfig).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Update the configuration of kubernetes components, the components are restarted node by node.").
+		Reads(corev1.ClusterConfig{}).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run update cluster config.").
+			Required(false).DataType("boolean")).
+		Param(webservice.PathParameter(query.ParameterName, "cluster name").
+			Required(true).
+			DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+	webservice.Route(webservice.PUT("/projects/{project}/clusters/{name}/config").
+		To(h.UpdateClusterConfig).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Update the configuration of kubernetes components, the components are restarted node by node.").
+		Reads(corev1.ClusterConfig{}).
+		Param(webservice.PathParameter("project", "project name")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run update cluster config.").
+			Required(false).DataType("boolean")).
+		Param(webservice.PathParameter(query.ParameterName, "cluster name").
+			Required(true).
+			DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+
+	webservice.Route(webservice.POST("/clusters/{name}/runtime").
+		To(h.MigrateContainerRuntime).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Migrate container runtime of cluster nodes one by one.").
+		Reads(clusteroperation.MigrateRuntime{}).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run migrate container runtime.").
+			Required(false).DataType("boolean")).
+		Param(webservice.PathParameter(query.ParameterName, "cluster name").
+			Required(true).
+			DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+	webservice.Route(webservice.POST("/projects/{project}/clusters/{name}/runtime").
+		To(h.MigrateContainerRuntime).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Migrate container runtime of cluster nodes one by one.").
+		Reads(clusteroperation.MigrateRuntime{}).
+		Param(webservice.PathParameter("project", "project name")).
+		Param(webservice.QueryParameter(query.ParamDryRun, "dry run migrate container runtime.").
+			Required(false).DataType("boolean")).
+		Param(webservice.PathParameter(query.ParameterName, "cluster name").
+			Required(true).
+			DataType("string")).
+		Param(webservice.QueryParameter(query.ParamPriority, queuePriorityDesc).
+			Required(false).DataType("integer")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Cluster{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+
 	webservice.Route(webservice.PATCH("/clusters/{name}/status").
 		To(h.ResetClusterStatus).
 		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
@@ -1266,6 +1557,17 @@ func SetupWebService(h *handler) *restful.WebService {
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), nil).
 		Returns(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError), errors.HTTPError{}))
 
+	webservice.Route(webservice.POST("/backuppoints/{name}/import").
+		To(h.ImportBackup).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("Import the orphan backup file saved in the backup point as the backup of cluster.").
+		Reads(ImportBackup{}).
+		Param(webservice.PathParameter(query.ParameterName, "backup point name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), corev1.Backup{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+
 	webservice.Route(webservice.GET("/cronbackups").
 		Doc("List of cronbackup.").
 		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
@@ -1366,6 +1668,16 @@ func SetupWebService(h *handler) *restful.WebService {
 		Returns(http.StatusOK, http.StatusText(http.StatusOK), models.PageableResponse{}).
 		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
 
+	webservice.Route(webservice.GET("/cronbackups/{name}/retention").
+		To(h.ListCronBackupRetention).
+		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
+		Doc("List the retention buckets satisfied by backups of cronBackup, backups satisfying none are pruned next time.").
+		Param(webservice.PathParameter(query.ParameterName, "cronBackup name").
+			Required(true).
+			DataType("string")).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), []BackupRetention{}).
+		Returns(http.StatusNotFound, http.StatusText(http.StatusNotFound), nil))
+
 	webservice.Route(webservice.GET("/configmaps").
 		To(h.ListConfigMaps).
 		Metadata(restfulspec.KeyOpenAPITags, []string{CoreClusterTag}).
@@ -1617,8 +1929,8 @@ func AddToContainer(c *restful.Container, clusterOperator cluster.Operator,
 	op operation.Operator, platform platform.Operator, leaseOperator lease.Operator,
 	coreOperator core.Operator, delivery service.IDelivery,
 	tokenOperator auth.TokenManagementInterface, tenantOperator tenant.Operator,
-	conf *generic.ServerRunOptions) error {
-	h := newHandler(conf, clusterOperator, op, leaseOperator, platform, coreOperator, delivery, tokenOperator, tenantOperator)
+	conf *generic.ServerRunOptions, staticServerOptions *staticserver.Options) error {
+	h := newHandler(conf, clusterOperator, op, leaseOperator, platform, coreOperator, delivery, tokenOperator, tenantOperator, staticServerOptions)
 	webservice := SetupWebService(h)
 	c.Add(webservice)
 	return nil
@@ -21,9 +21,13 @@ package v1
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
+	jsonpatch "github.com/evanphx/json-patch"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/kubeclipper/kubeclipper/pkg/clusteroperation"
 	"github.com/kubeclipper/kubeclipper/pkg/component"
 	corev1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
 )
@@ -87,35 +91,175 @@ func (p *PatchComponents) checkComponents(cluster *corev1.Cluster) error {
 	return nil
 }
 
-// addOrRemoveComponentFromCluster update cluster components slice
-func (p *PatchComponents) addOrRemoveComponentFromCluster(cluster *corev1.Cluster) (*corev1.Cluster, error) {
-	if p.Uninstall {
-		for _, v := range p.Addons {
-			itf, ok := component.Load(fmt.Sprintf(component.RegisterFormat, v.Name, v.Version))
-			if !ok {
-				return nil, fmt.Errorf("kubeclipper does not support %s-%s component", v.Name, v.Version)
+// resolveDependencies sorts the addons to be installed in the order of their dependencies, the missing dependencies
+// are added with their default configurations if addDependencies is true.
+func (p *PatchComponents) resolveDependencies(cluster *corev1.Cluster, addDependencies bool) error {
+	installed, err := addonMetas(cluster.Addons, true)
+	if err != nil {
+		return err
+	}
+	for {
+		metas, err := addonMetas(p.Addons, false)
+		if err != nil {
+			return err
+		}
+		order, missing, err := component.SortByDependence(metas, installed)
+		if err != nil {
+			return err
+		}
+		if len(missing) == 0 {
+			sorted := make([]corev1.Addon, 0, len(order))
+			for _, i := range order {
+				sorted = append(sorted, p.Addons[i])
 			}
-			currentCompMeta := itf.NewInstance()
-			if err := json.Unmarshal(v.Config.Raw, currentCompMeta); err != nil {
-				return nil, fmt.Errorf("%s-%s component configuration resolution error: %s", v.Name, v.Version, err.Error())
+			p.Addons = sorted
+			return nil
+		}
+		if !addDependencies {
+			return fmt.Errorf("%s required by the plugins are not installed", strings.Join(missing, ", "))
+		}
+		for _, dependence := range missing {
+			addon, err := defaultAddon(dependence)
+			if err != nil {
+				return err
 			}
-			currentNewComp, _ := currentCompMeta.(component.Interface)
-			for k, comp := range cluster.Addons {
-				existCompItf, _ := component.Load(fmt.Sprintf(component.RegisterFormat, comp.Name, comp.Version))
-				existCompMeta := existCompItf.NewInstance()
-				if err := json.Unmarshal(comp.Config.Raw, existCompMeta); err != nil {
-					return nil, fmt.Errorf("%s-%s component configuration resolution error: %s", v.Name, v.Version, err.Error())
-				}
-				existNewComp, _ := existCompMeta.(component.Interface)
-				if currentNewComp.GetInstanceName() == existNewComp.GetInstanceName() {
-					cluster.Addons = append(cluster.Addons[:k], cluster.Addons[k+1:]...)
-				}
+			p.Addons = append(p.Addons, addon)
+		}
+	}
+}
+
+// checkRemoval checks whether the remaining addons still have their dependencies, and sorts the addons
+// to be uninstalled so that every addon is uninstalled before the addons it depends on.
+func (p *PatchComponents) checkRemoval(cluster *corev1.Cluster) error {
+	removed := make(map[int]bool, len(p.Addons))
+	for _, addon := range p.Addons {
+		comp, err := clusteroperation.DecodeAddon(addon)
+		if err != nil {
+			return err
+		}
+		i, err := clusteroperation.FindAddon(cluster.Addons, addon.Name, comp.GetInstanceName())
+		if err != nil {
+			return err
+		}
+		removed[i] = true
+	}
+	var remaining []corev1.Addon
+	for i, addon := range cluster.Addons {
+		if !removed[i] {
+			remaining = append(remaining, addon)
+		}
+	}
+	metas, err := addonMetas(p.Addons, false)
+	if err != nil {
+		return err
+	}
+	remainingMetas, err := addonMetas(remaining, true)
+	if err != nil {
+		return err
+	}
+	if err = component.CheckRemoval(metas, remainingMetas); err != nil {
+		return err
+	}
+	order, _, err := component.SortByDependence(metas, nil)
+	if err != nil {
+		return err
+	}
+	sorted := make([]corev1.Addon, 0, len(order))
+	for i := len(order) - 1; i >= 0; i-- {
+		sorted = append(sorted, p.Addons[order[i]])
+	}
+	p.Addons = sorted
+	return nil
+}
+
+// addonMetas returns the metadata of addons, the addons not supported any more are skipped if skipUnknown is true.
+func addonMetas(addons []corev1.Addon, skipUnknown bool) ([]component.Meta, error) {
+	metas := make([]component.Meta, 0, len(addons))
+	for _, addon := range addons {
+		itf, ok := component.Load(fmt.Sprintf(component.RegisterFormat, addon.Name, addon.Version))
+		if !ok {
+			if skipUnknown {
+				continue
 			}
+			return nil, fmt.Errorf("kubeclipper does not support %s-%s component", addon.Name, addon.Version)
 		}
-		return cluster, nil
+		metas = append(metas, itf.GetComponentMeta(component.English))
 	}
-	cluster.Addons = append(cluster.Addons, p.Addons...)
-	return cluster, nil
+	return metas, nil
+}
+
+// defaultAddon returns the latest plugin which provides the dependence with its default configurations.
+func defaultAddon(dependence string) (corev1.Addon, error) {
+	meta, ok := component.LatestProvider(dependence)
+	if !ok {
+		return corev1.Addon{}, fmt.Errorf("no plugin provides %s", dependence)
+	}
+	config, err := component.DefaultConfig(meta.Schema)
+	if err != nil {
+		return corev1.Addon{}, err
+	}
+	if err = component.ValidateConfig(meta.Schema, config); err != nil {
+		return corev1.Addon{}, fmt.Errorf("%s-%s required as %s can not be installed with the default configurations: %w",
+			meta.Name, meta.Version, dependence, err)
+	}
+	return corev1.Addon{Name: meta.Name, Version: meta.Version, Config: runtime.RawExtension{Raw: config}}, nil
+}
+
+// UpgradeComponents upgrades the installed components to other versions in place, they are not uninstalled before.
+type UpgradeComponents struct {
+	Addons []ComponentUpgrade `json:"addons"`
+}
+
+type ComponentUpgrade struct {
+	Name string `json:"name"`
+	// InstanceName the installed instance to be upgraded, e.g. the storage class name of nfs provisioner,
+	// it can be omitted if only one instance of the component is installed.
+	InstanceName string `json:"instanceName,omitempty"`
+	// Version the version upgraded to.
+	Version string `json:"version"`
+	// Config the configurations changed along with the upgrade, they are merged into the installed configurations.
+	Config runtime.RawExtension `json:"config,omitempty"`
+}
+
+// makeUpgradedAddons returns the upgraded addons, the configurations of every addon are validated against
+// the schema of its new version.
+func (u *UpgradeComponents) makeUpgradedAddons(cluster *corev1.Cluster) ([]corev1.Addon, error) {
+	addons := make([]corev1.Addon, 0, len(u.Addons))
+	for _, up := range u.Addons {
+		i, err := clusteroperation.FindAddon(cluster.Addons, up.Name, up.InstanceName)
+		if err != nil {
+			return nil, err
+		}
+		installed := cluster.Addons[i]
+		itf, ok := component.Load(fmt.Sprintf(component.RegisterFormat, up.Name, up.Version))
+		if !ok {
+			return nil, fmt.Errorf("kubeclipper does not support %s-%s component", up.Name, up.Version)
+		}
+		config := installed.Config.Raw
+		if len(up.Config.Raw) > 0 {
+			if config, err = jsonpatch.MergePatch(config, up.Config.Raw); err != nil {
+				return nil, fmt.Errorf("%s-%s component configuration resolution error: %s", up.Name, up.Version, err.Error())
+			}
+		}
+		if err = component.ValidateConfig(itf.GetComponentMeta(component.English).Schema, config); err != nil {
+			return nil, fmt.Errorf("%s-%s component configuration is invalid: %w", up.Name, up.Version, err)
+		}
+		addon := corev1.Addon{Name: up.Name, Version: up.Version, Config: runtime.RawExtension{Raw: config}}
+		oldComp, err := clusteroperation.DecodeAddon(installed)
+		if err != nil {
+			return nil, err
+		}
+		newComp, err := clusteroperation.DecodeAddon(addon)
+		if err != nil {
+			return nil, err
+		}
+		// the instance is upgraded in place, it must keep its name.
+		if newComp.GetInstanceName() != oldComp.GetInstanceName() {
+			return nil, fmt.Errorf("the instance name of %s component can not be changed by upgrade", up.Name)
+		}
+		addons = append(addons, addon)
+	}
+	return addons, nil
 }
 
 type StepLog struct {
@@ -131,4 +275,50 @@ type ClusterUpgrade struct {
 	Version       string `json:"version"`
 	Offline       bool   `json:"offline"`
 	LocalRegistry string `json:"localRegistry"`
+	// Strategy how the nodes are upgraded, the nodes are drained and upgraded one by one by default.
+	Strategy *corev1.UpgradeStrategy `json:"strategy,omitempty"`
+}
+
+// OperationQueue the operations of cluster, the queued operations start one at a time after the running operations end.
+type OperationQueue struct {
+	Running []RunningOperation `json:"running"`
+	// Pending the operations which are about to be created.
+	Pending []corev1.PendingOperation `json:"pending"`
+	// Queued the operations in the order they start.
+	Queued []corev1.QueuedOperation `json:"queued"`
+}
+
+type RunningOperation struct {
+	Name          string                     `json:"name"`
+	OperationType string                     `json:"operationType"`
+	Status        corev1.OperationStatusType `json:"status"`
+}
+
+type QueuedOperationPriority struct {
+	// Priority the operation of higher priority starts earlier.
+	Priority int32 `json:"priority"`
+}
+
+// ImportBackup the backup file saved in the backup point is imported as the backup of cluster.
+type ImportBackup struct {
+	// FileName the backup file listed in the orphan backups of backup point, it is also the name of backup.
+	FileName string `json:"fileName"`
+	// ClusterName the cluster which the backup is restored to, the nodes of cluster are recorded in the backup.
+	ClusterName string `json:"clusterName"`
+	// KubernetesVersion the version of cluster which the backup was taken from, the backup is only restored to
+	// the cluster of the same version.
+	KubernetesVersion string `json:"kubernetesVersion"`
+	// ConfirmNodes the caller confirms that the backup was taken from the nodes of cluster. The nodes of backup are
+	// unknown, so the recovery can't check that the cluster runs on the nodes the backup was taken from.
+	ConfirmNodes bool   `json:"confirmNodes"`
+	Description  string `json:"description,omitempty"`
+}
+
+// BackupRetention the retention buckets a backup of cron backup satisfies, it is pruned next time if it satisfies none.
+type BackupRetention struct {
+	Name                string                     `json:"name"`
+	CreationTimestamp   metav1.Time                `json:"creationTimestamp"`
+	ClusterBackupStatus corev1.ClusterBackupStatus `json:"status"`
+	// Buckets the retention buckets, including last, daily, weekly, monthly and yearly.
+	Buckets []string `json:"buckets"`
 }
@@ -19,10 +19,12 @@
 package v1
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
 
+	"github.com/kubeclipper/kubeclipper/pkg/clusteroperation"
 	"github.com/kubeclipper/kubeclipper/pkg/constatns"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -174,70 +176,93 @@ func Test_checkComponents(t *testing.T) {
 	}
 }
 
-func Test_addOrRemoveComponentFromCluster(t *testing.T) {
-	type args struct {
-		cluster *v1.Cluster
-		pc      *PatchComponents
+func Test_makeUpgradedAddons(t *testing.T) {
+	addon := func(scName string) v1.Addon {
+		return v1.Addon{
+			Name:    "nfs-provisioner",
+			Version: "v1",
+			Config:  runtime.RawExtension{Raw: []byte(fmt.Sprintf(`{"serverAddr":"10.0.0.1","sharedPath":"/data","scName":"%s"}`, scName))},
+		}
 	}
-	tests := []struct {
-		name string
-		arg  args
-		want []v1.Addon
-	}{
-		{
-			name: "test uninstall component",
-			arg: args{
-				cluster: c2,
-				pc: &PatchComponents{
-					Uninstall: true,
-					Addons: []v1.Addon{
-						{
-							Name:    "nfs-provisioner",
-							Version: "v1",
-							Config: runtime.RawExtension{
-								Raw: []byte(`{"scName": "nfs-provisioner-v1"}`),
-							},
-						},
-					},
-				},
-			},
-			want: []v1.Addon{},
-		},
-		{
-			name: "test install component",
-			arg: args{
-				cluster: c2,
-				pc: &PatchComponents{
-					Uninstall: false,
-					Addons: []v1.Addon{
-						{
-							Name:    "testComponent",
-							Version: "v1",
-							Config: runtime.RawExtension{
-								Raw: []byte(`{"scName": "testComponent-v1"}`),
-							},
-						},
-					},
-				},
-			},
-			want: []v1.Addon{
-				{
-					Name:    "testComponent",
-					Version: "v1",
-					Config:  runtime.RawExtension{Raw: []byte(`{"scName": "testComponent-v1"}`)},
-				},
-			},
-		},
+	clu := &v1.Cluster{Addons: []v1.Addon{addon("a"), addon("b")}}
+	upgrade := func(instance, config string) *UpgradeComponents {
+		return &UpgradeComponents{Addons: []ComponentUpgrade{{
+			Name:         "nfs-provisioner",
+			InstanceName: instance,
+			Version:      "v1",
+			Config:       runtime.RawExtension{Raw: []byte(config)},
+		}}}
 	}
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			got, err := test.arg.pc.addOrRemoveComponentFromCluster(test.arg.cluster)
-			if err != nil {
-				t.Errorf("addOrRemoveComponentFromCluster() error: %v", err)
-			}
-			if !reflect.DeepEqual(got.Addons, test.want) {
-				t.Errorf(" addOrRemoveComponentFromCluster() error: got %v, want %v", got.Addons, test.want)
-			}
-		})
+
+	for name, u := range map[string]*UpgradeComponents{
+		"ambiguous instance": upgrade("", ""),
+		"unknown instance":   upgrade("c", ""),
+		"rename instance":    upgrade("b", `{"scName":"c"}`),
+		"invalid schema":     upgrade("b", `{"reclaimPolicy":"Recycle"}`),
+	} {
+		if _, err := u.makeUpgradedAddons(clu); err == nil {
+			t.Errorf("%s: makeUpgradedAddons() must fail", name)
+		}
+	}
+
+	addons, err := upgrade("b", `{"archiveOnDelete":true}`).makeUpgradedAddons(clu)
+	if err != nil {
+		t.Fatalf("makeUpgradedAddons() error: %v", err)
+	}
+	want := `{"archiveOnDelete":true,"scName":"b","serverAddr":"10.0.0.1","sharedPath":"/data"}`
+	if len(addons) != 1 || string(addons[0].Config.Raw) != want {
+		t.Fatalf("unexpected upgraded addons: %s", addons[0].Config.Raw)
+	}
+	if err = clusteroperation.UpgradeComponents(clu, addons); err != nil {
+		t.Fatalf("UpgradeComponents() error: %v", err)
+	}
+	if string(clu.Addons[1].Config.Raw) != want || !reflect.DeepEqual(clu.Addons[0], addon("a")) {
+		t.Errorf("the addon is not upgraded in place: %+v", clu.Addons)
+	}
+}
+
+func Test_makeUpgradedAddonsToNewVersion(t *testing.T) {
+	// the v2 of nfs provisioner shares the implementation of v1 in test.
+	key := fmt.Sprintf(component.RegisterFormat, "nfs-provisioner", "v2")
+	if _, ok := component.Load(key); !ok {
+		if err := component.Register(key, &nfsprovisioner.NFSProvisioner{}); err != nil {
+			t.Fatalf("register nfs-provisioner v2 error: %v", err)
+		}
+	}
+	installed := v1.Addon{
+		Name:    "nfs-provisioner",
+		Version: "v1",
+		Config:  runtime.RawExtension{Raw: []byte(`{"serverAddr":"10.0.0.1","sharedPath":"/data","scName":"a"}`)},
+	}
+	clu := c1.DeepCopy()
+	clu.Addons = []v1.Addon{installed}
+	u := &UpgradeComponents{Addons: []ComponentUpgrade{{
+		Name:    "nfs-provisioner",
+		Version: "v2",
+		Config:  runtime.RawExtension{Raw: []byte(`{"archiveOnDelete":true}`)},
+	}}}
+
+	addons, err := u.makeUpgradedAddons(clu)
+	if err != nil {
+		t.Fatalf("makeUpgradedAddons() error: %v", err)
+	}
+	if len(addons) != 1 || addons[0].Version != "v2" {
+		t.Fatalf("unexpected upgraded addons: %+v", addons)
+	}
+	h := newHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	op, err := h.parseOperationFromComponent(context.TODO(), extraMeta, addons, clu, v1.ActionUpgrade, false)
+	if err != nil {
+		t.Fatalf("parseOperationFromComponent() error: %v", err)
+	}
+	if len(op.Steps) == 0 {
+		t.Errorf("the upgrade operation has no step")
+	}
+
+	if err = clusteroperation.UpgradeComponents(clu, addons); err != nil {
+		t.Fatalf("UpgradeComponents() error: %v", err)
+	}
+	want := `{"archiveOnDelete":true,"scName":"a","serverAddr":"10.0.0.1","sharedPath":"/data"}`
+	if len(clu.Addons) != 1 || clu.Addons[0].Version != "v2" || string(clu.Addons[0].Config.Raw) != want {
+		t.Errorf("the addon is not upgraded to v2 in place: %+v", clu.Addons)
 	}
 }
@@ -0,0 +1,278 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package v1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sliceutil"
+)
+
+var (
+	ErrInvalidTemplate      = errors.New("invalid template")
+	ErrInvalidTemplateValue = errors.New("invalid template parameter value")
+
+	templateParameterName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	templatePlaceholder   = regexp.MustCompile(`\$\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+)
+
+// validateTemplate checks the parameters and the placeholders in config.
+func validateTemplate(t *v1.Template) error {
+	params := make(map[string]*v1.TemplateParameter, len(t.Parameters))
+	for i := range t.Parameters {
+		p := &t.Parameters[i]
+		if !templateParameterName.MatchString(p.Name) {
+			return fmt.Errorf("%w: parameter name %q must match %s", ErrInvalidTemplate, p.Name, templateParameterName)
+		}
+		if _, ok := params[p.Name]; ok {
+			return fmt.Errorf("%w: duplicated parameter %s", ErrInvalidTemplate, p.Name)
+		}
+		params[p.Name] = p
+		if !v1.AllowedTemplateParameterType.Has(p.Type) {
+			return fmt.Errorf("%w: parameter %s has unsupported type %q, support %v", ErrInvalidTemplate,
+				p.Name, p.Type, v1.AllowedTemplateParameterType.List())
+		}
+		if p.Pattern != "" {
+			if _, err := regexp.Compile(p.Pattern); err != nil {
+				return fmt.Errorf("%w: parameter %s has invalid pattern: %v", ErrInvalidTemplate, p.Name, err)
+			}
+		}
+		if p.Default != nil {
+			if _, err := resolveTemplateParameter(p, p.Default.Raw); err != nil {
+				return fmt.Errorf("%w: default value of parameter %s: %v", ErrInvalidTemplate, p.Name, err)
+			}
+		}
+	}
+	if len(t.Config.Raw) > 0 && !json.Valid(t.Config.Raw) {
+		return fmt.Errorf("%w: config must be json", ErrInvalidTemplate)
+	}
+	for _, m := range templatePlaceholder.FindAllSubmatch(t.Config.Raw, -1) {
+		if _, ok := params[string(m[1])]; !ok {
+			return fmt.Errorf("%w: config refers to undeclared parameter %s", ErrInvalidTemplate, m[1])
+		}
+	}
+	return nil
+}
+
+// nextTemplateRevision keeps the revision of template unless its config or parameters change,
+// otherwise the previous revision is moved into the history.
+func nextTemplateRevision(t, previous *v1.Template) {
+	t.Revision = previous.Revision
+	if t.Revision == 0 {
+		t.Revision = 1
+	}
+	t.History = previous.History
+	if bytes.Equal(t.Config.Raw, previous.Config.Raw) && reflect.DeepEqual(t.Parameters, previous.Parameters) {
+		return
+	}
+	t.History = append(t.History, v1.TemplateRevision{
+		Revision:   t.Revision,
+		Config:     previous.Config,
+		Parameters: previous.Parameters,
+	})
+	if len(t.History) > v1.TemplateRevisionHistoryLimit {
+		t.History = t.History[len(t.History)-v1.TemplateRevisionHistoryLimit:]
+	}
+	t.Revision++
+}
+
+// renderTemplate replaces the placeholders in config with the parameter values or their defaults.
+func renderTemplate(rev *v1.TemplateRevision, values map[string]runtime.RawExtension) ([]byte, error) {
+	resolved := make(map[string]interface{}, len(rev.Parameters))
+	for i := range rev.Parameters {
+		p := &rev.Parameters[i]
+		raw := p.Default
+		if v, ok := values[p.Name]; ok {
+			raw = &v
+		}
+		if raw == nil {
+			if p.Required {
+				return nil, fmt.Errorf("%w: parameter %s is required", ErrInvalidTemplateValue, p.Name)
+			}
+			resolved[p.Name] = nil
+			continue
+		}
+		v, err := resolveTemplateParameter(p, raw.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parameter %s: %v", ErrInvalidTemplateValue, p.Name, err)
+		}
+		resolved[p.Name] = v
+	}
+	for name := range values {
+		if _, ok := resolved[name]; !ok {
+			return nil, fmt.Errorf("%w: undeclared parameter %s", ErrInvalidTemplateValue, name)
+		}
+	}
+
+	var config interface{}
+	decoder := json.NewDecoder(bytes.NewReader(rev.Config.Raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	return json.Marshal(replacePlaceholders(config, resolved))
+}
+
+func replacePlaceholders(config interface{}, values map[string]interface{}) interface{} {
+	switch val := config.(type) {
+	case map[string]interface{}:
+		for k, v := range val {
+			val[k] = replacePlaceholders(v, values)
+		}
+	case []interface{}:
+		for i, v := range val {
+			val[i] = replacePlaceholders(v, values)
+		}
+	case string:
+		if m := templatePlaceholder.FindStringSubmatch(val); m != nil && m[0] == val {
+			return values[m[1]]
+		}
+		return templatePlaceholder.ReplaceAllStringFunc(val, func(s string) string {
+			v := values[templatePlaceholder.FindStringSubmatch(s)[1]]
+			switch v := v.(type) {
+			case nil:
+				return ""
+			case []string:
+				return strings.Join(v, ",")
+			case []map[string]string:
+				ids := make([]string, 0, len(v))
+				for _, node := range v {
+					ids = append(ids, node["id"])
+				}
+				return strings.Join(ids, ",")
+			}
+			return fmt.Sprint(v)
+		})
+	}
+	return config
+}
+
+// resolveTemplateParameter decodes the json value of parameter into its type.
+func resolveTemplateParameter(p *v1.TemplateParameter, raw []byte) (interface{}, error) {
+	var pattern *regexp.Regexp
+	if p.Pattern != "" {
+		var err error
+		if pattern, err = regexp.Compile(p.Pattern); err != nil {
+			return nil, err
+		}
+	}
+	match := func(s string) error {
+		if pattern != nil && !pattern.MatchString(s) {
+			return fmt.Errorf("%q does not match %s", s, p.Pattern)
+		}
+		return nil
+	}
+
+	switch p.Type {
+	case v1.TemplateParameterString:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		if len(p.Enum) > 0 && !sliceutil.HasString(p.Enum, s) {
+			return nil, fmt.Errorf("%q is not one of %v", s, p.Enum)
+		}
+		return s, match(s)
+	case v1.TemplateParameterInteger:
+		var i int64
+		if err := json.Unmarshal(raw, &i); err != nil {
+			return nil, err
+		}
+		return i, nil
+	case v1.TemplateParameterBoolean:
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	case v1.TemplateParameterArray, v1.TemplateParameterNodes:
+		var items []string
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if err := match(item); err != nil {
+				return nil, err
+			}
+		}
+		if p.Type == v1.TemplateParameterArray {
+			return items, nil
+		}
+		// only the node ids are rendered, the other fields of node keep their defaults.
+		nodes := make([]map[string]string, 0, len(items))
+		for _, id := range items {
+			nodes = append(nodes, map[string]string{"id": id})
+		}
+		return nodes, nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", p.Type)
+}
+
+// renderClusterTemplate replaces the cluster with the one rendered from its template reference,
+// the metadata in request takes precedence over the one in template.
+func (h *handler) renderClusterTemplate(ctx context.Context, c *v1.Cluster) error {
+	ref := c.TemplateRef.DeepCopy()
+	t, err := h.clusterOperator.GetTemplateEx(ctx, ref.Name, "0")
+	if err != nil {
+		return err
+	}
+	rev, err := t.GetRevision(ref.Revision)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTemplateValue, err)
+	}
+	data, err := renderTemplate(rev, ref.Values)
+	if err != nil {
+		return err
+	}
+	rendered := v1.Cluster{}
+	if err = json.Unmarshal(data, &rendered); err != nil {
+		return fmt.Errorf("%w: the rendered config is not a cluster: %v", ErrInvalidTemplate, err)
+	}
+
+	rendered.TypeMeta = c.TypeMeta
+	rendered.Name = c.Name
+	rendered.Labels = mergeStringMap(rendered.Labels, c.Labels)
+	rendered.Labels[common.LabelTemplate] = t.Name
+	rendered.Annotations = mergeStringMap(rendered.Annotations, c.Annotations)
+	ref.Revision = rev.Revision
+	rendered.TemplateRef = ref
+	*c = rendered
+	return nil
+}
+
+func mergeStringMap(dst, src map[string]string) map[string]string {
+	if dst == nil {
+		dst = make(map[string]string, len(src))
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
@@ -0,0 +1,226 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	mock_cluster "github.com/kubeclipper/kubeclipper/pkg/models/cluster/mock"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func raw(s string) runtime.RawExtension {
+	return runtime.RawExtension{Raw: []byte(s)}
+}
+
+func newClusterTemplate() *v1.Template {
+	def := raw(`"v1.23.6"`)
+	return &v1.Template{
+		ObjectMeta: metav1.ObjectMeta{Name: "tmpl-test"},
+		Config: raw(`{"kubernetesVersion":"${version}","masters":"${masters}","workers":"${workers}",
+"networking":{"pods":{"cidrBlocks":["${podCIDR}"]},"dnsDomain":"${name}.local"},"kubelet":{"maxPods":"${maxPods}"}}`),
+		Parameters: []v1.TemplateParameter{
+			{Name: "name", Type: v1.TemplateParameterString, Required: true},
+			{Name: "masters", Type: v1.TemplateParameterNodes, Required: true},
+			{Name: "workers", Type: v1.TemplateParameterNodes},
+			{Name: "version", Type: v1.TemplateParameterString, Default: &def, Enum: []string{"v1.23.6", "v1.24.3"}},
+			{Name: "podCIDR", Type: v1.TemplateParameterString, Required: true, Pattern: `^\d+\.\d+\.\d+\.\d+/\d+$`},
+			{Name: "maxPods", Type: v1.TemplateParameterInteger},
+		},
+	}
+}
+
+func Test_validateTemplate(t *testing.T) {
+	if err := validateTemplate(newClusterTemplate()); err != nil {
+		t.Fatalf("validateTemplate() error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(t *v1.Template)
+	}{
+		{
+			name:   "undeclared parameter",
+			mutate: func(t *v1.Template) { t.Parameters = t.Parameters[1:] },
+		},
+		{
+			name:   "duplicated parameter",
+			mutate: func(t *v1.Template) { t.Parameters = append(t.Parameters, t.Parameters[0]) },
+		},
+		{
+			name:   "unsupported type",
+			mutate: func(t *v1.Template) { t.Parameters[0].Type = "object" },
+		},
+		{
+			name: "invalid default",
+			mutate: func(t *v1.Template) {
+				def := raw(`"v1.18.6"`)
+				t.Parameters[3].Default = &def
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := newClusterTemplate()
+			tt.mutate(tmpl)
+			if err := validateTemplate(tmpl); !errors.Is(err, ErrInvalidTemplate) {
+				t.Errorf("validateTemplate() error = %v, want %v", err, ErrInvalidTemplate)
+			}
+		})
+	}
+}
+
+func Test_renderTemplate(t *testing.T) {
+	rev, _ := newClusterTemplate().GetRevision(0)
+	tests := []struct {
+		name    string
+		values  map[string]runtime.RawExtension
+		want    string
+		wantErr error
+	}{
+		{
+			name: "defaults",
+			values: map[string]runtime.RawExtension{
+				"name":    raw(`"prod"`),
+				"masters": raw(`["m1","m2","m3"]`),
+				"podCIDR": raw(`"172.25.0.0/16"`),
+				"maxPods": raw(`200`),
+			},
+			want: `{"kubelet":{"maxPods":200},"kubernetesVersion":"v1.23.6","masters":[{"id":"m1"},{"id":"m2"},{"id":"m3"}],` +
+				`"networking":{"dnsDomain":"prod.local","pods":{"cidrBlocks":["172.25.0.0/16"]}},"workers":null}`,
+		},
+		{
+			name: "missing required",
+			values: map[string]runtime.RawExtension{
+				"name":    raw(`"prod"`),
+				"podCIDR": raw(`"172.25.0.0/16"`),
+			},
+			wantErr: ErrInvalidTemplateValue,
+		},
+		{
+			name: "pattern mismatch",
+			values: map[string]runtime.RawExtension{
+				"name":    raw(`"prod"`),
+				"masters": raw(`["m1"]`),
+				"podCIDR": raw(`"172.25.0.0"`),
+			},
+			wantErr: ErrInvalidTemplateValue,
+		},
+		{
+			name: "wrong type",
+			values: map[string]runtime.RawExtension{
+				"name":    raw(`"prod"`),
+				"masters": raw(`["m1"]`),
+				"podCIDR": raw(`"172.25.0.0/16"`),
+				"maxPods": raw(`"200"`),
+			},
+			wantErr: ErrInvalidTemplateValue,
+		},
+		{
+			name: "undeclared value",
+			values: map[string]runtime.RawExtension{
+				"name":    raw(`"prod"`),
+				"masters": raw(`["m1"]`),
+				"podCIDR": raw(`"172.25.0.0/16"`),
+				"cni":     raw(`"calico"`),
+			},
+			wantErr: ErrInvalidTemplateValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderTemplate(rev, tt.values)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("renderTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("renderTemplate() got = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_nextTemplateRevision(t *testing.T) {
+	previous := newClusterTemplate()
+	tmpl := newClusterTemplate()
+	tmpl.Annotations = map[string]string{common.AnnotationDescription: "only the description changes"}
+	nextTemplateRevision(tmpl, previous)
+	if tmpl.Revision != 1 || len(tmpl.History) != 0 {
+		t.Errorf("revision = %d, history = %d, want 1, 0", tmpl.Revision, len(tmpl.History))
+	}
+
+	for i := 0; i < v1.TemplateRevisionHistoryLimit+2; i++ {
+		previous = tmpl.DeepCopy()
+		tmpl.Parameters[0].Description = previous.Parameters[0].Description + "."
+		nextTemplateRevision(tmpl, previous)
+	}
+	if tmpl.Revision != v1.TemplateRevisionHistoryLimit+3 {
+		t.Errorf("revision = %d, want %d", tmpl.Revision, v1.TemplateRevisionHistoryLimit+3)
+	}
+	if len(tmpl.History) != v1.TemplateRevisionHistoryLimit || tmpl.History[0].Revision != 3 {
+		t.Errorf("history = %d from revision %d, want %d from revision 3", len(tmpl.History),
+			tmpl.History[0].Revision, v1.TemplateRevisionHistoryLimit)
+	}
+	rev, err := tmpl.GetRevision(3)
+	if err != nil || rev.Parameters[0].Description != ".." {
+		t.Errorf("GetRevision(3) = %v, %v", rev, err)
+	}
+	if _, err = tmpl.GetRevision(2); err == nil {
+		t.Errorf("GetRevision(2) must fail after the revision is pruned")
+	}
+}
+
+func Test_renderClusterTemplate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tmpl := newClusterTemplate()
+	previous := tmpl.DeepCopy()
+	tmpl.Parameters[3].Enum = nil
+	nextTemplateRevision(tmpl, previous)
+
+	clusterMockOperator := mock_cluster.NewMockOperator(ctrl)
+	clusterMockOperator.EXPECT().GetTemplateEx(gomock.Any(), "tmpl-test", "0").Return(tmpl, nil).AnyTimes()
+	h := &handler{clusterOperator: clusterMockOperator}
+
+	c := &v1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{common.LabelProject: "default"}},
+		TemplateRef: &v1.TemplateReference{
+			Name:     "tmpl-test",
+			Revision: 1,
+			Values: map[string]runtime.RawExtension{
+				"name":    raw(`"prod"`),
+				"masters": raw(`["m1"]`),
+				"podCIDR": raw(`"172.25.0.0/16"`),
+			},
+		},
+	}
+	if err := h.renderClusterTemplate(context.TODO(), c); err != nil {
+		t.Fatalf("renderClusterTemplate() error: %v", err)
+	}
+	if c.Name != "prod" || c.KubernetesVersion != "v1.23.6" || !reflect.DeepEqual(c.Masters.GetNodeIDs(), []string{"m1"}) {
+		t.Errorf("unexpected rendered cluster: %+v", c)
+	}
+	if c.Labels[common.LabelTemplate] != "tmpl-test" || c.Labels[common.LabelProject] != "default" {
+		t.Errorf("unexpected labels: %v", c.Labels)
+	}
+	if c.TemplateRef.Revision != 1 {
+		t.Errorf("template revision = %d, want 1", c.TemplateRef.Revision)
+	}
+
+	c.TemplateRef.Revision = 0
+	c.TemplateRef.Values["version"] = raw(`"v1.18.6"`)
+	if err := h.renderClusterTemplate(context.TODO(), c); err != nil {
+		t.Fatalf("renderClusterTemplate() error: %v", err)
+	}
+	if c.TemplateRef.Revision != 2 || c.KubernetesVersion != "v1.18.6" {
+		t.Errorf("got revision %d with version %s, want revision 2 with version v1.18.6",
+			c.TemplateRef.Revision, c.KubernetesVersion)
+	}
+}
@@ -22,7 +22,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"path/filepath"
+	"strings"
 
 	"github.com/kubeclipper/kubeclipper/pkg/utils/strutil"
 
@@ -32,6 +34,7 @@ import (
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 
 	"github.com/kubeclipper/kubeclipper/pkg/models/cluster"
+	"github.com/kubeclipper/kubeclipper/pkg/models/core"
 	"github.com/kubeclipper/kubeclipper/pkg/query"
 
 	"github.com/kubeclipper/kubeclipper/pkg/component"
@@ -66,6 +69,13 @@ func getCriStep(ctx context.Context, c *v1.Cluster, action v1.StepAction, nodes
 			return nil, err
 		}
 		return r.GetActionSteps(action), nil
+	case v1.CRICrio:
+		r := cri.CrioRunnable{}
+		err := r.InitStep(ctx, c, nodes)
+		if err != nil {
+			return nil, err
+		}
+		return r.GetActionSteps(action), nil
 	}
 	return nil, fmt.Errorf("%v type CRI is not supported", c.ContainerRuntime.Type)
 }
@@ -89,7 +99,10 @@ func getSteps(c component.Interface, action v1.StepAction) ([]v1.Step, error) {
 	}
 }
 
-func (h *handler) parseOperationFromCluster(extraMetadata *component.ExtraMetadata, c *v1.Cluster, action v1.StepAction) (*v1.Operation, error) {
+// parseOperationFromCluster parse operation instance from cluster, the install operation registers the uninstall
+// steps as its rollback if rollback is true. The install steps run as a graph, the node environment is set up
+// along with the container runtime installation.
+func (h *handler) parseOperationFromCluster(extraMetadata *component.ExtraMetadata, c *v1.Cluster, action v1.StepAction, rollback bool) (*v1.Operation, error) {
 	var steps []v1.Step
 	region := extraMetadata.Masters[0].Region
 	if c.Labels == nil {
@@ -106,7 +119,7 @@ func (h *handler) parseOperationFromCluster(extraMetadata *component.ExtraMetada
 
 	// Container runtime should be installed on all nodes.
 	ctx := component.WithExtraMetadata(context.TODO(), *extraMetadata)
-	stepNodes := utils.UnwrapNodeList(extraMetadata.GetAllNodes())
+	stepNodes := utils.UnwrapNodeList(append(extraMetadata.GetAllNodes(), extraMetadata.EtcdNodes...))
 	cSteps, err := getCriStep(ctx, c, action, stepNodes)
 	if err != nil {
 		return nil, err
@@ -124,14 +137,26 @@ func (h *handler) parseOperationFromCluster(extraMetadata *component.ExtraMetada
 		// reverse order of the addons
 		reverseComponents(carr)
 	} else {
+		k8s.InstallAfterRuntime(k8sSteps, cSteps)
 		steps = append(steps, cSteps...)
 		steps = append(steps, k8sSteps...)
 	}
 
-	addonSteps, err := h.parseAddonStep(ctx, c, carr, action)
+	var addonSteps []v1.Step
+	if rollback && action == v1.ActionInstall {
+		if err = addClusterRollback(ctx, c, op, stepNodes, cSteps, k8sSteps); err != nil {
+			return nil, err
+		}
+		addonSteps, err = h.parseAddonStepWithRollback(ctx, c, carr, op)
+	} else {
+		addonSteps, err = h.parseAddonStep(ctx, c, carr, action)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if action == v1.ActionInstall && len(k8sSteps) > 0 {
+		v1.ChainSteps(addonSteps, k8sSteps[len(k8sSteps)-1].ID)
+	}
 	steps = append(steps, addonSteps...)
 
 	if action == v1.ActionUninstall {
@@ -143,6 +168,22 @@ func (h *handler) parseOperationFromCluster(extraMetadata *component.ExtraMetada
 	return op, nil
 }
 
+// addClusterRollback registers the uninstall steps of the container runtime and kubernetes as the rollback of
+// their install steps.
+func addClusterRollback(ctx context.Context, c *v1.Cluster, op *v1.Operation, stepNodes []v1.StepNode, cSteps, k8sSteps []v1.Step) error {
+	steps, err := getCriStep(ctx, c, v1.ActionUninstall, stepNodes)
+	if err != nil {
+		return err
+	}
+	op.AddRollback(cSteps, steps)
+	steps, err = getK8sSteps(ctx, c, v1.ActionUninstall)
+	if err != nil {
+		return err
+	}
+	op.AddRollback(k8sSteps, steps)
+	return nil
+}
+
 func (h *handler) initComponentExtraCluster(ctx context.Context, p component.Interface) error {
 	cluNames := p.RequireExtraCluster()
 	extraClulsterMeta := make(map[string]component.ExtraMetadata, len(cluNames))
@@ -172,23 +213,28 @@ func (h *handler) parseRecoverySteps(c *v1.Cluster, b *v1.Backup, restoreDir str
 
 	names := make([]string, 0)
 	ips := make([]string, 0)
-	var masters, workers []component.Node
+	var masters, workers, etcdNodes []component.Node
 	for _, node := range nodeList.Items {
-		if node.Labels[common.LabelNodeRole] != k8s.NodeRoleMaster {
-			workers = append(workers, component.Node{
-				ID:       node.Name,
-				IPv4:     node.Status.Ipv4DefaultIP,
-				Hostname: node.Status.NodeInfo.Hostname,
-			})
+		item := component.Node{
+			ID:       node.Name,
+			IPv4:     node.Status.Ipv4DefaultIP,
+			Hostname: node.Status.NodeInfo.Hostname,
+		}
+		// the etcd members are the masters for stacked etcd, or the etcd nodes for managed external etcd.
+		switch node.Labels[common.LabelNodeRole] {
+		case string(common.NodeRoleEtcd):
+			etcdNodes = append(etcdNodes, item)
+		case k8s.NodeRoleMaster:
+			masters = append(masters, item)
+			if c.Etcd.IsManaged() {
+				continue
+			}
+		default:
+			workers = append(workers, item)
 			continue
 		}
 		names = append(names, node.Status.NodeInfo.Hostname)
 		ips = append(ips, node.Status.Ipv4DefaultIP)
-		masters = append(masters, component.Node{
-			ID:       node.Name,
-			IPv4:     node.Status.Ipv4DefaultIP,
-			Hostname: node.Status.NodeInfo.Hostname,
-		})
 	}
 
 	bp, err := h.clusterOperator.GetBackupPoint(context.TODO(), b.BackupPointName, "0")
@@ -196,7 +242,15 @@ func (h *handler) parseRecoverySteps(c *v1.Cluster, b *v1.Backup, restoreDir str
 		return nil, err
 	}
 
-	recoveryStep, err := getRecoveryStep(c, bp, b, restoreDir, masters, workers, names, ips, action)
+	// the encrypted backup is decrypted when it is downloaded, the agent resolves the key by its id,
+	// the recovery is refused here if the key is lost.
+	if b.Status.EncryptionKeyID != "" {
+		if _, err = core.BackupDecryptionKey(context.TODO(), h.coreOperator, bp, b.Status.EncryptionKeyID); err != nil {
+			return nil, err
+		}
+	}
+
+	recoveryStep, err := getRecoveryStep(c, bp, b, restoreDir, masters, workers, etcdNodes, names, ips, action)
 	if err != nil {
 		return nil, err
 	}
@@ -206,11 +260,12 @@ func (h *handler) parseRecoverySteps(c *v1.Cluster, b *v1.Backup, restoreDir str
 	return steps, nil
 }
 
-func getRecoveryStep(c *v1.Cluster, bp *v1.BackupPoint, b *v1.Backup, restoreDir string, masters, workers []component.Node, nodeNames, nodeIPs []string, action v1.StepAction) (steps []v1.Step, err error) {
+func getRecoveryStep(c *v1.Cluster, bp *v1.BackupPoint, b *v1.Backup, restoreDir string, masters, workers, etcdNodes []component.Node, nodeNames, nodeIPs []string, action v1.StepAction) (steps []v1.Step, err error) {
 	meta := component.ExtraMetadata{
 		ClusterName:        c.Name,
 		Masters:            masters,
 		Workers:            workers,
+		EtcdNodes:          etcdNodes,
 		CNI:                c.CNI.Type,
 		CNINamespace:       c.CNI.Namespace,
 		ControlPlaneStatus: c.Status.ControlPlaneHealth,
@@ -233,6 +288,10 @@ func getRecoveryStep(c *v1.Cluster, bp *v1.BackupPoint, b *v1.Backup, restoreDir
 		BackupFileSize: b.Status.BackupFileSize,
 		BackupFileMD5:  b.Status.BackupFileMD5,
 		FileDir:        f,
+		ExternalEtcd:   c.Etcd.IsManaged(),
+		// the key id is empty if the backup is not encrypted.
+		BackupPointName: bp.Name,
+		EncryptionKeyID: b.Status.EncryptionKeyID,
 	}
 
 	switch bp.StorageType {
@@ -243,6 +302,8 @@ func getRecoveryStep(c *v1.Cluster, bp *v1.BackupPoint, b *v1.Backup, restoreDir
 		r.AccessKeySecret = bp.S3Config.AccessKeySecret
 		r.Bucket = bp.S3Config.Bucket
 		r.Endpoint = bp.S3Config.Endpoint
+	case bs.SFTPStorage:
+		r.SftpConfig = bp.SftpConfig
 	}
 
 	err = r.InitSteps(ctx)
@@ -257,8 +318,9 @@ func getRecoveryStep(c *v1.Cluster, bp *v1.BackupPoint, b *v1.Backup, restoreDir
 	return nil, nil
 }
 
-// parseOperationFromComponent parse operation instance from component
-func (h *handler) parseOperationFromComponent(ctx context.Context, extraMetadata *component.ExtraMetadata, addons []v1.Addon, c *v1.Cluster, action v1.StepAction) (*v1.Operation, error) {
+// parseOperationFromComponent parse operation instance from component, the install operation registers
+// the uninstall steps of every component as its rollback if rollback is true.
+func (h *handler) parseOperationFromComponent(ctx context.Context, extraMetadata *component.ExtraMetadata, addons []v1.Addon, c *v1.Cluster, action v1.StepAction, rollback bool) (*v1.Operation, error) {
 	var err error
 	op := &v1.Operation{}
 	op.Name = uuid.New().String()
@@ -268,13 +330,52 @@ func (h *handler) parseOperationFromComponent(ctx context.Context, extraMetadata
 	}
 	// with extra meta data
 	ctx = component.WithExtraMetadata(ctx, *extraMetadata)
-	op.Steps, err = h.parseAddonStep(ctx, c, addons, action)
+	if rollback && action == v1.ActionInstall {
+		op.Steps, err = h.parseAddonStepWithRollback(ctx, c, addons, op)
+	} else {
+		op.Steps, err = h.parseAddonStep(ctx, c, addons, action)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return op, nil
 }
 
+// validateExternalEtcd the external etcd is either existing endpoints with client certs,
+// or dedicated etcd nodes which are not used by kubernetes.
+func validateExternalEtcd(c *v1.Cluster) error {
+	if !c.Etcd.IsExternal() {
+		return nil
+	}
+	external := c.Etcd.External
+	if c.Etcd.IsManaged() {
+		if len(external.Endpoints) > 0 || external.CACert != "" || external.ClientCert != "" || external.ClientKey != "" {
+			return fmt.Errorf("the endpoints and certs of external etcd are generated if etcd nodes are specified")
+		}
+		k8sNodes := sets.NewString(c.Masters.GetNodeIDs()...).Insert(c.Workers.GetNodeIDs()...)
+		etcdNodes := sets.NewString()
+		for _, node := range external.Nodes {
+			if k8sNodes.Has(node.ID) || etcdNodes.Has(node.ID) {
+				return fmt.Errorf("etcd node %s is duplicated", node.ID)
+			}
+			etcdNodes.Insert(node.ID)
+		}
+		return nil
+	}
+	if len(external.Endpoints) == 0 {
+		return fmt.Errorf("either the endpoints or the nodes of external etcd must be specified")
+	}
+	for _, endpoint := range external.Endpoints {
+		if u, err := url.Parse(endpoint); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("invalid external etcd endpoint %s, must be like https://<host>:<port>", endpoint)
+		}
+	}
+	if external.CACert == "" || external.ClientCert == "" || external.ClientKey == "" {
+		return fmt.Errorf("the ca cert, client cert and client key of external etcd must be specified")
+	}
+	return nil
+}
+
 func (h *handler) parseActBackupSteps(c *v1.Cluster, b *v1.Backup, action v1.StepAction) ([]v1.Step, error) {
 	steps := make([]v1.Step, 0)
 
@@ -289,7 +390,15 @@ func (h *handler) parseActBackupSteps(c *v1.Cluster, b *v1.Backup, action v1.Ste
 	if err != nil {
 		return nil, err
 	}
-	actBackupStep, err := getActBackupStep(c, b, bp, pNode, action)
+	// the key which encrypts the new backup is recorded in its status, the deletion does not need the key.
+	var keyID string
+	if action == v1.ActionInstall {
+		if keyID, _, err = core.BackupEncryptionKey(context.TODO(), h.coreOperator, bp); err != nil {
+			return nil, err
+		}
+		b.Status.EncryptionKeyID = keyID
+	}
+	actBackupStep, err := getActBackupStep(c, b, bp, pNode, keyID, action)
 	if err != nil {
 		return nil, err
 	}
@@ -299,7 +408,7 @@ func (h *handler) parseActBackupSteps(c *v1.Cluster, b *v1.Backup, action v1.Ste
 	return steps, nil
 }
 
-func getActBackupStep(c *v1.Cluster, b *v1.Backup, bp *v1.BackupPoint, pNode *v1.Node, action v1.StepAction) (steps []v1.Step, err error) {
+func getActBackupStep(c *v1.Cluster, b *v1.Backup, bp *v1.BackupPoint, pNode *v1.Node, keyID string, action v1.StepAction) (steps []v1.Step, err error) {
 	var actBackup *k8s.ActBackup
 	meta := component.ExtraMetadata{
 		ClusterName: c.Name,
@@ -327,8 +436,18 @@ func getActBackupStep(c *v1.Cluster, b *v1.Backup, bp *v1.BackupPoint, pNode *v1
 			BackupFileName:     b.Status.FileName,
 			BackupPointRootDir: bp.FsConfig.BackupRootDir,
 		}
+	case bs.SFTPStorage:
+		actBackup = &k8s.ActBackup{
+			StoreType:      bp.StorageType,
+			BackupFileName: b.Status.FileName,
+			SftpConfig:     bp.SftpConfig,
+		}
 	}
 
+	if c.Etcd.IsExternal() {
+		actBackup.ExternalEtcdEndpoints = c.Etcd.External.Endpoints
+	}
+	actBackup.BackupPointName, actBackup.EncryptionKeyID = bp.Name, keyID
 	if err = actBackup.InitSteps(ctx); err != nil {
 		return
 	}
@@ -353,6 +472,19 @@ func (h *handler) checkBackupPointInUse(backups *v1.BackupList, name string) boo
 	return false
 }
 
+// validateSftpConfig checks the sftp backup point can login the host, the connection is not made.
+func validateSftpConfig(c *v1.SftpConfig) error {
+	if c == nil {
+		return fmt.Errorf("sftp config must be provided for %s backup point", bs.SFTPStorage)
+	}
+	if c.BackupRootDir != "" && !filepath.IsAbs(c.BackupRootDir) {
+		return fmt.Errorf("sftp backup root dir %s must be an absolute path", c.BackupRootDir)
+	}
+	store := &bs.SftpStore{Host: c.Host, User: c.User, Password: c.Password, PrivateKey: c.PrivateKey}
+	_, err := store.Create()
+	return err
+}
+
 func MarkToOriginNode(ctx context.Context, operator cluster.Operator, kcNodeID string) (*v1.Node, error) {
 	node, err := operator.GetNode(ctx, kcNodeID)
 	if err != nil {
@@ -405,6 +537,25 @@ func (h *handler) parseAddonStep(ctx context.Context, clu *v1.Cluster, addons []
 	return steps, nil
 }
 
+// parseAddonStepWithRollback parses the install steps of addons, the uninstall steps of every addon are registered
+// as the rollback of its install steps.
+func (h *handler) parseAddonStepWithRollback(ctx context.Context, clu *v1.Cluster, addons []v1.Addon, op *v1.Operation) ([]v1.Step, error) {
+	var steps []v1.Step
+	for i := range addons {
+		s, err := h.parseAddonStep(ctx, clu, addons[i:i+1], v1.ActionInstall)
+		if err != nil {
+			return nil, err
+		}
+		rollback, err := h.parseAddonStep(ctx, clu, addons[i:i+1], v1.ActionUninstall)
+		if err != nil {
+			return nil, err
+		}
+		op.AddRollback(s, rollback)
+		steps = append(steps, s...)
+	}
+	return steps, nil
+}
+
 // CreateWithToken creates a KubeConfig object with access to the API server with a token
 // Copy from  k8s.io/kubernetes/cmd/kubeadm/app/util/kubeconfig/kubeconfig.go
 func CreateWithToken(serverURL, clusterName, userName string, caCert []byte, token string) *clientcmdapi.Config {
@@ -458,3 +609,61 @@ func buildPendingOperation(operationType, timeout, clusterResourceVersion string
 		ExtraData:              extraData,
 	}, nil
 }
+
+// makeUpgradeOperation make the upgrade steps of every version in the path, the cluster is upgraded one hop after another.
+// The annotations of operation record which steps end the hops, change the progress of nodes and pause the operation.
+func makeUpgradeOperation(extra component.ExtraMetadata, c *v1.Cluster, path []string, strategy *v1.UpgradeStrategy) (*v1.Operation, error) {
+	op := &v1.Operation{}
+	hops := make(map[string]string, len(path))
+	nodeSteps := make(map[string]v1.OperationNodeSteps)
+	var pauseAfterSteps []string
+	for _, ver := range path {
+		extra.KubeVersion = ver
+		upgradeComp := &k8s.Upgrade{}
+		upgradeComp.InitStepper(&extra, c)
+		upgradeComp.Strategy = k8s.MergeUpgradeStrategy(strategy)
+		if err := upgradeComp.Validate(); err != nil {
+			return nil, err
+		}
+		if err := upgradeComp.InitSteps(component.WithExtraMetadata(context.TODO(), extra)); err != nil {
+			return nil, err
+		}
+		hopSteps := upgradeComp.GetInstallSteps()
+		if len(hopSteps) == 0 {
+			return nil, fmt.Errorf("no upgrade steps for version %s", ver)
+		}
+		hops[hopSteps[len(hopSteps)-1].ID] = ver
+		for id, nodes := range upgradeComp.GetNodeSteps() {
+			nodeSteps[id] = nodes
+		}
+		pauseAfterSteps = append(pauseAfterSteps, upgradeComp.GetPauseAfterSteps()...)
+		op.Steps = append(op.Steps, hopSteps...)
+	}
+
+	hopsBytes, err := json.Marshal(hops)
+	if err != nil {
+		return nil, err
+	}
+	nodeStepsBytes, err := json.Marshal(nodeSteps)
+	if err != nil {
+		return nil, err
+	}
+	op.Annotations = map[string]string{
+		common.AnnotationUpgradeHops: string(hopsBytes),
+		common.AnnotationNodeSteps:   string(nodeStepsBytes),
+	}
+	if len(pauseAfterSteps) > 0 {
+		op.Annotations[common.AnnotationPauseAfterSteps] = strings.Join(pauseAfterSteps, ",")
+	}
+	nodes := make([]component.Node, 0, len(extra.Masters)+len(extra.Workers))
+	nodes = append(nodes, extra.Masters...)
+	nodes = append(nodes, extra.Workers...)
+	for _, node := range nodes {
+		op.Status.Nodes = append(op.Status.Nodes, v1.OperationNodeStatus{
+			ID:       node.ID,
+			Hostname: node.Hostname,
+			Phase:    v1.OperationNodePending,
+		})
+	}
+	return op, nil
+}
@@ -21,6 +21,7 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/kubeclipper/kubeclipper/pkg/constatns"
@@ -147,7 +148,7 @@ var (
 )
 
 func Test_parseOperationFromCluster(t *testing.T) {
-	h := newHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	h := newHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 	type args struct {
 		c      *v1.Cluster
 		meta   *component.ExtraMetadata
@@ -179,7 +180,7 @@ func Test_parseOperationFromCluster(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			_, err := h.parseOperationFromCluster(tt.args.meta, tt.args.c, tt.args.action)
+			_, err := h.parseOperationFromCluster(tt.args.meta, tt.args.c, tt.args.action, false)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("parseOperationFromCluster() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -195,7 +196,7 @@ func Test_parseOperationFromComponent(t *testing.T) {
 		cluster    *v1.Cluster
 		components []v1.Addon
 	}
-	h := newHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	h := newHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 	nfs := nfsprovisioner.NFSProvisioner{
 		ManifestsDir:     "/tmp/.nfs",
 		Namespace:        "kube-system",
@@ -244,7 +245,7 @@ func Test_parseOperationFromComponent(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			_, err := h.parseOperationFromComponent(context.Background(), test.arg.meta, test.arg.components, test.arg.cluster, test.arg.action)
+			_, err := h.parseOperationFromComponent(context.Background(), test.arg.meta, test.arg.components, test.arg.cluster, test.arg.action, false)
 			if err != nil {
 				t.Errorf("  parseOperationFromComponent() error: %v", err)
 			}
@@ -252,6 +253,32 @@ func Test_parseOperationFromComponent(t *testing.T) {
 	}
 }
 
+func Test_parseOperationFromComponentRollback(t *testing.T) {
+	h := newHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	nfsByte, _ := json.Marshal(nfsprovisioner.NFSProvisioner{
+		ManifestsDir:     "/tmp/.nfs",
+		Namespace:        "kube-system",
+		Replicas:         1,
+		ServerAddr:       "172.20.151.105",
+		SharedPath:       "/tmp/nfs/data",
+		StorageClassName: "nfs-sc",
+		ReclaimPolicy:    "Delete",
+	})
+	com := []v1.Addon{{Name: "nfs-provisioner", Version: "v1", Config: runtime.RawExtension{Raw: nfsByte}}}
+	op, err := h.parseOperationFromComponent(context.Background(), extraMeta, com, c1, v1.ActionInstall, true)
+	if err != nil {
+		t.Fatalf("parseOperationFromComponent() error: %v", err)
+	}
+	if len(op.Rollbacks) != 1 || op.Rollbacks[0].StepID != op.Steps[0].ID || len(op.Rollbacks[0].Steps) == 0 {
+		t.Fatalf("unexpected rollbacks: %v", op.Rollbacks)
+	}
+	for _, step := range op.Rollbacks[0].Steps {
+		if _, ok := op.GetStep(step.ID); ok || !op.IsRollbackStep(step.ID) {
+			t.Errorf("rollback step %s(%s) must not be a step of operation", step.Name, step.ID)
+		}
+	}
+}
+
 func setUpClusterMock(clusterMockOperator *mock_cluster.MockOperator) {
 	clusterMockOperator.EXPECT().ListNodes(gomock.Any(), gomock.Any()).Return(
 		&v1.NodeList{
@@ -452,3 +479,136 @@ func Test_parseActBackupSteps(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateExternalEtcd(t *testing.T) {
+	tests := []struct {
+		name    string
+		etcd    *v1.ExternalEtcd
+		wantErr bool
+	}{
+		{
+			name: "stacked etcd",
+		},
+		{
+			name: "existing etcd",
+			etcd: &v1.ExternalEtcd{
+				Endpoints: []string{"https://192.168.10.1:2379"},
+				CACert:    "ca", ClientCert: "cert", ClientKey: "key",
+			},
+		},
+		{
+			name: "existing etcd without certs",
+			etcd: &v1.ExternalEtcd{
+				Endpoints: []string{"https://192.168.10.1:2379"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid endpoint",
+			etcd: &v1.ExternalEtcd{
+				Endpoints: []string{"192.168.10.1:2379"},
+				CACert:    "ca", ClientCert: "cert", ClientKey: "key",
+			},
+			wantErr: true,
+		},
+		{
+			name:    "neither endpoints nor nodes",
+			etcd:    &v1.ExternalEtcd{},
+			wantErr: true,
+		},
+		{
+			name: "managed etcd",
+			etcd: &v1.ExternalEtcd{
+				Nodes: v1.WorkerNodeList{{ID: "b8ee0a2d-5d3a-4e3b-9d43-0d8a4e5a1f01"}},
+			},
+		},
+		{
+			name: "managed etcd on master",
+			etcd: &v1.ExternalEtcd{
+				Nodes: v1.WorkerNodeList{{ID: "1e3ea00f-1403-46e5-a486-70e4cb29d541"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "managed etcd with endpoints",
+			etcd: &v1.ExternalEtcd{
+				Endpoints: []string{"https://192.168.10.1:2379"},
+				Nodes:     v1.WorkerNodeList{{ID: "b8ee0a2d-5d3a-4e3b-9d43-0d8a4e5a1f01"}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := c1.DeepCopy()
+			c.Etcd.External = tt.etcd
+			if err := validateExternalEtcd(c); (err != nil) != tt.wantErr {
+				t.Errorf("validateExternalEtcd() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_makeUpgradeOperation(t *testing.T) {
+	path := []string{"v1.19.16", "v1.20.13"}
+	op, err := makeUpgradeOperation(*extraMeta, c1, path, &v1.UpgradeStrategy{MaxUnavailable: 2, PauseAfterControlPlane: true})
+	if err != nil {
+		t.Fatalf("makeUpgradeOperation() error: %v", err)
+	}
+	hops := make(map[string]string)
+	if err = json.Unmarshal([]byte(op.Annotations[common.AnnotationUpgradeHops]), &hops); err != nil {
+		t.Fatalf("unmarshal upgrade hops error: %v", err)
+	}
+	if len(hops) != len(path) {
+		t.Fatalf("makeUpgradeOperation() hops = %v, want %d hops", hops, len(path))
+	}
+	// the hops are run in order and every hop ends with its last step
+	next := 0
+	for _, step := range op.Steps {
+		if ver, ok := hops[step.ID]; ok {
+			if ver != path[next] {
+				t.Errorf("hop %d upgrades to %s, want %s", next, ver, path[next])
+			}
+			next++
+		}
+	}
+	if hops[op.Steps[len(op.Steps)-1].ID] != "v1.20.13" {
+		t.Errorf("the last step must end the last hop")
+	}
+	// every hop pauses after its control plane
+	if got := len(strings.Split(op.Annotations[common.AnnotationPauseAfterSteps], ",")); got != len(path) {
+		t.Errorf("pause after %d steps, want %d", got, len(path))
+	}
+	if len(op.Status.Nodes) != len(extraMeta.Masters)+len(extraMeta.Workers) {
+		t.Errorf("operation nodes = %v, want all cluster nodes", op.Status.Nodes)
+	}
+	for _, node := range op.Status.Nodes {
+		if node.Phase != v1.OperationNodePending {
+			t.Errorf("node %s is %s, want pending", node.ID, node.Phase)
+		}
+	}
+}
+
+func Test_makeUpgradeOperationPartialStrategy(t *testing.T) {
+	// the fields not set by the strategy keep their defaults, e.g. the nodes are still drained.
+	op, err := makeUpgradeOperation(*extraMeta, c1, []string{"v1.19.16"}, &v1.UpgradeStrategy{PauseAfterControlPlane: true})
+	if err != nil {
+		t.Fatalf("makeUpgradeOperation() error: %v", err)
+	}
+	drained := false
+	for _, step := range op.Steps {
+		if strings.HasPrefix(step.Name, "DrainNode-") {
+			cmd := step.Commands[0].ShellCommand[2]
+			if !strings.Contains(cmd, "kubectl drain") || !strings.Contains(cmd, "--timeout=5m0s") {
+				t.Errorf("step %s evicts the node by %s, want drain with the default timeout", step.Name, cmd)
+			}
+			drained = true
+		}
+	}
+	if !drained {
+		t.Errorf("makeUpgradeOperation() drains no worker")
+	}
+	if op.Annotations[common.AnnotationPauseAfterSteps] == "" {
+		t.Errorf("makeUpgradeOperation() must pause after the control plane")
+	}
+}
@@ -41,6 +41,17 @@ func New(username, token string) *Authenticator {
 
 var ErrInvalidToken = errors.New("invalid internal token")
 
+// internalExtraKey marks the user authenticated by the internal token, no other authenticator sets it.
+const internalExtraKey = "kubeclipper.io/internal"
+
+// IsInternalUser reports whether the user is the internal user of server, e.g. its informers.
+func IsInternalUser(u user.Info) bool {
+	if u == nil {
+		return false
+	}
+	return len(u.GetExtra()[internalExtraKey]) > 0
+}
+
 func (a *Authenticator) AuthenticateRequest(req *http.Request) (*authenticator.Response, bool, error) {
 	username := strings.TrimSpace(req.Header.Get(clientrest.KcUserHeader))
 	token := strings.TrimSpace(req.Header.Get(clientrest.KcTokenHeader))
@@ -53,6 +64,7 @@ func (a *Authenticator) AuthenticateRequest(req *http.Request) (*authenticator.R
 			User: &user.DefaultInfo{
 				Name:   a.username,
 				Groups: []string{user.AllAuthenticated},
+				Extra:  map[string][]string{internalExtraKey: {"true"}},
 			},
 		}, true, nil
 	}
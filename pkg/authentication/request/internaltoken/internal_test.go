@@ -77,6 +77,7 @@ func TestAuthenticator_AuthenticateRequest(t *testing.T) {
 				User: &user.DefaultInfo{
 					Name:   "testName",
 					Groups: []string{user.AllAuthenticated},
+					Extra:  map[string][]string{internalExtraKey: {"true"}},
 				},
 			},
 			want1:   true,
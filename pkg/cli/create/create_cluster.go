@@ -81,6 +81,7 @@ type CreateClusterOptions struct {
 	K8sVersion    string
 	CNI           string
 	CNIVersion    string
+	ProxyMode     string
 	Name          string
 	createdByIP   bool
 	CertSans      []string
@@ -90,8 +91,9 @@ type CreateClusterOptions struct {
 }
 
 var (
-	allowedCRI = sets.NewString("containerd", "docker")
-	allowedCNI = sets.NewString("calico")
+	allowedCRI       = sets.NewString("containerd", "docker", "crio")
+	allowedCNI       = sets.NewString("calico", "cilium")
+	allowedProxyMode = sets.NewString(v1.ProxyModeIPVS, v1.ProxyModeIPTables, v1.ProxyModeEBPF)
 )
 
 func NewCreateClusterOptions(streams options.IOStreams) *CreateClusterOptions {
@@ -105,6 +107,7 @@ func NewCreateClusterOptions(streams options.IOStreams) *CreateClusterOptions {
 		Offline:       true,
 		CRI:           "containerd",
 		CNI:           "calico",
+		ProxyMode:     v1.ProxyModeIPVS,
 		createdByIP:   false,
 	}
 }
@@ -131,11 +134,12 @@ func NewCmdCreateCluster(streams options.IOStreams) *cobra.Command {
 	cmd.Flags().BoolVar(&o.UntaintMaster, "untaint-master", o.UntaintMaster, "untaint master node after cluster create")
 	cmd.Flags().BoolVar(&o.Offline, "offline", o.Offline, "create cluster online or offline")
 	cmd.Flags().StringVar(&o.LocalRegistry, "local-registry", o.LocalRegistry, "use local registry address to pull image")
-	cmd.Flags().StringVar(&o.CRI, "cri", o.CRI, "k8s cri type, docker or containerd")
+	cmd.Flags().StringVar(&o.CRI, "cri", o.CRI, "k8s cri type, docker, containerd or crio")
 	cmd.Flags().StringVar(&o.CRIVersion, "cri-version", o.CRIVersion, "k8s cri version")
 	cmd.Flags().StringVar(&o.K8sVersion, "k8s-version", o.K8sVersion, "k8s version")
 	cmd.Flags().StringVar(&o.CNI, "cni", o.CNI, "k8s cni type, calico or others")
 	cmd.Flags().StringVar(&o.CNIVersion, "cni-version", o.CNIVersion, "k8s cni version")
+	cmd.Flags().StringVar(&o.ProxyMode, "proxy-mode", o.ProxyMode, "k8s service proxy mode, ipvs, iptables or ebpf(cilium only, kube-proxy will not be deployed)")
 	cmd.Flags().StringSliceVar(&o.CertSans, "cert-sans", o.CertSans, "k8s cluster certificate signing ipList or domainList")
 	cmd.Flags().StringVar(&o.CaCertFile, "ca-cert", o.CaCertFile, "k8s external root-ca cert file")
 	cmd.Flags().StringVar(&o.CaKeyFile, "ca-key", o.CaKeyFile, "k8s external root-ca key file")
@@ -158,6 +162,9 @@ func NewCmdCreateCluster(streams options.IOStreams) *cobra.Command {
 	utils.CheckErr(cmd.RegisterFlagCompletionFunc("cni", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return allowedCNI.List(), cobra.ShellCompDirectiveNoFileComp
 	}))
+	utils.CheckErr(cmd.RegisterFlagCompletionFunc("proxy-mode", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return allowedProxyMode.List(), cobra.ShellCompDirectiveNoFileComp
+	}))
 	utils.CheckErr(cmd.RegisterFlagCompletionFunc("cri-version", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return o.listCRI(toComplete), cobra.ShellCompDirectiveNoFileComp
 	}))
@@ -220,6 +227,12 @@ func (l *CreateClusterOptions) ValidateArgs(cmd *cobra.Command) error {
 	if !allowedCNI.Has(l.CNI) {
 		return utils.UsageErrorf(cmd, "unsupported cni,support %v now", allowedCNI.List())
 	}
+	if !allowedProxyMode.Has(l.ProxyMode) {
+		return utils.UsageErrorf(cmd, "unsupported proxy mode,support %v now", allowedProxyMode.List())
+	}
+	if l.ProxyMode == v1.ProxyModeEBPF && l.CNI != "cilium" {
+		return utils.UsageErrorf(cmd, "proxy mode ebpf is only supported by cilium")
+	}
 	if len(l.Masters)%2 == 0 {
 		return utils.UsageErrorf(cmd, "master node must be odd")
 	}
@@ -348,7 +361,7 @@ func (l *CreateClusterOptions) newCluster() *v1.Cluster {
 			Services:      v1.NetworkRanges{CIDRBlocks: []string{constatns.ClusterServiceSubnet}},
 			Pods:          v1.NetworkRanges{CIDRBlocks: []string{constatns.ClusterPodSubnet}},
 			DNSDomain:     "cluster.local",
-			ProxyMode:     "ipvs",
+			ProxyMode:     l.ProxyMode,
 			WorkerNodeVip: "169.254.169.100",
 		},
 
@@ -358,18 +371,26 @@ func (l *CreateClusterOptions) newCluster() *v1.Cluster {
 			LocalRegistry: l.LocalRegistry,
 			Type:          l.CNI,
 			Version:       l.CNIVersion,
-			Calico: &v1.Calico{
-				IPv4AutoDetection: "first-found",
-				IPv6AutoDetection: "first-found",
-				Mode:              "Overlay-Vxlan-All",
-				IPManger:          true,
-				MTU:               1440,
-			},
 		},
 
 		Status: v1.ClusterStatus{},
 	}
 
+	switch l.CNI {
+	case "calico":
+		c.CNI.Calico = &v1.Calico{
+			IPv4AutoDetection: "first-found",
+			IPv6AutoDetection: "first-found",
+			Mode:              "Overlay-Vxlan-All",
+			IPManger:          true,
+			MTU:               1440,
+		}
+	case "cilium":
+		c.CNI.Cilium = &v1.Cilium{
+			Mode: "vxlan",
+		}
+	}
+
 	if l.Project != "" {
 		c.Labels = map[string]string{common.LabelProject: l.Project}
 	}
@@ -417,6 +438,12 @@ func (l *CreateClusterOptions) newCluster() *v1.Cluster {
 			Version:          l.CRIVersion,
 			InsecureRegistry: insecureRegistry,
 		}
+	case "crio":
+		c.ContainerRuntime = v1.ContainerRuntime{
+			Type:             v1.CRICrio,
+			Version:          l.CRIVersion,
+			InsecureRegistry: insecureRegistry,
+		}
 	case "containerd":
 		fallthrough
 	default:
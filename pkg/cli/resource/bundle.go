@@ -0,0 +1,91 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package resource
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component/bundle"
+)
+
+// typeComponent the type of the component bundles, they are loaded when kubeclipper-server starts.
+const typeComponent = "component"
+
+// packBundle validates the component bundle in the directory and packs it into dst as the resource package
+// name-version-arch.tar.gz, whose files are laid out as name/version/arch/... .
+func packBundle(dir, dst, arch string) (string, error) {
+	b, err := bundle.Load(dir)
+	if err != nil {
+		return "", err
+	}
+	pkg := filepath.Join(dst, fmt.Sprintf("%s-%s-%s.tar.gz", b.Name, b.Version, arch))
+	f, err := os.Create(pkg)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	prefix := filepath.Join(b.Name, b.Version, arch)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(filepath.Join(prefix, rel))
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		_, err = io.Copy(tw, src)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if err = tw.Close(); err != nil {
+		return "", err
+	}
+	if err = gw.Close(); err != nil {
+		return "", err
+	}
+	return pkg, nil
+}
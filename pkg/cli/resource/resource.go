@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -99,6 +100,9 @@ const (
   # Push offline resource nfs
   kcctl resource push --pkg /root/nfs-v4.0.2-amd64.tar.gz --type csi
 
+  # Push the component bundle in the directory, it is loaded when kubeclipper-server restarts
+  kcctl resource push --pkg /root/my-addon --type component --arch amd64
+
   Please read 'kcctl resource push -h' get more resource push flags`
 	deleteLongDescription = `
   Delete offline resource packs
@@ -226,6 +230,7 @@ func NewCmdResourcePush(o *ResourceOptions) *cobra.Command {
 	o.cliOpts.AddFlags(cmd.Flags())
 	cmd.Flags().StringVar(&o.Type, "type", o.Type, "offline resource type.")
 	cmd.Flags().StringVar(&o.Pkg, "pkg", o.Pkg, "docker service and images pkg.")
+	cmd.Flags().StringVar(&o.Arch, "arch", o.Arch, "the arch of the component bundle directory.")
 
 	utils.CheckErr(cmd.RegisterFlagCompletionFunc("type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return o.listType(toComplete), cobra.ShellCompDirectiveNoFileComp
@@ -402,6 +407,24 @@ func (o *ResourceOptions) ResourcePush() error {
 		5.send tmp metadata.json to remote
 		6.del tmp metadata.json
 	*/
+	// the component bundle directory is packed as the resource package
+	if info, err := os.Stat(o.Pkg); err == nil && info.IsDir() {
+		if o.Type != typeComponent {
+			return fmt.Errorf("only the %s bundle can be pushed as a directory", typeComponent)
+		}
+		tmp, err := os.MkdirTemp("", "kc-bundle")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		arch := o.Arch
+		if arch == "" {
+			arch = runtime.GOARCH
+		}
+		if o.Pkg, err = packBundle(o.Pkg, tmp, arch); err != nil {
+			return err
+		}
+	}
 	// check local package
 	name, version, arch, err := o.parsePackageName()
 	if err != nil {
@@ -486,6 +509,9 @@ func (o *ResourceOptions) ResourcePush() error {
 	_ = os.RemoveAll("metadata.json")
 
 	logger.Info("resource push successfully")
+	if o.Type == typeComponent {
+		logger.Info("the component bundle is loaded when kubeclipper-server restarts")
+	}
 	return nil
 }
 
@@ -783,6 +783,8 @@ func (r *Kubeadm) kubectlTerminal(ctx context.Context, node KubeNode, action v1.
 			loadImage = fmt.Sprintf("docker load -i %s", exImage)
 		case v1.CRIContainerd:
 			loadImage = fmt.Sprintf("ctr --namespace k8s.io image import --all-platforms %s", exImage)
+		case v1.CRICrio:
+			loadImage = fmt.Sprintf("podman load -i %s", exImage)
 		default:
 			logger.Warnf("unsupported cri types: ", node.cri)
 		}
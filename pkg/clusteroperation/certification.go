@@ -0,0 +1,42 @@
+package clusteroperation
+
+import (
+	"github.com/kubeclipper/kubeclipper/pkg/component/utils"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	corev1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/k8s"
+)
+
+var _ Interface = (*CertificationOperation)(nil)
+
+// CertificationOperation renew all certificates of the control plane nodes.
+type CertificationOperation struct {
+	Options
+}
+
+func NewCertificationOperation(options Options) *CertificationOperation {
+	return &CertificationOperation{options}
+}
+
+func (c *CertificationOperation) Builder() (*corev1.Operation, error) {
+	if len(c.extra.Masters) == 0 {
+		return nil, ErrZeroNode
+	}
+	steps, err := (&k8s.Certification{}).InstallSteps(c.cluster, utils.UnwrapNodeList(c.extra.Masters))
+	if err != nil {
+		return nil, err
+	}
+
+	op := &corev1.Operation{}
+	// use pass-through operationID
+	op.Name = c.extra.OperationID
+	op.Labels = map[string]string{
+		common.LabelClusterName:     c.cluster.Name,
+		common.LabelOperationAction: corev1.OperationUpdateCertification,
+		common.LabelTimeoutSeconds:  c.pendingOperation.Timeout,
+	}
+	op.Steps = steps
+	op.Status.Status = corev1.OperationStatusPending
+
+	return op, nil
+}
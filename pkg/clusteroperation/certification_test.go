@@ -0,0 +1,43 @@
+package clusteroperation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func Test_CertificationBuilder(t *testing.T) {
+	pendingOp := v1.PendingOperation{
+		OperationID:   "renew",
+		OperationType: v1.OperationUpdateCertification,
+		Timeout:       v1.DefaultOperationTimeoutSecs,
+	}
+	extra := &component.ExtraMetadata{
+		OperationID: "renew",
+		Masters: []component.Node{
+			{ID: "1e3ea00f-1403-46e5-a486-70e4cb29d541", IPv4: "192.168.1.1", Hostname: "master-1"},
+		},
+	}
+	clu := c2.DeepCopy()
+	clu.KubernetesVersion = "v1.23.6"
+	op, err := BuildOperationAdapter(clu, pendingOp, extra, nil)
+	if err != nil {
+		t.Fatalf("BuildOperationAdapter() error: %v", err)
+	}
+	if op.Name != "renew" || op.Labels[common.LabelOperationAction] != v1.OperationUpdateCertification {
+		t.Errorf("unexpected operation %s with action %s", op.Name, op.Labels[common.LabelOperationAction])
+	}
+	for _, step := range op.Steps {
+		if len(step.Nodes) != 1 || step.Nodes[0].Hostname != "master-1" {
+			t.Errorf("step %s must run on the control plane nodes", step.Name)
+		}
+	}
+
+	extra.Masters = nil
+	if _, err = BuildOperationAdapter(clu, pendingOp, extra, nil); !errors.Is(err, ErrZeroNode) {
+		t.Errorf("BuildOperationAdapter() error = %v, want %v", err, ErrZeroNode)
+	}
+}
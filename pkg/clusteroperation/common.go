@@ -24,6 +24,13 @@ func getCriStep(ctx context.Context, c *v1.Cluster, action v1.StepAction, nodes
 			return nil, err
 		}
 		return r.GetActionSteps(action), nil
+	case v1.CRICrio:
+		r := cri.CrioRunnable{}
+		err := r.InitStep(ctx, c, nodes)
+		if err != nil {
+			return nil, err
+		}
+		return r.GetActionSteps(action), nil
 	}
 	return nil, fmt.Errorf("%v type CRI is not supported", c.ContainerRuntime.Type)
 }
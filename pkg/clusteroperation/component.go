@@ -0,0 +1,149 @@
+package clusteroperation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+// SetComponents records the addons installed, uninstalled or upgraded by the components operation,
+// the addons of cluster are changed with them when the operation is done, whether it is queued or not.
+func SetComponents(op *v1.Operation, addons []v1.Addon) error {
+	data, err := json.Marshal(addons)
+	if err != nil {
+		return err
+	}
+	if op.Annotations == nil {
+		op.Annotations = make(map[string]string)
+	}
+	op.Annotations[common.AnnotationComponents] = string(data)
+	return nil
+}
+
+// ApplyComponents changes the addons of cluster with the addons recorded by the components operation,
+// it can be applied again after the operation is retried.
+func ApplyComponents(c *v1.Cluster, op *v1.Operation) error {
+	data, ok := op.Annotations[common.AnnotationComponents]
+	if !ok {
+		return nil
+	}
+	var addons []v1.Addon
+	if err := json.Unmarshal([]byte(data), &addons); err != nil {
+		return err
+	}
+	switch op.Labels[common.LabelOperationAction] {
+	case v1.OperationInstallComponents:
+		for _, addon := range addons {
+			i, err := instanceIndex(c.Addons, addon)
+			if err != nil {
+				return err
+			}
+			if i < 0 {
+				c.Addons = append(c.Addons, addon)
+			} else {
+				c.Addons[i] = addon
+			}
+		}
+	case v1.OperationUninstallComponents:
+		for _, addon := range addons {
+			i, err := instanceIndex(c.Addons, addon)
+			if err != nil {
+				return err
+			}
+			if i >= 0 {
+				c.Addons = append(c.Addons[:i], c.Addons[i+1:]...)
+			}
+		}
+	case v1.OperationUpgradeComponents:
+		return UpgradeComponents(c, addons)
+	}
+	return nil
+}
+
+// UpgradeComponents replaces the installed instances of the upgraded addons in place.
+func UpgradeComponents(c *v1.Cluster, addons []v1.Addon) error {
+	for _, addon := range addons {
+		i, err := instanceIndex(c.Addons, addon)
+		if err != nil {
+			return err
+		}
+		if i < 0 {
+			return fmt.Errorf("%s component is not installed in the current cluster", addon.Name)
+		}
+		c.Addons[i] = addon
+	}
+	return nil
+}
+
+// instanceIndex returns the index of the installed instance of addon, -1 if it is not installed.
+func instanceIndex(addons []v1.Addon, addon v1.Addon) (int, error) {
+	comp, err := DecodeAddon(addon)
+	if err != nil {
+		return -1, err
+	}
+	for i, installed := range addons {
+		if installed.Name != addon.Name {
+			continue
+		}
+		installedComp, err := DecodeAddon(installed)
+		if err != nil {
+			return -1, err
+		}
+		if installedComp.GetInstanceName() == comp.GetInstanceName() {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// FindAddon returns the index of the installed instance of component, the instance name can be omitted
+// if only one instance of the component is installed.
+func FindAddon(addons []v1.Addon, name, instanceName string) (int, error) {
+	index := -1
+	for i, addon := range addons {
+		if addon.Name != name {
+			continue
+		}
+		if instanceName == "" {
+			if index >= 0 {
+				return -1, fmt.Errorf("multiple instances of %s component are installed, the instance name must be specified", name)
+			}
+			index = i
+			continue
+		}
+		comp, err := DecodeAddon(addon)
+		if err != nil {
+			return -1, err
+		}
+		if comp.GetInstanceName() == instanceName {
+			return i, nil
+		}
+	}
+	if index < 0 {
+		if instanceName != "" {
+			name = fmt.Sprintf("%s(%s)", name, instanceName)
+		}
+		return -1, fmt.Errorf("%s component is not installed in the current cluster", name)
+	}
+	return index, nil
+}
+
+// DecodeAddon decodes the configurations of addon into its component.
+func DecodeAddon(addon v1.Addon) (component.Interface, error) {
+	itf, ok := component.Load(fmt.Sprintf(component.RegisterFormat, addon.Name, addon.Version))
+	if !ok {
+		return nil, fmt.Errorf("kubeclipper does not support %s-%s component", addon.Name, addon.Version)
+	}
+	instance := itf.NewInstance()
+	if err := json.Unmarshal(addon.Config.Raw, instance); err != nil {
+		return nil, fmt.Errorf("%s-%s component configuration resolution error: %s", addon.Name, addon.Version, err.Error())
+	}
+	comp, ok := instance.(component.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s-%s is not a component", addon.Name, addon.Version)
+	}
+	return comp, nil
+}
@@ -0,0 +1,54 @@
+package clusteroperation
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	_ "github.com/kubeclipper/kubeclipper/pkg/component/nfs"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func TestApplyComponents(t *testing.T) {
+	addon := func(scName string, archive bool) v1.Addon {
+		return v1.Addon{
+			Name:    "nfs-provisioner",
+			Version: "v1",
+			Config: runtime.RawExtension{Raw: []byte(fmt.Sprintf(
+				`{"serverAddr":"10.0.0.1","sharedPath":"/data","scName":"%s","archiveOnDelete":%t}`, scName, archive))},
+		}
+	}
+	apply := func(c *v1.Cluster, action string, addons ...v1.Addon) {
+		op := &v1.Operation{}
+		op.Labels = map[string]string{common.LabelOperationAction: action}
+		if err := SetComponents(op, addons); err != nil {
+			t.Fatalf("SetComponents() error: %v", err)
+		}
+		if err := ApplyComponents(c, op); err != nil {
+			t.Fatalf("ApplyComponents(%s) error: %v", action, err)
+		}
+	}
+	c := &v1.Cluster{}
+	apply(c, v1.OperationInstallComponents, addon("a", false), addon("b", false))
+	apply(c, v1.OperationUpgradeComponents, addon("b", true))
+	if len(c.Addons) != 2 || string(c.Addons[1].Config.Raw) != string(addon("b", true).Config.Raw) {
+		t.Fatalf("the addon is not upgraded in place: %+v", c.Addons)
+	}
+	// the addons are applied again after the operation is retried.
+	apply(c, v1.OperationInstallComponents, addon("a", false))
+	apply(c, v1.OperationUninstallComponents, addon("a", false))
+	apply(c, v1.OperationUninstallComponents, addon("a", false))
+	if len(c.Addons) != 1 || string(c.Addons[0].Config.Raw) != string(addon("b", true).Config.Raw) {
+		t.Errorf("unexpected addons after uninstall: %+v", c.Addons)
+	}
+
+	// the operation without recorded addons does not change the cluster.
+	if err := ApplyComponents(c, &v1.Operation{}); err != nil || len(c.Addons) != 1 {
+		t.Errorf("ApplyComponents() = %v, addons %+v", err, c.Addons)
+	}
+	if err := UpgradeComponents(c, []v1.Addon{addon("c", false)}); err == nil {
+		t.Errorf("UpgradeComponents() must fail for the instance not installed")
+	}
+}
@@ -0,0 +1,99 @@
+package clusteroperation
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	corev1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/k8s"
+)
+
+var _ Interface = (*ConfigOperation)(nil)
+
+// ConfigOperation update the configuration of the kubernetes components of cluster.
+type ConfigOperation struct {
+	Options
+}
+
+func NewConfigOperation(options Options) *ConfigOperation {
+	return &ConfigOperation{options}
+}
+
+func (c *ConfigOperation) Builder() (*corev1.Operation, error) {
+	var config corev1.ClusterConfig
+	if err := json.Unmarshal(c.pendingOperation.ExtraData, &config); err != nil {
+		return nil, err
+	}
+	op, err := MakeConfigOperation(config, *c.extra, c.cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	op.Labels[common.LabelTimeoutSeconds] = c.pendingOperation.Timeout
+	op.Status.Status = corev1.OperationStatusPending
+
+	return op, nil
+}
+
+// MakeConfigOperation make the steps to apply the configuration node by node, the control plane nodes go first.
+// The static pods of control plane components and the kubelet are restarted on every node,
+// then the kube-proxy pods are restarted once the config is uploaded into the cluster.
+func MakeConfigOperation(config corev1.ClusterConfig, extra component.ExtraMetadata, cluster *corev1.Cluster) (*corev1.Operation, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if len(extra.Masters) == 0 {
+		return nil, ErrZeroNode
+	}
+	configBytes, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &corev1.Operation{}
+	// use pass-through operationID
+	op.Name = extra.OperationID
+	op.Labels = map[string]string{
+		common.LabelClusterName:     cluster.Name,
+		common.LabelOperationAction: corev1.OperationUpdateClusterConfig,
+	}
+	op.Annotations = map[string]string{
+		common.AnnotationClusterConfig: string(configBytes),
+	}
+
+	target := cluster.DeepCopy()
+	target.SetConfig(config)
+	kubeadm := (&k8s.KubeadmConfig{}).InitStepper(target, &extra)
+
+	for _, master := range extra.Masters {
+		stepNodes := []corev1.StepNode{{ID: master.ID, IPv4: master.IPv4, Hostname: master.Hostname}}
+		steps, err := kubeadm.InstallSteps(stepNodes)
+		if err != nil {
+			return nil, fmt.Errorf("make config steps of node %s failed: %w", master.Hostname, err)
+		}
+		op.Steps = append(op.Steps, steps...)
+		op.Steps = append(op.Steps, k8s.UpdateControlPlaneConfig(stepNodes), k8s.UpdateKubeletConfig(stepNodes))
+	}
+
+	// the config rendered on the last control plane node is uploaded.
+	runner := extra.Masters[len(extra.Masters)-1]
+	runnerNodes := []corev1.StepNode{{ID: runner.ID, IPv4: runner.IPv4, Hostname: runner.Hostname}}
+	op.Steps = append(op.Steps, k8s.UploadClusterConfig(runnerNodes))
+	if target.Networking.ProxyMode != corev1.ProxyModeEBPF {
+		op.Steps = append(op.Steps, k8s.UpdateKubeProxyConfig(runnerNodes))
+	}
+
+	for _, worker := range extra.Workers {
+		stepNodes := []corev1.StepNode{{ID: worker.ID, IPv4: worker.IPv4, Hostname: worker.Hostname}}
+		steps, err := kubeadm.InstallSteps(stepNodes)
+		if err != nil {
+			return nil, fmt.Errorf("make config steps of node %s failed: %w", worker.Hostname, err)
+		}
+		op.Steps = append(op.Steps, steps...)
+		op.Steps = append(op.Steps, k8s.UpdateKubeletConfig(stepNodes))
+	}
+
+	return op, nil
+}
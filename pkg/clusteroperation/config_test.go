@@ -0,0 +1,80 @@
+package clusteroperation
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func Test_MakeConfigOperation(t *testing.T) {
+	extra := component.ExtraMetadata{
+		OperationID: "config",
+		CRI:         v1.CRIContainerd,
+		KubeVersion: "v1.23.6",
+		Masters: []component.Node{
+			{ID: "1e3ea00f-1403-46e5-a486-70e4cb29d541", IPv4: "192.168.1.1", Hostname: "master-1"},
+			{ID: "43ed594a-a76f-4370-a14d-551e7b6153de", IPv4: "192.168.1.2", Hostname: "master-2"},
+		},
+		Workers: []component.Node{
+			{ID: "4cf1ad74-704c-4290-a523-e524e930245d", IPv4: "192.168.1.4", Hostname: "worker-1"},
+		},
+	}
+	config := v1.ClusterConfig{
+		FeatureGates: map[string]bool{"EphemeralContainers": true},
+		Kubelet:      v1.Kubelet{RootDir: "/data/kubelet", MaxPods: 200},
+	}
+	clu := c2.DeepCopy()
+	clu.KubernetesVersion = "v1.23.6"
+	clu.Kubelet.RootDir = "/var/lib/kubelet"
+
+	op, err := MakeConfigOperation(config, extra, clu)
+	if err != nil {
+		t.Fatalf("MakeConfigOperation() error: %v", err)
+	}
+	var got []string
+	for _, step := range op.Steps {
+		got = append(got, step.Name+"@"+step.Nodes[0].Hostname)
+	}
+	want := []string{
+		"renderKubeadmConfig@master-1", "updateControlPlaneConfig@master-1", "updateKubeletConfig@master-1",
+		"renderKubeadmConfig@master-2", "updateControlPlaneConfig@master-2", "updateKubeletConfig@master-2",
+		"uploadClusterConfig@master-2", "updateKubeProxyConfig@master-2",
+		"renderKubeadmConfig@worker-1", "updateKubeletConfig@worker-1",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("steps = %v, want %v", got, want)
+	}
+
+	var applied v1.ClusterConfig
+	if err = json.Unmarshal([]byte(op.Annotations[common.AnnotationClusterConfig]), &applied); err != nil {
+		t.Fatalf("unmarshal applied config error: %v", err)
+	}
+	if applied.Kubelet.MaxPods != 200 {
+		t.Errorf("applied config = %+v", applied)
+	}
+	// the kubelet root dir can not be changed on a running cluster.
+	var kubeadm struct {
+		Kubelet v1.Kubelet `json:"kubelet"`
+	}
+	if err = json.Unmarshal(op.Steps[0].Commands[0].Template.Data, &kubeadm); err != nil {
+		t.Fatalf("unmarshal kubeadm config error: %v", err)
+	}
+	if kubeadm.Kubelet.RootDir != "/var/lib/kubelet" || kubeadm.Kubelet.MaxPods != 200 {
+		t.Errorf("rendered kubelet = %+v", kubeadm.Kubelet)
+	}
+
+	// kube-proxy is not deployed in ebpf proxy mode.
+	clu.Networking.ProxyMode = v1.ProxyModeEBPF
+	if op, err = MakeConfigOperation(config, extra, clu); err != nil {
+		t.Fatalf("MakeConfigOperation() error: %v", err)
+	}
+	for _, step := range op.Steps {
+		if step.Name == "updateKubeProxyConfig" {
+			t.Errorf("step %s is not expected in ebpf proxy mode", step.Name)
+		}
+	}
+}
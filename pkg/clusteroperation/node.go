@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/kubeclipper/kubeclipper/pkg/clustermanage/kubeadm"
 	"github.com/kubeclipper/kubeclipper/pkg/component"
@@ -22,6 +23,7 @@ var (
 	ErrInvalidNodesOperation = errors.New("invalid nodes patch operation")
 	ErrInvalidNodesRole      = errors.New("invalid node role")
 	ErrZeroNode              = errors.New("zero node")
+	ErrEtcdQuorum            = errors.New("removing these control plane nodes would break etcd quorum")
 )
 
 type NodesPatchOperation string
@@ -31,6 +33,8 @@ type PatchNodes struct {
 	Nodes        corev1.WorkerNodeList `json:"nodes"`
 	ConvertNodes []component.Node      `json:"convertNodes"`
 	Role         common.NodeRole       `json:"role"`
+	// Rollback whether the added nodes are uninstalled if the operation fails.
+	Rollback bool `json:"rollback,omitempty"`
 }
 
 var _ Interface = (*NodeOperation)(nil)
@@ -77,8 +81,39 @@ func (p *PatchNodes) MakeCompare(cluster *corev1.Cluster) error {
 }
 
 func (p *PatchNodes) makeMasterCompare(cluster *corev1.Cluster) error {
-	// support later
-	return ErrInvalidNodesRole
+	// Compare between nodes unfiltered and existed master nodes in cluster.
+	switch p.Operation {
+	case NodesOperationAdd:
+		// Add nodes to cluster.
+		// Check nodes in cluster already.
+		// Filter out nodes to be added.
+		p.Nodes = p.Nodes.Complement(cluster.Masters...)
+		cluster.Masters = append(cluster.Masters, p.Nodes...)
+	case NodesOperationRemove:
+		// Remove nodes from cluster.
+		// Filter out nodes in cluster already.
+		// Filter out nodes to be removed.
+		p.Nodes = cluster.Masters.Intersect(p.Nodes...)
+		remaining := cluster.Masters.Complement(p.Nodes...)
+		// The remaining etcd members must still hold the quorum of the current etcd cluster,
+		// even if all the members to be removed are unavailable.
+		quorum := etcdQuorum(len(cluster.Masters))
+		if cluster.Etcd.IsExternal() {
+			// the masters are not etcd members, just keep at least one control plane node.
+			quorum = 1
+		}
+		if len(p.Nodes) > 0 && len(remaining) < quorum {
+			return ErrEtcdQuorum
+		}
+		cluster.Masters = remaining
+	default:
+		return ErrInvalidNodesOperation
+	}
+	return nil
+}
+
+func etcdQuorum(members int) int {
+	return members/2 + 1
 }
 
 func (p *PatchNodes) makeWorkerCompare(cluster *corev1.Cluster) error {
@@ -119,7 +154,7 @@ func (p *PatchNodes) MakeOperation(extra component.ExtraMetadata, cluster *corev
 func (p *PatchNodes) doMakeOperation(extra component.ExtraMetadata, cluster *corev1.Cluster) (*corev1.Operation, error) {
 	switch p.Role {
 	case common.NodeRoleMaster:
-		return nil, ErrInvalidNodesRole
+		return p.makeMasterOperation(extra, cluster)
 	case common.NodeRoleWorker:
 		return p.makeWorkerOperation(extra, cluster)
 	default:
@@ -161,18 +196,18 @@ func (p *PatchNodes) makeWorkerOperation(extra component.ExtraMetadata, cluster
 		op.Labels[common.LabelOperationAction] = corev1.OperationAddNodes
 
 		// container runtime
-		steps, err := getCriStep(ctx, cluster, action, stepNodes)
+		criSteps, err := getCriStep(ctx, cluster, action, stepNodes)
 		if err != nil {
 			return nil, err
 		}
-		op.Steps = append(op.Steps, steps...)
+		op.Steps = append(op.Steps, criSteps...)
 
 		// kubernetes
-		steps, err = p.getPackageSteps(cluster, action, stepNodes)
+		packageSteps, err := p.getPackageSteps(cluster, action, stepNodes)
 		if err != nil {
 			return nil, err
 		}
-		op.Steps = append(op.Steps, steps...)
+		op.Steps = append(op.Steps, packageSteps...)
 
 		// join node
 		gen := k8s.GenNode{}
@@ -180,14 +215,21 @@ func (p *PatchNodes) makeWorkerOperation(extra component.ExtraMetadata, cluster
 		if err != nil {
 			return nil, err
 		}
-		op.Steps = append(op.Steps, gen.GetSteps(action)...)
+		joinSteps := gen.GetSteps(action)
+		op.Steps = append(op.Steps, joinSteps...)
+
+		if p.Rollback {
+			if err = p.addRollback(op, extra, cluster, stepNodes, criSteps, packageSteps, joinSteps); err != nil {
+				return nil, err
+			}
+		}
 	case NodesOperationRemove:
 		action = corev1.ActionUninstall
 		op.Labels[common.LabelOperationAction] = corev1.OperationRemoveNodes
 
 		// drain node
 		gen := k8s.GenNode{}
-		err := gen.InitStepper(&extra, cluster, p.Role.String()).MakeUninstallSteps(&extra, stepNodes)
+		err := gen.InitStepper(&extra, cluster, p.Role.String()).MakeUninstallSteps(&extra, stepNodes, p.Role.String())
 		if err != nil {
 			return nil, err
 		}
@@ -213,6 +255,148 @@ func (p *PatchNodes) makeWorkerOperation(extra component.ExtraMetadata, cluster
 	return op, nil
 }
 
+func (p *PatchNodes) makeMasterOperation(extra component.ExtraMetadata, cluster *corev1.Cluster) (*corev1.Operation, error) {
+	// no node need to be operated
+	if len(p.ConvertNodes) == 0 {
+		return nil, ErrZeroNode
+	}
+	// make operation for adding master nodes to cluster
+	op := &corev1.Operation{}
+	// use pass-through operationID
+	op.Name = extra.OperationID
+	op.Labels = map[string]string{
+		common.LabelClusterName: cluster.Name,
+	}
+
+	// nodes to be added or removed, removed nodes are no longer in the master list of extra metadata.
+	var stepNodes []corev1.StepNode
+	patchIDs := make(map[string]struct{})
+	for _, node := range p.ConvertNodes {
+		patchIDs[node.ID] = struct{}{}
+		stepNodes = append(stepNodes, corev1.StepNode{
+			ID:       node.ID,
+			IPv4:     node.IPv4,
+			Hostname: node.Hostname,
+		})
+	}
+	masters := make([]component.Node, 0, len(extra.Masters)+len(p.ConvertNodes))
+	for _, node := range extra.Masters {
+		if _, ok := patchIDs[node.ID]; !ok {
+			masters = append(masters, node)
+		}
+	}
+
+	var action corev1.StepAction
+	switch p.Operation {
+	case NodesOperationAdd:
+		action = corev1.ActionInstall
+		op.Labels[common.LabelOperationAction] = corev1.OperationAddNodes
+		// the new control plane nodes must join through an existing one, so append them to the end.
+		extra.Masters = append(masters, p.ConvertNodes...)
+		// pass extra metadata in context
+		ctx := component.WithExtraMetadata(context.TODO(), extra)
+		// container runtime
+		criSteps, err := getCriStep(ctx, cluster, action, stepNodes)
+		if err != nil {
+			return nil, err
+		}
+		op.Steps = append(op.Steps, criSteps...)
+		// kubernetes
+		packageSteps, err := p.getPackageSteps(cluster, action, stepNodes)
+		if err != nil {
+			return nil, err
+		}
+		op.Steps = append(op.Steps, packageSteps...)
+		// join control plane node
+		gen := k8s.GenNode{}
+		err = gen.InitStepper(&extra, cluster, p.Role.String()).MakeInstallSteps(&extra, stepNodes, p.Role.String())
+		if err != nil {
+			return nil, err
+		}
+		joinSteps := gen.GetSteps(action)
+		op.Steps = append(op.Steps, joinSteps...)
+		// taint and label
+		steps, err := k8s.PatchTaintAndLabelStep(p.Nodes, nil, &extra)
+		if err != nil {
+			return nil, err
+		}
+		op.Steps = append(op.Steps, steps...)
+		if p.Rollback {
+			// the added control plane nodes are removed as the remove operation does.
+			extra.Masters = masters
+			if err = p.addRollback(op, extra, cluster, stepNodes, criSteps, packageSteps, joinSteps); err != nil {
+				return nil, err
+			}
+		}
+	case NodesOperationRemove:
+		action = corev1.ActionUninstall
+		op.Labels[common.LabelOperationAction] = corev1.OperationRemoveNodes
+		if len(masters) == 0 {
+			return nil, ErrEtcdQuorum
+		}
+		// the remaining control plane nodes
+		extra.Masters = masters
+		// pass extra metadata in context
+		ctx := component.WithExtraMetadata(context.TODO(), extra)
+		// drain node and remove etcd member
+		gen := k8s.GenNode{}
+		err := gen.InitStepper(&extra, cluster, p.Role.String()).MakeUninstallSteps(&extra, stepNodes, p.Role.String())
+		if err != nil {
+			return nil, err
+		}
+		op.Steps = append(op.Steps, gen.GetSteps(action)...)
+		// kubernetes
+		steps, err := p.getPackageSteps(cluster, action, stepNodes)
+		if err != nil {
+			return nil, err
+		}
+		op.Steps = append(op.Steps, steps...)
+		// container runtime
+		steps, err = getCriStep(ctx, cluster, action, stepNodes)
+		if err != nil {
+			return nil, err
+		}
+		op.Steps = append(op.Steps, steps...)
+	default:
+		return nil, ErrInvalidNodesOperation
+	}
+	return op, nil
+}
+
+// addRollback registers the uninstall steps of the container runtime, kubernetes packages and node joining
+// as the rollback of their install steps, and records the added nodes which are removed from the cluster
+// once the operation is rolled back.
+func (p *PatchNodes) addRollback(op *corev1.Operation, extra component.ExtraMetadata, cluster *corev1.Cluster,
+	stepNodes []corev1.StepNode, criSteps, packageSteps, joinSteps []corev1.Step) error {
+	ctx := component.WithExtraMetadata(context.TODO(), extra)
+	steps, err := getCriStep(ctx, cluster, corev1.ActionUninstall, stepNodes)
+	if err != nil {
+		return err
+	}
+	op.AddRollback(criSteps, steps)
+	steps, err = p.getPackageSteps(cluster, corev1.ActionUninstall, stepNodes)
+	if err != nil {
+		return err
+	}
+	op.AddRollback(packageSteps, steps)
+	gen := k8s.GenNode{}
+	err = gen.InitStepper(&extra, cluster, p.Role.String()).MakeUninstallSteps(&extra, stepNodes, p.Role.String())
+	if err != nil {
+		return err
+	}
+	op.AddRollback(joinSteps, gen.GetSteps(corev1.ActionUninstall))
+
+	ids := make([]string, 0, len(stepNodes))
+	for _, node := range stepNodes {
+		ids = append(ids, node.ID)
+	}
+	if op.Annotations == nil {
+		op.Annotations = make(map[string]string)
+	}
+	op.Annotations[common.AnnotationAddedNodes] = strings.Join(ids, ",")
+	return nil
+}
+
 func (p *PatchNodes) getPackageSteps(cluster *corev1.Cluster, action corev1.StepAction, pNodes []corev1.StepNode) ([]corev1.Step, error) {
 	pack := &k8s.Package{}
 	pack = pack.InitStepper(cluster)
@@ -152,37 +152,94 @@ func Test_MakeCompare(t *testing.T) {
 		{
 			name: "addMaster",
 			arg: args{
-				cluster: c2,
+				cluster: c2.DeepCopy(),
 				patchNode: &PatchNodes{
 					Operation: "add",
+					Nodes: v1.WorkerNodeList{
+						{
+							ID: "6b8456e8-2489-4321-bbb0-f8d75c065384",
+						},
+					},
+					Role: "master",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "removeMaster",
+			arg: args{
+				cluster: c2.DeepCopy(),
+				patchNode: &PatchNodes{
+					Operation: "remove",
+					Nodes:     master[:1],
+					Role:      "master",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "removeMasterBreakQuorum",
+			arg: args{
+				cluster: c2.DeepCopy(),
+				patchNode: &PatchNodes{
+					Operation: "remove",
+					Nodes:     master[:2],
+					Role:      "master",
+				},
+			},
+			wantErr: ErrEtcdQuorum,
+		},
+		{
+			name: "removeAllMaster",
+			arg: args{
+				cluster: c2.DeepCopy(),
+				patchNode: &PatchNodes{
+					Operation: "remove",
 					Nodes:     master,
 					Role:      "master",
 				},
 			},
-			wantErr: ErrInvalidNodesRole,
+			wantErr: ErrEtcdQuorum,
 		},
 		{
-			name: "removeMaster",
+			name: "removeMasterWithExternalEtcd",
+			arg: args{
+				cluster: externalEtcd(c2.DeepCopy()),
+				patchNode: &PatchNodes{
+					Operation: "remove",
+					Nodes:     master[:2],
+					Role:      "master",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "removeAllMasterWithExternalEtcd",
 			arg: args{
-				cluster: nil,
+				cluster: externalEtcd(c2.DeepCopy()),
 				patchNode: &PatchNodes{
 					Operation: "remove",
 					Nodes:     master,
 					Role:      "master",
 				},
 			},
-			wantErr: ErrInvalidNodesRole,
+			wantErr: ErrEtcdQuorum,
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			if err := test.arg.patchNode.MakeCompare(test.arg.cluster); err != nil && err != test.wantErr {
+			if err := test.arg.patchNode.MakeCompare(test.arg.cluster); err != test.wantErr {
 				t.Errorf(" MakeCompare() err: %v ", err)
 			}
 		})
 	}
 }
 
+func externalEtcd(c *v1.Cluster) *v1.Cluster {
+	c.Etcd.External = &v1.ExternalEtcd{Endpoints: []string{"https://192.168.1.10:2379"}}
+	return c
+}
+
 func Test_MakeOperation(t *testing.T) {
 	type args struct {
 		cluster    *v1.Cluster
@@ -230,6 +287,29 @@ func Test_MakeOperation(t *testing.T) {
 		},
 		{
 			name: "test add master node operation",
+			arg: args{
+				cluster: c2,
+				meta:    *extraMeta,
+				patchNodes: &PatchNodes{
+					Operation: "add",
+					Nodes: []v1.WorkerNode{
+						{
+							ID: "6b8456e8-2489-4321-bbb0-f8d75c065384",
+						},
+					},
+					ConvertNodes: []component.Node{
+						{
+							ID:   "6b8456e8-2489-4321-bbb0-f8d75c065384",
+							IPv4: "192.168.1.6",
+						},
+					},
+					Role: "master",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "test remove master node operation",
 			arg: args{
 				cluster: c2,
 				meta:    *extraMeta,
@@ -240,10 +320,33 @@ func Test_MakeOperation(t *testing.T) {
 							ID: "1e3ea00f-1403-46e5-a486-70e4cb29d541",
 						},
 					},
+					ConvertNodes: []component.Node{
+						{
+							ID:   "1e3ea00f-1403-46e5-a486-70e4cb29d541",
+							IPv4: "192.168.1.1",
+						},
+					},
 					Role: "master",
 				},
 			},
-			wantErr: ErrInvalidNodesRole,
+			wantErr: nil,
+		},
+		{
+			name: "test master node operation without node",
+			arg: args{
+				cluster: c2,
+				meta:    *extraMeta,
+				patchNodes: &PatchNodes{
+					Operation: "remove",
+					Nodes: []v1.WorkerNode{
+						{
+							ID: "1e3ea00f-1403-46e5-a486-70e4cb29d541",
+						},
+					},
+					Role: "master",
+				},
+			},
+			wantErr: ErrZeroNode,
 		},
 	}
 	for _, test := range tests {
@@ -256,3 +359,35 @@ func Test_MakeOperation(t *testing.T) {
 	}
 
 }
+
+func Test_MakeRemoveMasterOperation(t *testing.T) {
+	pn := &PatchNodes{
+		Operation: NodesOperationRemove,
+		Nodes: []v1.WorkerNode{
+			{
+				ID: "1e3ea00f-1403-46e5-a486-70e4cb29d541",
+			},
+		},
+		ConvertNodes: []component.Node{
+			{
+				ID:   "1e3ea00f-1403-46e5-a486-70e4cb29d541",
+				IPv4: "192.168.1.1",
+			},
+		},
+		Role: "master",
+	}
+	op, err := pn.MakeOperation(*extraMeta, c2)
+	if err != nil {
+		t.Fatalf(" MakeOperation() error: %v ", err)
+	}
+	for _, step := range op.Steps {
+		if step.Name != "removeEtcdMember" && step.Name != "drainNode" {
+			continue
+		}
+		for _, node := range step.Nodes {
+			if node.ID == pn.Nodes[0].ID {
+				t.Errorf("step %s must run on a remaining control plane node", step.Name)
+			}
+		}
+	}
+}
@@ -2,9 +2,12 @@ package clusteroperation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	"github.com/kubeclipper/kubeclipper/pkg/component"
 	"github.com/kubeclipper/kubeclipper/pkg/models/operation"
 	"github.com/kubeclipper/kubeclipper/pkg/query"
@@ -16,6 +19,12 @@ import (
 // TODO: Later, if necessary, can set a separate retry number for each operation ?
 const RetryTimes = 3
 
+// ErrOperationRolledBack the rolled back operation can not be retried, its succeeded steps have been undone.
+var ErrOperationRolledBack = errors.New("the rolled back operation can not be retried")
+
+// ErrOperationDropped the operation dropped from the queue can not be retried, it has never started.
+var ErrOperationDropped = errors.New("the operation dropped from the queue can not be retried")
+
 type Options struct {
 	cluster           *v1.Cluster
 	pendingOperation  v1.PendingOperation
@@ -50,14 +59,17 @@ func BuildOperationAdapter(
 	switch pendingOp.OperationType {
 	case v1.OperationAddNodes, v1.OperationRemoveNodes:
 		instance = NewNodeOperation(options)
-	case v1.OperationInstallComponents, v1.OperationUninstallComponents:
+	case v1.OperationMigrateContainerRuntime:
+		instance = NewRuntimeOperation(options)
+	case v1.OperationUpdateCertification:
+		instance = NewCertificationOperation(options)
+	case v1.OperationUpdateClusterConfig:
+		instance = NewConfigOperation(options)
+	case v1.OperationInstallComponents, v1.OperationUninstallComponents, v1.OperationUpgradeComponents,
+		v1.OperationUpgradeCluster, v1.OperationBackupCluster, v1.OperationDeleteBackup, v1.OperationRecoverCluster:
+		instance = NewPrebuiltOperation(options)
 	case v1.OperationCreateCluster:
 	case v1.OperationDeleteCluster:
-	case v1.OperationUpgradeCluster:
-	case v1.OperationBackupCluster:
-	case v1.OperationDeleteBackup:
-	case v1.OperationRecoverCluster:
-	case v1.OperationUpdateCertification:
 		// TODO support all operations
 	default:
 		return &v1.Operation{}, fmt.Errorf("unsupported %s operation type", pendingOp.OperationType)
@@ -69,7 +81,8 @@ func BuildOperationAdapter(
 // IsRetry whether the operation supports retry
 func IsRetry(opType string) bool {
 	switch opType {
-	case v1.OperationBackupCluster, v1.OperationRecoverCluster, v1.OperationUpgradeCluster:
+	case v1.OperationBackupCluster, v1.OperationRecoverCluster, v1.OperationUpgradeCluster,
+		v1.OperationMigrateContainerRuntime, v1.OperationUpgradeComponents:
 		return false
 	}
 	return true
@@ -80,9 +93,11 @@ func IsRetry(opType string) bool {
 // 1. operation support retry.
 // 2. the maximum number of retries has not been exceeded.
 // 3. the operation is the latest in the cluster.
+// 4. the operation is not rolled back.
+// 5. the operation is not dropped from the queue.
 // TODO: better retry judgment, perhaps it is made up of global and custom rules ?
 func AutomaticRetry(ctx context.Context, op *v1.Operation, operator operation.Operator) (bool, error) {
-	if !IsRetry(op.Labels[common.LabelOperationAction]) {
+	if !IsRetry(op.Labels[common.LabelOperationAction]) || op.IsRolledBack() || op.IsDropped() {
 		return false, nil
 	}
 	times, _ := strconv.Atoi(op.Labels[common.LabelOperationRetry])
@@ -112,17 +127,27 @@ func AutomaticRetry(ctx context.Context, op *v1.Operation, operator operation.Op
 
 // Retry operation retry
 func Retry(op *v1.Operation) (context.Context, *v1.Operation, []v1.Step, error) {
-	if op.Status.Status == v1.OperationStatusSuccessful || op.Status.Status == v1.OperationStatusRunning {
+	if op.Status.Status == v1.OperationStatusSuccessful || op.Status.Status == v1.OperationStatusRunning ||
+		op.Status.Status == v1.OperationStatusPaused {
 		return nil, nil, nil, fmt.Errorf("only the latest faild operation can do a retry")
 	}
+	if op.IsRolledBack() {
+		return nil, nil, nil, ErrOperationRolledBack
+	}
+	if op.IsDropped() {
+		return nil, nil, nil, ErrOperationDropped
+	}
 
 	// error step index
 	failedIndex := len(op.Status.Conditions) - 1
 	ctx := component.WithRetry(context.TODO(), true)
+	graph := op.HasStepDependencies()
 
 	// if an installation error occurs, proceed directly from the current step
 	var continueSteps []v1.Step
-	if op.Steps[0].Action == v1.ActionInstall {
+	if graph {
+		continueSteps = RetryStepGraph(op)
+	} else if op.Steps[0].Action == v1.ActionInstall {
 		findStepNode := func(nodes []v1.StepNode, nodeID string) v1.StepNode {
 			for _, v := range nodes {
 				if v.ID == nodeID {
@@ -168,13 +193,42 @@ func Retry(op *v1.Operation) (context.Context, *v1.Operation, []v1.Step, error)
 	}
 
 	// if an uninstallation error occurs, start over
-	if op.Steps[0].Action == v1.ActionUninstall {
+	if !graph && op.Steps[0].Action == v1.ActionUninstall {
 		continueSteps = op.Steps
 		op.Status.Conditions = make([]v1.OperationCondition, 0)
 	}
 	return ctx, op, continueSteps, nil
 }
 
+// RetryStepGraph returns the steps which are not done of the operation whose steps run as a graph,
+// only the conditions of the done steps are kept. The dependencies on the done steps are satisfied.
+func RetryStepGraph(op *v1.Operation) []v1.Step {
+	done := sets.NewString()
+	conditions := make([]v1.OperationCondition, 0, len(op.Status.Conditions))
+	for _, cond := range op.Status.Conditions {
+		succeeded := true
+		for _, status := range cond.Status {
+			if status.Status != v1.StepStatusSuccessful {
+				succeeded = false
+				break
+			}
+		}
+		if succeeded {
+			done.Insert(cond.StepID)
+			conditions = append(conditions, cond)
+		}
+	}
+	op.Status.Conditions = conditions
+
+	var continueSteps []v1.Step
+	for _, step := range op.Steps {
+		if !done.Has(step.ID) {
+			continueSteps = append(continueSteps, step)
+		}
+	}
+	return continueSteps
+}
+
 // SupportConcurrent whether concurrency is supported
 func SupportConcurrent(opType string) bool {
 	switch opType {
@@ -187,22 +241,36 @@ func SupportConcurrent(opType string) bool {
 
 // Executable the operation is committed for execution
 func Executable(ctx context.Context, opType, cluName string, operator operation.Operator) (bool, error) {
-	q := query.New()
-	q.LabelSelector = fmt.Sprintf("%s=%s,%s=%s", common.LabelClusterName, cluName, common.LabelOperationAction, opType)
-	q.FieldSelector = fmt.Sprintf("status.status=%s", v1.OperationStatusRunning)
-	q.Pagination.Offset = 0
-	q.Pagination.Limit = 1
 	// gets whether the same type of operations are being performed in the cluster
-	resp, err := operator.ListOperationsEx(ctx, q)
+	running, err := IsRunning(ctx, opType, cluName, operator)
 	if err != nil {
 		return false, err
 	}
-	if resp.TotalCount == 0 {
+	if !running {
 		return true, nil
 	}
 	return SupportConcurrent(opType), nil
 }
 
+// IsRunning whether the specified type of operations are being performed in the cluster, a paused operation is still being performed.
+func IsRunning(ctx context.Context, opType, cluName string, operator operation.Operator) (bool, error) {
+	for _, status := range []v1.OperationStatusType{v1.OperationStatusRunning, v1.OperationStatusPaused} {
+		q := query.New()
+		q.LabelSelector = fmt.Sprintf("%s=%s,%s=%s", common.LabelClusterName, cluName, common.LabelOperationAction, opType)
+		q.FieldSelector = fmt.Sprintf("status.status=%s", status)
+		q.Pagination.Offset = 0
+		q.Pagination.Limit = 1
+		resp, err := operator.ListOperationsEx(ctx, q)
+		if err != nil {
+			return false, err
+		}
+		if resp.TotalCount != 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // GetClusterPhase get the cluster phase based on the type of operation
 func GetClusterPhase(opType string) v1.ClusterPhase {
 	switch opType {
@@ -218,7 +286,7 @@ func GetClusterPhase(opType string) v1.ClusterPhase {
 		return v1.ClusterRestoring
 	default:
 		// OperationAddNodes,OperationRemoveNodes
-		// OperationInstallComponents,OperationUninstallComponents
+		// OperationInstallComponents,OperationUninstallComponents,OperationUpgradeComponents
 		// OperationUpdateCertification
 		return v1.ClusterUpdating
 	}
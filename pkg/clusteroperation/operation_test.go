@@ -0,0 +1,67 @@
+package clusteroperation
+
+import (
+	"errors"
+	"testing"
+
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func Test_RetryStepGraph(t *testing.T) {
+	op := &v1.Operation{
+		Steps: []v1.Step{
+			{ID: "a"},
+			{ID: "b", DependsOn: []string{"a"}},
+			{ID: "c", DependsOn: []string{"a"}},
+			{ID: "d", DependsOn: []string{"b", "c"}},
+		},
+		Status: v1.OperationStatus{
+			Status: v1.OperationStatusFailed,
+			Conditions: []v1.OperationCondition{
+				{StepID: "a", Status: []v1.StepStatus{{Node: "n1", Status: v1.StepStatusSuccessful}}},
+				{StepID: "c", Status: []v1.StepStatus{{Node: "n1", Status: v1.StepStatusSuccessful}}},
+				{StepID: "b", Status: []v1.StepStatus{{Node: "n1", Status: v1.StepStatusSuccessful}, {Node: "n2", Status: v1.StepStatusFailed}}},
+			},
+		},
+	}
+	_, newOp, steps, err := Retry(op)
+	if err != nil {
+		t.Fatalf("Retry() error: %v", err)
+	}
+	if len(steps) != 2 || steps[0].ID != "b" || steps[1].ID != "d" {
+		t.Errorf("unexpected continue steps: %v", steps)
+	}
+	if len(newOp.Status.Conditions) != 2 || newOp.Status.Conditions[1].StepID != "c" {
+		t.Errorf("unexpected conditions: %v", newOp.Status.Conditions)
+	}
+}
+
+func Test_RetryRolledBack(t *testing.T) {
+	op := &v1.Operation{
+		Steps: []v1.Step{{ID: "a", Action: v1.ActionInstall}},
+		Status: v1.OperationStatus{
+			Status:             v1.OperationStatusFailed,
+			Conditions:         []v1.OperationCondition{{StepID: "a", Status: []v1.StepStatus{{Node: "n1", Status: v1.StepStatusFailed}}}},
+			RollbackConditions: []v1.OperationCondition{{StepID: "rollback-a"}},
+		},
+	}
+	if _, _, _, err := Retry(op); !errors.Is(err, ErrOperationRolledBack) {
+		t.Errorf("Retry() error = %v, want %v", err, ErrOperationRolledBack)
+	}
+}
+
+func Test_IsRetry(t *testing.T) {
+	for opType, want := range map[string]bool{
+		v1.OperationCreateCluster:           true,
+		v1.OperationAddNodes:                true,
+		v1.OperationBackupCluster:           false,
+		v1.OperationRecoverCluster:          false,
+		v1.OperationUpgradeCluster:          false,
+		v1.OperationMigrateContainerRuntime: false,
+		v1.OperationUpgradeComponents:       false,
+	} {
+		if got := IsRetry(opType); got != want {
+			t.Errorf("IsRetry(%s) = %v, want %v", opType, got, want)
+		}
+	}
+}
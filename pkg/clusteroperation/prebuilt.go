@@ -0,0 +1,88 @@
+package clusteroperation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	corev1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+var _ Interface = (*PrebuiltOperation)(nil)
+
+// ErrClusterNodesChanged the nodes of cluster have been changed since the operation was built.
+var ErrClusterNodesChanged = errors.New("the nodes of cluster have been changed since the operation was queued")
+
+// Prebuilt the operation built when it is requested, it waits in the queue of cluster as it is,
+// because it can not be built from the cluster alone.
+type Prebuilt struct {
+	Operation *corev1.Operation `json:"operation"`
+	// Nodes the ids of the cluster nodes the operation is built with.
+	Nodes []string `json:"nodes"`
+}
+
+// PrebuiltOperation starts the prebuilt operation.
+type PrebuiltOperation struct {
+	Options
+}
+
+func NewPrebuiltOperation(options Options) *PrebuiltOperation {
+	return &PrebuiltOperation{options}
+}
+
+func (p *PrebuiltOperation) Builder() (*corev1.Operation, error) {
+	var prebuilt Prebuilt
+	if err := json.Unmarshal(p.pendingOperation.ExtraData, &prebuilt); err != nil {
+		return nil, err
+	}
+	if prebuilt.Operation == nil {
+		return nil, fmt.Errorf("%s operation %s is not prebuilt", p.pendingOperation.OperationType, p.pendingOperation.OperationID)
+	}
+	op := prebuilt.Operation
+	op.Status = corev1.OperationStatus{Status: corev1.OperationStatusPending}
+	return op, nil
+}
+
+// NewPrebuiltPendingOperation makes the pending operation of the operation built with the cluster.
+func NewPrebuiltPendingOperation(c *corev1.Cluster, op *corev1.Operation) (corev1.PendingOperation, error) {
+	data, err := json.Marshal(Prebuilt{Operation: op, Nodes: c.GetAllNodes().List()})
+	if err != nil {
+		return corev1.PendingOperation{}, err
+	}
+	return corev1.PendingOperation{
+		OperationID:            op.Name,
+		OperationType:          op.Labels[common.LabelOperationAction],
+		Timeout:                op.Labels[common.LabelTimeoutSeconds],
+		ClusterResourceVersion: c.ResourceVersion,
+		ExtraData:              data,
+	}, nil
+}
+
+// IsPrebuilt whether the operation of the type is built when it is requested.
+func IsPrebuilt(opType string) bool {
+	switch opType {
+	case corev1.OperationInstallComponents, corev1.OperationUninstallComponents, corev1.OperationUpgradeComponents,
+		corev1.OperationUpgradeCluster, corev1.OperationBackupCluster, corev1.OperationDeleteBackup, corev1.OperationRecoverCluster:
+		return true
+	}
+	return false
+}
+
+// checkPrebuiltOperation checks whether the prebuilt operation is still valid for the nodes of cluster,
+// its steps are run on the nodes of cluster when it is built.
+func checkPrebuiltOperation(c *corev1.Cluster, pendingOp corev1.PendingOperation) error {
+	if !IsPrebuilt(pendingOp.OperationType) {
+		return nil
+	}
+	var prebuilt Prebuilt
+	if err := json.Unmarshal(pendingOp.ExtraData, &prebuilt); err != nil {
+		return err
+	}
+	if !c.GetAllNodes().Equal(sets.NewString(prebuilt.Nodes...)) {
+		return fmt.Errorf("%s operation %s: %w", pendingOp.OperationType, pendingOp.OperationID, ErrClusterNodesChanged)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package clusteroperation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/models/operation"
+	"github.com/kubeclipper/kubeclipper/pkg/query"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+// ErrQueuedOperationNotFound the operation is not in the queue, it may have been started.
+var ErrQueuedOperationNotFound = errors.New("the operation is not in the queue of cluster")
+
+// Enqueue adds the operation to the queue of cluster, it starts after the queued operations of the same or higher priority.
+func Enqueue(c *v1.Cluster, pendingOp v1.PendingOperation, priority int32, now time.Time) {
+	c.QueuedOperations = append(c.QueuedOperations, v1.QueuedOperation{
+		PendingOperation: pendingOp,
+		Priority:         priority,
+		QueuedAt:         metav1.NewTime(now),
+	})
+	v1.SortQueuedOperations(c.QueuedOperations)
+}
+
+// Dequeue moves the first queued operation to the pending operations of cluster, the operation is built
+// with the cluster when it starts, because the cluster of the time it is queued may have been compacted.
+// The nodes of cluster are changed by the nodes operation only when it starts, the operation is dropped
+// from the queue with an error if it is not valid for the cluster anymore.
+func Dequeue(c *v1.Cluster) (v1.PendingOperation, bool, error) {
+	if len(c.QueuedOperations) == 0 {
+		return v1.PendingOperation{}, false, nil
+	}
+	pendingOp := c.QueuedOperations[0].PendingOperation
+	c.QueuedOperations = c.QueuedOperations[1:]
+	if err := checkPrebuiltOperation(c, pendingOp); err != nil {
+		return pendingOp, false, err
+	}
+	if err := applyNodesOperation(c, &pendingOp); err != nil {
+		return pendingOp, false, err
+	}
+	pendingOp.ClusterResourceVersion = c.ResourceVersion
+	c.PendingOperations = append(c.PendingOperations, pendingOp)
+	c.Status.Phase = GetClusterPhase(pendingOp.OperationType)
+	return pendingOp, true, nil
+}
+
+// ApplyQueuedNodes changes the nodes of cluster as if the queued nodes operations were performed in order,
+// the nodes operation to be queued is validated against it.
+func ApplyQueuedNodes(c *v1.Cluster) error {
+	for i := range c.QueuedOperations {
+		pendingOp := c.QueuedOperations[i].PendingOperation
+		if err := applyNodesOperation(c, &pendingOp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyNodesOperation changes the nodes of cluster with the patch of nodes operation,
+// the patch is filtered with the nodes of cluster the same as the operation which is not queued.
+func applyNodesOperation(c *v1.Cluster, pendingOp *v1.PendingOperation) error {
+	switch pendingOp.OperationType {
+	case v1.OperationAddNodes, v1.OperationRemoveNodes:
+	default:
+		return nil
+	}
+	var pn PatchNodes
+	if err := json.Unmarshal(pendingOp.ExtraData, &pn); err != nil {
+		return err
+	}
+	if err := pn.MakeCompare(c); err != nil {
+		return err
+	}
+	if len(pn.Nodes) == 0 {
+		return fmt.Errorf("%s operation %s: %w", pendingOp.OperationType, pendingOp.OperationID, ErrZeroNode)
+	}
+	ids := sets.NewString(pn.Nodes.GetNodeIDs()...)
+	convertNodes := make([]component.Node, 0, len(pn.ConvertNodes))
+	for _, n := range pn.ConvertNodes {
+		if ids.Has(n.ID) {
+			convertNodes = append(convertNodes, n)
+		}
+	}
+	pn.ConvertNodes = convertNodes
+	data, err := json.Marshal(pn)
+	if err != nil {
+		return err
+	}
+	pendingOp.ExtraData = data
+	return nil
+}
+
+// NewDroppedOperation records the operation dropped from the queue as failed, so that it can be found
+// by the id returned when it was queued.
+func NewDroppedOperation(c *v1.Cluster, pendingOp v1.PendingOperation, reason error, now time.Time) *v1.Operation {
+	op := &v1.Operation{}
+	op.Name = pendingOp.OperationID
+	op.Labels = map[string]string{
+		common.LabelClusterName:     c.Name,
+		common.LabelOperationAction: pendingOp.OperationType,
+		common.LabelTimeoutSeconds:  pendingOp.Timeout,
+	}
+	op.Annotations = map[string]string{
+		common.AnnotationDropReason: reason.Error(),
+	}
+	op.Status.Status = v1.OperationStatusFailed
+	op.Status.Conditions = []v1.OperationCondition{{
+		Status: []v1.StepStatus{{
+			StartAt: metav1.NewTime(now),
+			EndAt:   metav1.NewTime(now),
+			Status:  v1.StepStatusFailed,
+			Reason:  "DroppedFromQueue",
+			Message: reason.Error(),
+		}},
+	}}
+	return op
+}
+
+// IsQueued whether the operation waits in the queue of cluster or is about to be created.
+func IsQueued(c *v1.Cluster, operationID string) bool {
+	for _, queuedOp := range c.QueuedOperations {
+		if queuedOp.OperationID == operationID {
+			return true
+		}
+	}
+	for _, pendingOp := range c.PendingOperations {
+		if pendingOp.OperationID == operationID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetQueuedPriority changes the priority of the queued operation and reorders the queue.
+func SetQueuedPriority(c *v1.Cluster, operationID string, priority int32) error {
+	for i := range c.QueuedOperations {
+		if c.QueuedOperations[i].OperationID == operationID {
+			c.QueuedOperations[i].Priority = priority
+			v1.SortQueuedOperations(c.QueuedOperations)
+			return nil
+		}
+	}
+	return ErrQueuedOperationNotFound
+}
+
+// RemoveQueued removes the operation from the queue of cluster before it starts.
+func RemoveQueued(c *v1.Cluster, operationID string) (v1.QueuedOperation, error) {
+	for i, queuedOp := range c.QueuedOperations {
+		if queuedOp.OperationID != operationID {
+			continue
+		}
+		c.QueuedOperations = append(c.QueuedOperations[:i], c.QueuedOperations[i+1:]...)
+		return queuedOp, nil
+	}
+	return v1.QueuedOperation{}, ErrQueuedOperationNotFound
+}
+
+// HasRunning whether any operation is being performed in the cluster, including the operation which is created but not started.
+func HasRunning(ctx context.Context, cluName string, operator operation.Operator) (bool, error) {
+	ops, err := listRunning(ctx, cluName, operator, 1)
+	return len(ops) > 0, err
+}
+
+// ListRunning lists the operations being performed in the cluster, including the operation which is created but not started.
+func ListRunning(ctx context.Context, cluName string, operator operation.Operator) ([]*v1.Operation, error) {
+	return listRunning(ctx, cluName, operator, 0)
+}
+
+func listRunning(ctx context.Context, cluName string, operator operation.Operator, limit int) ([]*v1.Operation, error) {
+	var ops []*v1.Operation
+	for _, status := range []v1.OperationStatusType{v1.OperationStatusPending, v1.OperationStatusRunning, v1.OperationStatusPaused} {
+		q := query.New()
+		q.LabelSelector = fmt.Sprintf("%s=%s", common.LabelClusterName, cluName)
+		q.FieldSelector = fmt.Sprintf("status.status=%s", status)
+		if limit > 0 {
+			q.Pagination.Offset = 0
+			q.Pagination.Limit = limit
+		}
+		resp, err := operator.ListOperationsEx(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range resp.Items {
+			ops = append(ops, item.(*v1.Operation))
+		}
+		if limit > 0 && len(ops) >= limit {
+			break
+		}
+	}
+	return ops, nil
+}
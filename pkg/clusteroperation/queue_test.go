@@ -0,0 +1,200 @@
+package clusteroperation
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func queuedIDs(c *v1.Cluster) []string {
+	var ids []string
+	for _, op := range c.QueuedOperations {
+		ids = append(ids, op.OperationID)
+	}
+	return ids
+}
+
+func TestOperationQueue(t *testing.T) {
+	c := &v1.Cluster{Workers: v1.WorkerNodeList{{ID: "w1"}}}
+	c.ResourceVersion = "10"
+	now := time.Now()
+	add, _ := json.Marshal(PatchNodes{Operation: NodesOperationAdd, Role: common.NodeRoleWorker, Nodes: v1.WorkerNodeList{{ID: "w2"}}})
+	remove, _ := json.Marshal(PatchNodes{Operation: NodesOperationRemove, Role: common.NodeRoleWorker, Nodes: v1.WorkerNodeList{{ID: "w1"}}})
+	Enqueue(c, v1.PendingOperation{OperationID: "a", OperationType: v1.OperationAddNodes, ExtraData: add}, 0, now)
+	Enqueue(c, v1.PendingOperation{OperationID: "b", OperationType: v1.OperationRemoveNodes, ExtraData: remove}, 1, now.Add(time.Second))
+	Enqueue(c, v1.PendingOperation{OperationID: "c", OperationType: v1.OperationAddNodes, ExtraData: add}, 0, now.Add(2*time.Second))
+	if ids := queuedIDs(c); len(ids) != 3 || ids[0] != "b" || ids[1] != "a" || ids[2] != "c" {
+		t.Fatalf("unexpected queue: %v", ids)
+	}
+
+	if err := SetQueuedPriority(c, "c", 2); err != nil {
+		t.Fatalf("SetQueuedPriority() error: %v", err)
+	}
+	if ids := queuedIDs(c); ids[0] != "c" || ids[1] != "b" || ids[2] != "a" {
+		t.Errorf("unexpected queue after reorder: %v", ids)
+	}
+	if err := SetQueuedPriority(c, "d", 2); !errors.Is(err, ErrQueuedOperationNotFound) {
+		t.Errorf("SetQueuedPriority() error = %v, want %v", err, ErrQueuedOperationNotFound)
+	}
+
+	op, ok, err := Dequeue(c)
+	if err != nil || !ok || op.OperationID != "c" || op.ClusterResourceVersion != "10" {
+		t.Fatalf("unexpected dequeued operation: %+v", op)
+	}
+	if len(c.PendingOperations) != 1 || c.Status.Phase != v1.ClusterUpdating || len(c.QueuedOperations) != 2 {
+		t.Errorf("the dequeued operation is not pending: %+v", c)
+	}
+}
+
+func enqueueNodes(c *v1.Cluster, id string, pn PatchNodes) {
+	opType := v1.OperationAddNodes
+	if pn.Operation == NodesOperationRemove {
+		opType = v1.OperationRemoveNodes
+	}
+	data, _ := json.Marshal(pn)
+	Enqueue(c, v1.PendingOperation{OperationID: id, OperationType: opType, ExtraData: data}, 0, time.Now())
+}
+
+func TestDequeueNodesOperation(t *testing.T) {
+	c := &v1.Cluster{
+		Masters: v1.WorkerNodeList{{ID: "m1"}},
+		Workers: v1.WorkerNodeList{{ID: "w1"}, {ID: "w2"}},
+	}
+	enqueueNodes(c, "add", PatchNodes{Operation: NodesOperationAdd, Role: common.NodeRoleWorker,
+		Nodes: v1.WorkerNodeList{{ID: "w2"}, {ID: "w3"}}, ConvertNodes: []component.Node{{ID: "w2"}, {ID: "w3"}}})
+	enqueueNodes(c, "remove", PatchNodes{Operation: NodesOperationRemove, Role: common.NodeRoleWorker,
+		Nodes: v1.WorkerNodeList{{ID: "w1"}}, ConvertNodes: []component.Node{{ID: "w1"}}})
+	enqueueNodes(c, "again", PatchNodes{Operation: NodesOperationRemove, Role: common.NodeRoleWorker,
+		Nodes: v1.WorkerNodeList{{ID: "w1"}}, ConvertNodes: []component.Node{{ID: "w1"}}})
+
+	// the queued operations change the nodes of cluster only when they start.
+	target := c.DeepCopy()
+	if err := ApplyQueuedNodes(target); !errors.Is(err, ErrZeroNode) {
+		t.Errorf("ApplyQueuedNodes() error = %v, want %v", err, ErrZeroNode)
+	}
+	if ids := c.Workers.GetNodeIDs(); len(ids) != 2 || ids[0] != "w1" || ids[1] != "w2" {
+		t.Fatalf("the workers are changed by queued operations: %v", ids)
+	}
+
+	op, ok, err := Dequeue(c)
+	if err != nil || !ok || op.OperationID != "add" {
+		t.Fatalf("Dequeue() = %+v, %v, %v", op, ok, err)
+	}
+	if ids := c.Workers.GetNodeIDs(); len(ids) != 3 || ids[2] != "w3" {
+		t.Errorf("unexpected workers: %v", ids)
+	}
+	var pn PatchNodes
+	if err = json.Unmarshal(c.PendingOperations[0].ExtraData, &pn); err != nil {
+		t.Fatal(err)
+	}
+	if len(pn.Nodes) != 1 || pn.Nodes[0].ID != "w3" || len(pn.ConvertNodes) != 1 || pn.ConvertNodes[0].ID != "w3" {
+		t.Errorf("the nodes of pending operation are not filtered: %+v", pn)
+	}
+
+	if _, ok, err = Dequeue(c); err != nil || !ok {
+		t.Fatalf("Dequeue() = %v, %v", ok, err)
+	}
+	if ids := c.Workers.GetNodeIDs(); len(ids) != 2 || ids[0] != "w2" || ids[1] != "w3" {
+		t.Errorf("unexpected workers: %v", ids)
+	}
+
+	// w1 has been removed, the operation is dropped.
+	if _, ok, err = Dequeue(c); !errors.Is(err, ErrZeroNode) || ok {
+		t.Errorf("Dequeue() = %v, %v, want %v", ok, err, ErrZeroNode)
+	}
+	if len(c.QueuedOperations) != 0 || len(c.PendingOperations) != 2 {
+		t.Errorf("unexpected queue %v and pending operations %+v", queuedIDs(c), c.PendingOperations)
+	}
+}
+
+func TestRemoveQueued(t *testing.T) {
+	c := &v1.Cluster{
+		Masters: v1.WorkerNodeList{{ID: "m1"}},
+		Workers: v1.WorkerNodeList{{ID: "w1"}, {ID: "w2"}},
+	}
+	enqueueNodes(c, "add", PatchNodes{Operation: NodesOperationAdd, Role: common.NodeRoleWorker, Nodes: v1.WorkerNodeList{{ID: "w3"}}})
+	enqueueNodes(c, "remove", PatchNodes{Operation: NodesOperationRemove, Role: common.NodeRoleWorker, Nodes: v1.WorkerNodeList{{ID: "w2"}}})
+
+	for _, id := range []string{"add", "remove"} {
+		if _, err := RemoveQueued(c, id); err != nil {
+			t.Fatalf("RemoveQueued() error: %v", err)
+		}
+	}
+	if len(c.QueuedOperations) != 0 {
+		t.Errorf("unexpected queue: %v", queuedIDs(c))
+	}
+	if ids := c.Workers.GetNodeIDs(); len(ids) != 2 || ids[0] != "w1" || ids[1] != "w2" {
+		t.Errorf("the workers are changed: %+v", c.Workers)
+	}
+	if _, err := RemoveQueued(c, "a"); !errors.Is(err, ErrQueuedOperationNotFound) {
+		t.Errorf("RemoveQueued() error = %v, want %v", err, ErrQueuedOperationNotFound)
+	}
+}
+
+func TestDequeuePrebuiltOperation(t *testing.T) {
+	c := &v1.Cluster{
+		Masters: v1.WorkerNodeList{{ID: "m1"}},
+		Workers: v1.WorkerNodeList{{ID: "w1"}},
+	}
+	c.Name = "demo"
+	upgrade := &v1.Operation{Steps: []v1.Step{{ID: "s1"}}}
+	upgrade.Name = "upgrade"
+	upgrade.Labels = map[string]string{
+		common.LabelOperationAction: v1.OperationUpgradeCluster,
+		common.LabelTimeoutSeconds:  "60",
+	}
+	upgrade.Status.Status = v1.OperationStatusRunning
+	pendingOp, err := NewPrebuiltPendingOperation(c, upgrade)
+	if err != nil {
+		t.Fatalf("NewPrebuiltPendingOperation() error: %v", err)
+	}
+	enqueueNodes(c, "add", PatchNodes{Operation: NodesOperationAdd, Role: common.NodeRoleWorker, Nodes: v1.WorkerNodeList{{ID: "w2"}}})
+	Enqueue(c, pendingOp, 0, time.Now().Add(time.Second))
+	Enqueue(c, pendingOp, 0, time.Now().Add(2*time.Second))
+	c.QueuedOperations[2].OperationID = "again"
+
+	// the upgrade is built with the nodes before w2 is added, it is dropped.
+	if _, _, err = Dequeue(c); err != nil {
+		t.Fatalf("Dequeue() error: %v", err)
+	}
+	dropped, ok, err := Dequeue(c)
+	if !errors.Is(err, ErrClusterNodesChanged) || ok {
+		t.Fatalf("Dequeue() = %v, %v, want %v", ok, err, ErrClusterNodesChanged)
+	}
+	op := NewDroppedOperation(c, dropped, err, time.Now())
+	if op.Name != "upgrade" || op.Status.Status != v1.OperationStatusFailed || !op.IsDropped() ||
+		op.Labels[common.LabelOperationAction] != v1.OperationUpgradeCluster || op.Labels[common.LabelClusterName] != "demo" {
+		t.Errorf("unexpected dropped operation: %+v", op)
+	}
+	if _, _, _, err = Retry(op); !errors.Is(err, ErrOperationDropped) {
+		t.Errorf("Retry() error = %v, want %v", err, ErrOperationDropped)
+	}
+
+	// the nodes are not changed since the upgrade is queued again.
+	pendingOp, err = NewPrebuiltPendingOperation(c, upgrade)
+	if err != nil {
+		t.Fatalf("NewPrebuiltPendingOperation() error: %v", err)
+	}
+	c.QueuedOperations[0].PendingOperation = pendingOp
+	if !IsQueued(c, "upgrade") {
+		t.Errorf("the upgrade is not queued")
+	}
+	if _, ok, err = Dequeue(c); err != nil || !ok {
+		t.Fatalf("Dequeue() = %v, %v", ok, err)
+	}
+	if c.Status.Phase != v1.ClusterUpgrading {
+		t.Errorf("unexpected phase %s", c.Status.Phase)
+	}
+	built, err := BuildOperationAdapter(c, c.PendingOperations[1], nil, nil)
+	if err != nil {
+		t.Fatalf("BuildOperationAdapter() error: %v", err)
+	}
+	if built.Name != "upgrade" || built.Status.Status != v1.OperationStatusPending || len(built.Steps) != 1 {
+		t.Errorf("unexpected built operation: %+v", built)
+	}
+}
@@ -0,0 +1,176 @@
+package clusteroperation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	corev1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/k8s"
+)
+
+var (
+	ErrInvalidRuntime     = errors.New("invalid container runtime")
+	ErrSameRuntime        = errors.New("the container runtime is already in use")
+	ErrUnsupportedRuntime = errors.New("migrating to docker is not supported")
+)
+
+// MigrateRuntime the target container runtime of cluster nodes.
+// The target container runtime always uses its default data root dir.
+type MigrateRuntime struct {
+	Type    string `json:"type"`
+	Version string `json:"version"`
+}
+
+var _ Interface = (*RuntimeOperation)(nil)
+
+type RuntimeOperation struct {
+	Options
+}
+
+func NewRuntimeOperation(options Options) *RuntimeOperation {
+	return &RuntimeOperation{options}
+}
+
+func (r *RuntimeOperation) Builder() (*corev1.Operation, error) {
+	var mr MigrateRuntime
+	if err := json.Unmarshal(r.pendingOperation.ExtraData, &mr); err != nil {
+		return nil, err
+	}
+	op, err := mr.MakeOperation(*r.extra, r.cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	op.Labels[common.LabelTimeoutSeconds] = r.pendingOperation.Timeout
+	op.Status.Status = corev1.OperationStatusPending
+
+	return op, nil
+}
+
+// Validate checks whether the container runtime of cluster can be migrated to the target.
+func (m *MigrateRuntime) Validate(cluster *corev1.Cluster) error {
+	if !corev1.AllowedCRIType.Has(m.Type) || m.Version == "" {
+		return ErrInvalidRuntime
+	}
+	if m.Type == cluster.ContainerRuntime.Type {
+		return ErrSameRuntime
+	}
+	// kubelet is moving away from dockershim, so docker is only supported by creating a new cluster.
+	if m.Type == corev1.CRIDocker {
+		return ErrUnsupportedRuntime
+	}
+	return nil
+}
+
+// MakeOperation make the steps to migrate nodes one by one, the workers are migrated before the control plane nodes.
+// Every node is cordoned and drained, then its container runtime is replaced while the kubelet is stopped,
+// finally the node is uncordoned after it becomes ready with the new container runtime.
+func (m *MigrateRuntime) MakeOperation(extra component.ExtraMetadata, cluster *corev1.Cluster) (*corev1.Operation, error) {
+	if err := m.Validate(cluster); err != nil {
+		return nil, err
+	}
+	if len(extra.Masters) == 0 {
+		return nil, ErrZeroNode
+	}
+
+	op := &corev1.Operation{}
+	// use pass-through operationID
+	op.Name = extra.OperationID
+	op.Labels = map[string]string{
+		common.LabelClusterName:     cluster.Name,
+		common.LabelOperationAction: corev1.OperationMigrateContainerRuntime,
+		common.LabelRuntimeType:     m.Type,
+		common.LabelRuntimeVersion:  m.Version,
+	}
+
+	target := cluster.DeepCopy()
+	target.ContainerRuntime.Type = m.Type
+	target.ContainerRuntime.Version = m.Version
+	target.ContainerRuntime.DataRootDir = ""
+	targetExtra := extra
+	targetExtra.CRI = m.Type
+
+	nodes := make([]component.Node, 0, len(extra.Workers)+len(extra.Masters))
+	nodes = append(nodes, extra.Workers...)
+	nodes = append(nodes, extra.Masters...)
+	for _, node := range nodes {
+		steps, err := m.makeNodeSteps(node, extra, cluster, targetExtra, target)
+		if err != nil {
+			return nil, fmt.Errorf("make migration steps of node %s failed: %w", node.Hostname, err)
+		}
+		op.Steps = append(op.Steps, steps...)
+	}
+
+	return op, nil
+}
+
+func (m *MigrateRuntime) makeNodeSteps(node component.Node, extra component.ExtraMetadata, cluster *corev1.Cluster,
+	targetExtra component.ExtraMetadata, target *corev1.Cluster) ([]corev1.Step, error) {
+	var nodeSteps []corev1.Step
+	stepNodes := []corev1.StepNode{{ID: node.ID, IPv4: node.IPv4, Hostname: node.Hostname}}
+	// kubectl runs on another control plane node if possible, the apiserver on the node will be restarted.
+	runner := extra.Masters[0]
+	for _, master := range extra.Masters {
+		if master.ID != node.ID {
+			runner = master
+			break
+		}
+	}
+	runnerNodes := []corev1.StepNode{{ID: runner.ID, IPv4: runner.IPv4, Hostname: runner.Hostname}}
+
+	// cordon and drain node
+	c := &k8s.Cordon{}
+	c.InitStepper(node.Hostname, []string{"--ignore-daemonsets", "--delete-local-data"}, k8s.CRISocket(m.Type))
+	steps, err := c.InstallSteps(runnerNodes)
+	if err != nil {
+		return nil, err
+	}
+	nodeSteps = append(nodeSteps, steps...)
+	nodeSteps = append(nodeSteps, k8s.StopKubelet(stepNodes))
+
+	// replace container runtime
+	steps, err = getCriStep(component.WithExtraMetadata(context.TODO(), extra), cluster, corev1.ActionUninstall, stepNodes)
+	if err != nil {
+		return nil, err
+	}
+	nodeSteps = append(nodeSteps, steps...)
+	ctx := component.WithExtraMetadata(context.TODO(), targetExtra)
+	steps, err = getCriStep(ctx, target, corev1.ActionInstall, stepNodes)
+	if err != nil {
+		return nil, err
+	}
+	nodeSteps = append(nodeSteps, steps...)
+
+	// point kubelet to the new container runtime
+	kr := &k8s.KubeletRuntime{}
+	steps, err = kr.InitStepper(m.Type).InstallSteps(stepNodes)
+	if err != nil {
+		return nil, err
+	}
+	nodeSteps = append(nodeSteps, steps...)
+
+	// load images into the new container runtime and start kubelet
+	steps, err = (&k8s.Package{}).InitStepper(target).InstallSteps(stepNodes)
+	if err != nil {
+		return nil, err
+	}
+	nodeSteps = append(nodeSteps, steps...)
+	steps, err = k8s.LoadOfflineImageSteps(&targetExtra, target, stepNodes)
+	if err != nil {
+		return nil, err
+	}
+	nodeSteps = append(nodeSteps, steps...)
+
+	// uncordon node
+	steps, err = c.UninstallSteps(runnerNodes)
+	if err != nil {
+		return nil, err
+	}
+	nodeSteps = append(nodeSteps, steps...)
+
+	return nodeSteps, nil
+}
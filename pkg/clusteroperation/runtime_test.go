@@ -0,0 +1,88 @@
+package clusteroperation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func Test_MigrateRuntimeValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mr      MigrateRuntime
+		wantErr error
+	}{
+		{
+			name: "docker to containerd",
+			mr:   MigrateRuntime{Type: v1.CRIContainerd, Version: "1.6.4"},
+		},
+		{
+			name:    "same runtime",
+			mr:      MigrateRuntime{Type: v1.CRIDocker, Version: "20.10.20"},
+			wantErr: ErrSameRuntime,
+		},
+		{
+			name:    "unknown runtime",
+			mr:      MigrateRuntime{Type: "rkt", Version: "1.0.0"},
+			wantErr: ErrInvalidRuntime,
+		},
+		{
+			name:    "empty version",
+			mr:      MigrateRuntime{Type: v1.CRIContainerd},
+			wantErr: ErrInvalidRuntime,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mr.Validate(c2.DeepCopy()); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_MigrateRuntimeMakeOperation(t *testing.T) {
+	extra := component.ExtraMetadata{
+		OperationID: "migrate",
+		CRI:         v1.CRIDocker,
+		KubeVersion: "v1.23.6",
+		Masters: []component.Node{
+			{ID: "1e3ea00f-1403-46e5-a486-70e4cb29d541", IPv4: "192.168.1.1", Hostname: "master-1"},
+		},
+		Workers: []component.Node{
+			{ID: "4cf1ad74-704c-4290-a523-e524e930245d", IPv4: "192.168.1.4", Hostname: "worker-1"},
+			{ID: "ae4ba282-27f9-4a93-8fe9-63f786781d48", IPv4: "192.168.1.5", Hostname: "worker-2"},
+		},
+	}
+	mr := &MigrateRuntime{Type: v1.CRIContainerd, Version: "1.6.4"}
+	op, err := mr.MakeOperation(extra, c2.DeepCopy())
+	if err != nil {
+		t.Fatalf("MakeOperation() error: %v", err)
+	}
+
+	// nodes are migrated one by one, the workers go first.
+	var order []string
+	for _, step := range op.Steps {
+		if strings.HasPrefix(step.Name, "cordonNode-") {
+			order = append(order, strings.TrimPrefix(step.Name, "cordonNode-"))
+		}
+		if strings.HasPrefix(step.Name, "uncordonNode-") && order[len(order)-1] != strings.TrimPrefix(step.Name, "uncordonNode-") {
+			t.Errorf("step %s is out of order", step.Name)
+		}
+	}
+	if got := strings.Join(order, ","); got != "worker-1,worker-2,master-1" {
+		t.Errorf("migration order = %s, want worker-1,worker-2,master-1", got)
+	}
+
+	// the kubectl steps of the only control plane node run on itself, the others run on the control plane node.
+	for _, step := range op.Steps {
+		if strings.HasPrefix(step.Name, "cordonNode-") || strings.HasPrefix(step.Name, "uncordonNode-") {
+			if len(step.Nodes) != 1 || step.Nodes[0].Hostname != "master-1" {
+				t.Errorf("step %s must run on the control plane node", step.Name)
+			}
+		}
+	}
+}
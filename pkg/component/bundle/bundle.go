@@ -0,0 +1,169 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package bundle
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	tmplutil "github.com/kubeclipper/kubeclipper/pkg/utils/template"
+)
+
+const (
+	// Filename the file describes the component in the bundle.
+	Filename = "bundle.json"
+	// ManifestsDir the directory of the manifest templates in the bundle.
+	ManifestsDir = "manifests"
+)
+
+var (
+	errEmptyName      = errors.New("bundle component name must be provided")
+	errEmptyVersion   = errors.New("bundle component version must be provided")
+	errEmptyManifests = errors.New("bundle must contain at least one manifest")
+)
+
+// Spec describes the component in the bundle.
+type Spec struct {
+	component.Meta
+	// InstanceNameKey the configuration key used as the instance name of the plugin,
+	// it must be set if the component is not unique, e.g. the key of the storage class name.
+	InstanceNameKey string `json:"instanceNameKey,omitempty"`
+	// Images the image archives in the bundle, they are loaded to the nodes when the cluster is offline.
+	Images []string `json:"images,omitempty"`
+}
+
+// Bundle is a component shipped as the manifest templates instead of the code,
+// e.g. name/version/arch/{bundle.json,manifests/*.yaml,images.tar.gz} pushed by 'kcctl resource push'.
+type Bundle struct {
+	Spec
+	// Manifests the go templates of the manifests, keyed by the file name.
+	Manifests map[string]string
+}
+
+// Load reads the bundle in the directory.
+func Load(dir string) (*Bundle, error) {
+	data, err := os.ReadFile(filepath.Join(dir, Filename))
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{Manifests: make(map[string]string)}
+	if err = json.Unmarshal(data, &b.Spec); err != nil {
+		return nil, fmt.Errorf("decode %s error: %w", Filename, err)
+	}
+	files, err := os.ReadDir(filepath.Join(dir, ManifestsDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.IsDir() || !(strings.HasSuffix(f.Name(), ".yaml") || strings.HasSuffix(f.Name(), ".yml")) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, ManifestsDir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		b.Manifests[f.Name()] = string(content)
+	}
+	if err = b.Validate(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Validate checks the bundle can be registered as a component.
+func (b *Bundle) Validate() error {
+	if b.Name == "" {
+		return errEmptyName
+	}
+	if b.Version == "" {
+		return errEmptyVersion
+	}
+	if strings.Contains(b.Name, "/") || strings.Contains(b.Version, "/") {
+		return fmt.Errorf("bundle component %s-%s: name and version must not contain '/'", b.Name, b.Version)
+	}
+	if !b.Unique && b.InstanceNameKey == "" {
+		return fmt.Errorf("bundle component %s-%s is not unique, instanceNameKey must be provided", b.Name, b.Version)
+	}
+	if len(b.Manifests) == 0 {
+		return errEmptyManifests
+	}
+	for name, manifest := range b.Manifests {
+		if _, err := tmplutil.New().Parse(manifest); err != nil {
+			return fmt.Errorf("bundle component %s-%s: parse manifest %s error: %w", b.Name, b.Version, name, err)
+		}
+	}
+	if b.Schema != nil && b.Schema.Type != component.JSONSchemaTypeObject {
+		return fmt.Errorf("bundle component %s-%s: schema type must be %s", b.Name, b.Version, component.JSONSchemaTypeObject)
+	}
+	return nil
+}
+
+// LoadAll registers the bundles in the static resource directory, which are laid out as root/name/version/arch.
+// The bundle of the same component for another arch is skipped, and the bundle which fails to be loaded
+// does not stop the others.
+func LoadAll(root string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(root, "*", "*", "*", Filename))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	var (
+		loaded []string
+		errs   []error
+	)
+	for _, p := range paths {
+		b, err := Load(filepath.Dir(p))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("load bundle %s error: %w", filepath.Dir(p), err))
+			continue
+		}
+		key := fmt.Sprintf(component.RegisterFormat, b.Name, b.Version)
+		if containsString(loaded, key) {
+			continue
+		}
+		if err = Register(b); err != nil {
+			errs = append(errs, fmt.Errorf("register bundle %s error: %w", filepath.Dir(p), err))
+			continue
+		}
+		loaded = append(loaded, key)
+	}
+	return loaded, utilerrors.NewAggregate(errs)
+}
+
+// Register registers the bundle as a component, the component compiled in the binaries can not be replaced.
+func Register(b *Bundle) error {
+	return component.Register(fmt.Sprintf(component.RegisterFormat, b.Name, b.Version), &Component{bundle: b})
+}
+
+func containsString(s []string, v string) bool {
+	for _, str := range s {
+		if str == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+const testManifest = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: {{ .InstanceName }}
+data:
+  image: {{ with .ImageRepoMirror }}{{ . }}/{{ end }}demo:{{ .Values.tag }}
+`
+
+func writeBundle(t *testing.T, dir string, spec Spec, manifests map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, ManifestsDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, Filename), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range manifests {
+		if err = os.WriteFile(filepath.Join(dir, ManifestsDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testSpec(name string) Spec {
+	return Spec{
+		Meta: component.Meta{
+			Name:     name,
+			Version:  "v1",
+			Category: component.InternalCategoryPAAS,
+			Schema: &component.JSONSchemaProps{
+				Type: component.JSONSchemaTypeObject,
+				Properties: map[string]component.JSONSchemaProps{
+					"name": {Type: component.JSONSchemaTypeString},
+					"tag":  {Type: component.JSONSchemaTypeString, Default: "1.0"},
+				},
+				Required: []string{"name"},
+			},
+		},
+		InstanceNameKey: "name",
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      func() Spec
+		manifests map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "valid",
+			spec:      func() Spec { return testSpec("demo") },
+			manifests: map[string]string{"demo.yaml": testManifest, "README.md": "ignored"},
+		},
+		{
+			name:    "no manifest",
+			spec:    func() Spec { return testSpec("demo") },
+			wantErr: true,
+		},
+		{
+			name: "no instance name",
+			spec: func() Spec {
+				s := testSpec("demo")
+				s.InstanceNameKey = ""
+				return s
+			},
+			manifests: map[string]string{"demo.yaml": testManifest},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid template",
+			spec:      func() Spec { return testSpec("demo") },
+			manifests: map[string]string{"demo.yaml": "{{ .Values"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeBundle(t, dir, tt.spec(), tt.manifests)
+			b, err := Load(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(b.Manifests) != 1 {
+				t.Errorf("unexpected manifests: %v", b.Manifests)
+			}
+		})
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	root := t.TempDir()
+	manifests := map[string]string{"demo.yaml": testManifest}
+	writeBundle(t, filepath.Join(root, "bundle-test", "v1", "amd64"), testSpec("bundle-test"), manifests)
+	writeBundle(t, filepath.Join(root, "bundle-test", "v1", "arm64"), testSpec("bundle-test"), manifests)
+	writeBundle(t, filepath.Join(root, "broken", "v1", "amd64"), testSpec("broken"), nil)
+
+	loaded, err := LoadAll(root)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("LoadAll() error = %v, want the error of broken bundle", err)
+	}
+	if len(loaded) != 1 || loaded[0] != "bundle-test/v1" {
+		t.Fatalf("unexpected loaded bundles: %v", loaded)
+	}
+	itf, ok := component.Load("bundle-test/v1")
+	if !ok {
+		t.Fatalf("bundle is not registered")
+	}
+
+	comp := itf.NewInstance().(*Component)
+	if err = json.Unmarshal([]byte(`{"name":"demo-1","imageRepoMirror":"mirror.local"}`), comp); err != nil {
+		t.Fatal(err)
+	}
+	if err = comp.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if comp.GetInstanceName() != "demo-1" || comp.GetImageRepoMirror() != "mirror.local" {
+		t.Errorf("unexpected instance %s mirror %s", comp.GetInstanceName(), comp.GetImageRepoMirror())
+	}
+	ctx := component.WithExtraMetadata(context.TODO(), component.ExtraMetadata{
+		Masters:       component.NodeList{{ID: "node1"}},
+		OperationType: v1.OperationInstallComponents,
+	})
+	if err = comp.InitSteps(ctx); err != nil {
+		t.Fatalf("InitSteps() error: %v", err)
+	}
+	if len(comp.GetInstallSteps()) != 2 || len(comp.GetUninstallSteps()) != 2 || len(comp.GetUpgradeSteps()) != 2 {
+		t.Fatalf("unexpected steps: %v", comp.GetInstallSteps())
+	}
+	m := &Manifests{}
+	if err = json.Unmarshal(comp.GetInstallSteps()[0].Commands[0].Template.Data, m); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(m.Files["demo.yaml"], "name: demo-1") || !strings.Contains(m.Files["demo.yaml"], "mirror.local/demo:1.0") {
+		t.Errorf("unexpected rendered manifest: %s", m.Files["demo.yaml"])
+	}
+
+	missing := itf.NewInstance().(*Component)
+	if err = missing.Validate(); err == nil {
+		t.Errorf("Validate() must fail without the required configuration")
+	}
+}
@@ -0,0 +1,290 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package bundle
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/component/common"
+	"github.com/kubeclipper/kubeclipper/pkg/component/utils"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/strutil"
+	tmplutil "github.com/kubeclipper/kubeclipper/pkg/utils/template"
+)
+
+const (
+	imageRepoMirrorKey = "imageRepoMirror"
+	baseManifestsDir   = "/tmp/.bundle"
+	defaultTimeout     = 5 * time.Minute
+)
+
+var _ component.Interface = (*Component)(nil)
+
+// Component is the plugin of a bundle, the configurations are kept as they are given by the user,
+// the manifests are rendered on the server and applied on the first master.
+type Component struct {
+	bundle                                     *Bundle
+	values                                     map[string]interface{}
+	installSteps, uninstallSteps, upgradeSteps []v1.Step
+}
+
+// RenderData is the data the manifest templates are rendered with.
+type RenderData struct {
+	// Values the configurations of the plugin.
+	Values          map[string]interface{}
+	Name            string
+	Version         string
+	InstanceName    string
+	ImageRepoMirror string
+	ClusterName     string
+	KubeVersion     string
+	Masters         int
+	Workers         int
+}
+
+func (c *Component) UnmarshalJSON(data []byte) error {
+	if c.values == nil {
+		c.values = make(map[string]interface{})
+	}
+	return json.Unmarshal(data, &c.values)
+}
+
+func (c *Component) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.values)
+}
+
+func (c *Component) Ns() string {
+	return ""
+}
+
+func (c *Component) Svc() string {
+	return ""
+}
+
+func (c *Component) RequestPath() string {
+	return ""
+}
+
+func (c *Component) Supported() bool {
+	return false
+}
+
+func (c *Component) GetInstanceName() string {
+	if c.bundle.InstanceNameKey == "" {
+		return ""
+	}
+	if v, ok := c.values[c.bundle.InstanceNameKey]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+func (c *Component) GetComponentMeta(lang component.Lang) component.Meta {
+	return c.bundle.Meta
+}
+
+func (c *Component) GetDependence() []string {
+	return c.bundle.Dependence
+}
+
+func (c *Component) RequireExtraCluster() []string {
+	return nil
+}
+
+func (c *Component) CompleteWithExtraCluster(extra map[string]component.ExtraMetadata) error {
+	return nil
+}
+
+func (c *Component) Validate() error {
+	if c.bundle.Schema != nil {
+		config, err := json.Marshal(c.values)
+		if err != nil {
+			return err
+		}
+		if err = component.ValidateConfig(c.bundle.Schema, config); err != nil {
+			return err
+		}
+	}
+	if c.bundle.InstanceNameKey != "" && c.GetInstanceName() == "" {
+		return fmt.Errorf("%s must be provided", c.bundle.InstanceNameKey)
+	}
+	return nil
+}
+
+func (c *Component) NewInstance() component.ObjectMeta {
+	values := make(map[string]interface{})
+	if c.bundle.Schema != nil {
+		if config, err := component.DefaultConfig(c.bundle.Schema); err == nil {
+			_ = json.Unmarshal(config, &values)
+		}
+	}
+	return &Component{bundle: c.bundle, values: values}
+}
+
+func (c *Component) InitSteps(ctx context.Context) error {
+	metadata := component.GetExtraMetadata(ctx)
+	// when the component does not specify an ImageRepoMirror, the cluster LocalRegistry is inherited
+	mirror := c.GetImageRepoMirror()
+	if mirror == "" {
+		mirror = metadata.LocalRegistry
+	}
+	stepMaster0 := utils.UnwrapNodeList(metadata.Masters[:1])
+	timeout := defaultTimeout
+	if c.bundle.TimeoutSeconds > 0 {
+		timeout = time.Duration(c.bundle.TimeoutSeconds) * time.Second
+	}
+
+	var imageSteps []v1.Step
+	if metadata.Offline && mirror == "" && len(c.bundle.Images) > 0 {
+		imager := &common.Imager{
+			PkgName:         c.bundle.Name,
+			Version:         c.bundle.Version,
+			CriName:         metadata.CRI,
+			Offline:         metadata.Offline,
+			CustomImageList: c.bundle.Images,
+		}
+		steps, err := imager.InstallSteps(metadata.GetAllNodes())
+		if err != nil {
+			return err
+		}
+		imageSteps = steps
+	}
+
+	manifests, err := c.render(RenderData{
+		Values:          c.values,
+		Name:            c.bundle.Name,
+		Version:         c.bundle.Version,
+		InstanceName:    c.GetInstanceName(),
+		ImageRepoMirror: mirror,
+		ClusterName:     metadata.ClusterName,
+		KubeVersion:     metadata.KubeVersion,
+		Masters:         len(metadata.Masters),
+		Workers:         len(metadata.Workers),
+	})
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(manifests)
+	if err != nil {
+		return err
+	}
+	renderStep := func(action v1.StepAction) v1.Step {
+		return v1.Step{
+			ID:         strutil.GetUUID(),
+			Name:       fmt.Sprintf("render-%s-manifests", c.bundle.Name),
+			Timeout:    metav1.Duration{Duration: 10 * time.Second},
+			RetryTimes: 1,
+			Nodes:      stepMaster0,
+			Action:     action,
+			Commands: []v1.Command{
+				{
+					Type: v1.CommandTemplateRender,
+					Template: &v1.TemplateCommand{
+						Identity: manifestsTemplateKey,
+						Data:     data,
+					},
+				},
+			},
+		}
+	}
+	kubectlStep := func(name string, action v1.StepAction, args ...string) v1.Step {
+		return v1.Step{
+			ID:         strutil.GetUUID(),
+			Name:       name,
+			Timeout:    metav1.Duration{Duration: timeout},
+			RetryTimes: 1,
+			Nodes:      stepMaster0,
+			Action:     action,
+			Commands: []v1.Command{
+				{
+					Type:         v1.CommandShell,
+					ShellCommand: append([]string{"kubectl"}, args...),
+				},
+			},
+		}
+	}
+
+	c.installSteps = append(append([]v1.Step{}, imageSteps...),
+		renderStep(v1.ActionInstall),
+		kubectlStep(fmt.Sprintf("deploy-%s", c.bundle.Name), v1.ActionInstall, "apply", "-f", manifests.Dir))
+	c.upgradeSteps = append(append([]v1.Step{}, imageSteps...),
+		renderStep(v1.ActionUpgrade),
+		kubectlStep(fmt.Sprintf("upgrade-%s", c.bundle.Name), v1.ActionUpgrade, "apply", "-f", manifests.Dir))
+	if metadata.OperationType != v1.OperationDeleteCluster {
+		remove := kubectlStep(fmt.Sprintf("remove-%s", c.bundle.Name), v1.ActionUninstall,
+			"delete", "--ignore-not-found", "-f", manifests.Dir)
+		remove.ErrIgnore = true
+		c.uninstallSteps = []v1.Step{renderStep(v1.ActionUninstall), remove}
+	}
+	return nil
+}
+
+// render renders the manifest templates of bundle into the directory of this instance.
+func (c *Component) render(data RenderData) (*Manifests, error) {
+	dir := c.bundle.Name
+	if name := c.GetInstanceName(); name != "" {
+		dir = name
+	}
+	m := &Manifests{
+		Dir:   filepath.Join(baseManifestsDir, c.bundle.Name, dir),
+		Files: make(map[string]string, len(c.bundle.Manifests)),
+	}
+	names := make([]string, 0, len(c.bundle.Manifests))
+	for name := range c.bundle.Manifests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		buf := &bytes.Buffer{}
+		if _, err := tmplutil.New().RenderTo(buf, c.bundle.Manifests[name], data); err != nil {
+			return nil, fmt.Errorf("render manifest %s of %s-%s error: %w", name, c.bundle.Name, c.bundle.Version, err)
+		}
+		m.Files[name] = buf.String()
+	}
+	return m, nil
+}
+
+func (c *Component) GetInstallSteps() []v1.Step {
+	return c.installSteps
+}
+
+func (c *Component) GetUninstallSteps() []v1.Step {
+	return c.uninstallSteps
+}
+
+func (c *Component) GetUpgradeSteps() []v1.Step {
+	return c.upgradeSteps
+}
+
+// GetImageRepoMirror return ImageRepoMirror
+func (c *Component) GetImageRepoMirror() string {
+	if v, ok := c.values[imageRepoMirrorKey].(string); ok {
+		return v
+	}
+	return ""
+}
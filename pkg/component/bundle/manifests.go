@@ -0,0 +1,78 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/fileutil"
+)
+
+const (
+	name    = "bundle"
+	version = "v1"
+)
+
+var manifestsTemplateKey = fmt.Sprintf(component.RegisterTemplateKeyFormat, name, version, "manifests")
+
+func init() {
+	if err := component.RegisterTemplate(manifestsTemplateKey, &Manifests{}); err != nil {
+		panic(err)
+	}
+}
+
+var _ component.TemplateRender = (*Manifests)(nil)
+
+// Manifests are the manifests rendered by the server, the agent writes them into the directory.
+type Manifests struct {
+	Dir   string            `json:"dir"`
+	Files map[string]string `json:"files"`
+}
+
+func (m *Manifests) NewInstance() component.ObjectMeta {
+	return &Manifests{}
+}
+
+func (m *Manifests) Render(ctx context.Context, opts component.Options) error {
+	if !opts.DryRun {
+		// the manifests removed from the bundle must not be applied again.
+		if err := os.RemoveAll(m.Dir); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(m.Dir, 0755); err != nil {
+			return err
+		}
+	}
+	for name, content := range m.Files {
+		content := content
+		if err := fileutil.WriteFileWithContext(ctx, filepath.Join(m.Dir, filepath.Base(name)), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644,
+			func(w io.Writer) error {
+				_, err := io.WriteString(w, content)
+				return err
+			}, opts.DryRun); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -32,11 +32,18 @@ type (
 	oplogKey     struct{}
 	retryKey     struct{}
 	repoMirror   struct{}
+	backupKeyKey struct{}
 )
 
+// BackupKeyResolver returns the key of backup encryption of id which the backup point uses,
+// the keys are kept by the server and are not carried by the steps.
+type BackupKeyResolver func(ctx context.Context, backupPoint, keyID string) ([]byte, error)
+
 type ExtraMetadata struct {
-	Masters            NodeList
-	Workers            NodeList
+	Masters NodeList
+	Workers NodeList
+	// EtcdNodes the external etcd members managed by kubeclipper, they are not a part of kubernetes nodes.
+	EtcdNodes          NodeList
 	Offline            bool
 	LocalRegistry      string
 	CRI                string
@@ -229,3 +236,14 @@ func GetRepoMirror(ctx context.Context) string {
 	}
 	return ""
 }
+
+func WithBackupKeyResolver(ctx context.Context, resolver BackupKeyResolver) context.Context {
+	return context.WithValue(ctx, backupKeyKey{}, resolver)
+}
+
+func GetBackupKeyResolver(ctx context.Context) BackupKeyResolver {
+	if v := ctx.Value(backupKeyKey{}); v != nil {
+		return v.(BackupKeyResolver)
+	}
+	return nil
+}
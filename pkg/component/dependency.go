@@ -0,0 +1,199 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package component
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrDependenceCycle the components depend on each other.
+	ErrDependenceCycle = errors.New("component dependence cycle")
+	// ErrDependenceInUse the component is still depended on by other components.
+	ErrDependenceInUse = errors.New("component is depended on by other components")
+)
+
+// IsInternalDependence whether the dependence is provided by the cluster itself.
+func IsInternalDependence(dependence string) bool {
+	return dependence == InternalCategoryNodes || dependence == InternalCategoryKubernetes
+}
+
+// Provides whether the component satisfies the dependence, a dependence is either the name or the category of components.
+func (m Meta) Provides(dependence string) bool {
+	return m.Name == dependence || m.Category == dependence
+}
+
+func provided(metas []Meta, dependence string) bool {
+	for _, m := range metas {
+		if m.Provides(dependence) {
+			return true
+		}
+	}
+	return false
+}
+
+// SortByDependence returns the order in which the components are installed, every component is installed after
+// the components it depends on. The dependencies provided by the cluster or the installed components are satisfied
+// already, the dependencies provided by none of them are returned as missing.
+func SortByDependence(metas, installed []Meta) ([]int, []string, error) {
+	deps := make([][]int, len(metas))
+	var missing []string
+	for i, m := range metas {
+		for _, dependence := range m.Dependence {
+			if IsInternalDependence(dependence) {
+				continue
+			}
+			found := provided(installed, dependence)
+			for j := range metas {
+				if j != i && metas[j].Provides(dependence) {
+					deps[i] = append(deps[i], j)
+					found = true
+				}
+			}
+			if !found && !containsString(missing, dependence) {
+				missing = append(missing, dependence)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(metas))
+	order := make([]int, 0, len(metas))
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("%w: %s -> %s", ErrDependenceCycle, strings.Join(path, " -> "), metas[i].Name)
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		path = append(path, metas[i].Name)
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		order = append(order, i)
+		return nil
+	}
+	for i := range metas {
+		if err := visit(i); err != nil {
+			return nil, nil, err
+		}
+	}
+	return order, missing, nil
+}
+
+// CheckRemoval checks whether the remaining components still have their dependencies after the components are removed.
+func CheckRemoval(removed, remaining []Meta) error {
+	for _, m := range remaining {
+		for _, dependence := range m.Dependence {
+			if IsInternalDependence(dependence) || !provided(removed, dependence) {
+				continue
+			}
+			if !provided(remaining, dependence) {
+				return fmt.Errorf("%w: %s depends on %s", ErrDependenceInUse, m.Name, dependence)
+			}
+		}
+	}
+	return nil
+}
+
+// LatestProvider returns the latest version of the registered components which provide the dependence,
+// the component of the same name is preferred to the components of the category, and the deprecated components
+// are used only if no other component provides the dependence.
+func LatestProvider(dependence string) (Meta, bool) {
+	var candidates []Meta
+	for _, c := range _components.componentsMap {
+		if m := c.GetComponentMeta(English); m.Provides(dependence) {
+			candidates = append(candidates, m)
+		}
+	}
+	if len(candidates) == 0 {
+		return Meta{}, false
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (a.Name == dependence) != (b.Name == dependence) {
+			return a.Name == dependence
+		}
+		if a.Deprecated != b.Deprecated {
+			return !a.Deprecated
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return compareVersion(a.Version, b.Version) > 0
+	})
+	return candidates[0], true
+}
+
+// DefaultConfig returns the configurations made up of the defaults in schema.
+func DefaultConfig(schema *JSONSchemaProps) ([]byte, error) {
+	config := make(map[string]interface{})
+	if schema != nil {
+		for key, prop := range schema.Properties {
+			if prop.Default != nil {
+				config[key] = prop.Default
+			}
+		}
+	}
+	return json.Marshal(config)
+}
+
+// compareVersion compares the versions like v1 or v1.2.3 part by part.
+func compareVersion(a, b string) int {
+	as := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	bs := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
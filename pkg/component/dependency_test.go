@@ -0,0 +1,53 @@
+package component
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSortByDependence(t *testing.T) {
+	metas := []Meta{
+		{Name: "app", Dependence: []string{InternalCategoryKubernetes, "db", InternalCategoryStorage}},
+		{Name: "db", Dependence: []string{InternalCategoryStorage, "monitor"}},
+		{Name: "nfs", Category: InternalCategoryStorage},
+	}
+	order, missing, err := SortByDependence(metas, nil)
+	if err != nil {
+		t.Fatalf("SortByDependence() error: %v", err)
+	}
+	if !reflect.DeepEqual(order, []int{2, 1, 0}) || !reflect.DeepEqual(missing, []string{"monitor"}) {
+		t.Errorf("SortByDependence() = %v, %v", order, missing)
+	}
+	// the dependencies of installed components are satisfied.
+	_, missing, _ = SortByDependence(metas, []Meta{{Name: "prometheus", Category: "monitor"}})
+	if len(missing) != 0 {
+		t.Errorf("unexpected missing dependencies: %v", missing)
+	}
+
+	metas[2].Dependence = []string{"app"}
+	if _, _, err = SortByDependence(metas, nil); !errors.Is(err, ErrDependenceCycle) {
+		t.Fatalf("SortByDependence() error = %v, want %v", err, ErrDependenceCycle)
+	}
+	if want := "component dependence cycle: app -> db -> nfs -> app"; err.Error() != want {
+		t.Errorf("SortByDependence() error = %v, want %s", err, want)
+	}
+}
+
+func TestCheckRemoval(t *testing.T) {
+	nfs := Meta{Name: "nfs", Category: InternalCategoryStorage}
+	csi := Meta{Name: "nfs-csi", Category: InternalCategoryStorage}
+	app := Meta{Name: "app", Dependence: []string{InternalCategoryStorage}}
+	if err := CheckRemoval([]Meta{nfs}, []Meta{app}); !errors.Is(err, ErrDependenceInUse) {
+		t.Errorf("CheckRemoval() error = %v, want %v", err, ErrDependenceInUse)
+	}
+	if err := CheckRemoval([]Meta{nfs}, []Meta{app, csi}); err != nil {
+		t.Errorf("CheckRemoval() error = %v", err)
+	}
+}
+
+func Test_compareVersion(t *testing.T) {
+	if compareVersion("v2", "v1.9") <= 0 || compareVersion("v1.10", "v1.9") <= 0 || compareVersion("v1", "v1") != 0 {
+		t.Errorf("unexpected version comparison")
+	}
+}
@@ -150,4 +150,6 @@ type OperationLogFile interface {
 	GetStepLogContent(opID, stepID string, offset int64, length int) (content []byte, deliverySize int64, logSize int64, err error)
 	CreateStepLogFileAndAppend(opID, stepID string, data []byte) error
 	TruncateStepLogFile(opID, stepID string) error
+	DeleteOperationDir(opID string) error
+	PruneOperationDirs() error
 }
@@ -177,6 +177,7 @@ func (n *NFSProvisioner) InitSteps(ctx context.Context) error {
 			return err
 		}
 		n.installSteps = append(n.installSteps, steps...)
+		n.upgradeSteps = append(n.upgradeSteps, steps...)
 	}
 
 	bytes, err := json.Marshal(n)
@@ -245,6 +246,27 @@ func (n *NFSProvisioner) InitSteps(ctx context.Context) error {
 	}
 	n.installSteps = append(n.installSteps, checkCSIHealthStep...)
 
+	// upgrade applies the manifests of this version in place, the storage class and the provisioned volumes are kept.
+	rsUpgrade := rs
+	rsUpgrade.ID = strutil.GetUUID()
+	rsUpgrade.Action = v1.ActionUpgrade
+	rsUpgrade.ErrIgnore = false
+	n.upgradeSteps = append(n.upgradeSteps, rsUpgrade, v1.Step{
+		ID:         strutil.GetUUID(),
+		Name:       "upgradeNFSProvisioner",
+		Timeout:    metav1.Duration{Duration: time.Minute},
+		RetryTimes: 1,
+		Nodes:      stepMaster0,
+		Action:     v1.ActionUpgrade,
+		Commands: []v1.Command{
+			{
+				Type:         v1.CommandShell,
+				ShellCommand: []string{"kubectl", "apply", "-f", filepath.Join(n.ManifestsDir, fmt.Sprintf(filenameFormat, n.StorageClassName))},
+			},
+		},
+	})
+	n.upgradeSteps = append(n.upgradeSteps, checkCSIHealthStep...)
+
 	// uninstall
 	if metadata.OperationType != v1.OperationDeleteCluster {
 		n.uninstallSteps = []v1.Step{
@@ -0,0 +1,106 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package component
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// ValidateConfig validates the configurations of component against its schema, the required properties must be set
+// and the properties must match their types and enums. The properties not in schema are ignored.
+func ValidateConfig(schema *JSONSchemaProps, config []byte) error {
+	if schema == nil {
+		return nil
+	}
+	var v interface{}
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, &v); err != nil {
+			return err
+		}
+	}
+	if v == nil {
+		v = map[string]interface{}{}
+	}
+	return validateValue("config", schema, v)
+}
+
+func validateValue(path string, schema *JSONSchemaProps, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	var ok bool
+	switch schema.Type {
+	case JSONSchemaTypeObject:
+		var obj map[string]interface{}
+		if obj, ok = v.(map[string]interface{}); ok {
+			return validateObject(path, schema, obj)
+		}
+	case JSONSchemaTypeArray:
+		var items []interface{}
+		if items, ok = v.([]interface{}); ok && schema.Items != nil {
+			for i, item := range items {
+				if err := validateValue(fmt.Sprintf("%s[%d]", path, i), schema.Items, item); err != nil {
+					return err
+				}
+			}
+		}
+	case JSONSchemaTypeString:
+		_, ok = v.(string)
+	case JSONSchemaTypeBool:
+		_, ok = v.(bool)
+	case JSONSchemaTypeInt:
+		_, ok = v.(float64)
+	default:
+		ok = true
+	}
+	if !ok {
+		return fmt.Errorf("%s must be %s", path, schema.Type)
+	}
+	if len(schema.Enum) == 0 {
+		return nil
+	}
+	for _, e := range schema.Enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s must be one of %v", path, schema.Enum)
+}
+
+func validateObject(path string, schema *JSONSchemaProps, obj map[string]interface{}) error {
+	for _, key := range schema.Required {
+		if obj[key] == nil {
+			return fmt.Errorf("%s.%s is required", path, key)
+		}
+	}
+	keys := make([]string, 0, len(schema.Properties))
+	for key := range schema.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		prop := schema.Properties[key]
+		if err := validateValue(path+"."+key, &prop, obj[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
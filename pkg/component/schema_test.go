@@ -0,0 +1,36 @@
+package component
+
+import "testing"
+
+func TestValidateConfig(t *testing.T) {
+	schema := &JSONSchemaProps{
+		Type:     JSONSchemaTypeObject,
+		Required: []string{"serverAddr"},
+		Properties: map[string]JSONSchemaProps{
+			"serverAddr":    {Type: JSONSchemaTypeString},
+			"isDefaultSC":   {Type: JSONSchemaTypeBool},
+			"replicas":      {Type: JSONSchemaTypeInt},
+			"reclaimPolicy": {Type: JSONSchemaTypeString, Enum: []JSON{"Retain", "Delete"}},
+			"mountOptions":  {Type: JSONSchemaTypeArray, Items: &JSONSchemaProps{Type: JSONSchemaTypeString}},
+		},
+	}
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "valid", config: `{"serverAddr":"10.0.0.1","isDefaultSC":true,"replicas":2,"reclaimPolicy":"Retain","mountOptions":["soft"],"namespace":"kube-system"}`},
+		{name: "required", config: `{"replicas":2}`, wantErr: true},
+		{name: "type", config: `{"serverAddr":"10.0.0.1","replicas":"2"}`, wantErr: true},
+		{name: "enum", config: `{"serverAddr":"10.0.0.1","reclaimPolicy":"Recycle"}`, wantErr: true},
+		{name: "items", config: `{"serverAddr":"10.0.0.1","mountOptions":[1]}`, wantErr: true},
+		{name: "empty", config: ``, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateConfig(schema, []byte(tt.config)); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
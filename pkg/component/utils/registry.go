@@ -23,6 +23,10 @@ func AddOrRemoveInsecureRegistryToCRI(ctx context.Context, criType, registry str
 		return addOrRemoveContainerdInsecureRegistry(ctx, registry, add, dryRun)
 	case "docker":
 		return addOrRemoveDockerInsecureRegistry(ctx, registry, add, dryRun)
+	case "crio":
+		// the insecure registries of cri-o are written to the registries.conf drop-in of kubeclipper
+		// with the other registries of cluster, see cri.CrioRegistryConfigure.
+		return nil
 	default:
 		return fmt.Errorf("%s CNI is not supported", criType)
 	}
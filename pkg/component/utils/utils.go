@@ -54,6 +54,14 @@ func LoadImage(ctx context.Context, dryRun bool, file, criType string) error {
 		if err != nil {
 			return err
 		}
+	case "crio":
+		// podman load -i xxx/images.tar
+		// cri-o has no image import api, podman is shipped with the cri-o package and shares the same
+		// containers-storage with cri-o by /etc/containers/storage.conf.
+		_, err = cmdutil.RunCmdWithContext(ctx, dryRun, "podman", "load", "-i", file)
+		if err != nil {
+			return err
+		}
 	}
 
 	_, err = cmdutil.RunCmdWithContext(ctx, dryRun, "rm", "-rf", file)
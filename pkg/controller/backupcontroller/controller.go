@@ -31,6 +31,7 @@ import (
 
 	"github.com/kubeclipper/kubeclipper/pkg/client/informers"
 	listerv1 "github.com/kubeclipper/kubeclipper/pkg/client/lister/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/clusteroperation"
 	ctrl "github.com/kubeclipper/kubeclipper/pkg/controller-runtime"
 	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/client"
 	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/controller"
@@ -111,6 +112,16 @@ func (r *BackupReconciler) updateBackupStatus(ctx context.Context, log logger.Lo
 		return err
 	}
 
+	// the imported backup is not created by an operation.
+	if b.Labels[common.LabelOperationName] == "" {
+		return nil
+	}
+
+	// the operation waits in the queue of cluster, it is created when it starts.
+	if oErr != nil && errors.IsNotFound(oErr) && clusteroperation.IsQueued(c, b.Labels[common.LabelOperationName]) {
+		return nil
+	}
+
 	// if operation not exist, backup change to error
 	if oErr != nil && errors.IsNotFound(oErr) {
 		b.Status.ClusterBackupStatus = v1.ClusterBackupError
@@ -124,7 +135,7 @@ func (r *BackupReconciler) updateBackupStatus(ctx context.Context, log logger.Lo
 
 	// when the action of creating backup is timed out, set the cluster status to running, and the backup status to error
 	if b.Status.ClusterBackupStatus == v1.ClusterBackupCreating {
-		timeout := checkBackupTimeout(log, b)
+		timeout := checkBackupTimeout(log, b, o)
 		if timeout {
 			b.Status.ClusterBackupStatus = v1.ClusterBackupError
 			_, err := r.BackupWriter.UpdateBackup(context.TODO(), b)
@@ -186,9 +197,10 @@ func (r *BackupReconciler) updateBackupStatus(ctx context.Context, log logger.Lo
 		}
 	}
 
-	// when the operation status is failed, set the backup status to error
-	if o != nil && o.Status.Status == v1.OperationStatusFailed {
-		if c.Status.Phase == v1.ClusterRestoreFailed {
+	// when the operation status is failed or cancelled, set the backup status to error
+	if o != nil && (o.Status.Status == v1.OperationStatusFailed || o.Status.Status == v1.OperationStatusCancelled) {
+		// the backup is intact after the recovery fails or is dropped from the queue.
+		if c.Status.Phase == v1.ClusterRestoreFailed || o.Labels[common.LabelOperationAction] == v1.OperationRecoverCluster {
 			b.Status.ClusterBackupStatus = v1.ClusterBackupAvailable
 		} else {
 			b.Status.ClusterBackupStatus = v1.ClusterBackupError
@@ -221,13 +233,15 @@ func (r *BackupReconciler) findObjectsForOperation(clu client.Object) []reconcil
 	return requests
 }
 
-func checkBackupTimeout(log logger.Logging, b *v1.Backup) bool {
+// checkBackupTimeout whether the backup is timed out since its operation is created,
+// the operation may wait in the queue of cluster long after the backup is created.
+func checkBackupTimeout(log logger.Logging, b *v1.Backup, o *v1.Operation) bool {
 	if b.Labels[common.LabelTimeoutSeconds] == "" {
 		log.Warn("unexpected error, backup should always has a timeout label. will be considered as timeout")
 		return true
 	}
 
-	createTime := b.CreationTimestamp
+	createTime := o.CreationTimestamp
 	nowTime := time.Now()
 	subTime := nowTime.Sub(createTime.Time)
 
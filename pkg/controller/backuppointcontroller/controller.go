@@ -0,0 +1,236 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package backuppointcontroller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/kubeclipper/kubeclipper/pkg/client/informers"
+	listerv1 "github.com/kubeclipper/kubeclipper/pkg/client/lister/core/v1"
+	ctrl "github.com/kubeclipper/kubeclipper/pkg/controller-runtime"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/controller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/handler"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/manager"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/source"
+	kcerrors "github.com/kubeclipper/kubeclipper/pkg/errors"
+	"github.com/kubeclipper/kubeclipper/pkg/logger"
+	"github.com/kubeclipper/kubeclipper/pkg/models/cluster"
+	"github.com/kubeclipper/kubeclipper/pkg/models/core"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/k8s"
+	bs "github.com/kubeclipper/kubeclipper/pkg/simple/backupstore"
+)
+
+// ErrNotListable the backup files saved in the backup point can not be listed by the server.
+var ErrNotListable = errors.New("backup files can not be listed")
+
+// BackupPointReconciler lists the backup files saved in the backup points periodically. The available backups whose
+// file vanished are marked missing, and the files which no backup refers to are recorded in the status of backup point
+// so that they can be imported.
+type BackupPointReconciler struct {
+	BackupLister      listerv1.BackupLister
+	BackupPointLister listerv1.BackupPointLister
+	BackupWriter      cluster.BackupWriter
+	BackupPointWriter cluster.BackupPointWriter
+	Options           *Options
+	Now               func() time.Time
+}
+
+func (r *BackupPointReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx)
+	if !r.Options.Enabled() {
+		return ctrl.Result{}, nil
+	}
+	bp, err := r.BackupPointLister.Get(req.Name)
+	if err != nil {
+		if kcerrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error("Failed to get backup point", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	if Listable(bp) != nil {
+		return ctrl.Result{}, nil
+	}
+	now := r.Now()
+	// the update of status enqueues the backup point again, it is not listed until the interval elapses.
+	if bp.Status.SyncedTime != nil {
+		if next := bp.Status.SyncedTime.Add(r.Options.SyncInterval); now.Before(next) {
+			return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+		}
+	}
+
+	store, err := k8s.NewBackupStore(bp, "", nil)
+	if err != nil {
+		log.Error("Failed to create backup store", zap.String("backupPoint", bp.Name), zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	files, err := store.List(ctx)
+	if err != nil {
+		log.Error("Failed to list backup files", zap.String("backupPoint", bp.Name), zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	backups, err := r.BackupLister.List(labels.Everything())
+	if err != nil {
+		log.Error("Failed to list backups", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+
+	orphans, vanished, reappeared := compareBackups(bp.Name, files, backups)
+	for _, b := range vanished {
+		// the file may be saved after it was listed.
+		if _, err = store.Stat(ctx, b.Status.FileName); !errors.Is(err, bs.ErrNotFound) {
+			continue
+		}
+		b = b.DeepCopy()
+		b.Status.ClusterBackupStatus = v1.ClusterBackupMissing
+		b.Status.VerifyMessage = err.Error()
+		if _, err = r.BackupWriter.UpdateBackup(ctx, b); err != nil {
+			log.Error("Failed to update backup", zap.String("backup", b.Name), zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		log.Warn("backup file vanished", zap.String("backup", b.Name), zap.String("backupPoint", bp.Name))
+	}
+	for _, b := range reappeared {
+		b = b.DeepCopy()
+		b.Status.ClusterBackupStatus = v1.ClusterBackupAvailable
+		b.Status.VerifyMessage = ""
+		if _, err = r.BackupWriter.UpdateBackup(ctx, b); err != nil {
+			log.Error("Failed to update backup", zap.String("backup", b.Name), zap.Error(err))
+			return ctrl.Result{}, err
+		}
+	}
+
+	bp = bp.DeepCopy()
+	synced := metav1.NewTime(now)
+	bp.Status.SyncedTime = &synced
+	bp.Status.OrphanBackups = orphans
+	if _, err = r.BackupPointWriter.UpdateBackupPoint(ctx, bp); err != nil {
+		log.Error("Failed to update backup point", zap.String("backupPoint", bp.Name), zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: r.Options.SyncInterval}, nil
+}
+
+func (r *BackupPointReconciler) SetupWithManager(mgr manager.Manager, cache informers.InformerCache) error {
+	c, err := controller.NewUnmanaged("backuppoint", controller.Options{
+		MaxConcurrentReconciles: 1,
+		Reconciler:              r,
+		Log:                     mgr.GetLogger().WithName("backuppoint-controller"),
+		RecoverPanic:            true,
+	})
+	if err != nil {
+		return err
+	}
+	if err = c.Watch(source.NewKindWithCache(&v1.BackupPoint{}, cache), &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+	mgr.AddRunnable(c)
+	return nil
+}
+
+// compareBackups returns the files which no backup refers to, the available backups whose file is not listed,
+// and the missing backups whose file is listed again.
+func compareBackups(bpName string, files []bs.FileInfo, backups []*v1.Backup) ([]v1.BackupFile, []*v1.Backup, []*v1.Backup) {
+	listed := make(map[string]bool, len(files))
+	for _, f := range files {
+		listed[f.Name] = true
+	}
+	referenced := make(map[string]bool)
+	var vanished, reappeared []*v1.Backup
+	for _, b := range backups {
+		if b.BackupPointName != bpName {
+			continue
+		}
+		referenced[b.Status.FileName] = true
+		switch {
+		case b.Status.ClusterBackupStatus == v1.ClusterBackupAvailable && !listed[b.Status.FileName]:
+			vanished = append(vanished, b)
+		case b.Status.ClusterBackupStatus == v1.ClusterBackupMissing && listed[b.Status.FileName]:
+			reappeared = append(reappeared, b)
+		}
+	}
+	var orphans []v1.BackupFile
+	for _, f := range files {
+		if !referenced[f.Name] {
+			orphans = append(orphans, v1.BackupFile{FileName: f.Name, Size: f.Size, ModTime: metav1.NewTime(f.ModTime)})
+		}
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].FileName < orphans[j].FileName
+	})
+	return orphans, vanished, reappeared
+}
+
+// Listable returns ErrNotListable if the backups are saved by the fs backup point on the nodes which the server
+// can not access.
+func Listable(bp *v1.BackupPoint) error {
+	if bp.StorageType == bs.FSStorage {
+		return fmt.Errorf("%w: backup point %s saves backups on the nodes", ErrNotListable, bp.Name)
+	}
+	return nil
+}
+
+// InspectBackup downloads the backup file saved in backup point bp, and returns the status of backup imported from it.
+// The key which the file is encrypted by is found by the key id in the header of file.
+func InspectBackup(ctx context.Context, o core.Operator, bp *v1.BackupPoint, fileName string) (*v1.BackupStatus, error) {
+	raw, err := k8s.NewBackupStore(bp, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = raw.Stat(ctx, fileName); err != nil {
+		return nil, err
+	}
+	var key []byte
+	keyID, err := bs.EncryptionKeyID(ctx, raw, fileName)
+	switch {
+	case err == nil:
+		if key, err = core.BackupDecryptionKey(ctx, o, bp, keyID); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, bs.ErrNotEncrypted):
+		keyID = ""
+	default:
+		return nil, err
+	}
+
+	store, err := k8s.NewBackupStore(bp, keyID, key)
+	if err != nil {
+		return nil, err
+	}
+	checksum := bs.NewChecksum()
+	if err = store.Download(ctx, fileName, checksum); err != nil {
+		return nil, err
+	}
+	return &v1.BackupStatus{
+		FileName:            fileName,
+		BackupFileSize:      checksum.Size(),
+		BackupFileMD5:       checksum.MD5(),
+		ClusterBackupStatus: v1.ClusterBackupAvailable,
+		EncryptionKeyID:     keyID,
+	}, nil
+}
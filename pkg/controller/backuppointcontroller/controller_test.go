@@ -0,0 +1,114 @@
+package backuppointcontroller
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	bs "github.com/kubeclipper/kubeclipper/pkg/simple/backupstore"
+)
+
+func TestCompareBackups(t *testing.T) {
+	modTime := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	newBackup := func(name, bp string, status v1.ClusterBackupStatus) *v1.Backup {
+		return &v1.Backup{
+			ObjectMeta:      metav1.ObjectMeta{Name: name},
+			BackupPointName: bp,
+			Status:          v1.BackupStatus{FileName: name, ClusterBackupStatus: status},
+		}
+	}
+	files := []bs.FileInfo{
+		{Name: "orphan-2", Size: 2, ModTime: modTime},
+		{Name: "available", Size: 1, ModTime: modTime},
+		{Name: "missing", Size: 1, ModTime: modTime},
+		{Name: "orphan-1", Size: 1, ModTime: modTime},
+		{Name: "other", Size: 1, ModTime: modTime},
+	}
+	backups := []*v1.Backup{
+		newBackup("available", "s3", v1.ClusterBackupAvailable),
+		newBackup("vanished", "s3", v1.ClusterBackupAvailable),
+		newBackup("missing", "s3", v1.ClusterBackupMissing),
+		newBackup("creating", "s3", v1.ClusterBackupCreating),
+		// the backup of another backup point does not refer to the file.
+		newBackup("other", "sftp", v1.ClusterBackupAvailable),
+	}
+
+	orphans, vanished, reappeared := compareBackups("s3", files, backups)
+	wantOrphans := []v1.BackupFile{
+		{FileName: "orphan-1", Size: 1, ModTime: metav1.NewTime(modTime)},
+		{FileName: "orphan-2", Size: 2, ModTime: metav1.NewTime(modTime)},
+		{FileName: "other", Size: 1, ModTime: metav1.NewTime(modTime)},
+	}
+	if !reflect.DeepEqual(orphans, wantOrphans) {
+		t.Errorf("compareBackups() orphans = %v, want %v", orphans, wantOrphans)
+	}
+	if len(vanished) != 1 || vanished[0].Name != "vanished" {
+		t.Errorf("compareBackups() vanished = %v, want [vanished]", vanished)
+	}
+	if len(reappeared) != 1 || reappeared[0].Name != "missing" {
+		t.Errorf("compareBackups() reappeared = %v, want [missing]", reappeared)
+	}
+}
+
+func TestInspectBackup(t *testing.T) {
+	dir := t.TempDir()
+	snapshot := []byte("etcd snapshot")
+	sum := fmt.Sprintf("%x", md5.Sum(snapshot))
+	if err := os.WriteFile(filepath.Join(dir, "plain"), snapshot, 0644); err != nil {
+		t.Fatal(err)
+	}
+	key, err := bs.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := bs.NewEncryptedStore(&bs.FilesystemStore{RootDir: dir}, "imported", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Save(context.TODO(), bytes.NewReader(snapshot), "encrypted"); err != nil {
+		t.Fatal(err)
+	}
+	bp := &v1.BackupPoint{
+		ObjectMeta:  metav1.ObjectMeta{Name: "imported"},
+		StorageType: bs.FSStorage,
+		FsConfig:    &v1.FsConfig{BackupRootDir: dir},
+		Encryption:  &v1.BackupEncryption{Key: base64.StdEncoding.EncodeToString(key)},
+	}
+
+	for _, tt := range []struct {
+		fileName string
+		keyID    string
+	}{
+		{fileName: "plain"},
+		{fileName: "encrypted", keyID: "imported"},
+	} {
+		status, err := InspectBackup(context.TODO(), nil, bp, tt.fileName)
+		if err != nil {
+			t.Fatalf("InspectBackup(%s) error: %v", tt.fileName, err)
+		}
+		want := &v1.BackupStatus{
+			FileName:            tt.fileName,
+			BackupFileSize:      int64(len(snapshot)),
+			BackupFileMD5:       sum,
+			ClusterBackupStatus: v1.ClusterBackupAvailable,
+			EncryptionKeyID:     tt.keyID,
+		}
+		if !reflect.DeepEqual(status, want) {
+			t.Errorf("InspectBackup(%s) = %+v, want %+v", tt.fileName, status, want)
+		}
+	}
+	if _, err = InspectBackup(context.TODO(), nil, bp, "missing"); !errors.Is(err, bs.ErrNotFound) {
+		t.Errorf("InspectBackup() error = %v, want %v", err, bs.ErrNotFound)
+	}
+}
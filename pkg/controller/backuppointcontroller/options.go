@@ -0,0 +1,54 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package backuppointcontroller
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	// SyncInterval the backup files saved in the backup points are listed at it, 0 means they are not listed.
+	SyncInterval time.Duration `json:"syncInterval" yaml:"syncInterval"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		SyncInterval: 30 * time.Minute,
+	}
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if o.SyncInterval != 0 && o.SyncInterval < 5*time.Minute {
+		errs = append(errs, errors.New("backup point sync interval should not less than 5 minutes"))
+	}
+	return errs
+}
+
+// Enabled whether the backup files are listed periodically.
+func (o *Options) Enabled() bool {
+	return o.SyncInterval > 0
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&o.SyncInterval, "backup-point-sync-interval", o.SyncInterval, "Backup files saved in backup points are listed at this interval to find missing and orphan backups, minimal value is 5 minutes, 0 means backup files are not listed")
+}
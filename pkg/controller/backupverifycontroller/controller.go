@@ -0,0 +1,172 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package backupverifycontroller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeclipper/kubeclipper/pkg/client/informers"
+	listerv1 "github.com/kubeclipper/kubeclipper/pkg/client/lister/core/v1"
+	ctrl "github.com/kubeclipper/kubeclipper/pkg/controller-runtime"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/controller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/handler"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/manager"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/source"
+	kcerrors "github.com/kubeclipper/kubeclipper/pkg/errors"
+	"github.com/kubeclipper/kubeclipper/pkg/logger"
+	"github.com/kubeclipper/kubeclipper/pkg/models/cluster"
+	"github.com/kubeclipper/kubeclipper/pkg/models/core"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/k8s"
+	bs "github.com/kubeclipper/kubeclipper/pkg/simple/backupstore"
+)
+
+// ErrNotVerifiable the backup can not be verified by the server.
+var ErrNotVerifiable = errors.New("backup can not be verified")
+
+// BackupVerifyReconciler downloads the backups periodically and checks them against the size and md5 recorded
+// when they were created, the corrupted and missing backups are marked so that they are not restored.
+type BackupVerifyReconciler struct {
+	BackupLister      listerv1.BackupLister
+	BackupPointLister listerv1.BackupPointLister
+	BackupWriter      cluster.BackupWriter
+	CoreOperator      core.Operator
+	Options           *Options
+	Now               func() time.Time
+}
+
+func (r *BackupVerifyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx)
+	if !r.Options.Enabled() {
+		return ctrl.Result{}, nil
+	}
+	b, err := r.BackupLister.Get(req.Name)
+	if err != nil {
+		if kcerrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error("Failed to get backup", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	bp, err := r.BackupPointLister.Get(b.BackupPointName)
+	if err != nil {
+		if kcerrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error("Failed to get backup point", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	if Verifiable(bp, b) != nil {
+		return ctrl.Result{}, nil
+	}
+	now := r.Now()
+	if b.Status.VerifiedTime != nil {
+		if next := b.Status.VerifiedTime.Add(r.Options.Interval); now.Before(next) {
+			return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+		}
+	}
+
+	b = b.DeepCopy()
+	if err = VerifyBackup(ctx, r.CoreOperator, bp, b, now); err != nil {
+		log.Error("Failed to verify backup", zap.String("backup", b.Name), zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	if _, err = r.BackupWriter.UpdateBackup(ctx, b); err != nil {
+		log.Error("Failed to update backup", zap.String("backup", b.Name), zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	if b.Status.ClusterBackupStatus != v1.ClusterBackupAvailable {
+		log.Warn("backup is not intact", zap.String("backup", b.Name),
+			zap.String("status", string(b.Status.ClusterBackupStatus)), zap.String("reason", b.Status.VerifyMessage))
+	}
+	return ctrl.Result{RequeueAfter: r.Options.Interval}, nil
+}
+
+func (r *BackupVerifyReconciler) SetupWithManager(mgr manager.Manager, cache informers.InformerCache) error {
+	c, err := controller.NewUnmanaged("backupverify", controller.Options{
+		MaxConcurrentReconciles: 1,
+		Reconciler:              r,
+		Log:                     mgr.GetLogger().WithName("backupverify-controller"),
+		RecoverPanic:            true,
+	})
+	if err != nil {
+		return err
+	}
+	if err = c.Watch(source.NewKindWithCache(&v1.Backup{}, cache), &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+	mgr.AddRunnable(c)
+	return nil
+}
+
+// Verifiable returns ErrNotVerifiable if the backup is being created or restored, or it is saved by the fs backup point
+// on the nodes which the server can not access, such backup is verified when it is restored.
+func Verifiable(bp *v1.BackupPoint, b *v1.Backup) error {
+	switch b.Status.ClusterBackupStatus {
+	case v1.ClusterBackupAvailable, v1.ClusterBackupCorrupted, v1.ClusterBackupMissing:
+	default:
+		return fmt.Errorf("%w: backup %s is %s", ErrNotVerifiable, b.Name, b.Status.ClusterBackupStatus)
+	}
+	if bp.StorageType == bs.FSStorage {
+		return fmt.Errorf("%w: backup %s is saved on the nodes by %s backup point", ErrNotVerifiable, b.Name, bs.FSStorage)
+	}
+	return nil
+}
+
+// VerifyBackup downloads the backup b from backup point bp and records the result in the status of b. The error is
+// returned only if the backup can not be downloaded, e.g. the backup point is unreachable.
+func VerifyBackup(ctx context.Context, o core.Operator, bp *v1.BackupPoint, b *v1.Backup, now time.Time) error {
+	var (
+		key []byte
+		err error
+	)
+	if b.Status.EncryptionKeyID != "" {
+		if key, err = core.BackupDecryptionKey(ctx, o, bp, b.Status.EncryptionKeyID); err != nil {
+			return err
+		}
+	}
+	store, err := k8s.NewBackupStore(bp, b.Status.EncryptionKeyID, key)
+	if err != nil {
+		return err
+	}
+	// the backup is kept as it was if the error is not caused by the backup itself.
+	switch err = bs.Verify(ctx, store, b.Status.FileName, b.Status.BackupFileSize, b.Status.BackupFileMD5); {
+	case err == nil:
+		b.Status.ClusterBackupStatus = v1.ClusterBackupAvailable
+		b.Status.VerifyMessage = ""
+	case errors.Is(err, bs.ErrNotFound):
+		b.Status.ClusterBackupStatus = v1.ClusterBackupMissing
+		b.Status.VerifyMessage = err.Error()
+	case errors.Is(err, bs.ErrChecksumMismatch), errors.Is(err, bs.ErrBackupCorrupted), errors.Is(err, bs.ErrBackupTruncated),
+		errors.Is(err, bs.ErrNotEncrypted), errors.Is(err, bs.ErrEncryptionKeyID):
+		b.Status.ClusterBackupStatus = v1.ClusterBackupCorrupted
+		b.Status.VerifyMessage = err.Error()
+	default:
+		return err
+	}
+	verified := metav1.NewTime(now)
+	b.Status.VerifiedTime = &verified
+	return nil
+}
@@ -0,0 +1,104 @@
+package backupverifycontroller
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	bs "github.com/kubeclipper/kubeclipper/pkg/simple/backupstore"
+)
+
+func TestVerifyBackup(t *testing.T) {
+	now := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	dir := t.TempDir()
+	snapshot := []byte("etcd snapshot")
+	sum := fmt.Sprintf("%x", md5.Sum(snapshot))
+	if err := os.WriteFile(filepath.Join(dir, "intact"), snapshot, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "corrupted"), []byte("etcd snapshoT"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := bs.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := &v1.BackupPoint{
+		ObjectMeta:  metav1.ObjectMeta{Name: "encrypted"},
+		StorageType: bs.FSStorage,
+		FsConfig:    &v1.FsConfig{BackupRootDir: dir},
+		Encryption:  &v1.BackupEncryption{Key: base64.StdEncoding.EncodeToString(key)},
+	}
+	store, err := bs.NewEncryptedStore(&bs.FilesystemStore{RootDir: dir}, "encrypted", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Save(context.TODO(), bytes.NewReader(snapshot), "encrypted"); err != nil {
+		t.Fatal(err)
+	}
+	plain := &v1.BackupPoint{StorageType: bs.FSStorage, FsConfig: &v1.FsConfig{BackupRootDir: dir}}
+
+	newBackup := func(fileName, keyID string, status v1.ClusterBackupStatus) *v1.Backup {
+		return &v1.Backup{Status: v1.BackupStatus{
+			FileName:            fileName,
+			BackupFileSize:      int64(len(snapshot)),
+			BackupFileMD5:       sum,
+			ClusterBackupStatus: status,
+			EncryptionKeyID:     keyID,
+		}}
+	}
+	tests := []struct {
+		name   string
+		bp     *v1.BackupPoint
+		backup *v1.Backup
+		want   v1.ClusterBackupStatus
+	}{
+		{name: "intact", bp: plain, backup: newBackup("intact", "", v1.ClusterBackupAvailable), want: v1.ClusterBackupAvailable},
+		{name: "recovered", bp: plain, backup: newBackup("intact", "", v1.ClusterBackupMissing), want: v1.ClusterBackupAvailable},
+		{name: "corrupted", bp: plain, backup: newBackup("corrupted", "", v1.ClusterBackupAvailable), want: v1.ClusterBackupCorrupted},
+		{name: "missing", bp: plain, backup: newBackup("missing", "", v1.ClusterBackupAvailable), want: v1.ClusterBackupMissing},
+		{name: "encrypted", bp: encrypted, backup: newBackup("encrypted", "encrypted", v1.ClusterBackupAvailable), want: v1.ClusterBackupAvailable},
+		{name: "not encrypted", bp: encrypted, backup: newBackup("intact", "encrypted", v1.ClusterBackupAvailable), want: v1.ClusterBackupCorrupted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyBackup(context.TODO(), nil, tt.bp, tt.backup, now); err != nil {
+				t.Fatalf("VerifyBackup() error: %v", err)
+			}
+			if got := tt.backup.Status.ClusterBackupStatus; got != tt.want {
+				t.Errorf("VerifyBackup() status = %s, want %s, message: %s", got, tt.want, tt.backup.Status.VerifyMessage)
+			}
+			if tt.backup.Status.VerifiedTime == nil || !tt.backup.Status.VerifiedTime.Time.Equal(now) {
+				t.Errorf("VerifyBackup() verified time = %v, want %v", tt.backup.Status.VerifiedTime, now)
+			}
+		})
+	}
+}
+
+func TestVerifiable(t *testing.T) {
+	s3 := &v1.BackupPoint{StorageType: bs.S3Storage}
+	fs := &v1.BackupPoint{StorageType: bs.FSStorage}
+	available := &v1.Backup{Status: v1.BackupStatus{ClusterBackupStatus: v1.ClusterBackupAvailable}}
+	creating := &v1.Backup{Status: v1.BackupStatus{ClusterBackupStatus: v1.ClusterBackupCreating}}
+
+	if err := Verifiable(s3, available); err != nil {
+		t.Errorf("Verifiable() error: %v", err)
+	}
+	if err := Verifiable(s3, creating); !errors.Is(err, ErrNotVerifiable) {
+		t.Errorf("Verifiable() error = %v, want %v", err, ErrNotVerifiable)
+	}
+	if err := Verifiable(fs, available); !errors.Is(err, ErrNotVerifiable) {
+		t.Errorf("Verifiable() error = %v, want %v", err, ErrNotVerifiable)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package backupverifycontroller
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	// Interval the available backups are verified again after it, 0 means the backups are verified only on demand.
+	Interval time.Duration `json:"interval" yaml:"interval"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Interval: 24 * time.Hour,
+	}
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if o.Interval != 0 && o.Interval < time.Hour {
+		errs = append(errs, errors.New("backup verify interval should not less than 1 hour"))
+	}
+	return errs
+}
+
+// Enabled whether the backups are verified periodically.
+func (o *Options) Enabled() bool {
+	return o.Interval > 0
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&o.Interval, "backup-verify-interval", o.Interval, "Backups are downloaded and checked against their size and md5 at this interval, minimal value is 1 hour, 0 means backups are verified only on demand")
+}
@@ -0,0 +1,227 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package certcontroller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	apimachineryErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/kubeclipper/kubeclipper/pkg/client/informers"
+	listerv1 "github.com/kubeclipper/kubeclipper/pkg/client/lister/core/v1"
+	ctrl "github.com/kubeclipper/kubeclipper/pkg/controller-runtime"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/controller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/handler"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/manager"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/source"
+	"github.com/kubeclipper/kubeclipper/pkg/errors"
+	"github.com/kubeclipper/kubeclipper/pkg/logger"
+	"github.com/kubeclipper/kubeclipper/pkg/models/cluster"
+	"github.com/kubeclipper/kubeclipper/pkg/models/platform"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+// CertRenewalReconciler renew the certificates of clusters before they expire.
+// The renewal is delivered as a pending operation of cluster, it only starts in the maintenance window of cluster if any.
+type CertRenewalReconciler struct {
+	ClusterLister   listerv1.ClusterLister
+	ClusterWriter   cluster.ClusterWriter
+	OperationLister listerv1.OperationLister
+	EventWriter     platform.EventWriter
+	Options         *Options
+	Now             func() time.Time
+}
+
+func (r *CertRenewalReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx)
+	clu, err := r.ClusterLister.Get(req.Name)
+	if err != nil {
+		// cluster not found, possibly been deleted
+		if errors.IsNotFound(err) {
+			CertExpiration.DeleteLabelValues(req.Name)
+			CertRenewalFailed.DeleteLabelValues(req.Name)
+			return ctrl.Result{}, nil
+		}
+		log.Error("Failed to get cluster with name", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+
+	// the certificates are collected by the cluster status monitor, wait for them.
+	expiration := earliestExpiration(clu)
+	if expiration.IsZero() {
+		return ctrl.Result{}, nil
+	}
+	CertExpiration.WithLabelValues(clu.Name).Set(float64(expiration.Unix()))
+
+	latest, err := r.latestRenewal(clu.Name)
+	if err != nil {
+		log.Error("Failed to list certification operations of cluster", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	if latest != nil && latest.Status.Status == v1.OperationStatusFailed {
+		CertRenewalFailed.WithLabelValues(clu.Name).Set(1)
+		r.recordFailure(ctx, log, clu, latest.Name)
+	} else {
+		CertRenewalFailed.WithLabelValues(clu.Name).Set(0)
+	}
+
+	next, err := r.renew(ctx, log, clu, expiration, latest)
+	if err != nil {
+		log.Error("Failed to trigger certificates renewal", zap.String("cluster", clu.Name), zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: next}, nil
+}
+
+// renew triggers the renewal if it is due, returns how long to wait for the next check.
+func (r *CertRenewalReconciler) renew(ctx context.Context, log logger.Logging, clu *v1.Cluster,
+	expiration time.Time, latest *v1.Operation) (time.Duration, error) {
+	now := r.Now()
+	if !r.Options.Enabled {
+		return 0, nil
+	}
+	if renewAt := expiration.Add(-r.Options.RenewBefore); now.Before(renewAt) {
+		return renewAt.Sub(now), nil
+	}
+	if latest != nil {
+		switch latest.Status.Status {
+		case v1.OperationStatusPending, v1.OperationStatusRunning, v1.OperationStatusPaused:
+			return r.Options.RetryInterval, nil
+		}
+		if retryAt := latest.CreationTimestamp.Add(r.Options.RetryInterval); now.Before(retryAt) {
+			return retryAt.Sub(now), nil
+		}
+	}
+	// do not interrupt the other operations of cluster, while the cluster failed by the last renewal is retried,
+	// otherwise the certificates expire before anyone notices.
+	if !(clu.Status.Phase == v1.ClusterRunning || failedByRenewal(clu, latest)) || len(clu.PendingOperations) > 0 {
+		return r.Options.RetryInterval, nil
+	}
+	if w := clu.MaintenanceWindow; w != nil && !w.Contains(now) {
+		return w.Next(now).Sub(now), nil
+	}
+
+	pendingOperation := v1.PendingOperation{
+		OperationID:            uuid.New().String(),
+		OperationType:          v1.OperationUpdateCertification,
+		Timeout:                v1.DefaultOperationTimeoutSecs,
+		ClusterResourceVersion: clu.ResourceVersion,
+	}
+	clu = clu.DeepCopy()
+	clu.Status.Phase = v1.ClusterUpdating
+	clu.PendingOperations = append(clu.PendingOperations, pendingOperation)
+	if _, err := r.ClusterWriter.UpdateCluster(ctx, clu); err != nil {
+		return 0, err
+	}
+	log.Info("certificates renewal triggered", zap.String("cluster", clu.Name),
+		zap.Time("expiration", expiration), zap.String("operation-id", pendingOperation.OperationID))
+	return r.Options.RetryInterval, nil
+}
+
+// failedByRenewal reports whether the cluster failed to update because the last renewal failed.
+func failedByRenewal(clu *v1.Cluster, latest *v1.Operation) bool {
+	return clu.Status.Phase == v1.ClusterUpdateFailed && latest != nil && latest.Status.Status == v1.OperationStatusFailed
+}
+
+// latestRenewal returns the last created certification operation of cluster.
+func (r *CertRenewalReconciler) latestRenewal(clusterName string) (*v1.Operation, error) {
+	selector := labels.SelectorFromSet(labels.Set{
+		common.LabelClusterName:     clusterName,
+		common.LabelOperationAction: v1.OperationUpdateCertification,
+	})
+	ops, err := r.OperationLister.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(ops) == 0 {
+		return nil, nil
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].CreationTimestamp.After(ops[j].CreationTimestamp.Time)
+	})
+	return ops[0], nil
+}
+
+// recordFailure records the failed renewal as a platform event, the event of the same failure is only recorded once.
+func (r *CertRenewalReconciler) recordFailure(ctx context.Context, log logger.Logging, clu *v1.Cluster, opName string) {
+	ev := &v1.Event{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Event",
+			APIVersion: "core.kubeclipper.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("cert-renewal-%s", opName),
+		},
+		AuditID:                  opName,
+		RequestURI:               fmt.Sprintf("/api/core.kubeclipper.io/v1/operations/%s", opName),
+		Username:                 "system",
+		Verb:                     "renew",
+		Type:                     "certRenewal",
+		Success:                  false,
+		RequestReceivedTimestamp: metav1.NewMicroTime(r.Now()),
+		StageTimestamp:           metav1.NewMicroTime(r.Now()),
+		Resource:                 "clusters",
+		ResourceName:             clu.Name,
+		Subresource:              "certification",
+		ResourceAPIGroup:         "core.kubeclipper.io",
+		ResourceAPIVersion:       "v1",
+	}
+	if _, err := r.EventWriter.CreateEvent(ctx, ev); err != nil && !apimachineryErrors.IsAlreadyExists(err) {
+		log.Error("Failed to record certificates renewal failure", zap.String("cluster", clu.Name), zap.Error(err))
+	}
+}
+
+// earliestExpiration returns the expiration time of the certificate which expires first.
+func earliestExpiration(clu *v1.Cluster) time.Time {
+	var earliest time.Time
+	for _, cert := range clu.Status.Certifications {
+		if cert.ExpirationTime.IsZero() {
+			continue
+		}
+		if earliest.IsZero() || cert.ExpirationTime.Before(&metav1.Time{Time: earliest}) {
+			earliest = cert.ExpirationTime.Time
+		}
+	}
+	return earliest
+}
+
+func (r *CertRenewalReconciler) SetupWithManager(mgr manager.Manager, cache informers.InformerCache) error {
+	c, err := controller.NewUnmanaged("certrenewal", controller.Options{
+		MaxConcurrentReconciles: 2,
+		Reconciler:              r,
+		Log:                     mgr.GetLogger().WithName("certrenewal-controller"),
+		RecoverPanic:            true,
+	})
+	if err != nil {
+		return err
+	}
+	if err = c.Watch(source.NewKindWithCache(&v1.Cluster{}, cache), &handler.EnqueueRequestForObject{}); err != nil {
+		return err
+	}
+	mgr.AddRunnable(c)
+	return nil
+}
@@ -0,0 +1,188 @@
+package certcontroller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeclipper/kubeclipper/pkg/logger"
+	mock_cluster "github.com/kubeclipper/kubeclipper/pkg/models/cluster/mock"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func TestMaintenanceWindow_Next(t *testing.T) {
+	day := func(hour, min int) time.Time {
+		return time.Date(2022, 10, 1, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name   string
+		window v1.MaintenanceWindow
+		now    time.Time
+		want   time.Time
+	}{
+		{
+			name:   "before window",
+			window: v1.MaintenanceWindow{Start: "02:00", Duration: metav1.Duration{Duration: 2 * time.Hour}},
+			now:    day(1, 0),
+			want:   day(2, 0),
+		},
+		{
+			name:   "in window",
+			window: v1.MaintenanceWindow{Start: "02:00", Duration: metav1.Duration{Duration: 2 * time.Hour}},
+			now:    day(3, 30),
+			want:   day(2, 0),
+		},
+		{
+			name:   "after window",
+			window: v1.MaintenanceWindow{Start: "02:00", Duration: metav1.Duration{Duration: 2 * time.Hour}},
+			now:    day(4, 0),
+			want:   day(2, 0).AddDate(0, 0, 1),
+		},
+		{
+			name:   "window opened yesterday",
+			window: v1.MaintenanceWindow{Start: "23:00", Duration: metav1.Duration{Duration: 3 * time.Hour}},
+			now:    day(1, 0),
+			want:   day(23, 0).AddDate(0, 0, -1),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Next(tt.now); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCertRenewalReconciler_renew(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	newCluster := func(window *v1.MaintenanceWindow) *v1.Cluster {
+		clu := &v1.Cluster{MaintenanceWindow: window}
+		clu.Name = "test"
+		clu.Status.Phase = v1.ClusterRunning
+		return clu
+	}
+	failedCluster := newCluster(nil)
+	failedCluster.Status.Phase = v1.ClusterUpdateFailed
+	tests := []struct {
+		name       string
+		cluster    *v1.Cluster
+		expiration time.Time
+		latest     *v1.Operation
+		trigger    bool
+		want       time.Duration
+	}{
+		{
+			name:       "not due",
+			cluster:    newCluster(nil),
+			expiration: now.Add(40 * 24 * time.Hour),
+			want:       10 * 24 * time.Hour,
+		},
+		{
+			name:       "due",
+			cluster:    newCluster(nil),
+			expiration: now.Add(24 * time.Hour),
+			trigger:    true,
+			want:       time.Hour,
+		},
+		{
+			name:       "out of maintenance window",
+			cluster:    newCluster(&v1.MaintenanceWindow{Start: "14:00", Duration: metav1.Duration{Duration: time.Hour}}),
+			expiration: now.Add(24 * time.Hour),
+			want:       2 * time.Hour,
+		},
+		{
+			name:       "in maintenance window",
+			cluster:    newCluster(&v1.MaintenanceWindow{Start: "11:00", Duration: metav1.Duration{Duration: 2 * time.Hour}}),
+			expiration: now.Add(24 * time.Hour),
+			trigger:    true,
+			want:       time.Hour,
+		},
+		{
+			name:       "renewal is running",
+			cluster:    newCluster(nil),
+			expiration: now.Add(24 * time.Hour),
+			latest: &v1.Operation{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour))},
+				Status:     v1.OperationStatus{Status: v1.OperationStatusRunning},
+			},
+			want: time.Hour,
+		},
+		{
+			name:       "retry failed renewal later",
+			cluster:    newCluster(nil),
+			expiration: now.Add(24 * time.Hour),
+			latest: &v1.Operation{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-20 * time.Minute))},
+				Status:     v1.OperationStatus{Status: v1.OperationStatusFailed},
+			},
+			want: 40 * time.Minute,
+		},
+		{
+			name:       "retry failed renewal",
+			cluster:    newCluster(nil),
+			expiration: now.Add(24 * time.Hour),
+			latest: &v1.Operation{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour))},
+				Status:     v1.OperationStatus{Status: v1.OperationStatusFailed},
+			},
+			trigger: true,
+			want:    time.Hour,
+		},
+		{
+			name:       "retry renewal which failed the cluster",
+			cluster:    failedCluster,
+			expiration: now.Add(24 * time.Hour),
+			latest: &v1.Operation{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour))},
+				Status:     v1.OperationStatus{Status: v1.OperationStatusFailed},
+			},
+			trigger: true,
+			want:    time.Hour,
+		},
+		{
+			name:       "cluster failed by other operation",
+			cluster:    failedCluster,
+			expiration: now.Add(24 * time.Hour),
+			latest: &v1.Operation{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour))},
+				Status:     v1.OperationStatus{Status: v1.OperationStatusSuccessful},
+			},
+			want: time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			writer := mock_cluster.NewMockClusterWriter(ctrl)
+			if tt.trigger {
+				writer.EXPECT().UpdateCluster(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, clu *v1.Cluster) (*v1.Cluster, error) {
+						if len(clu.PendingOperations) != 1 || clu.PendingOperations[0].OperationType != v1.OperationUpdateCertification {
+							t.Errorf("unexpected pending operations %v", clu.PendingOperations)
+						}
+						if clu.Status.Phase != v1.ClusterUpdating {
+							t.Errorf("cluster phase = %s, want %s", clu.Status.Phase, v1.ClusterUpdating)
+						}
+						return clu, nil
+					})
+			}
+			r := &CertRenewalReconciler{
+				ClusterWriter: writer,
+				Options:       NewOptions(),
+				Now:           func() time.Time { return now },
+			}
+			got, err := r.renew(context.TODO(), logger.FromContext(context.TODO()), tt.cluster, tt.expiration, tt.latest)
+			if err != nil {
+				t.Fatalf("renew() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("renew() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package certcontroller
+
+import (
+	compbasemetrics "k8s.io/component-base/metrics"
+)
+
+var (
+	CertExpiration = compbasemetrics.NewGaugeVec(
+		&compbasemetrics.GaugeOpts{
+			Name:           "kc_cluster_certificate_expiration_timestamp_seconds",
+			Help:           "Expiration time of the earliest expiring certificate of cluster, in unix seconds.",
+			StabilityLevel: compbasemetrics.ALPHA,
+		},
+		[]string{"cluster"},
+	)
+
+	CertRenewalFailed = compbasemetrics.NewGaugeVec(
+		&compbasemetrics.GaugeOpts{
+			Name:           "kc_cluster_certificate_renewal_failed",
+			Help:           "Whether the last automatic certificate renewal of cluster failed, 1 for failed.",
+			StabilityLevel: compbasemetrics.ALPHA,
+		},
+		[]string{"cluster"},
+	)
+)
@@ -0,0 +1,60 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package certcontroller
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	// Enabled renew the certificates of clusters automatically.
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// RenewBefore how long before the certificates expire the renewal is triggered.
+	RenewBefore time.Duration `json:"renewBefore" yaml:"renewBefore"`
+	// RetryInterval the minimal interval between two renewals of the same cluster.
+	RetryInterval time.Duration `json:"retryInterval" yaml:"retryInterval"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		Enabled:       true,
+		RenewBefore:   30 * 24 * time.Hour,
+		RetryInterval: time.Hour,
+	}
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if o.RenewBefore <= 0 {
+		errs = append(errs, errors.New("cert renewal threshold must be greater than 0"))
+	}
+	if o.RetryInterval < time.Minute {
+		errs = append(errs, errors.New("cert renewal retry interval should not less than 1 minute"))
+	}
+	return errs
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.BoolVar(&o.Enabled, "cert-renewal-enabled", o.Enabled, "Renew the certificates of clusters automatically before they expire")
+	fs.DurationVar(&o.RenewBefore, "cert-renew-before", o.RenewBefore, "How long before the certificates expire the renewal is triggered")
+	fs.DurationVar(&o.RetryInterval, "cert-renewal-retry-interval", o.RetryInterval, "Minimal interval between two renewals of the same cluster, minimal value is 1 minute")
+}
@@ -61,6 +61,9 @@ import (
 	"github.com/kubeclipper/kubeclipper/pkg/logger"
 )
 
+// queuedOperationCheckInterval how often the queue of cluster is checked while its operations wait.
+const queuedOperationCheckInterval = 30 * time.Second
+
 type ClusterReconciler struct {
 	CmdDelivery         service.CmdDelivery
 	mgr                 manager.Manager
@@ -213,11 +216,27 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	if err = r.syncClusterClient(ctx, log, clu); err != nil {
 		return ctrl.Result{}, nil
 	}
+	// the queued operation starts after the pending operations are created.
+	pending := len(clu.PendingOperations) > 0
 	if err = r.processPendingOperations(ctx, log, clu); err != nil {
 		log.Error("process pending operation error", zap.Error(err))
 		return ctrl.Result{}, nil
 	}
-	return ctrl.Result{}, r.updateCRIRegistries(ctx, clu)
+	var result ctrl.Result
+	if !pending && len(clu.QueuedOperations) > 0 {
+		started, err := r.processQueuedOperations(ctx, log, clu)
+		if err != nil {
+			log.Error("process queued operation error", zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		if started {
+			// the updated cluster is reconciled again.
+			return ctrl.Result{}, nil
+		}
+		// the end of running operation does not always update the cluster, so check the queue again later.
+		result.RequeueAfter = queuedOperationCheckInterval
+	}
+	return result, r.updateCRIRegistries(ctx, clu)
 }
 
 func (r *ClusterReconciler) updateClusterNode(ctx context.Context, c *v1.Cluster, del bool) error {
@@ -231,6 +250,13 @@ func (r *ClusterReconciler) updateClusterNode(ctx context.Context, c *v1.Cluster
 			return err
 		}
 	}
+	if c.Etcd.IsManaged() {
+		for _, item := range c.Etcd.External.Nodes {
+			if err := r.updateNodeRoleLabel(ctx, c.Name, item.ID, common.NodeRoleEtcd, del); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -520,6 +546,12 @@ func criRegistryUpdateStep(cluster *v1.Cluster, registries []v1.RegistrySpec, no
 			// TODO: get from config
 			ConfigDir: cri.ContainerdDefaultRegistryConfigDir,
 		}
+	case v1.CRICrio:
+		identity = cri.CrioRegistryConfigureIdentity
+		step = &cri.CrioRegistryConfigure{
+			Registries: cri.ToCrioRegistryConfig(registries),
+			ConfigDir:  cri.CrioDefaultRegistryConfigDir,
+		}
 	default:
 		return nil, fmt.Errorf("unknown CRI type:%s", cluster.ContainerRuntime.Type)
 	}
@@ -608,6 +640,34 @@ func getKubeConfig(clusterName string, address string, user string, token string
 	return fmt.Sprintf(kubeconfigFormat, address, clusterName, clusterName, user, user, clusterName, user, clusterName, user, token)
 }
 
+// processQueuedOperations starts the first queued operation when no operation is running on the cluster.
+func (r *ClusterReconciler) processQueuedOperations(ctx context.Context, log logger.Logging, c *v1.Cluster) (bool, error) {
+	running, err := clusteroperation.HasRunning(ctx, c.Name, r.OperationOperator)
+	if err != nil || running {
+		return false, err
+	}
+	c = c.DeepCopy()
+	pendingOperation, ok, err := clusteroperation.Dequeue(c)
+	switch {
+	case err != nil:
+		// the operation is dropped, otherwise it blocks the queue. It is recorded as failed with the reason.
+		log.Error("drop queued operation which is not valid for the cluster anymore",
+			zap.String("operation-id", pendingOperation.OperationID), zap.Error(err))
+		dropped := clusteroperation.NewDroppedOperation(c, pendingOperation, err, time.Now())
+		if _, err = r.OperationWriter.CreateOperation(ctx, dropped); err != nil && !errors.IsAlreadyExists(err) {
+			return false, err
+		}
+	case !ok:
+		return false, nil
+	default:
+		log.Debugf("start queued operation %s(%s)", pendingOperation.OperationType, pendingOperation.OperationID)
+	}
+	if _, err = r.ClusterWriter.UpdateCluster(ctx, c); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (r *ClusterReconciler) processPendingOperations(ctx context.Context, log logger.Logging, c *v1.Cluster) error {
 	log.Debug("process pending operations")
 
@@ -33,6 +33,13 @@ func (r *ClusterReconciler) assembleClusterExtraMetadata(ctx context.Context, c
 		return nil, err
 	}
 	meta.Workers = append(meta.Workers, workers...)
+	if c.Etcd.IsManaged() {
+		etcdNodes, err := r.convertNodes(ctx, c.Etcd.External.Nodes)
+		if err != nil {
+			return nil, err
+		}
+		meta.EtcdNodes = append(meta.EtcdNodes, etcdNodes...)
+	}
 	return meta, nil
 }
 
@@ -21,7 +21,6 @@ package cronbackupcontroller
 import (
 	"context"
 	"fmt"
-	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -36,9 +35,11 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/util/retry"
 
 	"github.com/kubeclipper/kubeclipper/pkg/client/informers"
 	listerv1 "github.com/kubeclipper/kubeclipper/pkg/client/lister/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/clusteroperation"
 	"github.com/kubeclipper/kubeclipper/pkg/component"
 	ctrl "github.com/kubeclipper/kubeclipper/pkg/controller-runtime"
 	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/controller"
@@ -48,6 +49,7 @@ import (
 	"github.com/kubeclipper/kubeclipper/pkg/errors"
 	"github.com/kubeclipper/kubeclipper/pkg/logger"
 	"github.com/kubeclipper/kubeclipper/pkg/models/cluster"
+	"github.com/kubeclipper/kubeclipper/pkg/models/core"
 	"github.com/kubeclipper/kubeclipper/pkg/models/operation"
 	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
 	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
@@ -62,12 +64,15 @@ type CronBackupReconciler struct {
 	NodeLister        listerv1.NodeLister
 	BackupLister      listerv1.BackupLister
 	OperationWriter   operation.Writer
+	OperationOperator operation.Operator
 	BackupWriter      cluster.BackupWriter
 	BackupPointLister listerv1.BackupPointLister
 	CronBackupLister  listerv1.CronBackupLister
 	CronBackupWriter  cluster.CronBackupWriter
 	CmdDelivery       service.CmdDelivery
 	Now               func() time.Time
+	// CoreOperator resolves the keys of the backup points which encrypt backups.
+	CoreOperator core.Operator
 }
 
 func (r *CronBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -169,36 +174,10 @@ func (r *CronBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				log.Error("Failed to update cronBackup", zap.Error(err))
 			}
 
-			// keep the backup num within the maxNum
-			backupList, err := r.BackupLister.List(labels.Everything())
-			if err != nil {
-				log.Error("Failed to list backups of the cronBackup", zap.Error(err))
+			// prune the backups by the retention policy
+			if err = r.pruneBackups(ctx, log, cronBackup); err != nil {
 				return ctrl.Result{}, err
 			}
-			backupsByCb := GroupBackupsByParent(backupList)
-			if len(backupsByCb[cronBackup.UID]) > cronBackup.Spec.MaxBackupNum {
-				// sort the backups by creation time
-				sort.Slice(backupsByCb[cronBackup.UID], func(i, j int) bool {
-					return backupsByCb[cronBackup.UID][i].CreationTimestamp.Time.Before(backupsByCb[cronBackup.UID][j].CreationTimestamp.Time)
-				})
-				sub := len(backupsByCb[cronBackup.UID]) - cronBackup.Spec.MaxBackupNum
-				for del := 0; del < sub; del++ {
-					if backupsByCb[cronBackup.UID][del].Status.ClusterBackupStatus != v1.ClusterBackupAvailable {
-						continue
-					}
-					err = r.BackupWriter.DeleteBackup(ctx, backupsByCb[cronBackup.UID][del].Name)
-					if err != nil {
-						log.Error("Failed to delete backup", zap.Error(err))
-						return ctrl.Result{}, err
-					}
-					// delivery the create backup operation
-					err = r.deleteBackup(log, backupsByCb[cronBackup.UID][del].Labels[common.LabelClusterName], backupsByCb[cronBackup.UID][del])
-					if err != nil {
-						log.Error("Failed to delivery operation to delete backup", zap.Error(err))
-						return ctrl.Result{}, err
-					}
-				}
-			}
 		}
 		sub := cronBackup.Status.NextScheduleTime.Sub(now.Time)
 		return ctrl.Result{
@@ -249,7 +228,13 @@ func (r *CronBackupReconciler) createBackup(log logger.Logging, cronBackup *v1.C
 		return err
 	}
 
-	if c.Status.Phase != v1.ClusterRunning {
+	// the backup waits in the queue of cluster while other operations are running.
+	queued, err := r.mustQueueOperation(c)
+	if err != nil {
+		log.Error("Failed to check the operations of cluster", zap.Error(err))
+		return err
+	}
+	if !queued && c.Status.Phase != v1.ClusterRunning {
 		log.Warnf("the cluster is %v, create backup in next reconcile", c.Status.Phase)
 		return err
 	}
@@ -285,6 +270,12 @@ func (r *CronBackupReconciler) createBackup(log logger.Logging, cronBackup *v1.C
 	backup.Status.KubernetesVersion = c.KubernetesVersion
 	backup.Status.FileName = backup.Name
 	backup.BackupPointName = c.Labels[common.LabelBackupPoint]
+	keyID, _, err := core.BackupEncryptionKey(context.TODO(), r.CoreOperator, bp)
+	if err != nil {
+		log.Error("Failed to get backup encryption key", zap.Error(err))
+		return err
+	}
+	backup.Status.EncryptionKeyID = keyID
 	// check preferred node in cluster
 	if backup.PreferredNode == "" {
 		backup.PreferredNode = c.Masters[0].ID
@@ -360,7 +351,17 @@ func (r *CronBackupReconciler) createBackup(log logger.Logging, cronBackup *v1.C
 			BackupFileName:     backup.Status.FileName,
 			BackupPointRootDir: bp.FsConfig.BackupRootDir,
 		}
+	case bs.SFTPStorage:
+		actBackup = &k8s.ActBackup{
+			StoreType:      bp.StorageType,
+			BackupFileName: backup.Status.FileName,
+			SftpConfig:     bp.SftpConfig,
+		}
 	}
+	if c.Etcd.IsExternal() {
+		actBackup.ExternalEtcdEndpoints = c.Etcd.External.Endpoints
+	}
+	actBackup.BackupPointName, actBackup.EncryptionKeyID = bp.Name, keyID
 
 	if err = actBackup.InitSteps(ctx); err != nil {
 		log.Error("Failed to init steps", zap.Error(err))
@@ -377,16 +378,27 @@ func (r *CronBackupReconciler) createBackup(log logger.Logging, cronBackup *v1.C
 		return err
 	}
 
-	if nc.Status.Phase != v1.ClusterRunning {
+	if !queued && nc.Status.Phase != v1.ClusterRunning {
 		log.Warnf("the cluster is %v, create backup in next reconcile", c.Status.Phase)
 		return err
 	}
 
+	actBackupStep := actBackup.GetStep(v1.ActionInstall)
+	op.Steps = append(steps, actBackupStep...)
+	if queued {
+		if err = r.enqueueOperation(ctx, c.Name, op); err != nil {
+			log.Error("Failed to queue operation", zap.Error(err))
+			return err
+		}
+		if _, err = r.BackupWriter.CreateBackup(ctx, backup); err != nil {
+			log.Error("Failed to create backup", zap.Error(err))
+		}
+		return err
+	}
+
 	// update cluster status to backing_up
 	c.Status.Phase = v1.ClusterBackingUp
 
-	actBackupStep := actBackup.GetStep(v1.ActionInstall)
-	op.Steps = append(steps, actBackupStep...)
 	op, err = r.OperationWriter.CreateOperation(ctx, op)
 	if err != nil {
 		log.Error("Failed to create operation", zap.Error(err))
@@ -439,6 +451,30 @@ func GetParentUIDFromBackup(b *v1.Backup) (types.UID, bool) {
 	return controllerRef.UID, true
 }
 
+// pruneBackups deletes the available backups which satisfy no bucket of the retention policy
+func (r *CronBackupReconciler) pruneBackups(ctx context.Context, log logger.Logging, cronBackup *v1.CronBackup) error {
+	backupList, err := r.BackupLister.List(labels.Everything())
+	if err != nil {
+		log.Error("Failed to list backups of the cronBackup", zap.Error(err))
+		return err
+	}
+	backupsByCb := GroupBackupsByParent(backupList)
+	for _, backup := range PrunableBackups(cronBackup, backupsByCb[cronBackup.UID]) {
+		err = r.BackupWriter.DeleteBackup(ctx, backup.Name)
+		if err != nil {
+			log.Error("Failed to delete backup", zap.Error(err))
+			return err
+		}
+		// delivery the delete backup operation
+		err = r.deleteBackup(log, backup.Labels[common.LabelClusterName], backup)
+		if err != nil {
+			log.Error("Failed to delivery operation to delete backup", zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *CronBackupReconciler) deleteBackup(log logger.Logging, clusterName string, backup *v1.Backup) error {
 	c, err := r.ClusterLister.Get(clusterName)
 	if err != nil {
@@ -517,6 +553,12 @@ func (r *CronBackupReconciler) deleteBackup(log logger.Logging, clusterName stri
 			BackupFileName:     backup.Status.FileName,
 			BackupPointRootDir: bp.FsConfig.BackupRootDir,
 		}
+	case bs.SFTPStorage:
+		actBackup = &k8s.ActBackup{
+			StoreType:      bp.StorageType,
+			BackupFileName: backup.Status.FileName,
+			SftpConfig:     bp.SftpConfig,
+		}
 	}
 
 	if err = actBackup.InitSteps(ctx); err != nil {
@@ -526,6 +568,17 @@ func (r *CronBackupReconciler) deleteBackup(log logger.Logging, clusterName stri
 
 	actBackupStep := actBackup.GetStep(v1.ActionUninstall)
 	op.Steps = append(steps, actBackupStep...)
+	queued, err := r.mustQueueOperation(c)
+	if err != nil {
+		log.Error("Failed to check the operations of cluster", zap.Error(err))
+		return err
+	}
+	if queued {
+		if err = r.enqueueOperation(ctx, c.Name, op); err != nil {
+			log.Error("Failed to queue operation", zap.Error(err))
+		}
+		return err
+	}
 	op, err = r.OperationWriter.CreateOperation(ctx, op)
 	if err != nil {
 		log.Error("Failed to create operation", zap.Error(err))
@@ -540,6 +593,33 @@ func (r *CronBackupReconciler) deleteBackup(log logger.Logging, clusterName stri
 	return nil
 }
 
+// mustQueueOperation whether the operation must wait in the queue of cluster, because other operations
+// are running or already waiting.
+func (r *CronBackupReconciler) mustQueueOperation(c *v1.Cluster) (bool, error) {
+	if len(c.QueuedOperations) > 0 || len(c.PendingOperations) > 0 {
+		return true, nil
+	}
+	return clusteroperation.HasRunning(context.TODO(), c.Name, r.OperationOperator)
+}
+
+// enqueueOperation adds the operation to the queue of cluster, it starts as it is after the running operations.
+func (r *CronBackupReconciler) enqueueOperation(ctx context.Context, clusterName string, op *v1.Operation) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		c, err := r.ClusterLister.Get(clusterName)
+		if err != nil {
+			return err
+		}
+		c = c.DeepCopy()
+		pendingOp, err := clusteroperation.NewPrebuiltPendingOperation(c, op)
+		if err != nil {
+			return err
+		}
+		clusteroperation.Enqueue(c, pendingOp, 0, r.Now())
+		_, err = r.ClusterWriter.UpdateCluster(ctx, c)
+		return err
+	})
+}
+
 func ParseSchedule(schedule string, now time.Time) string {
 	arr := strings.Split(schedule, " ")
 	if arr[2] == "L" {
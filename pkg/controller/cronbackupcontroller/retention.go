@@ -0,0 +1,129 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package cronbackupcontroller
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+// retention buckets a backup may satisfy
+const (
+	RetentionLast    = "last"
+	RetentionDaily   = "daily"
+	RetentionWeekly  = "weekly"
+	RetentionMonthly = "monthly"
+	RetentionYearly  = "yearly"
+)
+
+type retentionPeriod struct {
+	bucket string
+	keep   int
+	key    func(t time.Time) string
+}
+
+// RetentionOf returns the retention policy of the cronBackup,
+// it keeps the newest MaxBackupNum backups if no retention policy is set.
+func RetentionOf(cb *v1.CronBackup) v1.RetentionPolicy {
+	if cb.Spec.Retention != nil {
+		return *cb.Spec.Retention
+	}
+	return v1.RetentionPolicy{KeepLast: cb.Spec.MaxBackupNum}
+}
+
+// RetentionBuckets returns the retention buckets each backup of the cronBackup satisfies, keyed by backup name.
+// The newest KeepLast backups are kept whatever their status is except the missing or corrupted backups, which
+// can't be restored and are never pruned, while only available backups are kept as the newest backup of a day,
+// week, month or year.
+func RetentionBuckets(cb *v1.CronBackup, backups []*v1.Backup) map[string][]string {
+	policy := RetentionOf(cb)
+	sorted := make([]*v1.Backup, len(backups))
+	copy(sorted, backups)
+	// newest first
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[j].CreationTimestamp.Time.Before(sorted[i].CreationTimestamp.Time)
+	})
+
+	buckets := make(map[string][]string, len(sorted))
+	last := 0
+	for _, b := range sorted {
+		buckets[b.Name] = nil
+		switch b.Status.ClusterBackupStatus {
+		case v1.ClusterBackupMissing, v1.ClusterBackupCorrupted:
+			continue
+		}
+		if last < policy.KeepLast {
+			buckets[b.Name] = append(buckets[b.Name], RetentionLast)
+			last++
+		}
+	}
+
+	periods := []retentionPeriod{
+		{bucket: RetentionDaily, keep: policy.KeepDaily, key: func(t time.Time) string {
+			return t.Format("2006-01-02")
+		}},
+		{bucket: RetentionWeekly, keep: policy.KeepWeekly, key: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%02d", year, week)
+		}},
+		{bucket: RetentionMonthly, keep: policy.KeepMonthly, key: func(t time.Time) string {
+			return t.Format("2006-01")
+		}},
+		{bucket: RetentionYearly, keep: policy.KeepYearly, key: func(t time.Time) string {
+			return t.Format("2006")
+		}},
+	}
+	for _, p := range periods {
+		seen := make(map[string]struct{})
+		for _, b := range sorted {
+			if len(seen) >= p.keep {
+				break
+			}
+			if b.Status.ClusterBackupStatus != v1.ClusterBackupAvailable {
+				continue
+			}
+			key := p.key(b.CreationTimestamp.Time)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			buckets[b.Name] = append(buckets[b.Name], p.bucket)
+		}
+	}
+	return buckets
+}
+
+// PrunableBackups returns the available backups of the cronBackup which satisfy no retention bucket, oldest first.
+func PrunableBackups(cb *v1.CronBackup, backups []*v1.Backup) []*v1.Backup {
+	buckets := RetentionBuckets(cb, backups)
+	var prunable []*v1.Backup
+	for _, b := range backups {
+		if b.Status.ClusterBackupStatus != v1.ClusterBackupAvailable || len(buckets[b.Name]) > 0 {
+			continue
+		}
+		prunable = append(prunable, b)
+	}
+	sort.SliceStable(prunable, func(i, j int) bool {
+		return prunable[i].CreationTimestamp.Time.Before(prunable[j].CreationTimestamp.Time)
+	})
+	return prunable
+}
@@ -0,0 +1,104 @@
+package cronbackupcontroller
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func newRetentionBackup(name string, t time.Time, status v1.ClusterBackupStatus) *v1.Backup {
+	return &v1.Backup{
+		ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(t)},
+		Status:     v1.BackupStatus{ClusterBackupStatus: status},
+	}
+}
+
+func TestRetentionBuckets(t *testing.T) {
+	day := func(y int, m time.Month, d, h int) time.Time {
+		return time.Date(y, m, d, h, 0, 0, 0, time.UTC)
+	}
+	backups := []*v1.Backup{
+		newRetentionBackup("b1", day(2023, 12, 30, 1), v1.ClusterBackupAvailable),
+		newRetentionBackup("b2", day(2024, 1, 14, 1), v1.ClusterBackupAvailable),
+		newRetentionBackup("b3", day(2024, 1, 15, 1), v1.ClusterBackupAvailable),
+		newRetentionBackup("b4", day(2024, 1, 15, 2), v1.ClusterBackupAvailable),
+		newRetentionBackup("b5", day(2024, 1, 16, 1), v1.ClusterBackupError),
+		newRetentionBackup("b6", day(2024, 1, 16, 2), v1.ClusterBackupCreating),
+	}
+
+	tests := []struct {
+		name string
+		cb   *v1.CronBackup
+		want map[string][]string
+	}{
+		{
+			name: "max backup num without retention",
+			cb:   &v1.CronBackup{Spec: v1.CronBackupSpec{MaxBackupNum: 3}},
+			want: map[string][]string{
+				"b1": nil, "b2": nil, "b3": nil,
+				"b4": {RetentionLast}, "b5": {RetentionLast}, "b6": {RetentionLast},
+			},
+		},
+		{
+			name: "grandfather-father-son",
+			cb: &v1.CronBackup{Spec: v1.CronBackupSpec{MaxBackupNum: 10, Retention: &v1.RetentionPolicy{
+				KeepLast:    1,
+				KeepDaily:   2,
+				KeepWeekly:  2,
+				KeepMonthly: 1,
+				KeepYearly:  2,
+			}}},
+			want: map[string][]string{
+				"b1": {RetentionYearly},
+				"b2": {RetentionDaily, RetentionWeekly},
+				"b3": nil,
+				"b4": {RetentionDaily, RetentionWeekly, RetentionMonthly, RetentionYearly},
+				"b5": nil,
+				"b6": {RetentionLast},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RetentionBuckets(tt.cb, backups); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RetentionBuckets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrunableBackups(t *testing.T) {
+	base := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	backups := []*v1.Backup{
+		newRetentionBackup("b3", base.Add(3*time.Hour), v1.ClusterBackupAvailable),
+		newRetentionBackup("b1", base.Add(1*time.Hour), v1.ClusterBackupAvailable),
+		newRetentionBackup("b2", base.Add(2*time.Hour), v1.ClusterBackupError),
+		newRetentionBackup("b0", base, v1.ClusterBackupAvailable),
+	}
+	cb := &v1.CronBackup{Spec: v1.CronBackupSpec{Retention: &v1.RetentionPolicy{KeepLast: 1}}}
+
+	var got []string
+	for _, b := range PrunableBackups(cb, backups) {
+		got = append(got, b.Name)
+	}
+	if want := []string{"b0", "b1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PrunableBackups() = %v, want %v", got, want)
+	}
+
+	// the missing and corrupted backups can't take the place of the available ones.
+	backups = append(backups,
+		newRetentionBackup("b4", base.Add(4*time.Hour), v1.ClusterBackupMissing),
+		newRetentionBackup("b5", base.Add(5*time.Hour), v1.ClusterBackupCorrupted),
+	)
+	got = nil
+	for _, b := range PrunableBackups(cb, backups) {
+		got = append(got, b.Name)
+	}
+	if want := []string{"b0", "b1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PrunableBackups() with missing and corrupted backups = %v, want %v", got, want)
+	}
+}
@@ -109,6 +109,9 @@ func (r *NodeReconciler) syncNodeRole(ctx context.Context, node *v1.Node) error
 	if err = r.updateNodeRoleIfNotEqual(ctx, clu, node, common.NodeRoleMaster); err != nil {
 		return err
 	}
+	if err = r.updateNodeRoleIfNotEqual(ctx, clu, node, common.NodeRoleEtcd); err != nil {
+		return err
+	}
 	return r.updateNodeRoleIfNotEqual(ctx, clu, node, common.NodeRoleWorker)
 }
 
@@ -122,6 +125,11 @@ func (r *NodeReconciler) updateNodeRoleIfNotEqual(ctx context.Context, clu *v1.C
 		nodes = sets.NewString(clu.Masters.GetNodeIDs()...)
 	case common.NodeRoleWorker:
 		nodes = sets.NewString(clu.Workers.GetNodeIDs()...)
+	case common.NodeRoleEtcd:
+		nodes = sets.NewString()
+		if clu.Etcd.IsManaged() {
+			nodes.Insert(clu.Etcd.External.Nodes.GetNodeIDs()...)
+		}
 	default:
 		return fmt.Errorf("unsupported ")
 	}
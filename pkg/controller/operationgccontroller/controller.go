@@ -0,0 +1,164 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package operationgccontroller
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubeclipper/kubeclipper/pkg/client/informers"
+	listerv1 "github.com/kubeclipper/kubeclipper/pkg/client/lister/core/v1"
+	ctrl "github.com/kubeclipper/kubeclipper/pkg/controller-runtime"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/client"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/controller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/handler"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/manager"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/reconcile"
+	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/source"
+	"github.com/kubeclipper/kubeclipper/pkg/errors"
+	"github.com/kubeclipper/kubeclipper/pkg/logger"
+	"github.com/kubeclipper/kubeclipper/pkg/models/operation"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/service"
+)
+
+// OperationGCReconciler deletes the operations of cluster and their step logs on the nodes by the retention policy.
+// The running operations, the latest operation of every type and the operations referenced by backups are kept.
+type OperationGCReconciler struct {
+	CmdDelivery     service.CmdDelivery
+	OperationLister listerv1.OperationLister
+	BackupLister    listerv1.BackupLister
+	OperationWriter operation.Writer
+	Options         *Options
+	Now             func() time.Time
+}
+
+func (r *OperationGCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logger.FromContext(ctx)
+	if !r.Options.Enabled() {
+		return ctrl.Result{}, nil
+	}
+	selector := labels.SelectorFromSet(labels.Set{common.LabelClusterName: req.Name})
+	ops, err := r.OperationLister.List(selector)
+	if err != nil {
+		log.Error("Failed to list operations of cluster", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	backups, err := r.BackupLister.List(selector)
+	if err != nil {
+		log.Error("Failed to list backups of cluster", zap.Error(err))
+		return ctrl.Result{}, err
+	}
+	referenced := sets.NewString()
+	for _, b := range backups {
+		referenced.Insert(b.Labels[common.LabelOperationName])
+	}
+
+	pruned, next := r.prunableOperations(ops, referenced)
+	for _, op := range pruned {
+		if err = r.OperationWriter.DeleteOperation(ctx, op.Name); err != nil && !errors.IsNotFound(err) {
+			log.Error("Failed to delete operation", zap.String("operation", op.Name), zap.Error(err))
+			return ctrl.Result{}, err
+		}
+		if err = r.CmdDelivery.DeleteOperationLogs(ctx, op); err != nil {
+			log.Warn("Failed to delete logs of operation", zap.String("operation", op.Name), zap.Error(err))
+		}
+		log.Info("operation pruned", zap.String("cluster", req.Name), zap.String("operation", op.Name),
+			zap.String("action", op.Labels[common.LabelOperationAction]))
+	}
+	return ctrl.Result{RequeueAfter: next}, nil
+}
+
+// prunableOperations returns the operations to be deleted, and how long to wait until the next kept operation expires.
+func (r *OperationGCReconciler) prunableOperations(ops []*v1.Operation, referenced sets.String) ([]*v1.Operation, time.Duration) {
+	now := r.Now()
+	actions := make(map[string][]*v1.Operation)
+	for _, op := range ops {
+		action := op.Labels[common.LabelOperationAction]
+		actions[action] = append(actions[action], op)
+	}
+	var (
+		pruned []*v1.Operation
+		next   time.Duration
+	)
+	for _, items := range actions {
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].CreationTimestamp.After(items[j].CreationTimestamp.Time)
+		})
+		// the latest operation of every type is always kept.
+		for i, op := range items[1:] {
+			if isRunning(op) || referenced.Has(op.Name) {
+				continue
+			}
+			if r.Options.MaximumEntries > 0 && i+1 >= r.Options.MaximumEntries {
+				pruned = append(pruned, op)
+				continue
+			}
+			if r.Options.RetentionPeriod > 0 {
+				expiration := op.CreationTimestamp.Add(r.Options.RetentionPeriod)
+				if !now.Before(expiration) {
+					pruned = append(pruned, op)
+				} else if d := expiration.Sub(now); next == 0 || d < next {
+					next = d
+				}
+			}
+		}
+	}
+	return pruned, next
+}
+
+func isRunning(op *v1.Operation) bool {
+	switch op.Status.Status {
+	case v1.OperationStatusPending, v1.OperationStatusRunning, v1.OperationStatusPaused:
+		return true
+	}
+	return false
+}
+
+func (r *OperationGCReconciler) SetupWithManager(mgr manager.Manager, cache informers.InformerCache) error {
+	c, err := controller.NewUnmanaged("operationgc", controller.Options{
+		MaxConcurrentReconciles: 1,
+		Reconciler:              r,
+		Log:                     mgr.GetLogger().WithName("operationgc-controller"),
+		RecoverPanic:            true,
+	})
+	if err != nil {
+		return err
+	}
+	if err = c.Watch(source.NewKindWithCache(&v1.Operation{}, cache), handler.EnqueueRequestsFromMapFunc(r.findClusterForOperation)); err != nil {
+		return err
+	}
+	mgr.AddRunnable(c)
+	return nil
+}
+
+func (r *OperationGCReconciler) findClusterForOperation(op client.Object) []reconcile.Request {
+	name := op.GetLabels()[common.LabelClusterName]
+	if name == "" {
+		return []reconcile.Request{}
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: name}}}
+}
@@ -0,0 +1,53 @@
+package operationgccontroller
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func TestOperationGCReconciler_prunableOperations(t *testing.T) {
+	now := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	newOp := func(name, action string, age time.Duration, status v1.OperationStatusType) *v1.Operation {
+		return &v1.Operation{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Labels:            map[string]string{common.LabelOperationAction: action},
+				CreationTimestamp: metav1.NewTime(now.Add(-age)),
+			},
+			Status: v1.OperationStatus{Status: status},
+		}
+	}
+	day := 24 * time.Hour
+	ops := []*v1.Operation{
+		// the latest operation of its type is kept even if it expires.
+		newOp("create", v1.OperationCreateCluster, 100*day, v1.OperationStatusSuccessful),
+		newOp("add-1", v1.OperationAddNodes, 1*day, v1.OperationStatusSuccessful),
+		newOp("add-2", v1.OperationAddNodes, 2*day, v1.OperationStatusFailed),
+		newOp("add-3", v1.OperationAddNodes, 3*day, v1.OperationStatusSuccessful),
+		newOp("add-4", v1.OperationAddNodes, 4*day, v1.OperationStatusRunning),
+		newOp("backup-1", v1.OperationBackupCluster, 40*day, v1.OperationStatusSuccessful),
+		newOp("backup-2", v1.OperationBackupCluster, 50*day, v1.OperationStatusSuccessful),
+		newOp("backup-3", v1.OperationBackupCluster, 60*day, v1.OperationStatusSuccessful),
+	}
+	r := &OperationGCReconciler{
+		Options: &Options{RetentionPeriod: 30 * day, MaximumEntries: 2},
+		Now:     func() time.Time { return now },
+	}
+	pruned, next := r.prunableOperations(ops, sets.NewString("backup-2"))
+	got := sets.NewString()
+	for _, op := range pruned {
+		got.Insert(op.Name)
+	}
+	if want := sets.NewString("add-3", "backup-3"); !got.Equal(want) {
+		t.Errorf("prunableOperations() pruned %v, want %v", got.List(), want.List())
+	}
+	if want := 28 * day; next != want {
+		t.Errorf("prunableOperations() next = %v, want %v", next, want)
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package operationgccontroller
+
+import (
+	"errors"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type Options struct {
+	// RetentionPeriod the operations older than it are deleted, 0 means no limit.
+	RetentionPeriod time.Duration `json:"retentionPeriod" yaml:"retentionPeriod"`
+	// MaximumEntries the maximum number of operations kept for every cluster and operation type, 0 means no limit.
+	MaximumEntries int `json:"maximumEntries" yaml:"maximumEntries"`
+}
+
+func NewOptions() *Options {
+	return &Options{
+		RetentionPeriod: 30 * 24 * time.Hour,
+		MaximumEntries:  20,
+	}
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if o.RetentionPeriod != 0 && o.RetentionPeriod < time.Hour {
+		errs = append(errs, errors.New("operation retention period should not less than 1 hour"))
+	}
+	if o.MaximumEntries < 0 {
+		errs = append(errs, errors.New("operation maximum entries must not be negative"))
+	}
+	return errs
+}
+
+// Enabled whether the operations are pruned.
+func (o *Options) Enabled() bool {
+	return o.RetentionPeriod > 0 || o.MaximumEntries > 0
+}
+
+func (o *Options) AddFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&o.RetentionPeriod, "operation-retention-period", o.RetentionPeriod, "Operations older than it are deleted with their logs, minimal value is 1 hour, 0 means no limit")
+	fs.IntVar(&o.MaximumEntries, "operation-maximum-entries", o.MaximumEntries, "Maximum number of operations kept for every cluster and operation type, 0 means no limit")
+}
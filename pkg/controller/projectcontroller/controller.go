@@ -481,7 +481,7 @@ var ProjectRoles = []iamv1.ProjectRole{
 			},
 			{
 				APIGroups: []string{"core.kubeclipper.io"},
-				Resources: []string{"clusters", "clusters/plugins", "clusters/join", "clusters/nodes", "clusters/backups", "clusters/cronbackups", "clusters/certification", "clusters/kubeconfig", "nodes", "operations"},
+				Resources: []string{"clusters", "clusters/plugins", "clusters/join", "clusters/nodes", "clusters/backups", "clusters/cronbackups", "clusters/certification", "clusters/runtime", "clusters/config", "clusters/kubeconfig", "clusters/operations", "nodes", "operations", "operations/pause", "operations/resume", "operations/cancel"},
 				Verbs:     []string{"*"},
 			},
 		},
@@ -0,0 +1,167 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	apimachineryErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/registry/rest"
+
+	"github.com/kubeclipper/kubeclipper/pkg/models"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	bs "github.com/kubeclipper/kubeclipper/pkg/simple/backupstore"
+)
+
+const (
+	// BackupEncryptionKeys the config map keeps the platform-managed keys of backup encryption, keyed by the key id.
+	// It is saved by the backup key storage, which is not served by any API.
+	BackupEncryptionKeys = "kc-backup-encryption-keys"
+	// DefaultBackupKeyID the id of platform-managed key used when the backup point does not name one.
+	DefaultBackupKeyID = "default"
+)
+
+var ErrBackupKeyNotFound = errors.New("backup encryption key not found")
+
+type BackupKeyReader interface {
+	GetBackupKeys(ctx context.Context) (*v1.ConfigMap, error)
+}
+
+type BackupKeyWriter interface {
+	CreateBackupKeys(ctx context.Context, keys *v1.ConfigMap) (*v1.ConfigMap, error)
+	UpdateBackupKeys(ctx context.Context, keys *v1.ConfigMap) (*v1.ConfigMap, error)
+}
+
+func (o operator) GetBackupKeys(ctx context.Context) (*v1.ConfigMap, error) {
+	cm, err := models.GetV2(ctx, o.backupKeyStorage, BackupEncryptionKeys, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return cm.(*v1.ConfigMap), nil
+}
+
+func (o operator) CreateBackupKeys(ctx context.Context, keys *v1.ConfigMap) (*v1.ConfigMap, error) {
+	obj, err := o.backupKeyStorage.Create(ctx, keys, nil, &metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*v1.ConfigMap), nil
+}
+
+func (o operator) UpdateBackupKeys(ctx context.Context, keys *v1.ConfigMap) (*v1.ConfigMap, error) {
+	obj, _, err := o.backupKeyStorage.Update(ctx, keys.Name, rest.DefaultUpdatedObjectInfo(keys),
+		nil, nil, false, &metav1.UpdateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*v1.ConfigMap), nil
+}
+
+// ParseBackupKey decodes the base64 encoded key supplied by the backup point.
+func ParseBackupKey(key string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("backup encryption key must be base64 encoded: %w", err)
+	}
+	if len(data) != bs.EncryptionKeySize {
+		return nil, fmt.Errorf("backup encryption key must be %d bytes", bs.EncryptionKeySize)
+	}
+	return data, nil
+}
+
+// BackupEncryptionKey returns the key which encrypts the backups saved into the backup point, the platform-managed
+// key is generated the first time it is used. Nothing is returned if the backup point does not encrypt backups.
+func BackupEncryptionKey(ctx context.Context, o Operator, bp *v1.BackupPoint) (string, []byte, error) {
+	if bp.Encryption == nil {
+		return "", nil, nil
+	}
+	if bp.Encryption.Key != "" {
+		key, err := ParseBackupKey(bp.Encryption.Key)
+		return backupPointKeyID(bp), key, err
+	}
+	keyID := platformKeyID(bp)
+	key, err := platformBackupKey(ctx, o, keyID)
+	if !errors.Is(err, ErrBackupKeyNotFound) {
+		return keyID, key, err
+	}
+	if key, err = bs.GenerateEncryptionKey(); err != nil {
+		return "", nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+	cm, err := o.GetBackupKeys(ctx)
+	switch {
+	case apimachineryErrors.IsNotFound(err):
+		cm = &v1.ConfigMap{Data: map[string]string{keyID: encoded}}
+		cm.Name = BackupEncryptionKeys
+		_, err = o.CreateBackupKeys(ctx, cm)
+	case err == nil:
+		if cm.Data == nil {
+			cm.Data = make(map[string]string)
+		}
+		cm.Data[keyID] = encoded
+		_, err = o.UpdateBackupKeys(ctx, cm)
+	}
+	if err != nil {
+		// another backup may have generated the key at the same time, the backup fails rather than losing its key.
+		return "", nil, fmt.Errorf("save backup encryption key %s: %w", keyID, err)
+	}
+	return keyID, key, nil
+}
+
+// BackupDecryptionKey returns the key of id which the backup was encrypted by, the key supplied by the backup point
+// is preferred to the platform-managed key.
+func BackupDecryptionKey(ctx context.Context, o Operator, bp *v1.BackupPoint, keyID string) ([]byte, error) {
+	if bp.Encryption != nil && bp.Encryption.Key != "" && backupPointKeyID(bp) == keyID {
+		return ParseBackupKey(bp.Encryption.Key)
+	}
+	return platformBackupKey(ctx, o, keyID)
+}
+
+func platformBackupKey(ctx context.Context, o Operator, keyID string) ([]byte, error) {
+	cm, err := o.GetBackupKeys(ctx)
+	if err != nil {
+		if apimachineryErrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: %s", ErrBackupKeyNotFound, keyID)
+		}
+		return nil, err
+	}
+	encoded, ok := cm.Data[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrBackupKeyNotFound, keyID)
+	}
+	return ParseBackupKey(encoded)
+}
+
+func backupPointKeyID(bp *v1.BackupPoint) string {
+	if bp.Encryption.KeyID != "" {
+		return bp.Encryption.KeyID
+	}
+	return bp.Name
+}
+
+func platformKeyID(bp *v1.BackupPoint) string {
+	if bp.Encryption.KeyID != "" {
+		return bp.Encryption.KeyID
+	}
+	return DefaultBackupKeyID
+}
@@ -24,10 +24,12 @@ import (
 
 type OperatorReader interface {
 	ConfigMapReader
+	BackupKeyReader
 }
 
 type OperatorWriter interface {
 	ConfigMapWriter
+	BackupKeyWriter
 }
 
 type Operator interface {
@@ -38,11 +40,13 @@ type Operator interface {
 var _ Operator = (*operator)(nil)
 
 type operator struct {
-	cmStorage rest.StandardStorage
+	cmStorage        rest.StandardStorage
+	backupKeyStorage rest.StandardStorage
 }
 
-func NewOperator(cmStorage rest.StandardStorage) Operator {
+func NewOperator(cmStorage, backupKeyStorage rest.StandardStorage) Operator {
 	return &operator{
-		cmStorage: cmStorage,
+		cmStorage:        cmStorage,
+		backupKeyStorage: backupKeyStorage,
 	}
 }
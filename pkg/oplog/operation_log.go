@@ -22,6 +22,8 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/kubeclipper/kubeclipper/pkg/component"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/fileutil"
@@ -149,3 +151,77 @@ func (op *OperationLog) TruncateStepLogFile(opID, stepID string) error {
 	}
 	return os.Truncate(path, 0)
 }
+
+// DeleteOperationDir delete the operation dir and all step log files in it.
+func (op *OperationLog) DeleteOperationDir(opID string) error {
+	if filepath.Base(opID) != opID {
+		return errors.New("opID is invalid")
+	}
+	path, err := op.GetOperationDir(opID)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(path)
+}
+
+// PruneOperationDirs delete the operation dirs which exceed the retention period or the maximum entries,
+// the dir is as old as its latest modified step log file.
+func (op *OperationLog) PruneOperationDirs() error {
+	if op.cfg.RetentionPeriod <= 0 && op.cfg.MaximumEntries <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(op.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	type opDir struct {
+		name    string
+		modTime time.Time
+	}
+	dirs := make([]opDir, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		modTime, err := latestModTime(filepath.Join(op.cfg.Dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		dirs = append(dirs, opDir{name: entry.Name(), modTime: modTime})
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return dirs[i].modTime.After(dirs[j].modTime)
+	})
+	expiration := time.Now().Add(-op.cfg.RetentionPeriod)
+	for i, dir := range dirs {
+		if (op.cfg.MaximumEntries > 0 && i >= op.cfg.MaximumEntries) ||
+			(op.cfg.RetentionPeriod > 0 && dir.modTime.Before(expiration)) {
+			if err = op.DeleteOperationDir(dir.name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func latestModTime(dir string) (time.Time, error) {
+	stat, err := os.Stat(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	latest := stat.ModTime()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
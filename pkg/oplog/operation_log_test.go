@@ -0,0 +1,63 @@
+package oplog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOperationLog_PruneOperationDirs(t *testing.T) {
+	dir := t.TempDir()
+	op := &OperationLog{
+		cfg:    &Options{Dir: dir, RetentionPeriod: 24 * time.Hour, MaximumEntries: 2},
+		suffix: OperationLogSuffix,
+	}
+	now := time.Now()
+	ages := map[string]time.Duration{
+		"op-1": time.Minute,
+		"op-2": time.Hour,
+		"op-3": 2 * time.Hour,
+		"op-4": 48 * time.Hour,
+	}
+	for id, age := range ages {
+		if err := op.CreateStepLogFileAndAppend(id, "step", []byte("log")); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(-age)
+		for _, p := range []string{filepath.Join(dir, id, "step"+OperationLogSuffix), filepath.Join(dir, id)} {
+			if err := os.Chtimes(p, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := op.PruneOperationDirs(); err != nil {
+		t.Fatalf("PruneOperationDirs() error: %v", err)
+	}
+	for id, kept := range map[string]bool{"op-1": true, "op-2": true, "op-3": false, "op-4": false} {
+		if _, err := os.Stat(filepath.Join(dir, id)); (err == nil) != kept {
+			t.Errorf("operation dir %s kept = %v, want %v", id, err == nil, kept)
+		}
+	}
+	if err := op.DeleteOperationDir("../" + filepath.Base(dir)); err == nil {
+		t.Errorf("DeleteOperationDir() must reject the path out of root dir")
+	}
+}
+
+func TestOperationLog_PruneOperationDirsDisabled(t *testing.T) {
+	dir := t.TempDir()
+	op := &OperationLog{cfg: &Options{Dir: dir}, suffix: OperationLogSuffix}
+	if err := op.CreateStepLogFileAndAppend("op-1", "step", []byte("log")); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-365 * 24 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "op-1"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := op.PruneOperationDirs(); err != nil {
+		t.Fatalf("PruneOperationDirs() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "op-1")); err != nil {
+		t.Errorf("operation dir must be kept without the retention of agent: %v", err)
+	}
+}
@@ -21,6 +21,7 @@ package oplog
 import (
 	"errors"
 	"path/filepath"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -34,6 +35,12 @@ const (
 type Options struct {
 	Dir             string `json:"dir" yaml:"dir"`
 	SingleThreshold int64  `json:"singleThreshold" yaml:"singleThreshold"`
+	// RetentionPeriod the operation logs older than it are deleted by the agent, 0 means no limit.
+	// The logs are deleted with their operations by the server anyway, the retention of agent also deletes
+	// the logs of the operations which the server still keeps, so it is disabled by default.
+	RetentionPeriod time.Duration `json:"retentionPeriod" yaml:"retentionPeriod"`
+	// MaximumEntries the maximum number of operations whose logs are kept by the agent, 0 means no limit.
+	MaximumEntries int `json:"maximumEntries" yaml:"maximumEntries"`
 }
 
 func NewOptions() *Options {
@@ -62,10 +69,18 @@ func (s *Options) Validate() (errs []error) {
 	if s.SingleThreshold > MaximumThreshold {
 		return append(errs, errors.New("the threshold exceeded the limit, the maximum threshold is 1MB"))
 	}
+	if s.RetentionPeriod < 0 {
+		return append(errs, errors.New("the retention period of operation logs must not be negative"))
+	}
+	if s.MaximumEntries < 0 {
+		return append(errs, errors.New("the maximum entries of operation logs must not be negative"))
+	}
 	return
 }
 
 func (s *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&s.Dir, "oplog-dir", s.Dir, "directory of op log file")
 	fs.StringVar(&s.Dir, "oplog-threshold", s.Dir, "maximum value of log data transfer")
+	fs.DurationVar(&s.RetentionPeriod, "oplog-retention-period", s.RetentionPeriod, "op logs older than it are deleted by the agent, 0 means they are only deleted with their operations by the server")
+	fs.IntVar(&s.MaximumEntries, "oplog-maximum-entries", s.MaximumEntries, "maximum number of operations whose op logs are kept by the agent, 0 means no limit")
 }
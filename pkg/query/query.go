@@ -56,6 +56,9 @@ const (
 	ParameterSubDomain            = "subdomain"
 	ParameterFuzzySearch          = "fuzzy"
 	ParameterForce                = "force"
+	ParamRollback                 = "rollback"
+	ParamPriority                 = "priority"
+	ParamWithDependencies         = "withDependencies"
 )
 
 const (
@@ -69,6 +72,9 @@ const (
 	ResourceVersionMatchExact        = "Exact"
 )
 
+// DryRunPlan the value of dryRun parameter which returns the operation without persisting or running it.
+const DryRunPlan = "plan"
+
 // Query represents api search terms
 type Query struct {
 	Pagination      *Pagination
@@ -299,6 +305,11 @@ func GetBoolValueWithDefault(req *restful.Request, name string, dv bool) bool {
 	return dv
 }
 
+// IsDryRunPlan whether the request only plans the operation.
+func IsDryRunPlan(req *restful.Request) bool {
+	return req.QueryParameter(ParamDryRun) == DryRunPlan
+}
+
 func GetStringValueWithDefault(req *restful.Request, name string, dv string) string {
 	v := req.QueryParameter(name)
 	if v == "" {
@@ -40,9 +40,13 @@ const (
 	LabelUserReference     = "iam.kubeclipper.io/user-ref"
 	LabelExternalIP        = "kubeclipper.io/externalIP"
 	LabelUpgradeVersion    = "kubeclipper.io/upgrade-version"
+	LabelRuntimeType       = "kubeclipper.io/runtime-type"
+	LabelRuntimeVersion    = "kubeclipper.io/runtime-version"
 	LabelBackupPoint       = "kubeclipper.io/backupPoint"
 	LabelCronBackupDisable = "kubeclipper.io/cronBackupDisable"
 	LabelCronBackupEnable  = "kubeclipper.io/cronBackupEnable"
+	// LabelTemplate the template which the cluster is created from.
+	LabelTemplate = "kubeclipper.io/template"
 
 	LabelClusterProviderType = "kubeclipper.io/clusterProviderType"
 	LabelClusterProviderName = "kubeclipper.io/clusterProviderName"
@@ -62,6 +66,23 @@ const (
 	RoleAnnotation             = "iam.kubeclipper.io/role"
 	AnnotationInternal         = "kubeclipper.io/internal"
 	AnnotationHidden           = "kubeclipper.io/hidden"
+	// AnnotationUpgradeHops the json map from the last step id of every upgrade hop to the version it upgrades to.
+	AnnotationUpgradeHops = "kubeclipper.io/upgrade-hops"
+	// AnnotationNodeSteps the json map from step id to the nodes whose progress changes with the step,
+	// the nodes enter in progress when the step starts and are done when the step ends.
+	AnnotationNodeSteps = "kubeclipper.io/node-steps"
+	// AnnotationPauseAfterSteps the comma separated step ids, the operation is paused after any of them is done.
+	AnnotationPauseAfterSteps = "kubeclipper.io/pause-after-steps"
+	// AnnotationAddedNodes the comma separated ids of the nodes added by the operation,
+	// they are removed from the cluster if the operation is rolled back.
+	AnnotationAddedNodes = "kubeclipper.io/added-nodes"
+	// AnnotationClusterConfig the json configuration of the kubernetes components applied by the operation.
+	AnnotationClusterConfig = "kubeclipper.io/cluster-config"
+	// AnnotationComponents the json addons installed, uninstalled or upgraded by the operation,
+	// the addons of cluster are changed with them when the operation is done unless it is rolled back.
+	AnnotationComponents = "kubeclipper.io/components"
+	// AnnotationDropReason the reason the queued operation is dropped without starting, it is recorded as failed.
+	AnnotationDropReason = "kubeclipper.io/drop-reason"
 
 	AnnotationMetadataFloatIP        = "metadata.kubeclipper.io/floatIP"
 	AnnotationMetadataProxyServer    = "metadata.kubeclipper.io/proxyServer"
@@ -74,6 +95,8 @@ type NodeRole string // master/worker/ingress(worker)
 const (
 	NodeRoleMaster NodeRole = "master"
 	NodeRoleWorker NodeRole = "worker"
+	// NodeRoleEtcd the node only runs a member of the external etcd managed by kubeclipper.
+	NodeRoleEtcd NodeRole = "etcd"
 )
 
 func (nr NodeRole) String() string {
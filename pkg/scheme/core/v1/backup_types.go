@@ -45,6 +45,12 @@ type BackupStatus struct {
 	BackupFileSize      int64  `json:"backupFileSize"`
 	BackupFileMD5       string `json:"backupFileMD5"`
 	ClusterBackupStatus `json:"status"`
+	// EncryptionKeyID the id of key which the backup is encrypted by, the backup is not encrypted if it is empty.
+	EncryptionKeyID string `json:"encryptionKeyID,omitempty"`
+	// VerifiedTime the last time the backup was verified against its size and md5.
+	VerifiedTime *metav1.Time `json:"verifiedTime,omitempty"`
+	// VerifyMessage the reason why the backup is corrupted or missing.
+	VerifyMessage string `json:"verifyMessage,omitempty"`
 }
 
 // ClusterBackupStatus describes the status of a cluster backup
@@ -61,6 +67,10 @@ const (
 	ClusterBackupError ClusterBackupStatus = "error"
 	// ClusterBackupRestoring means the backup is in using for restoring.
 	ClusterBackupRestoring ClusterBackupStatus = "restoring"
+	// ClusterBackupCorrupted means the backup file is not the same as it was created and must not be used.
+	ClusterBackupCorrupted ClusterBackupStatus = "corrupted"
+	// ClusterBackupMissing means the backup file does not exist in the backup point.
+	ClusterBackupMissing ClusterBackupStatus = "missing"
 )
 
 /* type BackupStatus struct {
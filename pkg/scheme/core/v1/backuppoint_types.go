@@ -30,10 +30,15 @@ type BackupPoint struct {
 	// Standard object's metadata.
 	// +optional
 	metav1.ObjectMeta `json:"metadata,omitempty"`
-	StorageType       string    `json:"storageType,omitempty"`
-	Description       string    `json:"description,omitempty"`
-	FsConfig          *FsConfig `json:"fsConfig,omitempty"`
-	S3Config          *S3Config `json:"s3Config,omitempty"`
+	StorageType       string      `json:"storageType,omitempty"`
+	Description       string      `json:"description,omitempty"`
+	FsConfig          *FsConfig   `json:"fsConfig,omitempty"`
+	S3Config          *S3Config   `json:"s3Config,omitempty"`
+	SftpConfig        *SftpConfig `json:"sftpConfig,omitempty"`
+	// Encryption the backups are encrypted before they are saved if it is set.
+	Encryption *BackupEncryption `json:"encryption,omitempty"`
+	// Status the backup files found in the backup point, it is updated by the server.
+	Status BackupPointStatus `json:"status,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -59,3 +64,40 @@ type S3Config struct {
 	Region          string `json:"region" yaml:"region"`
 	SSL             bool   `json:"ssl" yaml:"ssl"`
 }
+
+// SftpConfig the backups are saved on the host over sftp, either the password or the private key is used to login.
+type SftpConfig struct {
+	Host          string `json:"host" yaml:"host"`
+	Port          int    `json:"port,omitempty" yaml:"port,omitempty"`
+	User          string `json:"user" yaml:"user"`
+	Password      string `json:"password,omitempty" yaml:"password,omitempty"`
+	PrivateKey    string `json:"privateKey,omitempty" yaml:"privateKey,omitempty"`
+	PkPassword    string `json:"pkPassword,omitempty" yaml:"pkPassword,omitempty"`
+	BackupRootDir string `json:"backupRootDir,omitempty" yaml:"backupRootDir,omitempty"`
+}
+
+// BackupEncryption the backups are encrypted by AES-GCM with the key supplied by the backup point,
+// or with the platform-managed key if the key is not supplied.
+type BackupEncryption struct {
+	// KeyID identifies the key, it is recorded in the backups and must be changed when the key is changed.
+	// The key id of backup point defaults to the name of backup point, and the platform-managed key defaults to "default".
+	KeyID string `json:"keyID,omitempty" yaml:"keyID,omitempty"`
+	// Key the base64 encoded 32 bytes key, it is masked in the responses of backup point.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+// BackupPointStatus the backup files saved in the backup point are compared with the backups periodically.
+type BackupPointStatus struct {
+	// SyncedTime the last time the backup files were listed.
+	SyncedTime *metav1.Time `json:"syncedTime,omitempty"`
+	// OrphanBackups the backup files which no backup refers to, e.g. they were created by another
+	// kubeclipper, they can be imported as the backups of a cluster.
+	OrphanBackups []BackupFile `json:"orphanBackups,omitempty"`
+}
+
+// BackupFile describes the backup file saved in the backup point.
+type BackupFile struct {
+	FileName string      `json:"fileName"`
+	Size     int64       `json:"size"`
+	ModTime  metav1.Time `json:"modTime"`
+}
@@ -19,6 +19,12 @@
 package v1
 
 import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -38,24 +44,32 @@ type Cluster struct {
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	// move offline to metadata annotation
 	// Offline           bool   `json:"offline" optional:"true"`
-	LocalRegistry     string             `json:"localRegistry,omitempty" optional:"true"`
-	Masters           WorkerNodeList     `json:"masters"`
-	Workers           WorkerNodeList     `json:"workers" optional:"true"`
-	KubernetesVersion string             `json:"kubernetesVersion" enum:"v1.20.13"`
-	CertSANs          []string           `json:"certSANs,omitempty" optional:"true"`
-	ExternalCaCert    string             `json:"externalCaCert,omitempty" optional:"true"`
-	ExternalCaKey     string             `json:"externalCaKey,omitempty" optional:"true"`
-	KubeProxy         KubeProxy          `json:"kubeProxy,omitempty" optional:"true"`
-	Etcd              Etcd               `json:"etcd,omitempty" optional:"true"`
-	Kubelet           Kubelet            `json:"kubelet,omitempty" optional:"true"`
-	Networking        Networking         `json:"networking"`
-	ContainerRuntime  ContainerRuntime   `json:"containerRuntime"`
-	CNI               CNI                `json:"cni"`
-	KubeConfig        []byte             `json:"kubeConfig,omitempty"`
-	Addons            []Addon            `json:"addons" optional:"true"`
-	Description       string             `json:"description,omitempty" optional:"true"`
-	Status            ClusterStatus      `json:"status,omitempty" optional:"true"`
-	PendingOperations []PendingOperation `json:"pendingOperations,omitempty" optional:"true"`
+	LocalRegistry     string                 `json:"localRegistry,omitempty" optional:"true"`
+	Masters           WorkerNodeList         `json:"masters"`
+	Workers           WorkerNodeList         `json:"workers" optional:"true"`
+	KubernetesVersion string                 `json:"kubernetesVersion" enum:"v1.20.13"`
+	CertSANs          []string               `json:"certSANs,omitempty" optional:"true"`
+	ExternalCaCert    string                 `json:"externalCaCert,omitempty" optional:"true"`
+	ExternalCaKey     string                 `json:"externalCaKey,omitempty" optional:"true"`
+	KubeProxy         KubeProxy              `json:"kubeProxy,omitempty" optional:"true"`
+	Etcd              Etcd                   `json:"etcd,omitempty" optional:"true"`
+	Kubelet           Kubelet                `json:"kubelet,omitempty" optional:"true"`
+	ControlPlane      ControlPlaneComponents `json:"controlPlane,omitempty" optional:"true"`
+	FeatureGates      map[string]bool        `json:"featureGates,omitempty" optional:"true"`
+	Networking        Networking             `json:"networking"`
+	ContainerRuntime  ContainerRuntime       `json:"containerRuntime"`
+	CNI               CNI                    `json:"cni"`
+	KubeConfig        []byte                 `json:"kubeConfig,omitempty"`
+	Addons            []Addon                `json:"addons" optional:"true"`
+	Description       string                 `json:"description,omitempty" optional:"true"`
+	MaintenanceWindow *MaintenanceWindow     `json:"maintenanceWindow,omitempty" optional:"true"`
+	ControlPlaneVIP   *ControlPlaneVIP       `json:"controlPlaneVIP,omitempty" optional:"true"`
+	TemplateRef       *TemplateReference     `json:"templateRef,omitempty" optional:"true"`
+	Status            ClusterStatus          `json:"status,omitempty" optional:"true"`
+	PendingOperations []PendingOperation     `json:"pendingOperations,omitempty" optional:"true"`
+	// QueuedOperations the operations wait until no operation is running on the cluster,
+	// they start one at a time in the order of SortQueuedOperations.
+	QueuedOperations []QueuedOperation `json:"queuedOperations,omitempty" optional:"true"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -88,6 +102,122 @@ type ClusterVersionsStatus struct {
 	Scheduler string `json:"scheduler"`
 }
 
+// UpgradeStrategy how the nodes of cluster are upgraded.
+// The control plane nodes are always upgraded one by one, the workers are upgraded in batches.
+type UpgradeStrategy struct {
+	// MaxUnavailable the maximum number of workers upgraded at the same time, defaults to 1.
+	// +optional
+	MaxUnavailable int `json:"maxUnavailable,omitempty"`
+	// Drain evict the workloads before a node is upgraded, otherwise the node is only cordoned, defaults to true.
+	// +optional
+	Drain *bool `json:"drain,omitempty"`
+	// DrainTimeout the upgrade fails if a node can not be drained in time, defaults to 5 minutes.
+	// +optional
+	DrainTimeout metav1.Duration `json:"drainTimeout,omitempty"`
+	// PauseAfterControlPlane pause the operation after the control plane nodes are upgraded,
+	// the workers are not upgraded until the operation is resumed.
+	// +optional
+	PauseAfterControlPlane bool `json:"pauseAfterControlPlane,omitempty"`
+}
+
+const maintenanceWindowLayout = "15:04"
+
+// MaintenanceWindow the daily time window in which the automatic operations of cluster are allowed to run,
+// e.g. the certificates renewal.
+type MaintenanceWindow struct {
+	// Start the start time of window in UTC, formatted as HH:MM.
+	Start string `json:"start"`
+	// Duration the length of window, it must be in (0, 24h].
+	Duration metav1.Duration `json:"duration"`
+}
+
+func (w *MaintenanceWindow) Validate() error {
+	if _, err := time.Parse(maintenanceWindowLayout, w.Start); err != nil {
+		return fmt.Errorf("invalid maintenance window start %q, must be formatted as HH:MM", w.Start)
+	}
+	if w.Duration.Duration <= 0 || w.Duration.Duration > 24*time.Hour {
+		return fmt.Errorf("invalid maintenance window duration %s, must be in (0, 24h]", w.Duration.Duration)
+	}
+	return nil
+}
+
+// Next returns the start time of the window which is open at t or opens next after t.
+func (w *MaintenanceWindow) Next(t time.Time) time.Time {
+	start, err := time.Parse(maintenanceWindowLayout, w.Start)
+	if err != nil {
+		return t
+	}
+	t = t.UTC()
+	today := time.Date(t.Year(), t.Month(), t.Day(), start.Hour(), start.Minute(), 0, 0, time.UTC)
+	// the window opened yesterday may be still open.
+	for _, begin := range []time.Time{today.AddDate(0, 0, -1), today} {
+		if !t.Before(begin) && t.Before(begin.Add(w.Duration.Duration)) {
+			return begin
+		}
+	}
+	if t.Before(today) {
+		return today
+	}
+	return today.AddDate(0, 0, 1)
+}
+
+// Contains whether the window is open at t.
+func (w *MaintenanceWindow) Contains(t time.Time) bool {
+	return !w.Next(t).After(t)
+}
+
+const (
+	// VIPModeKeepalived keepalived holds the vip and haproxy balances the kube-apiservers behind it.
+	VIPModeKeepalived = "keepalived"
+	// VIPModeKubeVIP kube-vip holds the vip on the leader control plane node.
+	VIPModeKubeVIP = "kube-vip"
+)
+
+var AllowedVIPMode = sets.NewString(VIPModeKeepalived, VIPModeKubeVIP)
+
+// ControlPlaneVIP the virtual ip floating among the control plane nodes,
+// it is the control plane endpoint of cluster when set.
+type ControlPlaneVIP struct {
+	Mode    string `json:"mode" enum:"keepalived|kube-vip"`
+	Address string `json:"address"`
+	// Interface the network interface the vip is bound to, defaults to the interface of node ip.
+	Interface string `json:"interface,omitempty" optional:"true"`
+	// Port the port of kube-apiserver on the vip, defaults to 6443 for kube-vip and 8443 for keepalived,
+	// since haproxy can not listen on the port which is already used by the kube-apiserver.
+	Port int `json:"port,omitempty" optional:"true"`
+}
+
+func (v *ControlPlaneVIP) Validate() error {
+	if !AllowedVIPMode.Has(v.Mode) {
+		return fmt.Errorf("invalid control plane vip mode %q, must be one of %v", v.Mode, AllowedVIPMode.List())
+	}
+	if ip := net.ParseIP(v.Address); ip == nil || ip.To4() == nil {
+		return fmt.Errorf("invalid control plane vip address %q, must be an ipv4 address", v.Address)
+	}
+	if v.Port != 0 && (v.Port < 1 || v.Port > 65535) {
+		return fmt.Errorf("invalid control plane vip port %d", v.Port)
+	}
+	if v.Mode == VIPModeKeepalived && v.GetPort() == 6443 {
+		return fmt.Errorf("the control plane vip port 6443 is used by the kube-apiserver in keepalived mode")
+	}
+	return nil
+}
+
+func (v *ControlPlaneVIP) GetPort() int {
+	if v.Port != 0 {
+		return v.Port
+	}
+	if v.Mode == VIPModeKeepalived {
+		return 8443
+	}
+	return 6443
+}
+
+// Endpoint the address of kube-apiserver on the vip, formatted as host:port.
+func (v *ControlPlaneVIP) Endpoint() string {
+	return net.JoinHostPort(v.Address, strconv.Itoa(v.GetPort()))
+}
+
 type ClusterPhase string
 
 // These are the valid phases of a project.
@@ -230,19 +360,27 @@ type Networking struct {
 	WorkerNodeVip string `json:"workerNodeVip" optional:"true"`
 }
 
+const (
+	ProxyModeIPVS     = "ipvs"
+	ProxyModeIPTables = "iptables"
+	// ProxyModeEBPF kube-proxy is not deployed, the cni takes over service load balancing.
+	ProxyModeEBPF = "ebpf"
+)
+
 var (
-	AllowedCNI = sets.NewString("calico")
+	AllowedCNI = sets.NewString("calico", "cilium")
 )
 
 type CNI struct {
 	LocalRegistry string `json:"localRegistry" optional:"true"`
 	// TODO: Cluster multiple cni plugins are not supported at this time
-	Type      string  `json:"type" enum:"calico"`
+	Type      string  `json:"type" enum:"calico|cilium"`
 	Version   string  `json:"version"`
 	CriType   string  `json:"criType"`
 	Offline   bool    `json:"offline"`
 	Namespace string  `json:"namespace"`
 	Calico    *Calico `json:"calico" optional:"true"`
+	Cilium    *Cilium `json:"cilium,omitempty" optional:"true"`
 }
 
 type Calico struct {
@@ -253,21 +391,148 @@ type Calico struct {
 	MTU               int    `json:"mtu"`
 }
 
+type Cilium struct {
+	// Mode is the datapath routing mode, "vxlan" / "geneve" for tunneling or "native" for direct routing.
+	Mode string `json:"mode" enum:"vxlan|geneve|native"`
+	MTU  int    `json:"mtu,omitempty" optional:"true"`
+	// IPv4NativeRoutingCIDR is the CIDR in which native routing can be performed, required by "native" mode.
+	IPv4NativeRoutingCIDR string `json:"ipv4NativeRoutingCIDR,omitempty" optional:"true"`
+}
+
 type Etcd struct {
 	DataDir string `json:"dataDir,omitempty" optional:"true"`
+	// External points kubeadm to an etcd cluster outside the control plane nodes.
+	// The etcd is stacked on the masters if it is not set.
+	External *ExternalEtcd `json:"external,omitempty" optional:"true"`
+}
+
+// ExternalEtcd is either an existing etcd cluster specified by Endpoints and client certs,
+// or a dedicated set of etcd Nodes installed and managed by kubeclipper.
+type ExternalEtcd struct {
+	// Endpoints of the etcd cluster, like https://192.168.10.10:2379.
+	// It is filled with the etcd nodes when the etcd is managed.
+	Endpoints []string `json:"endpoints,omitempty" optional:"true"`
+	// CACert, ClientCert and ClientKey are the PEM encoded certs to access an existing etcd cluster.
+	// ClientKey is masked in the responses of API.
+	CACert     string `json:"caCert,omitempty" optional:"true"`
+	ClientCert string `json:"clientCert,omitempty" optional:"true"`
+	ClientKey  string `json:"clientKey,omitempty" optional:"true"`
+	// Nodes the etcd members installed by kubeclipper.
+	Nodes WorkerNodeList `json:"nodes,omitempty" optional:"true"`
+}
+
+// IsExternal reports whether the etcd is outside the control plane nodes.
+func (e Etcd) IsExternal() bool {
+	return e.External != nil
+}
+
+// IsManaged reports whether the external etcd members are installed by kubeclipper.
+func (e Etcd) IsManaged() bool {
+	return e.External != nil && len(e.External.Nodes) > 0
 }
 
 type Kubelet struct {
 	RootDir string `json:"rootDir" yaml:"rootDir"`
+	// ExtraArgs the extra flags of kubelet, the root-dir flag is set by RootDir.
+	ExtraArgs map[string]string `json:"extraArgs,omitempty" yaml:"extraArgs,omitempty" optional:"true"`
+	// MaxPods the maximum number of pods on a node, 0 means the kubelet default.
+	MaxPods int32 `json:"maxPods,omitempty" yaml:"maxPods,omitempty" optional:"true"`
+	// EvictionHard the hard eviction thresholds, e.g. memory.available: 100Mi.
+	EvictionHard map[string]string `json:"evictionHard,omitempty" yaml:"evictionHard,omitempty" optional:"true"`
+	// EvictionSoft the soft eviction thresholds, each of them must have a grace period.
+	EvictionSoft map[string]string `json:"evictionSoft,omitempty" yaml:"evictionSoft,omitempty" optional:"true"`
+	// EvictionSoftGracePeriod the grace periods of the soft eviction thresholds, e.g. memory.available: 1m30s.
+	EvictionSoftGracePeriod map[string]string `json:"evictionSoftGracePeriod,omitempty" yaml:"evictionSoftGracePeriod,omitempty" optional:"true"`
+	// SystemReserved the resources reserved for the system daemons, e.g. cpu: 500m.
+	SystemReserved map[string]string `json:"systemReserved,omitempty" yaml:"systemReserved,omitempty" optional:"true"`
+	// KubeReserved the resources reserved for the kubernetes daemons, e.g. memory: 1Gi.
+	KubeReserved map[string]string `json:"kubeReserved,omitempty" yaml:"kubeReserved,omitempty" optional:"true"`
 }
 
 type KubeProxy struct {
+	// IPVSScheduler the ipvs scheduler, e.g. rr, lc or sh, only used in the ipvs proxy mode.
+	IPVSScheduler string `json:"ipvsScheduler,omitempty" yaml:"ipvsScheduler,omitempty" optional:"true"`
+	// Conntrack the conntrack settings, the kube-proxy defaults are used if not set.
+	Conntrack KubeProxyConntrack `json:"conntrack,omitempty" yaml:"conntrack,omitempty" optional:"true"`
+}
+
+type KubeProxyConntrack struct {
+	// MaxPerCore the maximum number of NAT connections to track per CPU core, 0 leaves the limit as-is.
+	MaxPerCore *int32 `json:"maxPerCore,omitempty" yaml:"maxPerCore,omitempty" optional:"true"`
+	// Min the minimum number of conntrack entries to allocate, regardless of MaxPerCore.
+	Min *int32 `json:"min,omitempty" yaml:"min,omitempty" optional:"true"`
+	// TCPEstablishedTimeout how long an idle TCP connection will be kept open.
+	TCPEstablishedTimeout *metav1.Duration `json:"tcpEstablishedTimeout,omitempty" yaml:"tcpEstablishedTimeout,omitempty" optional:"true"`
+	// TCPCloseWaitTimeout how long an idle conntrack entry in CLOSE_WAIT state will remain in the conntrack table.
+	TCPCloseWaitTimeout *metav1.Duration `json:"tcpCloseWaitTimeout,omitempty" yaml:"tcpCloseWaitTimeout,omitempty" optional:"true"`
+}
+
+// ControlPlaneComponents the extra configuration of the control plane components.
+type ControlPlaneComponents struct {
+	APIServer         ControlPlaneComponent `json:"apiServer,omitempty" yaml:"apiServer,omitempty" optional:"true"`
+	ControllerManager ControlPlaneComponent `json:"controllerManager,omitempty" yaml:"controllerManager,omitempty" optional:"true"`
+	Scheduler         ControlPlaneComponent `json:"scheduler,omitempty" yaml:"scheduler,omitempty" optional:"true"`
+}
+
+type ControlPlaneComponent struct {
+	// ExtraArgs the extra flags of component, they override the flags generated by kubeadm.
+	ExtraArgs map[string]string `json:"extraArgs,omitempty" yaml:"extraArgs,omitempty" optional:"true"`
+}
+
+// ClusterConfig the configuration of the kubernetes components, it can be updated on a running cluster.
+type ClusterConfig struct {
+	ControlPlane ControlPlaneComponents `json:"controlPlane,omitempty" optional:"true"`
+	// FeatureGates the feature gates enabled or disabled on all the kubernetes components.
+	FeatureGates map[string]bool `json:"featureGates,omitempty" optional:"true"`
+	Kubelet      Kubelet         `json:"kubelet,omitempty" optional:"true"`
+	KubeProxy    KubeProxy       `json:"kubeProxy,omitempty" optional:"true"`
+}
+
+// Validate checks whether the configuration can be rendered into the kubeadm config.
+func (c *ClusterConfig) Validate() error {
+	for name := range c.FeatureGates {
+		if name == "" || strings.ContainsAny(name, "=, ") {
+			return fmt.Errorf("invalid feature gate %q", name)
+		}
+	}
+	if _, ok := c.Kubelet.ExtraArgs["root-dir"]; ok {
+		return fmt.Errorf("the root-dir of kubelet must be set by the kubelet rootDir")
+	}
+	if c.Kubelet.MaxPods < 0 {
+		return fmt.Errorf("the max pods of kubelet must not be negative")
+	}
+	for signal := range c.Kubelet.EvictionSoft {
+		if _, ok := c.Kubelet.EvictionSoftGracePeriod[signal]; !ok {
+			return fmt.Errorf("the soft eviction threshold %s must have a grace period", signal)
+		}
+	}
+	return nil
+}
+
+// Config returns the configuration of the kubernetes components of cluster.
+func (c *Cluster) Config() ClusterConfig {
+	return ClusterConfig{
+		ControlPlane: c.ControlPlane,
+		FeatureGates: c.FeatureGates,
+		Kubelet:      c.Kubelet,
+		KubeProxy:    c.KubeProxy,
+	}
+}
+
+// SetConfig sets the configuration of the kubernetes components of cluster, the kubelet root dir is not changed.
+func (c *Cluster) SetConfig(config ClusterConfig) {
+	c.ControlPlane = config.ControlPlane
+	c.FeatureGates = config.FeatureGates
+	rootDir := c.Kubelet.RootDir
+	c.Kubelet = config.Kubelet
+	c.Kubelet.RootDir = rootDir
+	c.KubeProxy = config.KubeProxy
 }
 
 // container runtime define
 
 var (
-	AllowedCRIType = sets.NewString(CRIDocker, CRIContainerd)
+	AllowedCRIType = sets.NewString(CRIDocker, CRIContainerd, CRICrio)
 )
 
 type CRIType string
@@ -275,10 +540,11 @@ type CRIType string
 const (
 	CRIDocker     = "docker"
 	CRIContainerd = "containerd"
+	CRICrio       = "crio"
 )
 
 type ContainerRuntime struct {
-	Type        string `json:"type" enum:"docker|containerd"`
+	Type        string `json:"type" enum:"docker|containerd|crio"`
 	Version     string `json:"version,omitempty" enum:"1.4.4"`
 	DataRootDir string `json:"rootDir,omitempty"`
 	// Deprecated use Registries  insteadof
@@ -364,5 +630,8 @@ func (l WorkerNodeList) Complement(nodes ...WorkerNode) WorkerNodeList {
 func (c Cluster) GetAllNodes() sets.String {
 	s := sets.NewString(c.Masters.GetNodeIDs()...)
 	s.Insert(c.Workers.GetNodeIDs()...)
+	if c.Etcd.IsManaged() {
+		s.Insert(c.Etcd.External.Nodes.GetNodeIDs()...)
+	}
 	return s
 }
@@ -0,0 +1,201 @@
+package cni
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/fileutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/strutil"
+	tmplutil "github.com/kubeclipper/kubeclipper/pkg/utils/template"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// CiliumNetworkVXLAN vxlan tunnel mode
+	CiliumNetworkVXLAN = "vxlan"
+	// CiliumNetworkGeneve geneve tunnel mode
+	CiliumNetworkGeneve = "geneve"
+	// CiliumNetworkNative native routing mode
+	CiliumNetworkNative = "native"
+
+	ciliumAPIServerPort = 6443
+)
+
+func init() {
+	Register(&CiliumRunnable{})
+	if err := component.RegisterTemplate(fmt.Sprintf(component.RegisterTemplateKeyFormat,
+		cniInfo+"-cilium", version, component.TypeTemplate), &CiliumRunnable{}); err != nil {
+		panic(err)
+	}
+	if err := component.RegisterAgentStep(fmt.Sprintf(component.RegisterStepKeyFormat,
+		cniInfo+"-cilium", version, component.TypeStep), &CiliumRunnable{}); err != nil {
+		panic(err)
+	}
+}
+
+var _ KubeProxyReplacer = (*CiliumRunnable)(nil)
+
+type CiliumRunnable struct {
+	BaseCni
+	// KubeProxyReplacement run cilium in strict kube-proxy replacement mode, set when the proxy mode is ebpf.
+	KubeProxyReplacement bool   `json:"kubeProxyReplacement"`
+	APIServerHost        string `json:"apiServerHost"`
+	APIServerPort        int    `json:"apiServerPort"`
+}
+
+func (runnable *CiliumRunnable) Type() string {
+	return "cilium"
+}
+
+func (runnable *CiliumRunnable) Create() Stepper {
+	return &CiliumRunnable{}
+}
+
+func (runnable *CiliumRunnable) NewInstance() component.ObjectMeta {
+	return &CiliumRunnable{}
+}
+
+// SupportKubeProxyReplacement cilium is able to take over service load balancing from kube-proxy.
+func (runnable *CiliumRunnable) SupportKubeProxyReplacement() bool {
+	return true
+}
+
+func (runnable *CiliumRunnable) InitStep(metadata *component.ExtraMetadata, cni *v1.CNI, networking *v1.Networking) Stepper {
+	stepper := &CiliumRunnable{}
+	stepper.CNI = *cni
+	if stepper.Cilium == nil {
+		stepper.Cilium = &v1.Cilium{Mode: CiliumNetworkVXLAN}
+	}
+	stepper.LocalRegistry = cni.LocalRegistry
+	stepper.BaseCni.Type = "cilium"
+	stepper.Version = cni.Version
+	stepper.CriType = metadata.CRI
+	stepper.Offline = cni.Offline
+	stepper.Namespace = cni.Namespace
+	stepper.DualStack = networking.IPFamily == v1.IPFamilyDualStack
+	stepper.PodIPv4CIDR = networking.Pods.CIDRBlocks[0]
+	if stepper.DualStack {
+		stepper.PodIPv6CIDR = networking.Pods.CIDRBlocks[1]
+	}
+	if stepper.Cilium.Mode == CiliumNetworkNative && stepper.Cilium.IPv4NativeRoutingCIDR == "" {
+		cilium := *stepper.Cilium
+		cilium.IPv4NativeRoutingCIDR = stepper.PodIPv4CIDR
+		stepper.Cilium = &cilium
+	}
+	stepper.KubeProxyReplacement = networking.ProxyMode == v1.ProxyModeEBPF
+	// cilium agent runs in host network, reach the apiserver directly because there is no kube-proxy
+	// to serve the kubernetes service in kube-proxy replacement mode.
+	stepper.APIServerHost = "apiserver." + strutil.StringDefaultIfEmpty("cluster.local", networking.DNSDomain)
+	stepper.APIServerPort = ciliumAPIServerPort
+
+	return stepper
+}
+
+func (runnable *CiliumRunnable) LoadImage(nodes []v1.StepNode) ([]v1.Step, error) {
+	var steps []v1.Step
+	bytes, err := json.Marshal(runnable)
+	if err != nil {
+		return nil, err
+	}
+
+	if runnable.Offline && runnable.LocalRegistry == "" {
+		return []v1.Step{LoadImage("cilium", bytes, nodes)}, nil
+	}
+
+	return steps, nil
+}
+
+func (runnable *CiliumRunnable) InstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	var steps []v1.Step
+	bytes, err := json.Marshal(runnable)
+	if err != nil {
+		return nil, err
+	}
+
+	steps = append(steps, RenderYaml("cilium", bytes, nodes))
+	steps = append(steps, ApplyYaml(filepath.Join(manifestDir, "cilium.yaml"), nodes))
+
+	return steps, nil
+}
+
+func (runnable *CiliumRunnable) UninstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	bytes, err := json.Marshal(runnable)
+	if err != nil {
+		return nil, err
+	}
+	var steps []v1.Step
+	if runnable.Offline && runnable.LocalRegistry == "" {
+		steps = append(steps, RemoveImage("cilium", bytes, nodes))
+	}
+	steps = append(steps, runnable.clear(nodes)...)
+
+	return steps, nil
+}
+
+// clear remove the virtual interfaces and bpf state left by cilium agent.
+func (runnable *CiliumRunnable) clear(nodes []v1.StepNode) []v1.Step {
+	return []v1.Step{
+		{
+			ID:         strutil.GetUUID(),
+			Name:       "removeCiliumLink",
+			Timeout:    metav1.Duration{Duration: 10 * time.Second},
+			ErrIgnore:  true,
+			Nodes:      nodes,
+			Action:     v1.ActionUninstall,
+			RetryTimes: 1,
+			Commands: []v1.Command{
+				{
+					Type: v1.CommandShell,
+					ShellCommand: []string{"bash", "-c",
+						"for link in cilium_host cilium_net cilium_vxlan cilium_geneve; do ip link delete $link 2>/dev/null; done; " +
+							"rm -rf /sys/fs/bpf/tc/globals/cilium_* /var/run/cilium /etc/cni/net.d/05-cilium.conflist; true"},
+				},
+			},
+		},
+	}
+}
+
+// CmdList cni kubectl cmd list
+func (runnable *CiliumRunnable) CmdList(namespace string) map[string]string {
+	cmdList := make(map[string]string)
+	cmdList["get"] = fmt.Sprintf("kubectl get po -n %s | grep cilium", namespace)
+	cmdList["restart"] = fmt.Sprintf("kubectl rollout restart ds cilium -n %s", namespace)
+
+	return cmdList
+}
+
+func (runnable *CiliumRunnable) Render(ctx context.Context, opts component.Options) error {
+	if err := os.MkdirAll(manifestDir, 0755); err != nil {
+		return err
+	}
+	manifestFile := filepath.Join(manifestDir, "cilium.yaml")
+	return fileutil.WriteFileWithContext(ctx, manifestFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644,
+		runnable.renderCiliumTo, opts.DryRun)
+}
+
+func (runnable *CiliumRunnable) renderCiliumTo(w io.Writer) error {
+	at := tmplutil.New()
+	ciliumTemp, err := runnable.CiliumTemplate()
+	if err != nil {
+		return err
+	}
+	if _, err := at.RenderTo(w, ciliumTemp, runnable); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (runnable *CiliumRunnable) CiliumTemplate() (string, error) {
+	switch runnable.Version {
+	case "v1.12.7":
+		return ciliumV1127, nil
+	}
+	return "", fmt.Errorf("cilium does not support version: %s", runnable.Version)
+}
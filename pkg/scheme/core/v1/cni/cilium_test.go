@@ -0,0 +1,108 @@
+package cni
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/constatns"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func TestCNI_renderCiliumTo(t *testing.T) {
+	tests := []struct {
+		name       string
+		cni        v1.CNI
+		networking v1.Networking
+		want       []string
+		notWant    []string
+	}{
+		{
+			name: "vxlan with kube-proxy",
+			cni: v1.CNI{
+				LocalRegistry: "172.0.0.1:5000",
+				Type:          "cilium",
+				Version:       "v1.12.7",
+				Namespace:     "kube-system",
+			},
+			networking: v1.Networking{
+				IPFamily:  v1.IPFamilyIPv4,
+				Pods:      v1.NetworkRanges{CIDRBlocks: []string{constatns.ClusterPodSubnet}},
+				DNSDomain: "cluster.local",
+				ProxyMode: v1.ProxyModeIPVS,
+			},
+			want: []string{
+				`tunnel: "vxlan"`,
+				`kube-proxy-replacement: "disabled"`,
+				"image: 172.0.0.1:5000/cilium/cilium:v1.12.7",
+			},
+			notWant: []string{"cluster-pool-ipv6-cidr"},
+		},
+		{
+			name: "native routing with ebpf",
+			cni: v1.CNI{
+				Type:      "cilium",
+				Version:   "v1.12.7",
+				Namespace: "kube-system",
+				Cilium: &v1.Cilium{
+					Mode:                  CiliumNetworkNative,
+					MTU:                   1450,
+					IPv4NativeRoutingCIDR: constatns.ClusterPodSubnet,
+				},
+			},
+			networking: v1.Networking{
+				IPFamily:  v1.IPFamilyDualStack,
+				Pods:      v1.NetworkRanges{CIDRBlocks: []string{constatns.ClusterPodSubnet, "fd00::/108"}},
+				DNSDomain: "cluster.local",
+				ProxyMode: v1.ProxyModeEBPF,
+			},
+			want: []string{
+				`tunnel: "disabled"`,
+				`mtu: "1450"`,
+				`kube-proxy-replacement: "strict"`,
+				`cluster-pool-ipv6-cidr: "fd00::/108"`,
+				`value: "apiserver.cluster.local"`,
+				"image: cilium/operator-generic:v1.12.7",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stepper := (&CiliumRunnable{}).InitStep(&component.ExtraMetadata{}, &tt.cni, &tt.networking).(*CiliumRunnable)
+			w := &bytes.Buffer{}
+			if err := stepper.renderCiliumTo(w); err != nil {
+				t.Errorf("renderCiliumTo() error = %v", err)
+				return
+			}
+			for _, s := range tt.want {
+				if !strings.Contains(w.String(), s) {
+					t.Errorf("renderCiliumTo() output does not contain %q", s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(w.String(), s) {
+					t.Errorf("renderCiliumTo() output should not contain %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestSupportKubeProxyReplacement(t *testing.T) {
+	tests := []struct {
+		cniType string
+		want    bool
+	}{
+		{cniType: "calico", want: false},
+		{cniType: "cilium", want: true},
+		{cniType: "unknown", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cniType, func(t *testing.T) {
+			if got := SupportKubeProxyReplacement(tt.cniType); got != tt.want {
+				t.Errorf("SupportKubeProxyReplacement() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -52,6 +52,22 @@ type Stepper interface {
 	CmdList(namespace string) map[string]string
 }
 
+// KubeProxyReplacer is implemented by the cni which is able to replace kube-proxy,
+// the cluster proxy mode can be set to ebpf only with these cni.
+type KubeProxyReplacer interface {
+	SupportKubeProxyReplacement() bool
+}
+
+// SupportKubeProxyReplacement check whether the cni is able to replace kube-proxy.
+func SupportKubeProxyReplacement(cniType string) bool {
+	cf, err := Load(cniType)
+	if err != nil {
+		return false
+	}
+	r, ok := cf.Create().(KubeProxyReplacer)
+	return ok && r.SupportKubeProxyReplacement()
+}
+
 func (runnable *BaseCni) NewInstance() component.ObjectMeta {
 	return &BaseCni{}
 }
@@ -67,7 +83,7 @@ func (runnable *BaseCni) Install(ctx context.Context, opts component.Options) ([
 	}
 	// load image package
 	if err = utils.LoadImage(ctx, opts.DryRun, dstFile, runnable.CriType); err == nil {
-		logger.Info("calico packages offline install successfully")
+		logger.Infof("%s packages offline install successfully", runnable.Type)
 	}
 
 	return nil, err
@@ -79,7 +95,7 @@ func (runnable *BaseCni) Uninstall(ctx context.Context, opts component.Options)
 		return nil, err
 	}
 	if err = instance.RemoveImages(); err != nil {
-		logger.Error("remove calico images compressed file failed", zap.Error(err))
+		logger.Error("remove cni images compressed file failed", zap.String("cni", runnable.Type), zap.Error(err))
 	}
 	return nil, nil
 }
@@ -66,7 +66,7 @@ func (runnable *ContainerdRunnable) InitStep(ctx context.Context, cluster *v1.Cl
 	runnable.LocalRegistry = metadata.LocalRegistry
 	runnable.Registies = cluster.Status.Registries
 
-	runnable.PauseVersion, runnable.PauseRegistry = runnable.matchPauseVersion(metadata.KubeVersion)
+	runnable.PauseVersion, runnable.PauseRegistry = matchPauseVersion(metadata.KubeVersion)
 	runtimeBytes, err := json.Marshal(runnable)
 	if err != nil {
 		return err
@@ -199,7 +199,7 @@ func (runnable *ContainerdRunnable) OnlineUpgrade(ctx context.Context, dryRun bo
 	return nil, fmt.Errorf("ContainerdRunnable not supported onlineUpgrade")
 }
 
-func (runnable *ContainerdRunnable) matchPauseVersion(kubeVersion string) (string, string) {
+func matchPauseVersion(kubeVersion string) (string, string) {
 	registry := "k8s.gcr.io"
 	if kubeVersion == "" {
 		return "", registry
@@ -38,6 +38,12 @@ func init() {
 		panic(err)
 	}
 
+	if err := component.RegisterAgentStep(
+		fmt.Sprintf(component.RegisterStepKeyFormat, criCrio, criVersion, component.TypeStep),
+		&CrioRunnable{}); err != nil {
+		panic(err)
+	}
+
 	if err := component.RegisterAgentStep(
 		ContainerdRegistryConfigureIdentity,
 		&ContainerdRegistryConfigure{}); err != nil {
@@ -49,11 +55,18 @@ func init() {
 		&DockerInsecureRegistryConfigure{}); err != nil {
 		panic(err)
 	}
+
+	if err := component.RegisterAgentStep(
+		CrioRegistryConfigureIdentity,
+		&CrioRegistryConfigure{}); err != nil {
+		panic(err)
+	}
 }
 
 const (
 	criDocker     = "docker"
 	criContainerd = "containerd"
+	criCrio       = "crio"
 	criVersion    = "v1"
 )
 
@@ -69,6 +82,15 @@ const (
 	ContainerdDefaultRegistryConfigDir = "/etc/containerd/certs.d"
 	// containerdDefaultSystemdDir = "/etc/systemd/system"
 	containerdDefaultDataDir = "/var/lib/containerd"
+
+	crioDefaultConfigDir = "/etc/crio/crio.conf.d"
+	// CrioDefaultRegistryConfigDir registries.conf drop-in dir of containers/image, read by cri-o.
+	CrioDefaultRegistryConfigDir = "/etc/containers/registries.conf.d"
+	crioDefaultCertsDir          = "/etc/containers/certs.d"
+	crioDefaultDataDir           = "/var/lib/containers/storage"
+	crioDefaultRunDir            = "/run/containers/storage"
+	// crioStorageConfigFile the containers-storage config shared by cri-o and podman shipped with it.
+	crioStorageConfigFile = "/etc/containers/storage.conf"
 )
 
 var (
@@ -76,6 +98,8 @@ var (
 		component.RegisterStepKeyFormat, criDocker, criVersion, component.TypeRegistryConfigure)
 	ContainerdRegistryConfigureIdentity = fmt.Sprintf(
 		component.RegisterStepKeyFormat, criContainerd, criVersion, component.TypeRegistryConfigure)
+	CrioRegistryConfigureIdentity = fmt.Sprintf(
+		component.RegisterStepKeyFormat, criCrio, criVersion, component.TypeRegistryConfigure)
 )
 
 var k8sMatchPauseVersion = map[string]string{
@@ -91,6 +115,7 @@ var k8sMatchPauseVersion = map[string]string{
 
 var _ component.StepRunnable = (*ContainerdRunnable)(nil)
 var _ component.StepRunnable = (*DockerRunnable)(nil)
+var _ component.StepRunnable = (*CrioRunnable)(nil)
 
 type Base struct {
 	Version     string            `json:"version,omitempty"`
@@ -0,0 +1,384 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package cri
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"time"
+
+	"github.com/pelletier/go-toml"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/logger"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/downloader"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/cmdutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/fileutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/strutil"
+	tmplutil "github.com/kubeclipper/kubeclipper/pkg/utils/template"
+)
+
+const (
+	// CrioSocket the cri endpoint of cri-o, used by kubelet and crictl.
+	CrioSocket = "unix:///var/run/crio/crio.sock"
+	// crioRegistryConfigFile the registries.conf drop-in managed by kubeclipper
+	crioRegistryConfigFile = "kubeclipper.conf"
+)
+
+type CrioRunnable struct {
+	Base
+	RegistryConfigDir string `json:"registryConfigDir"`
+	LocalRegistry     string `json:"localRegistry"`
+	KubeVersion       string `json:"kubeVersion"`
+	PauseVersion      string `json:"pauseVersion"`
+	PauseRegistry     string `json:"pauseRegistry"`
+
+	installSteps   []v1.Step
+	uninstallSteps []v1.Step
+	upgradeSteps   []v1.Step
+}
+
+func (runnable *CrioRunnable) InitStep(ctx context.Context, cluster *v1.Cluster, nodes []v1.StepNode) error {
+	metadata := component.GetExtraMetadata(ctx)
+	runnable.Version = cluster.ContainerRuntime.Version
+	runnable.Offline = metadata.Offline
+	runnable.DataRootDir = strutil.StringDefaultIfEmpty(crioDefaultDataDir, cluster.ContainerRuntime.DataRootDir)
+	runnable.LocalRegistry = metadata.LocalRegistry
+	runnable.Registies = cluster.Status.Registries
+
+	runnable.PauseVersion, runnable.PauseRegistry = matchPauseVersion(metadata.KubeVersion)
+	runtimeBytes, err := json.Marshal(runnable)
+	if err != nil {
+		return err
+	}
+
+	if len(runnable.installSteps) == 0 {
+		runnable.installSteps = []v1.Step{
+			{
+				ID:         strutil.GetUUID(),
+				Name:       "installRuntime",
+				Timeout:    metav1.Duration{Duration: 10 * time.Minute},
+				ErrIgnore:  false,
+				RetryTimes: 1,
+				Nodes:      nodes,
+				Action:     v1.ActionInstall,
+				Commands: []v1.Command{
+					{
+						Type:          v1.CommandCustom,
+						Identity:      fmt.Sprintf(component.RegisterStepKeyFormat, criCrio, criVersion, component.TypeStep),
+						CustomCommand: runtimeBytes,
+					},
+				},
+			},
+		}
+	}
+	if len(runnable.uninstallSteps) == 0 {
+		runnable.uninstallSteps = []v1.Step{
+			{
+				ID:         strutil.GetUUID(),
+				Name:       "uninstallRuntime",
+				Timeout:    metav1.Duration{Duration: 10 * time.Minute},
+				ErrIgnore:  false,
+				RetryTimes: 1,
+				Nodes:      nodes,
+				Action:     v1.ActionUninstall,
+				Commands: []v1.Command{
+					{
+						Type:          v1.CommandCustom,
+						Identity:      fmt.Sprintf(component.RegisterStepKeyFormat, criCrio, criVersion, component.TypeStep),
+						CustomCommand: runtimeBytes,
+					},
+				},
+			},
+		}
+	}
+
+	return nil
+}
+
+func (runnable *CrioRunnable) GetActionSteps(action v1.StepAction) []v1.Step {
+	switch action {
+	case v1.ActionInstall:
+		return runnable.installSteps
+	case v1.ActionUninstall:
+		return runnable.uninstallSteps
+	case v1.ActionUpgrade:
+		return runnable.upgradeSteps
+	}
+
+	return nil
+}
+
+func (runnable *CrioRunnable) NewInstance() component.ObjectMeta {
+	return &CrioRunnable{}
+}
+
+func (runnable CrioRunnable) Install(ctx context.Context, opts component.Options) ([]byte, error) {
+	instance, err := downloader.NewInstance(ctx, criCrio, runnable.Version, runtime.GOARCH, !runnable.Offline, opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = instance.DownloadAndUnpackConfigs(); err != nil {
+		return nil, err
+	}
+	// generate cri-o drop-in config and registries config
+	if err = runnable.setupCrioConfig(ctx, opts.DryRun); err != nil {
+		return nil, err
+	}
+	// launch and enable cri-o service
+	if err = runnable.enableCrioService(ctx, opts.DryRun); err != nil {
+		return nil, err
+	}
+	_, err = cmdutil.RunCmdWithContext(ctx, opts.DryRun, "crictl", "config", "runtime-endpoint", CrioSocket)
+	if err != nil {
+		return nil, err
+	}
+	// cri-o has no image import api, the offline images are loaded by podman shipped with the cri-o package.
+	if runnable.Offline {
+		if _, err = cmdutil.RunCmdWithContext(ctx, opts.DryRun, "podman", "--version"); err != nil {
+			return nil, fmt.Errorf("podman is required to load images into cri-o, it must be shipped with the cri-o package: %w", err)
+		}
+	}
+	logger.Debugf("install cri-o successfully, online: %t", !runnable.Offline)
+	return nil, nil
+}
+
+func (runnable CrioRunnable) Uninstall(ctx context.Context, opts component.Options) ([]byte, error) {
+	if err := runnable.disableCrioService(ctx, opts.DryRun); err != nil {
+		return nil, err
+	}
+	// remove related binary configuration files
+	instance, err := downloader.NewInstance(ctx, criCrio, runnable.Version, runtime.GOARCH, !runnable.Offline, opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	if err = instance.RemoveConfigs(); err != nil {
+		logger.Error("remove cri-o configs compressed file failed", zap.Error(err))
+	}
+	if opts.DryRun {
+		return nil, nil
+	}
+	// remove cri-o run dir
+	if err = os.RemoveAll("/var/run/crio"); err == nil {
+		logger.Debug("remove cri-o run dir successfully")
+	}
+	if err = os.RemoveAll(crioDefaultRunDir); err == nil {
+		logger.Debug("remove containers storage run dir successfully")
+	}
+	// remove cri-o data dir
+	if err = os.RemoveAll(strutil.StringDefaultIfEmpty(crioDefaultDataDir, runnable.DataRootDir)); err == nil {
+		logger.Debug("remove cri-o data dir successfully")
+	}
+	// remove cri-o config dir
+	if err = os.RemoveAll(crioDefaultConfigDir); err == nil {
+		logger.Debug("remove cri-o config dir successfully")
+	}
+	if err = os.RemoveAll(crioStorageConfigFile); err == nil {
+		logger.Debug("remove containers storage config successfully")
+	}
+	registryConfigDir := strutil.StringDefaultIfEmpty(CrioDefaultRegistryConfigDir, runnable.RegistryConfigDir)
+	if err = os.RemoveAll(filepath.Join(registryConfigDir, crioRegistryConfigFile)); err == nil {
+		logger.Debug("remove cri-o registry config successfully")
+	}
+	logger.Debug("uninstall cri-o successfully")
+	return nil, nil
+}
+
+func (runnable *CrioRunnable) OfflineUpgrade(ctx context.Context, dryRun bool) ([]byte, error) {
+	return nil, fmt.Errorf("CrioRunnable does not support offlineUpgrade")
+}
+
+func (runnable *CrioRunnable) OnlineUpgrade(ctx context.Context, dryRun bool) ([]byte, error) {
+	return nil, fmt.Errorf("CrioRunnable does not support onlineUpgrade")
+}
+
+func (runnable *CrioRunnable) setupCrioConfig(ctx context.Context, dryRun bool) error {
+	// local registry not filled and is in online mode, the default repo mirror proxy will be used
+	if !runnable.Offline && runnable.LocalRegistry == "" {
+		runnable.LocalRegistry = component.GetRepoMirror(ctx)
+		logger.Info("render cri-o config, the default repo mirror proxy will be used", zap.String("local_registry", runnable.LocalRegistry))
+	}
+	if runnable.RegistryConfigDir == "" {
+		runnable.RegistryConfigDir = CrioDefaultRegistryConfigDir
+	}
+	cf := filepath.Join(crioDefaultConfigDir, "10-kubeclipper.conf")
+	if err := os.MkdirAll(crioDefaultConfigDir, 0755); err != nil {
+		return err
+	}
+	if err := fileutil.WriteFileWithContext(ctx, cf, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644, runnable.renderTo, dryRun); err != nil {
+		return err
+	}
+	// podman uses the same storage as cri-o, so that the images loaded by podman are seen by cri-o.
+	if err := os.MkdirAll(filepath.Dir(crioStorageConfigFile), 0755); err != nil {
+		return err
+	}
+	if err := fileutil.WriteFileWithContext(ctx, crioStorageConfigFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644, runnable.renderStorageTo, dryRun); err != nil {
+		return err
+	}
+	if dryRun {
+		return nil
+	}
+	return renderCrioRegistryConfig(runnable.RegistryConfigDir, crioDefaultCertsDir, ToCrioRegistryConfig(runnable.Registies))
+}
+
+func (runnable *CrioRunnable) enableCrioService(ctx context.Context, dryRun bool) error {
+	_, err := cmdutil.RunCmdWithContext(ctx, dryRun, "systemctl", "daemon-reload")
+	if err != nil {
+		return err
+	}
+	_, err = cmdutil.RunCmdWithContext(ctx, dryRun, "systemctl", "enable", "crio")
+	if err != nil {
+		return err
+	}
+	// restart cri-o to active config, if it is already running
+	_, err = cmdutil.RunCmdWithContext(ctx, dryRun, "systemctl", "restart", "crio")
+	if err != nil {
+		return err
+	}
+	logger.Debug("enable cri-o systemd service successfully")
+	return nil
+}
+
+func (runnable *CrioRunnable) disableCrioService(ctx context.Context, dryRun bool) error {
+	// the following command execution error is ignored
+	if _, err := cmdutil.RunCmdWithContext(ctx, dryRun, "systemctl", "stop", "crio"); err != nil {
+		logger.Warn("stop systemd cri-o service failed", zap.Error(err))
+	}
+	if _, err := cmdutil.RunCmdWithContext(ctx, dryRun, "systemctl", "disable", "crio"); err != nil {
+		logger.Warn("disable systemd cri-o service failed", zap.Error(err))
+	}
+	return nil
+}
+
+func (runnable *CrioRunnable) renderTo(w io.Writer) error {
+	at := tmplutil.New()
+	_, err := at.RenderTo(w, crioConfigTemplate, runnable)
+	return err
+}
+
+func (runnable *CrioRunnable) renderStorageTo(w io.Writer) error {
+	at := tmplutil.New()
+	_, err := at.RenderTo(w, crioStorageConfigTemplate, map[string]string{
+		"DataRootDir": strutil.StringDefaultIfEmpty(crioDefaultDataDir, runnable.DataRootDir),
+		"RunRootDir":  crioDefaultRunDir,
+	})
+	return err
+}
+
+type CrioRegistryConfigure struct {
+	Registries []CrioRegistry `json:"registries,omitempty"`
+	ConfigDir  string         `json:"configDir"`
+	CertsDir   string         `json:"certsDir"`
+}
+
+func (c *CrioRegistryConfigure) Install(ctx context.Context, opts component.Options) ([]byte, error) {
+	if opts.DryRun {
+		return nil, nil
+	}
+	err := renderCrioRegistryConfig(strutil.StringDefaultIfEmpty(CrioDefaultRegistryConfigDir, c.ConfigDir),
+		strutil.StringDefaultIfEmpty(crioDefaultCertsDir, c.CertsDir), c.Registries)
+	if err != nil {
+		return nil, fmt.Errorf("render registry config to %s failed:%w", c.ConfigDir, err)
+	}
+	// cri-o reloads the registries configuration on SIGHUP, running containers are not affected.
+	if _, err = cmdutil.RunCmdWithContext(ctx, opts.DryRun, "bash", "-c", "systemctl reload crio"); err != nil {
+		return nil, fmt.Errorf("reload cri-o:%w", err)
+	}
+	return nil, nil
+}
+
+func (c *CrioRegistryConfigure) Uninstall(_ context.Context, _ component.Options) ([]byte, error) {
+	return nil, nil
+}
+
+func (c *CrioRegistryConfigure) NewInstance() component.ObjectMeta {
+	return new(CrioRegistryConfigure)
+}
+
+// CrioRegistry is a [[registry]] table of containers-registries.conf(5).
+type CrioRegistry struct {
+	Location string `toml:"location" json:"location"`
+	Insecure bool   `toml:"insecure" json:"insecure,omitempty"`
+	CA       []byte `toml:"-" json:"ca,omitempty"`
+}
+
+type crioRegistryFile struct {
+	Registries []CrioRegistry `toml:"registry"`
+}
+
+// renderCrioRegistryConfig generate the registries.conf drop-in and ca file of every registry
+func renderCrioRegistryConfig(configDir, certsDir string, registries []CrioRegistry) error {
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		return err
+	}
+	for _, r := range registries {
+		if len(r.CA) == 0 {
+			continue
+		}
+		hostDir := filepath.Join(certsDir, r.Location)
+		if err := os.MkdirAll(hostDir, 0755); err != nil {
+			return err
+		}
+		caFile := filepath.Join(hostDir, "ca.crt")
+		if err := os.WriteFile(caFile, r.CA, 0644); err != nil {
+			return fmt.Errorf("write ca file:%s failed:%w", caFile, err)
+		}
+	}
+	buf := &bytes.Buffer{}
+	if err := toml.NewEncoder(buf).Encode(crioRegistryFile{Registries: registries}); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(configDir, crioRegistryConfigFile), buf.Bytes(), 0644)
+}
+
+func ToCrioRegistryConfig(registries []v1.RegistrySpec) []CrioRegistry {
+	cfgs := make(map[string]*CrioRegistry, len(registries))
+	for _, r := range registries {
+		cfg, ok := cfgs[r.Host]
+		if !ok {
+			cfg = &CrioRegistry{Location: r.Host}
+			cfgs[r.Host] = cfg
+		}
+		// registry is insecure if any of its endpoints does not validate tls certificate
+		if r.Scheme == "http" || r.SkipVerify {
+			cfg.Insecure = true
+		}
+		if r.CA != "" {
+			cfg.CA = []byte(r.CA)
+		}
+	}
+	list := make([]CrioRegistry, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		list = append(list, *cfg)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Location < list[j].Location
+	})
+	return list
+}
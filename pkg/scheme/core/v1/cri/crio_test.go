@@ -0,0 +1,121 @@
+package cri
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func TestCrioRunnable_renderTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		runnable CrioRunnable
+		want     string
+	}{
+		{
+			name: "default pause registry",
+			runnable: CrioRunnable{
+				Base:          Base{DataRootDir: "/data/containers"},
+				PauseVersion:  "3.6",
+				PauseRegistry: "k8s.gcr.io",
+			},
+			want: `[crio]
+root = "/data/containers"
+
+[crio.runtime]
+cgroup_manager = "systemd"
+conmon_cgroup = "pod"
+
+[crio.image]
+pause_image = "k8s.gcr.io/pause:3.6"
+
+[crio.network]
+network_dir = "/etc/cni/net.d/"
+plugin_dirs = ["/opt/cni/bin/"]
+`,
+		},
+		{
+			name: "use local registry",
+			runnable: CrioRunnable{
+				LocalRegistry: "127.0.0.1:5000",
+				PauseVersion:  "3.6",
+				PauseRegistry: "k8s.gcr.io",
+			},
+			want: `[crio]
+
+[crio.runtime]
+cgroup_manager = "systemd"
+conmon_cgroup = "pod"
+
+[crio.image]
+pause_image = "127.0.0.1:5000/pause:3.6"
+
+[crio.network]
+network_dir = "/etc/cni/net.d/"
+plugin_dirs = ["/opt/cni/bin/"]
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &bytes.Buffer{}
+			require.NoError(t, tt.runnable.renderTo(w))
+			assert.Equal(t, tt.want, w.String())
+		})
+	}
+}
+
+func TestCrioRunnable_renderStorageTo(t *testing.T) {
+	w := &bytes.Buffer{}
+	runnable := CrioRunnable{Base: Base{DataRootDir: "/data/containers"}}
+	require.NoError(t, runnable.renderStorageTo(w))
+	assert.Equal(t, `[storage]
+driver = "overlay"
+graphroot = "/data/containers"
+runroot = "/run/containers/storage"
+`, w.String())
+
+	w.Reset()
+	runnable.DataRootDir = ""
+	require.NoError(t, runnable.renderStorageTo(w))
+	assert.Contains(t, w.String(), `graphroot = "/var/lib/containers/storage"`)
+}
+
+func TestCrioRegistryRender(t *testing.T) {
+	dir, err := os.MkdirTemp("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	registries := ToCrioRegistryConfig([]v1.RegistrySpec{
+		{Scheme: "https", Host: "local2.registry.com", CA: "ca data"},
+		{Scheme: "http", Host: "local1.registry.com"},
+		{Scheme: "https", Host: "local1.registry.com"},
+	})
+	require.Len(t, registries, 2)
+
+	configDir := filepath.Join(dir, "registries.conf.d")
+	certsDir := filepath.Join(dir, "certs.d")
+	require.NoError(t, renderCrioRegistryConfig(configDir, certsDir, registries))
+
+	ca, err := os.ReadFile(filepath.Join(certsDir, "local2.registry.com", "ca.crt"))
+	require.NoError(t, err)
+	assert.Equal(t, "ca data", string(ca))
+
+	conf, err := os.ReadFile(filepath.Join(configDir, crioRegistryConfigFile))
+	require.NoError(t, err)
+	assert.Equal(t, `
+[[registry]]
+  insecure = true
+  location = "local1.registry.com"
+
+[[registry]]
+  insecure = false
+  location = "local2.registry.com"
+`, string(conf))
+}
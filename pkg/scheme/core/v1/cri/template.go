@@ -300,3 +300,31 @@ version = 2
   address = ""
   gid = 0
   uid = 0`
+
+const crioConfigTemplate = `[crio]
+{{- if .DataRootDir}}
+root = "{{.DataRootDir}}"
+{{- end}}
+
+[crio.runtime]
+cgroup_manager = "systemd"
+conmon_cgroup = "pod"
+
+[crio.image]
+{{- if .LocalRegistry }}
+pause_image = "{{.LocalRegistry}}/pause:{{.PauseVersion}}"
+{{- else}}
+pause_image = "{{.PauseRegistry}}/pause:{{.PauseVersion}}"
+{{- end}}
+
+[crio.network]
+network_dir = "/etc/cni/net.d/"
+plugin_dirs = ["/opt/cni/bin/"]
+`
+
+// crioStorageConfigTemplate podman loads the offline images into the storage of cri-o by this config.
+const crioStorageConfigTemplate = `[storage]
+driver = "overlay"
+graphroot = "{{.DataRootDir}}"
+runroot = "{{.RunRootDir}}"
+`
@@ -28,6 +28,19 @@ type CronBackupSpec struct {
 	MaxBackupNum int `json:"maxBackupNum,omitempty"`
 	// specific run time
 	RunAt *metav1.Time `json:"runAt,omitempty"`
+	// Retention the backups are pruned by it instead of MaxBackupNum if it is set.
+	Retention *RetentionPolicy `json:"retention,omitempty"`
+}
+
+// RetentionPolicy the grandfather-father-son retention of backups, the newest KeepLast backups are kept,
+// and the newest backup of every day, week, month and year is kept until the number of the period is reached.
+// The backup is pruned if it is kept by none of them, the missing or corrupted backups are neither counted nor pruned.
+type RetentionPolicy struct {
+	KeepLast    int `json:"keepLast,omitempty"`
+	KeepDaily   int `json:"keepDaily,omitempty"`
+	KeepWeekly  int `json:"keepWeekly,omitempty"`
+	KeepMonthly int `json:"keepMonthly,omitempty"`
+	KeepYearly  int `json:"keepYearly,omitempty"`
 }
 
 // CronBackupStatus defines the status of cronBackup
@@ -23,6 +23,8 @@ import (
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -45,6 +47,7 @@ import (
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sversion "k8s.io/apimachinery/pkg/util/version"
 )
 
 var (
@@ -57,6 +60,12 @@ const (
 	upgradePackage = "upgradePackage"
 	actBackup      = "actBackup"
 	recovery       = "Recovery"
+
+	apiServerManifest = "kube-apiserver.yaml"
+
+	defaultDrainTimeout = 5 * time.Minute
+	// upgradeSkipPhasesVersion 'kubeadm upgrade apply' accepts '--skip-phases' since it.
+	upgradeSkipPhasesVersion = "v1.31.0"
 )
 
 func init() {
@@ -72,11 +81,16 @@ func init() {
 }
 
 type Upgrade struct {
-	Kubeadm       *KubeadmConfig `json:"kubeadm"`
-	Offline       bool           `json:"offline"`
-	Version       string         `json:"version"`
-	LocalRegistry string         `json:"localRegistry"`
+	Kubeadm       *KubeadmConfig     `json:"kubeadm"`
+	Offline       bool               `json:"offline"`
+	Version       string             `json:"version"`
+	LocalRegistry string             `json:"localRegistry"`
+	Strategy      v1.UpgradeStrategy `json:"strategy"`
 	installSteps  []v1.Step
+	// nodeSteps the progress of nodes changed by the install steps
+	nodeSteps map[string]v1.OperationNodeSteps
+	// pauseAfterSteps the operation is paused after these steps
+	pauseAfterSteps []string
 }
 
 type UpgradePackage struct {
@@ -94,6 +108,13 @@ type ActBackup struct {
 	AccessKeySecret    string
 	Region             string
 	SSL                bool
+	SftpConfig         *v1.SftpConfig
+	// EncryptionKeyID the snapshot is encrypted before it is saved if it is set, the key is resolved by
+	// the id and the backup point when the step runs.
+	BackupPointName string
+	EncryptionKeyID string
+	// ExternalEtcdEndpoints the snapshot is saved from the external etcd if it is not empty.
+	ExternalEtcdEndpoints []string
 
 	installSteps   []v1.Step
 	uninstallSteps []v1.Step
@@ -124,9 +145,17 @@ type Recovery struct {
 	AccessKeySecret    string
 	Region             string
 	SSL                bool
+	SftpConfig         *v1.SftpConfig
 	BackupFileSize     int64
 	BackupFileMD5      string
+	// EncryptionKeyID the backup is decrypted when it is downloaded if it is set, the key is resolved by
+	// the id and the backup point when the step runs.
+	BackupPointName string
+	EncryptionKeyID string
 	FileDir
+	// ExternalEtcd the snapshot is restored on the managed etcd nodes instead of the masters,
+	// and the kube-apiserver is stopped until the etcd is restored.
+	ExternalEtcd bool
 
 	installSteps []v1.Step
 }
@@ -134,8 +163,7 @@ type Recovery struct {
 type AfterRecovery struct{}
 
 func (stepper *Upgrade) InitStepper(metadata *component.ExtraMetadata, c *v1.Cluster) {
-	apiServerDomain := APIServerDomainPrefix + strutil.StringDefaultIfEmpty("cluster.local", c.Networking.DNSDomain)
-	cpEndpoint := fmt.Sprintf("%s:6443", apiServerDomain)
+	cpEndpoint := ControlPlaneEndpoint(c)
 
 	stepper.Kubeadm = &KubeadmConfig{
 		ClusterConfigAPIVersion: "",
@@ -144,15 +172,48 @@ func (stepper *Upgrade) InitStepper(metadata *component.ExtraMetadata, c *v1.Clu
 		Networking:              c.Networking,
 		KubeProxy:               c.KubeProxy,
 		Kubelet:                 c.Kubelet,
+		ControlPlane:            c.ControlPlane,
+		FeatureGates:            c.FeatureGates,
 		ClusterName:             metadata.ClusterName,
 		KubernetesVersion:       c.KubernetesVersion,
 		ControlPlaneEndpoint:    cpEndpoint,
-		CertSANs:                c.CertSANs,
+		CertSANs:                certSANs(c),
 		LocalRegistry:           c.LocalRegistry,
 	}
 	stepper.Offline = metadata.Offline
 	stepper.Version = metadata.KubeVersion
 	stepper.LocalRegistry = metadata.LocalRegistry
+	stepper.Strategy = DefaultUpgradeStrategy()
+}
+
+// DefaultUpgradeStrategy drain and upgrade the nodes one by one.
+func DefaultUpgradeStrategy() v1.UpgradeStrategy {
+	drain := true
+	return v1.UpgradeStrategy{
+		MaxUnavailable: 1,
+		Drain:          &drain,
+		DrainTimeout:   metav1.Duration{Duration: defaultDrainTimeout},
+	}
+}
+
+// MergeUpgradeStrategy the fields set by the strategy override the ones of DefaultUpgradeStrategy.
+func MergeUpgradeStrategy(strategy *v1.UpgradeStrategy) v1.UpgradeStrategy {
+	merged := DefaultUpgradeStrategy()
+	if strategy == nil {
+		return merged
+	}
+	if strategy.MaxUnavailable > 0 {
+		merged.MaxUnavailable = strategy.MaxUnavailable
+	}
+	if strategy.Drain != nil {
+		drain := *strategy.Drain
+		merged.Drain = &drain
+	}
+	if strategy.DrainTimeout.Duration > 0 {
+		merged.DrainTimeout = strategy.DrainTimeout
+	}
+	merged.PauseAfterControlPlane = strategy.PauseAfterControlPlane
+	return merged
 }
 
 func (stepper *Upgrade) Validate() error {
@@ -162,6 +223,12 @@ func (stepper *Upgrade) Validate() error {
 	if stepper.Kubeadm.KubernetesVersion == "" {
 		return fmt.Errorf("upgrade version must be valid")
 	}
+	if stepper.Strategy.MaxUnavailable < 0 {
+		return fmt.Errorf("upgrade max unavailable nodes must not be negative")
+	}
+	if stepper.Strategy.DrainTimeout.Duration < 0 {
+		return fmt.Errorf("upgrade drain timeout must not be negative")
+	}
 	logger.Debug("validate upgrade kubeadm struct", zap.Any("kubeadm", stepper.Kubeadm))
 	return nil
 }
@@ -284,76 +351,82 @@ func (stepper *Upgrade) InitSteps(ctx context.Context) error {
 			},
 			RetryTimes: 1,
 		},
-		{
-			ID:        strutil.GetUUID(),
-			Name:      fmt.Sprintf("UpgradeControlPlane-%s", extraMetadata.GetMasterHostname(masters[0].ID)),
-			Nodes:     []v1.StepNode{utils.UnwrapNodeList(masters)[0]},
-			Action:    v1.ActionInstall,
-			Timeout:   metav1.Duration{Duration: 10 * time.Minute},
-			ErrIgnore: false,
-			Commands: []v1.Command{
-				{
-					Type: v1.CommandShell,
-					ShellCommand: []string{"/bin/bash", "-c", fmt.Sprintf(`
-kubeadm upgrade apply %s -f --ignore-preflight-errors all --config /tmp/.k8s/kubeadm.yaml
-sleep 10
-kubectl drain %s --ignore-daemonsets || true
-systemctl stop kubelet
-systemctl daemon-reload && systemctl restart kubelet
-kubectl uncordon %s || true`,
-						stepper.Version, extraMetadata.GetMasterHostname(masters[0].ID), extraMetadata.GetMasterHostname(masters[0].ID))},
-				},
-			},
-			RetryTimes: 0,
-		},
 	}...)
 
-	for i := 1; i < len(masters); i++ {
-		stepper.installSteps = append(stepper.installSteps, v1.Step{
+	for i := range masters {
+		hostname := extraMetadata.GetMasterHostname(masters[i].ID)
+		upgradeCmd := "kubeadm upgrade node"
+		if i == 0 {
+			upgradeCmd = stepper.upgradeApplyCommand()
+		}
+		step := v1.Step{
 			ID:        strutil.GetUUID(),
-			Name:      fmt.Sprintf("UpgradeControlPlane-%s", extraMetadata.GetMasterHostname(masters[i].ID)),
+			Name:      fmt.Sprintf("UpgradeControlPlane-%s", hostname),
 			Nodes:     []v1.StepNode{utils.UnwrapNodeList(masters)[i]},
 			Action:    v1.ActionInstall,
-			Timeout:   metav1.Duration{Duration: 10 * time.Minute},
+			Timeout:   metav1.Duration{Duration: 10*time.Minute + stepper.drainTimeout()},
 			ErrIgnore: false,
 			Commands: []v1.Command{
 				{
 					Type: v1.CommandShell,
 					ShellCommand: []string{"/bin/bash", "-c", fmt.Sprintf(`
-kubeadm upgrade node
+%s
 sleep 10
-kubectl drain %s --ignore-daemonsets || true
+%s
 systemctl stop kubelet
 systemctl daemon-reload && systemctl restart kubelet
 kubectl uncordon %s || true`,
-						extraMetadata.GetMasterHostname(masters[i].ID), extraMetadata.GetMasterHostname(masters[i].ID))},
+						upgradeCmd, stepper.evictCommand(hostname), hostname)},
 				},
 			},
 			RetryTimes: 0,
-		})
+		}
+		stepper.installSteps = append(stepper.installSteps, step)
+		stepper.addNodeSteps(step.ID, v1.OperationNodeSteps{Start: []string{masters[i].ID}, Done: []string{masters[i].ID}})
+		if i == 0 && stepper.skipKubeProxy() && !stepper.applySkipsPhases() {
+			stepper.installSteps = append(stepper.installSteps, stepper.removeKubeProxyStep(utils.UnwrapNodeList(masters)[0]))
+		}
+	}
+	if stepper.Strategy.PauseAfterControlPlane && len(workers) > 0 {
+		stepper.pauseAfterSteps = append(stepper.pauseAfterSteps, stepper.installSteps[len(stepper.installSteps)-1].ID)
 	}
 
-	for i := range workers {
-		stepper.installSteps = append(stepper.installSteps, []v1.Step{
+	batchSize := stepper.Strategy.MaxUnavailable
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	for i := 0; i < len(workers); i += batchSize {
+		end := i + batchSize
+		if end > len(workers) {
+			end = len(workers)
+		}
+		batch := workers[i:end]
+		var hostnames, ids []string
+		for _, node := range batch {
+			hostnames = append(hostnames, extraMetadata.GetWorkerHostname(node.ID))
+			ids = append(ids, node.ID)
+		}
+		batchName := strings.Join(hostnames, ",")
+		steps := []v1.Step{
 			{
 				ID:        strutil.GetUUID(),
-				Name:      fmt.Sprintf("DrainNode-%s", extraMetadata.GetWorkerHostname(workers[i].ID)),
+				Name:      fmt.Sprintf("DrainNode-%s", batchName),
 				Nodes:     []v1.StepNode{utils.UnwrapNodeList(masters)[0]},
 				Action:    v1.ActionInstall,
-				Timeout:   metav1.Duration{Duration: 1 * time.Minute},
-				ErrIgnore: true,
+				Timeout:   metav1.Duration{Duration: 1*time.Minute + stepper.drainTimeout()},
+				ErrIgnore: false,
 				Commands: []v1.Command{
 					{
 						Type:         v1.CommandShell,
-						ShellCommand: []string{"kubectl", "drain", extraMetadata.GetWorkerHostname(workers[i].ID)},
+						ShellCommand: []string{"/bin/bash", "-c", stepper.evictCommand(hostnames...)},
 					},
 				},
 				RetryTimes: 0,
 			},
 			{
 				ID:        strutil.GetUUID(),
-				Name:      fmt.Sprintf("UpgradeWorker-%s", extraMetadata.GetWorkerHostname(workers[i].ID)),
-				Nodes:     []v1.StepNode{utils.UnwrapNodeList(workers)[i]},
+				Name:      fmt.Sprintf("UpgradeWorker-%s", batchName),
+				Nodes:     utils.UnwrapNodeList(batch),
 				Action:    v1.ActionInstall,
 				Timeout:   metav1.Duration{Duration: 10 * time.Minute},
 				ErrIgnore: false,
@@ -370,7 +443,7 @@ systemctl daemon-reload && systemctl restart kubelet`},
 			},
 			{
 				ID:        strutil.GetUUID(),
-				Name:      fmt.Sprintf("UncordonNode-%s", extraMetadata.GetWorkerHostname(workers[i].ID)),
+				Name:      fmt.Sprintf("UncordonNode-%s", batchName),
 				Nodes:     []v1.StepNode{utils.UnwrapNodeList(masters)[0]},
 				Action:    v1.ActionInstall,
 				Timeout:   metav1.Duration{Duration: 1 * time.Minute},
@@ -378,19 +451,156 @@ systemctl daemon-reload && systemctl restart kubelet`},
 				Commands: []v1.Command{
 					{
 						Type:         v1.CommandShell,
-						ShellCommand: []string{"kubectl", "uncordon", extraMetadata.GetWorkerHostname(workers[i].ID)},
+						ShellCommand: append([]string{"kubectl", "uncordon"}, hostnames...),
 					},
 				},
 				RetryTimes: 0,
-			}}...)
+			}}
+		stepper.installSteps = append(stepper.installSteps, steps...)
+		stepper.addNodeSteps(steps[0].ID, v1.OperationNodeSteps{Start: ids})
+		stepper.addNodeSteps(steps[len(steps)-1].ID, v1.OperationNodeSteps{Done: ids})
 	}
 	return nil
 }
 
+// evictCommand drain the nodes if the strategy requires, otherwise the nodes are only cordoned.
+func (stepper *Upgrade) evictCommand(hostnames ...string) string {
+	nodes := strings.Join(hostnames, " ")
+	if !stepper.drain() {
+		return fmt.Sprintf("kubectl cordon %s || true", nodes)
+	}
+	return fmt.Sprintf("kubectl drain %s --ignore-daemonsets --delete-local-data --timeout=%s || exit 1",
+		nodes, stepper.drainTimeout())
+}
+
+// upgradeApplyCommand the kube-proxy addon is skipped for the ebpf proxy mode as 'kubeadm init' does,
+// the kubeadm which does not support skipping phases of upgrade deploys kube-proxy, it is removed by removeKubeProxyStep.
+func (stepper *Upgrade) upgradeApplyCommand() string {
+	cmd := fmt.Sprintf("kubeadm upgrade apply %s -f --ignore-preflight-errors all --config /tmp/.k8s/kubeadm.yaml", stepper.Version)
+	if stepper.skipKubeProxy() && stepper.applySkipsPhases() {
+		cmd += " --skip-phases=addon/kube-proxy"
+	}
+	return cmd
+}
+
+func (stepper *Upgrade) skipKubeProxy() bool {
+	return stepper.Kubeadm != nil && stepper.Kubeadm.Networking.ProxyMode == v1.ProxyModeEBPF
+}
+
+// applySkipsPhases reports whether 'kubeadm upgrade apply' of the target version accepts '--skip-phases'.
+func (stepper *Upgrade) applySkipsPhases() bool {
+	ver, err := k8sversion.ParseSemantic(stepper.Version)
+	if err != nil {
+		return false
+	}
+	return ver.AtLeast(k8sversion.MustParseSemantic(upgradeSkipPhasesVersion))
+}
+
+// removeKubeProxyStep removes the kube-proxy addon redeployed by 'kubeadm upgrade apply', the cni takes over
+// service load balancing in the ebpf proxy mode.
+func (stepper *Upgrade) removeKubeProxyStep(node v1.StepNode) v1.Step {
+	return v1.Step{
+		ID:        strutil.GetUUID(),
+		Name:      "RemoveKubeProxy",
+		Nodes:     []v1.StepNode{node},
+		Action:    v1.ActionInstall,
+		Timeout:   metav1.Duration{Duration: 1 * time.Minute},
+		ErrIgnore: false,
+		Commands: []v1.Command{
+			{
+				Type: v1.CommandShell,
+				ShellCommand: []string{"/bin/bash", "-c", "kubectl -n kube-system delete daemonset kube-proxy --ignore-not-found && " +
+					"kubectl -n kube-system delete configmap kube-proxy --ignore-not-found"},
+			},
+		},
+		RetryTimes: 1,
+	}
+}
+
+func (stepper *Upgrade) drain() bool {
+	return stepper.Strategy.Drain != nil && *stepper.Strategy.Drain
+}
+
+func (stepper *Upgrade) drainTimeout() time.Duration {
+	if !stepper.drain() {
+		return 0
+	}
+	if stepper.Strategy.DrainTimeout.Duration == 0 {
+		return defaultDrainTimeout
+	}
+	return stepper.Strategy.DrainTimeout.Duration
+}
+
+func (stepper *Upgrade) addNodeSteps(stepID string, nodeSteps v1.OperationNodeSteps) {
+	if stepper.nodeSteps == nil {
+		stepper.nodeSteps = make(map[string]v1.OperationNodeSteps)
+	}
+	stepper.nodeSteps[stepID] = nodeSteps
+}
+
 func (stepper *Upgrade) GetInstallSteps() []v1.Step {
 	return stepper.installSteps
 }
 
+// GetNodeSteps returns the steps that change the progress of nodes.
+func (stepper *Upgrade) GetNodeSteps() map[string]v1.OperationNodeSteps {
+	return stepper.nodeSteps
+}
+
+// GetPauseAfterSteps returns the steps after which the operation is paused.
+func (stepper *Upgrade) GetPauseAfterSteps() []string {
+	return stepper.pauseAfterSteps
+}
+
+// UpgradePath returns the versions the cluster is upgraded to one by one, kubeadm only allows upgrading one minor version at a time.
+// Every intermediate minor version uses its latest available patch version, the last version of the path is always the target.
+func UpgradePath(current, target string, available []string) ([]string, error) {
+	cur, err := k8sversion.ParseSemantic(current)
+	if err != nil {
+		return nil, fmt.Errorf("invalid current version %s: %v", current, err)
+	}
+	tgt, err := k8sversion.ParseSemantic(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upgrade version %s: %v", target, err)
+	}
+	if !cur.LessThan(tgt) {
+		return nil, fmt.Errorf("upgrade version %s must be greater than the current version %s", target, current)
+	}
+	if cur.Major() != tgt.Major() {
+		return nil, fmt.Errorf("upgrade across major versions from %s to %s is not supported", current, target)
+	}
+
+	// the latest patch version of every minor version
+	latest := make(map[uint]*k8sversion.Version)
+	raw := make(map[uint]string)
+	targetExist := false
+	for _, v := range available {
+		ver, err := k8sversion.ParseSemantic(v)
+		if err != nil || ver.Major() != tgt.Major() {
+			continue
+		}
+		if ver.String() == tgt.String() {
+			targetExist = true
+		}
+		if l, ok := latest[ver.Minor()]; !ok || l.LessThan(ver) {
+			latest[ver.Minor()] = ver
+			raw[ver.Minor()] = v
+		}
+	}
+	if !targetExist {
+		return nil, fmt.Errorf("kubernetes package %s is not available", target)
+	}
+
+	var path []string
+	for minor := cur.Minor() + 1; minor < tgt.Minor(); minor++ {
+		if _, ok := latest[minor]; !ok {
+			return nil, fmt.Errorf("no kubernetes package available for intermediate version v%d.%d", tgt.Major(), minor)
+		}
+		path = append(path, raw[minor])
+	}
+	return append(path, target), nil
+}
+
 func (stepper *UpgradePackage) Install(ctx context.Context, opts component.Options) ([]byte, error) {
 	// backup kubernetes binary tools
 	_, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "bash", "-c", "mkdir -pv /tmp/.k8s-bak && cp -rf /usr/bin/kubeadm /usr/bin/kubelet /usr/bin/kubectl /tmp/.k8s-bak/")
@@ -463,9 +673,7 @@ func (stepper *ActBackup) Install(ctx context.Context, opts component.Options) (
 	}
 
 	// etcdctl snapshot save
-	cmd := fmt.Sprintf("etcdctl --endpoints=https://%s:2379 --cacert=/etc/kubernetes/pki/etcd/ca.crt  --cert=/etc/kubernetes/pki/etcd/server.crt --key=/etc/kubernetes/pki/etcd/server.key snapshot save %s",
-		ip.String(), stepper.BackupFileName)
-	ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "bash", "-c", cmd)
+	ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "bash", "-c", stepper.snapshotSaveCmd(ip.String()))
 	if err != nil {
 		if ec != nil {
 			logger.Errorf("etcdctl snapshot save failed: %s", ec.StdErr())
@@ -480,7 +688,7 @@ func (stepper *ActBackup) Install(ctx context.Context, opts component.Options) (
 	}
 	defer render.Close()
 
-	store, err := stepper.BackupStoreCreate()
+	store, err := stepper.BackupStoreCreate(ctx)
 	if err != nil {
 		logger.Errorf("create backup store failed: %s", err.Error())
 		return nil, err
@@ -498,7 +706,12 @@ func (stepper *ActBackup) Install(ctx context.Context, opts component.Options) (
 	if err != nil {
 		return nil, err
 	}
-	data, err := os.ReadFile(filepath.Join(stepper.BackupPointRootDir, stepper.BackupFileName))
+	// the encrypted backup is checked after it is decrypted, so the md5 is of the snapshot.
+	md5File := filepath.Join(stepper.BackupPointRootDir, stepper.BackupFileName)
+	if stepper.EncryptionKeyID != "" {
+		md5File = stepper.BackupFileName
+	}
+	data, err := os.ReadFile(md5File)
 	if err != nil {
 		return nil, err
 	}
@@ -526,8 +739,20 @@ func (stepper *ActBackup) Install(ctx context.Context, opts component.Options) (
 	return cfJSON, nil
 }
 
+// snapshotSaveCmd the snapshot of stacked etcd is saved from the local member with the etcd server cert,
+// the external etcd is accessed by the first endpoint with the client cert of kube-apiserver.
+func (stepper *ActBackup) snapshotSaveCmd(ip string) string {
+	if len(stepper.ExternalEtcdEndpoints) > 0 {
+		return fmt.Sprintf("etcdctl --endpoints=%s --cacert=%s --cert=%s --key=%s snapshot save %s",
+			stepper.ExternalEtcdEndpoints[0], ExternalEtcdCAFile, ExternalEtcdCertFile, ExternalEtcdKeyFile, stepper.BackupFileName)
+	}
+	return fmt.Sprintf("etcdctl --endpoints=https://%s:2379 --cacert=/etc/kubernetes/pki/etcd/ca.crt  --cert=/etc/kubernetes/pki/etcd/server.crt --key=/etc/kubernetes/pki/etcd/server.key snapshot save %s",
+		ip, stepper.BackupFileName)
+}
+
 func (stepper *ActBackup) Uninstall(ctx context.Context, opts component.Options) ([]byte, error) {
-	store, err := stepper.BackupStoreCreate()
+	// the backup is deleted without the key.
+	store, err := stepper.createStore()
 	if err != nil {
 		logger.Errorf("create %v backup store failed: %s", stepper.StoreType, err.Error())
 		return nil, err
@@ -639,7 +864,15 @@ func (stepper *ActBackup) makeUninstallSteps(metadata *component.ExtraMetadata)
 	return nil
 }
 
-func (stepper *ActBackup) BackupStoreCreate() (bs.BackupStore, error) {
+func (stepper *ActBackup) BackupStoreCreate(ctx context.Context) (bs.BackupStore, error) {
+	store, err := stepper.createStore()
+	if err != nil {
+		return nil, err
+	}
+	return encryptedBackupStore(ctx, store, stepper.BackupPointName, stepper.EncryptionKeyID)
+}
+
+func (stepper *ActBackup) createStore() (bs.BackupStore, error) {
 	if stepper.StoreType == bs.S3Storage {
 		store := &bs.ObjectStore{
 			Bucket:          stepper.Bucket,
@@ -649,6 +882,9 @@ func (stepper *ActBackup) BackupStoreCreate() (bs.BackupStore, error) {
 		}
 		return store.Create()
 	}
+	if stepper.StoreType == bs.SFTPStorage {
+		return newSftpStore(stepper.SftpConfig)
+	}
 	store := &bs.FilesystemStore{
 		RootDir: stepper.BackupPointRootDir,
 	}
@@ -705,13 +941,22 @@ func (stepper *Recovery) MakeInstallSteps(ctx context.Context, metadata *compone
 		return err
 	}
 
+	etcdNodes := utils.UnwrapNodeList(metadata.Masters)
+	if stepper.ExternalEtcd {
+		if len(metadata.EtcdNodes) == 0 {
+			return errors.New("recovery external etcd requires at least one etcd node")
+		}
+		etcdNodes = utils.UnwrapNodeList(metadata.EtcdNodes)
+		stepper.installSteps = append(stepper.installSteps, stepper.moveAPIServerStep("stopAPIServer",
+			utils.UnwrapNodeList(metadata.Masters), filepath.Join(stepper.ManifestsYaml, apiServerManifest), stepper.TmpStaticYaml))
+	}
 	stepper.installSteps = append(stepper.installSteps, v1.Step{
 		ID:         strutil.GetUUID(),
 		Name:       "recovery",
 		Timeout:    metav1.Duration{Duration: 7 * time.Minute},
 		ErrIgnore:  false,
 		RetryTimes: 0,
-		Nodes:      utils.UnwrapNodeList(metadata.Masters),
+		Nodes:      etcdNodes,
 		Action:     v1.ActionInstall,
 		Commands: []v1.Command{
 			{
@@ -721,6 +966,10 @@ func (stepper *Recovery) MakeInstallSteps(ctx context.Context, metadata *compone
 			},
 		},
 	})
+	if stepper.ExternalEtcd {
+		stepper.installSteps = append(stepper.installSteps, stepper.moveAPIServerStep("startAPIServer",
+			utils.UnwrapNodeList(metadata.Masters), filepath.Join(stepper.TmpStaticYaml, apiServerManifest), stepper.ManifestsYaml))
+	}
 	if len(metadata.Workers) > 0 {
 		stepper.installSteps = append(stepper.installSteps,
 			v1.Step{
@@ -801,6 +1050,25 @@ func (stepper *Recovery) MakeInstallSteps(ctx context.Context, metadata *compone
 	return nil
 }
 
+// moveAPIServerStep stop or start the kube-apiserver by moving its static pod manifest.
+func (stepper *Recovery) moveAPIServerStep(name string, nodes []v1.StepNode, src, dst string) v1.Step {
+	return v1.Step{
+		ID:         strutil.GetUUID(),
+		Name:       name,
+		Timeout:    metav1.Duration{Duration: 1 * time.Minute},
+		ErrIgnore:  false,
+		RetryTimes: 1,
+		Nodes:      nodes,
+		Action:     v1.ActionInstall,
+		Commands: []v1.Command{
+			{
+				Type:         v1.CommandShell,
+				ShellCommand: []string{"bash", "-c", fmt.Sprintf("mkdir -p %s && mv -f %s %s", dst, src, dst)},
+			},
+		},
+	}
+}
+
 func (stepper *Recovery) Install(ctx context.Context, opts component.Options) ([]byte, error) {
 	_, err := stepper.Download(ctx, opts)
 	if err != nil {
@@ -833,22 +1101,30 @@ func (stepper *Recovery) Download(ctx context.Context, opts component.Options) (
 	}
 	downloadFile := filepath.Join(stepper.RestoreDir, stepper.BackupFileName)
 
-	writer, err := os.OpenFile(downloadFile, os.O_CREATE|os.O_RDWR, 0666)
+	writer, err := os.OpenFile(downloadFile, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0666)
 	if err != nil {
 		logger.Errorf("create restore file failed: %s", err.Error())
 		return nil, err
 	}
+	defer writer.Close()
 
-	b, err := stepper.BackupStoreCreate()
+	b, err := stepper.BackupStoreCreate(ctx)
 	if err != nil {
 		logger.Errorf("create backup store failed: %s", err.Error())
 		return nil, err
 	}
-	err = b.Download(ctx, stepper.BackupFileName, writer)
+	checksum := bs.NewChecksum()
+	err = b.Download(ctx, stepper.BackupFileName, io.MultiWriter(writer, checksum))
 	if err != nil {
 		logger.Errorf("download backup file failed: %s", err.Error())
 		return nil, err
 	}
+	// the snapshot must not be restored if it is not the same as it was saved.
+	if err = checksum.Check(stepper.BackupFileSize, stepper.BackupFileMD5); err != nil {
+		logger.Errorf("check backup file failed: %s", err.Error())
+		_ = os.Remove(downloadFile)
+		return nil, err
+	}
 
 	logger.Info("download backup file successfully")
 
@@ -864,35 +1140,7 @@ func (stepper *Recovery) BeforeRecovery(ctx context.Context, opts component.Opti
 		}
 	}()
 
-	// check the download file's size and md5 value
-	downloadFile := filepath.Join(stepper.RestoreDir, filepath.Base(stepper.BackupFileName))
-	render, err := os.Open(downloadFile)
-	if err != nil {
-		logger.Errorf("open backup file %s failed: %s", downloadFile, err.Error())
-		return nil, err
-	}
-	defer render.Close()
-
-	fileInfo, err := render.Stat()
-	if err != nil {
-		return nil, err
-	}
-	if fileInfo.Size() != stepper.BackupFileSize {
-		logger.Errorf("download backup file size is different from backup file size")
-		return nil, fmt.Errorf("download backup file size is different from backup file size")
-	}
-
-	data, err := os.ReadFile(downloadFile)
-	if err != nil {
-		logger.Errorf("read download backup file failed: %v", err)
-		return nil, err
-	}
-	md5Value := fmt.Sprintf("%x", md5.Sum(data))
-	if md5Value != stepper.BackupFileMD5 {
-		logger.Errorf("download backup file md5 value is different from backup file md5 value")
-		return nil, fmt.Errorf("download backup file md5 value is different from backup file md5 value")
-	}
-
+	// the size and md5 of backup file are checked when it is downloaded.
 	cmd := fmt.Sprintf(`rm -rf %s && mv -bf %s %s`,
 		stepper.TmpStaticYaml, stepper.ManifestsYaml, stepper.TmpStaticYaml)
 	ec, err = cmdutil.RunCmdWithContext(ctx, opts.DryRun, "bash", "-c", cmd)
@@ -905,6 +1153,10 @@ func (stepper *Recovery) BeforeRecovery(ctx context.Context, opts component.Opti
 
 	// wait static pod close
 	err = retryFunc(ctx, 5*time.Second, "check cluster status", func(ctx context.Context) error {
+		if stepper.ExternalEtcd {
+			// there is no apiserver on the etcd nodes, wait for the etcd member to be stopped.
+			return stepper.checkEtcdStopped(opts.DryRun)
+		}
 		ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "bash", "-c", "kubectl get po")
 		if ec != nil {
 			if strings.Contains(ec.StdErr(), "6443 was refused") {
@@ -938,6 +1190,26 @@ func (stepper *Recovery) BeforeRecovery(ctx context.Context, opts component.Opti
 	return nil, nil
 }
 
+func (stepper *Recovery) checkEtcdStopped(dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+	agentConfig, err := config.TryLoadFromDisk()
+	if err != nil {
+		return errors.WithMessage(err, "load agent config")
+	}
+	ip, err := netutil.GetDefaultIP(true, agentConfig.IPDetect)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip.String(), "2379"), 3*time.Second)
+	if err != nil {
+		return nil
+	}
+	_ = conn.Close()
+	return fmt.Errorf("etcd is still running")
+}
+
 func (stepper *Recovery) Recovering(ctx context.Context, opts component.Options) ([]byte, error) {
 	// etcd snapshot
 	hostInfo, err := sysutil.HostInfo()
@@ -1043,7 +1315,31 @@ func (stepper *Recovery) Recovering(ctx context.Context, opts component.Options)
 	return nil, nil
 }
 
-func (stepper *Recovery) BackupStoreCreate() (bs.BackupStore, error) {
+func (stepper *Recovery) BackupStoreCreate(ctx context.Context) (bs.BackupStore, error) {
+	store, err := stepper.createStore()
+	if err != nil {
+		return nil, err
+	}
+	return encryptedBackupStore(ctx, store, stepper.BackupPointName, stepper.EncryptionKeyID)
+}
+
+// encryptedBackupStore wraps the store with the key of id if it is set, the key is resolved from the server.
+func encryptedBackupStore(ctx context.Context, store bs.BackupStore, backupPoint, keyID string) (bs.BackupStore, error) {
+	if keyID == "" {
+		return store, nil
+	}
+	resolve := component.GetBackupKeyResolver(ctx)
+	if resolve == nil {
+		return nil, fmt.Errorf("backup encryption key %s could not be resolved", keyID)
+	}
+	key, err := resolve(ctx, backupPoint, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("resolve backup encryption key %s: %w", keyID, err)
+	}
+	return bs.NewEncryptedStore(store, keyID, key)
+}
+
+func (stepper *Recovery) createStore() (bs.BackupStore, error) {
 	if stepper.StoreType == bs.S3Storage {
 		store := &bs.ObjectStore{
 			Bucket:          stepper.Bucket,
@@ -1053,6 +1349,9 @@ func (stepper *Recovery) BackupStoreCreate() (bs.BackupStore, error) {
 		}
 		return store.Create()
 	}
+	if stepper.StoreType == bs.SFTPStorage {
+		return newSftpStore(stepper.SftpConfig)
+	}
 	store := &bs.FilesystemStore{
 		RootDir: stepper.BackupPointRootDir,
 	}
@@ -1088,3 +1387,42 @@ func retryFunc(ctx context.Context, intervalTime time.Duration, funcName string,
 		}
 	}
 }
+
+func newSftpStore(c *v1.SftpConfig) (bs.BackupStore, error) {
+	if c == nil {
+		return nil, fmt.Errorf("sftp config of backup point is missing")
+	}
+	store := &bs.SftpStore{
+		Host:       c.Host,
+		Port:       c.Port,
+		User:       c.User,
+		Password:   c.Password,
+		PrivateKey: c.PrivateKey,
+		PkPassword: c.PkPassword,
+		RootDir:    c.BackupRootDir,
+	}
+	return store.Create()
+}
+
+// NewBackupStore returns the store of backup point bp which the backups are downloaded from by the server,
+// the backups are decrypted if the key is set.
+func NewBackupStore(bp *v1.BackupPoint, keyID string, key []byte) (bs.BackupStore, error) {
+	r := &Recovery{
+		StoreType:  bp.StorageType,
+		SftpConfig: bp.SftpConfig,
+	}
+	if bp.FsConfig != nil {
+		r.BackupPointRootDir = bp.FsConfig.BackupRootDir
+	}
+	if bp.S3Config != nil {
+		r.Bucket = bp.S3Config.Bucket
+		r.Endpoint = bp.S3Config.Endpoint
+		r.AccessKeyID = bp.S3Config.AccessKeyID
+		r.AccessKeySecret = bp.S3Config.AccessKeySecret
+	}
+	store, err := r.createStore()
+	if err != nil || len(key) == 0 {
+		return store, err
+	}
+	return bs.NewEncryptedStore(store, keyID, key)
+}
@@ -0,0 +1,311 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package k8s
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	bs "github.com/kubeclipper/kubeclipper/pkg/simple/backupstore"
+)
+
+func TestUpgradePath(t *testing.T) {
+	available := []string{"v1.20.13", "v1.21.5", "v1.21.14", "v1.22.17", "v1.22.2", "v1.23.6", "v1.23.9", "v1.25.3"}
+	tests := []struct {
+		name    string
+		current string
+		target  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "patch upgrade",
+			current: "v1.23.6",
+			target:  "v1.23.9",
+			want:    []string{"v1.23.9"},
+		},
+		{
+			name:    "one minor version",
+			current: "v1.22.2",
+			target:  "v1.23.6",
+			want:    []string{"v1.23.6"},
+		},
+		{
+			name:    "multiple minor versions use the latest patch",
+			current: "v1.20.13",
+			target:  "v1.23.6",
+			want:    []string{"v1.21.14", "v1.22.17", "v1.23.6"},
+		},
+		{
+			name:    "intermediate version not available",
+			current: "v1.23.6",
+			target:  "v1.25.3",
+			wantErr: true,
+		},
+		{
+			name:    "target version not available",
+			current: "v1.20.13",
+			target:  "v1.22.3",
+			wantErr: true,
+		},
+		{
+			name:    "downgrade",
+			current: "v1.23.6",
+			target:  "v1.22.17",
+			wantErr: true,
+		},
+		{
+			name:    "same version",
+			current: "v1.23.6",
+			target:  "v1.23.6",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UpgradePath(tt.current, tt.target, available)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpgradePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UpgradePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpgrade_InitSteps(t *testing.T) {
+	extra := component.ExtraMetadata{
+		ClusterName: "test",
+		KubeVersion: "v1.23.6",
+		Masters: []component.Node{
+			{ID: "m1", IPv4: "192.168.1.1", Hostname: "master-1"},
+			{ID: "m2", IPv4: "192.168.1.2", Hostname: "master-2"},
+		},
+		Workers: []component.Node{
+			{ID: "w1", IPv4: "192.168.1.4", Hostname: "worker-1"},
+			{ID: "w2", IPv4: "192.168.1.5", Hostname: "worker-2"},
+			{ID: "w3", IPv4: "192.168.1.6", Hostname: "worker-3"},
+		},
+	}
+	c := &v1.Cluster{KubernetesVersion: "v1.22.17", ContainerRuntime: v1.ContainerRuntime{Type: v1.CRIContainerd}}
+
+	tests := []struct {
+		name      string
+		strategy  v1.UpgradeStrategy
+		batches   []string
+		evictCmd  string
+		pauseStep string
+	}{
+		{
+			name:     "default strategy",
+			strategy: DefaultUpgradeStrategy(),
+			batches:  []string{"worker-1", "worker-2", "worker-3"},
+			evictCmd: "kubectl drain worker-1 --ignore-daemonsets --delete-local-data --timeout=5m0s || exit 1",
+		},
+		{
+			name:      "cordon in batches and pause",
+			strategy:  v1.UpgradeStrategy{MaxUnavailable: 2, PauseAfterControlPlane: true},
+			batches:   []string{"worker-1,worker-2", "worker-3"},
+			evictCmd:  "kubectl cordon worker-1 worker-2 || true",
+			pauseStep: "UpgradeControlPlane-master-2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stepper := &Upgrade{}
+			stepper.InitStepper(&extra, c)
+			stepper.Strategy = tt.strategy
+			if err := stepper.InitSteps(component.WithExtraMetadata(context.TODO(), extra)); err != nil {
+				t.Fatalf("InitSteps() error: %v", err)
+			}
+			var batches []string
+			steps := make(map[string]v1.Step)
+			for _, step := range stepper.GetInstallSteps() {
+				steps[step.ID] = step
+				if strings.HasPrefix(step.Name, "UpgradeWorker-") {
+					batches = append(batches, strings.TrimPrefix(step.Name, "UpgradeWorker-"))
+				}
+				if step.Name == "DrainNode-"+tt.batches[0] && step.Commands[0].ShellCommand[2] != tt.evictCmd {
+					t.Errorf("evict command = %s, want %s", step.Commands[0].ShellCommand[2], tt.evictCmd)
+				}
+			}
+			if !reflect.DeepEqual(batches, tt.batches) {
+				t.Errorf("worker batches = %v, want %v", batches, tt.batches)
+			}
+
+			var pauseSteps []string
+			for _, id := range stepper.GetPauseAfterSteps() {
+				pauseSteps = append(pauseSteps, steps[id].Name)
+			}
+			if tt.pauseStep == "" && len(pauseSteps) != 0 || tt.pauseStep != "" && !reflect.DeepEqual(pauseSteps, []string{tt.pauseStep}) {
+				t.Errorf("pause after steps = %v, want %s", pauseSteps, tt.pauseStep)
+			}
+
+			// every node is started and done once
+			started, done := make(map[string]int), make(map[string]int)
+			for _, nodes := range stepper.GetNodeSteps() {
+				for _, id := range nodes.Start {
+					started[id]++
+				}
+				for _, id := range nodes.Done {
+					done[id]++
+				}
+			}
+			for _, node := range append(extra.Masters, extra.Workers...) {
+				if started[node.ID] != 1 || done[node.ID] != 1 {
+					t.Errorf("node %s is started %d times and done %d times", node.Hostname, started[node.ID], done[node.ID])
+				}
+			}
+		})
+	}
+}
+
+func TestUpgrade_upgradeApplyCommand(t *testing.T) {
+	stepper := &Upgrade{Version: "v1.23.6", Kubeadm: &KubeadmConfig{}}
+	if got := stepper.upgradeApplyCommand(); strings.Contains(got, "--skip-phases") {
+		t.Errorf("upgradeApplyCommand() = %s, must not skip kube-proxy without ebpf", got)
+	}
+	stepper.Kubeadm.Networking.ProxyMode = v1.ProxyModeEBPF
+	if got := stepper.upgradeApplyCommand(); strings.Contains(got, "--skip-phases") {
+		t.Errorf("upgradeApplyCommand() = %s, kubeadm %s does not support skipping phases", got, stepper.Version)
+	}
+	stepper.Version = "v1.31.1"
+	want := "kubeadm upgrade apply v1.31.1 -f --ignore-preflight-errors all --config /tmp/.k8s/kubeadm.yaml --skip-phases=addon/kube-proxy"
+	if got := stepper.upgradeApplyCommand(); got != want {
+		t.Errorf("upgradeApplyCommand() = %s, want %s", got, want)
+	}
+}
+
+func TestUpgrade_InitStepsRemoveKubeProxy(t *testing.T) {
+	extra := component.ExtraMetadata{
+		ClusterName: "test",
+		KubeVersion: "v1.23.6",
+		Masters: []component.Node{
+			{ID: "m1", IPv4: "192.168.1.1", Hostname: "master-1"},
+			{ID: "m2", IPv4: "192.168.1.2", Hostname: "master-2"},
+		},
+	}
+	tests := []struct {
+		version string
+		network v1.Networking
+		want    []string
+	}{
+		{version: "v1.23.6", want: []string{"UpgradeControlPlane-master-1", "UpgradeControlPlane-master-2"}},
+		{version: "v1.23.6", network: v1.Networking{ProxyMode: v1.ProxyModeEBPF},
+			want: []string{"UpgradeControlPlane-master-1", "RemoveKubeProxy", "UpgradeControlPlane-master-2"}},
+		{version: "v1.31.1", network: v1.Networking{ProxyMode: v1.ProxyModeEBPF},
+			want: []string{"UpgradeControlPlane-master-1", "UpgradeControlPlane-master-2"}},
+	}
+	for _, tt := range tests {
+		extra.KubeVersion = tt.version
+		c := &v1.Cluster{KubernetesVersion: "v1.22.17", Networking: tt.network, ContainerRuntime: v1.ContainerRuntime{Type: v1.CRIContainerd}}
+		stepper := &Upgrade{}
+		stepper.InitStepper(&extra, c)
+		if err := stepper.InitSteps(component.WithExtraMetadata(context.TODO(), extra)); err != nil {
+			t.Fatalf("InitSteps() error: %v", err)
+		}
+		var got []string
+		for _, step := range stepper.GetInstallSteps() {
+			if strings.HasPrefix(step.Name, "UpgradeControlPlane-") || step.Name == "RemoveKubeProxy" {
+				got = append(got, step.Name)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("upgrade %s with proxy mode %q steps = %v, want %v", tt.version, tt.network.ProxyMode, got, tt.want)
+		}
+	}
+}
+
+func TestMergeUpgradeStrategy(t *testing.T) {
+	drain := false
+	tests := []struct {
+		name     string
+		strategy *v1.UpgradeStrategy
+		want     v1.UpgradeStrategy
+	}{
+		{name: "no strategy", want: DefaultUpgradeStrategy()},
+		{
+			name:     "only pause",
+			strategy: &v1.UpgradeStrategy{PauseAfterControlPlane: true},
+			want: func() v1.UpgradeStrategy {
+				s := DefaultUpgradeStrategy()
+				s.PauseAfterControlPlane = true
+				return s
+			}(),
+		},
+		{
+			name:     "cordon in batches",
+			strategy: &v1.UpgradeStrategy{MaxUnavailable: 3, Drain: &drain},
+			want:     v1.UpgradeStrategy{MaxUnavailable: 3, Drain: &drain, DrainTimeout: metav1.Duration{Duration: defaultDrainTimeout}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeUpgradeStrategy(tt.strategy); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeUpgradeStrategy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecovery_BackupStoreCreate(t *testing.T) {
+	key, err := bs.GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &Recovery{
+		StoreType:          bs.FSStorage,
+		BackupPointRootDir: t.TempDir(),
+		BackupPointName:    "bp",
+		EncryptionKeyID:    "default",
+	}
+	if _, err = r.BackupStoreCreate(context.TODO()); err == nil {
+		t.Error("BackupStoreCreate() without the key resolver, want error")
+	}
+	var got []string
+	ctx := component.WithBackupKeyResolver(context.TODO(), func(ctx context.Context, backupPoint, keyID string) ([]byte, error) {
+		got = append(got, backupPoint, keyID)
+		return key, nil
+	})
+	store, err := r.BackupStoreCreate(ctx)
+	if err != nil {
+		t.Fatalf("BackupStoreCreate() error = %v", err)
+	}
+	if _, ok := store.(*bs.EncryptedStore); !ok {
+		t.Errorf("BackupStoreCreate() = %T, want encrypted store", store)
+	}
+	if !reflect.DeepEqual(got, []string{"bp", "default"}) {
+		t.Errorf("resolved key of %v, want %v", got, []string{"bp", "default"})
+	}
+
+	r.EncryptionKeyID = ""
+	if store, err = r.BackupStoreCreate(context.TODO()); err != nil {
+		t.Fatalf("BackupStoreCreate() error = %v", err)
+	}
+	if _, ok := store.(*bs.EncryptedStore); ok {
+		t.Error("BackupStoreCreate() of backup not encrypted = encrypted store")
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package k8s
+
+import (
+	"path/filepath"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/strutil"
+)
+
+// The steps below apply the kubeadm config rendered by KubeadmConfig.InstallSteps on the same nodes.
+
+// UpdateControlPlaneConfig regenerate the static pod manifests of the control plane components,
+// then wait for the restarted kube-apiserver to be healthy.
+func UpdateControlPlaneConfig(nodes []v1.StepNode) v1.Step {
+	return v1.Step{
+		ID:         strutil.GetUUID(),
+		Name:       "updateControlPlaneConfig",
+		Timeout:    metav1.Duration{Duration: 5 * time.Minute},
+		ErrIgnore:  false,
+		RetryTimes: 1,
+		Nodes:      nodes,
+		Action:     v1.ActionInstall,
+		Commands: []v1.Command{
+			{
+				Type:         v1.CommandShell,
+				ShellCommand: []string{"kubeadm", "init", "phase", "control-plane", "all", "--config", kubeadmConfigFile()},
+			},
+			{
+				Type: v1.CommandShell,
+				// the kubelet takes a while to notice the changed manifests.
+				ShellCommand: []string{"bash", "-c", "sleep 20 && timeout 240 bash -c 'until curl -fsk https://127.0.0.1:6443/healthz >/dev/null; do sleep 3; done'"},
+			},
+		},
+	}
+}
+
+// UpdateKubeletConfig rewrite the kubelet config and flags, then restart the kubelet.
+func UpdateKubeletConfig(nodes []v1.StepNode) v1.Step {
+	return v1.Step{
+		ID:         strutil.GetUUID(),
+		Name:       "updateKubeletConfig",
+		Timeout:    metav1.Duration{Duration: 3 * time.Minute},
+		ErrIgnore:  false,
+		RetryTimes: 1,
+		Nodes:      nodes,
+		Action:     v1.ActionInstall,
+		Commands: []v1.Command{
+			{
+				Type:         v1.CommandShell,
+				ShellCommand: []string{"kubeadm", "init", "phase", "kubelet-start", "--config", kubeadmConfigFile()},
+			},
+		},
+	}
+}
+
+// UploadClusterConfig store the kubeadm and kubelet config in the cluster, they are used by the nodes joined later.
+func UploadClusterConfig(nodes []v1.StepNode) v1.Step {
+	return v1.Step{
+		ID:         strutil.GetUUID(),
+		Name:       "uploadClusterConfig",
+		Timeout:    metav1.Duration{Duration: 1 * time.Minute},
+		ErrIgnore:  false,
+		RetryTimes: 1,
+		Nodes:      nodes,
+		Action:     v1.ActionInstall,
+		Commands: []v1.Command{
+			{
+				Type:         v1.CommandShell,
+				ShellCommand: []string{"kubeadm", "init", "phase", "upload-config", "all", "--config", kubeadmConfigFile()},
+			},
+		},
+	}
+}
+
+// UpdateKubeProxyConfig update the kube-proxy addon, then restart the kube-proxy pods to load the config.
+func UpdateKubeProxyConfig(nodes []v1.StepNode) v1.Step {
+	return v1.Step{
+		ID:         strutil.GetUUID(),
+		Name:       "updateKubeProxyConfig",
+		Timeout:    metav1.Duration{Duration: 3 * time.Minute},
+		ErrIgnore:  false,
+		RetryTimes: 1,
+		Nodes:      nodes,
+		Action:     v1.ActionInstall,
+		Commands: []v1.Command{
+			{
+				Type:         v1.CommandShell,
+				ShellCommand: []string{"kubeadm", "init", "phase", "addon", "kube-proxy", "--config", kubeadmConfigFile()},
+			},
+			{
+				Type: v1.CommandShell,
+				ShellCommand: []string{"bash", "-c", "kubectl -n kube-system rollout restart daemonset kube-proxy && " +
+					"kubectl -n kube-system rollout status daemonset kube-proxy --timeout=150s"},
+			},
+		},
+	}
+}
+
+func kubeadmConfigFile() string {
+	return filepath.Join(ManifestDir, "kubeadm.yaml")
+}
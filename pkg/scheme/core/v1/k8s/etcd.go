@@ -0,0 +1,476 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/component/utils"
+	"github.com/kubeclipper/kubeclipper/pkg/logger"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/cmdutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/fileutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/strutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sysutil"
+	tmplutil "github.com/kubeclipper/kubeclipper/pkg/utils/template"
+)
+
+var (
+	_ component.StepRunnable = (*EtcdCA)(nil)
+	_ component.StepRunnable = (*EtcdServer)(nil)
+	_ component.StepRunnable = (*EtcdClientCert)(nil)
+)
+
+const (
+	etcdCA         = "etcdCA"
+	etcdServer     = "etcdServer"
+	etcdClientCert = "etcdClientCert"
+
+	// ExternalEtcdCAFile, ExternalEtcdCertFile and ExternalEtcdKeyFile are the certs used by kube-apiserver
+	// to access the external etcd, they follow the kubeadm defaults.
+	ExternalEtcdCAFile   = "/etc/kubernetes/pki/etcd/ca.crt"
+	ExternalEtcdCertFile = "/etc/kubernetes/pki/apiserver-etcd-client.crt"
+	ExternalEtcdKeyFile  = "/etc/kubernetes/pki/apiserver-etcd-client.key"
+	etcdCAKeyFile        = "/etc/kubernetes/pki/etcd/ca.key"
+
+	// the kubelet of a dedicated etcd node only runs the etcd static pod.
+	etcdServiceManagerConf = "/etc/systemd/system/kubelet.service.d/20-etcd-service-manager.conf"
+	etcdKubeadmConfig      = "kubeadm-etcd.yaml"
+)
+
+func init() {
+	if err := component.RegisterAgentStep(fmt.Sprintf(component.RegisterStepKeyFormat, etcdCA, version, component.TypeStep), &EtcdCA{}); err != nil {
+		panic(err)
+	}
+	if err := component.RegisterAgentStep(fmt.Sprintf(component.RegisterStepKeyFormat, etcdServer, version, component.TypeStep), &EtcdServer{}); err != nil {
+		panic(err)
+	}
+	if err := component.RegisterAgentStep(fmt.Sprintf(component.RegisterStepKeyFormat, etcdClientCert, version, component.TypeStep), &EtcdClientCert{}); err != nil {
+		panic(err)
+	}
+}
+
+// EtcdCerts the PEM encoded certs passed between the etcd steps.
+type EtcdCerts struct {
+	CACert     string `json:"caCert"`
+	CAKey      string `json:"caKey,omitempty"`
+	ClientCert string `json:"clientCert"`
+	ClientKey  string `json:"clientKey"`
+}
+
+// EtcdPeer an etcd member.
+type EtcdPeer struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+}
+
+// EtcdEndpoints return the client urls of the etcd members.
+func EtcdEndpoints(nodes component.NodeList) []string {
+	endpoints := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		endpoints = append(endpoints, fmt.Sprintf("https://%s:2379", node.IPv4))
+	}
+	return endpoints
+}
+
+// EtcdInstallSteps make the steps to prepare the external etcd before the control plane is initialized.
+// The managed etcd members are installed on the etcd nodes, and the client certs are distributed to the masters.
+func EtcdInstallSteps(c *v1.Cluster, metadata *component.ExtraMetadata) ([]v1.Step, error) {
+	if !c.Etcd.IsExternal() {
+		return nil, nil
+	}
+	masters := utils.UnwrapNodeList(metadata.Masters)
+	if !c.Etcd.IsManaged() {
+		cert := &EtcdClientCert{
+			CACert:     c.Etcd.External.CACert,
+			ClientCert: c.Etcd.External.ClientCert,
+			ClientKey:  c.Etcd.External.ClientKey,
+		}
+		return cert.InstallSteps(masters)
+	}
+	if len(metadata.EtcdNodes) == 0 {
+		return nil, fmt.Errorf("the etcd nodes is empty")
+	}
+
+	var steps []v1.Step
+	etcdNodes := utils.UnwrapNodeList(metadata.EtcdNodes)
+	ca := &EtcdCA{}
+	caSteps, err := ca.InstallSteps(etcdNodes[:1])
+	if err != nil {
+		return nil, err
+	}
+	steps = append(steps, caSteps...)
+
+	member := &EtcdServer{}
+	memberSteps, err := member.InitStepper(c, metadata).InstallSteps(etcdNodes)
+	if err != nil {
+		return nil, err
+	}
+	steps = append(steps, memberSteps...)
+
+	// the certs are taken from the response of the previous step.
+	cert := &EtcdClientCert{}
+	certSteps, err := cert.InstallSteps(masters)
+	if err != nil {
+		return nil, err
+	}
+	steps = append(steps, certSteps...)
+	return steps, nil
+}
+
+// EtcdUninstallSteps make the steps to clean the managed etcd nodes.
+func EtcdUninstallSteps(c *v1.Cluster, metadata *component.ExtraMetadata) ([]v1.Step, error) {
+	if !c.Etcd.IsManaged() || len(metadata.EtcdNodes) == 0 {
+		return nil, nil
+	}
+	etcdNodes := utils.UnwrapNodeList(metadata.EtcdNodes)
+	return []v1.Step{
+		doCommandRemoveStep("clearEtcdDatabase", etcdNodes, c.Etcd.DataDir),
+		doCommandRemoveStep("removeEtcdConfig", etcdNodes, K8SDefaultConfigDir, etcdServiceManagerConf),
+	}, nil
+}
+
+// EtcdCA generate the etcd ca and the client cert of kube-apiserver.
+type EtcdCA struct{}
+
+func (stepper *EtcdCA) InstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	bytes, err := json.Marshal(stepper)
+	if err != nil {
+		return nil, err
+	}
+	return []v1.Step{
+		{
+			ID:         strutil.GetUUID(),
+			Name:       "generateEtcdCA",
+			Timeout:    metav1.Duration{Duration: 1 * time.Minute},
+			ErrIgnore:  false,
+			RetryTimes: 1,
+			Nodes:      nodes,
+			Action:     v1.ActionInstall,
+			Commands: []v1.Command{
+				{
+					Type:          v1.CommandCustom,
+					Identity:      fmt.Sprintf(component.RegisterStepKeyFormat, etcdCA, version, component.TypeStep),
+					CustomCommand: bytes,
+				},
+			},
+		},
+	}, nil
+}
+
+func (stepper *EtcdCA) NewInstance() component.ObjectMeta {
+	return &EtcdCA{}
+}
+
+func (stepper *EtcdCA) Install(ctx context.Context, opts component.Options) ([]byte, error) {
+	// kubeadm init phase certs etcd-ca
+	// kubeadm init phase certs apiserver-etcd-client
+	for _, phase := range []string{"etcd-ca", "apiserver-etcd-client"} {
+		ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "kubeadm", "init", "phase", "certs", phase)
+		if err != nil {
+			logger.Error("generate etcd certs failed", zap.String("phase", phase), zap.String("stderr", ec.StdErr()))
+			return nil, err
+		}
+	}
+	if opts.DryRun {
+		return json.Marshal(EtcdCerts{})
+	}
+	certs, err := readEtcdCerts(ExternalEtcdCAFile, etcdCAKeyFile, ExternalEtcdCertFile, ExternalEtcdKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(certs)
+}
+
+func (stepper *EtcdCA) Uninstall(ctx context.Context, opts component.Options) ([]byte, error) {
+	return nil, nil
+}
+
+// EtcdServer run an etcd member as a static pod which is managed by a standalone kubelet.
+// See https://kubernetes.io/docs/setup/production-environment/tools/kubeadm/setup-ha-etcd-with-kubeadm/
+type EtcdServer struct {
+	ClusterConfigAPIVersion string     `json:"clusterConfigAPIVersion"`
+	KubernetesVersion       string     `json:"kubernetesVersion"`
+	LocalRegistry           string     `json:"localRegistry"`
+	Offline                 bool       `json:"offline"`
+	CRISocket               string     `json:"criSocket"`
+	DataDir                 string     `json:"dataDir"`
+	Peers                   []EtcdPeer `json:"peers"`
+	// Name and IP are filled by agent with the current member.
+	Name string `json:"name,omitempty"`
+	IP   string `json:"ip,omitempty"`
+}
+
+func (stepper *EtcdServer) InitStepper(c *v1.Cluster, metadata *component.ExtraMetadata) *EtcdServer {
+	stepper.KubernetesVersion = c.KubernetesVersion
+	stepper.LocalRegistry = c.LocalRegistry
+	stepper.Offline = metadata.Offline
+	stepper.DataDir = c.Etcd.DataDir
+	// the kubelet talks to docker through the built-in dockershim.
+	if c.ContainerRuntime.Type != v1.CRIDocker {
+		stepper.CRISocket = CRISocket(c.ContainerRuntime.Type)
+		if !strings.Contains(stepper.CRISocket, "://") {
+			stepper.CRISocket = "unix://" + stepper.CRISocket
+		}
+	}
+	stepper.Peers = make([]EtcdPeer, 0, len(metadata.EtcdNodes))
+	for _, node := range metadata.EtcdNodes {
+		stepper.Peers = append(stepper.Peers, EtcdPeer{Name: node.Hostname, IP: node.IPv4})
+	}
+	return stepper
+}
+
+func (stepper *EtcdServer) InstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	bytes, err := json.Marshal(stepper)
+	if err != nil {
+		return nil, err
+	}
+	return []v1.Step{
+		{
+			ID:         strutil.GetUUID(),
+			Name:       "installEtcdMember",
+			Timeout:    metav1.Duration{Duration: 10 * time.Minute},
+			ErrIgnore:  false,
+			RetryTimes: 1,
+			Nodes:      nodes,
+			Action:     v1.ActionInstall,
+			Commands: []v1.Command{
+				{
+					Type:          v1.CommandCustom,
+					Identity:      fmt.Sprintf(component.RegisterStepKeyFormat, etcdServer, version, component.TypeStep),
+					CustomCommand: bytes,
+				},
+			},
+		},
+	}, nil
+}
+
+func (stepper *EtcdServer) NewInstance() component.ObjectMeta {
+	return &EtcdServer{}
+}
+
+func (stepper *EtcdServer) Install(ctx context.Context, opts component.Options) ([]byte, error) {
+	v := component.GetExtraData(ctx)
+	if v == nil {
+		return nil, fmt.Errorf("no etcd ca received")
+	}
+	certs := EtcdCerts{}
+	if err := json.Unmarshal(v, &certs); err != nil {
+		return nil, err
+	}
+	hostInfo, err := sysutil.HostInfo()
+	if err != nil {
+		return nil, err
+	}
+	for _, peer := range stepper.Peers {
+		if peer.Name == hostInfo.Hostname {
+			stepper.Name, stepper.IP = peer.Name, peer.IP
+		}
+	}
+	if stepper.IP == "" {
+		return nil, fmt.Errorf("node %s is not an etcd member", hostInfo.Hostname)
+	}
+	apiVersion, err := (&KubeadmConfig{KubernetesVersion: stepper.KubernetesVersion}).matchClusterConfigAPIVersion()
+	if err != nil {
+		return nil, err
+	}
+	stepper.ClusterConfigAPIVersion = apiVersion
+	if !stepper.Offline && stepper.LocalRegistry == "" {
+		stepper.LocalRegistry = component.GetRepoMirror(ctx)
+	}
+
+	// all members share the same ca, the member certs are signed on each node.
+	if !opts.DryRun {
+		if err = writeEtcdCerts(map[string]string{ExternalEtcdCAFile: certs.CACert, etcdCAKeyFile: certs.CAKey}); err != nil {
+			return nil, err
+		}
+	}
+	if err = os.MkdirAll(filepath.Dir(etcdServiceManagerConf), 0755); err != nil {
+		return nil, err
+	}
+	if err = fileutil.WriteFileWithContext(ctx, etcdServiceManagerConf, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644,
+		stepper.renderKubeletService, opts.DryRun); err != nil {
+		return nil, err
+	}
+	for _, cmd := range [][]string{{"systemctl", "daemon-reload"}, {"systemctl", "restart", "kubelet"}} {
+		if ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, cmd[0], cmd[1:]...); err != nil {
+			logger.Error("start standalone kubelet failed", zap.String("stderr", ec.StdErr()))
+			return nil, err
+		}
+	}
+
+	if err = os.MkdirAll(ManifestDir, 0755); err != nil {
+		return nil, err
+	}
+	manifestFile := filepath.Join(ManifestDir, etcdKubeadmConfig)
+	if err = fileutil.WriteFileWithContext(ctx, manifestFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644,
+		stepper.renderTo, opts.DryRun); err != nil {
+		return nil, err
+	}
+	// kubeadm init phase certs etcd-server --config kubeadm-etcd.yaml
+	// kubeadm init phase etcd local --config kubeadm-etcd.yaml
+	phases := [][]string{
+		{"certs", "etcd-server"},
+		{"certs", "etcd-peer"},
+		{"certs", "etcd-healthcheck-client"},
+		{"etcd", "local"},
+	}
+	for _, phase := range phases {
+		args := append([]string{"init", "phase"}, phase...)
+		args = append(args, "--config", manifestFile)
+		ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "kubeadm", args...)
+		if err != nil {
+			logger.Error("install etcd member failed", zap.Strings("phase", phase), zap.String("stderr", ec.StdErr()))
+			return nil, err
+		}
+	}
+
+	// the ca key is kept on the etcd nodes only.
+	certs.CAKey = ""
+	return json.Marshal(certs)
+}
+
+func (stepper *EtcdServer) Uninstall(ctx context.Context, opts component.Options) ([]byte, error) {
+	return nil, nil
+}
+
+func (stepper *EtcdServer) initialCluster() string {
+	members := make([]string, 0, len(stepper.Peers))
+	for _, peer := range stepper.Peers {
+		members = append(members, fmt.Sprintf("%s=https://%s:2380", peer.Name, peer.IP))
+	}
+	return strings.Join(members, ",")
+}
+
+func (stepper *EtcdServer) renderTo(w io.Writer) error {
+	at := tmplutil.New()
+	_, err := at.RenderTo(w, etcdServerTemplate, map[string]interface{}{
+		"Member":         stepper,
+		"InitialCluster": stepper.initialCluster(),
+	})
+	return err
+}
+
+func (stepper *EtcdServer) renderKubeletService(w io.Writer) error {
+	at := tmplutil.New()
+	_, err := at.RenderTo(w, etcdKubeletServiceTemplate, stepper)
+	return err
+}
+
+// EtcdClientCert write the certs used by kube-apiserver to access the external etcd.
+// The certs are taken from the response of the previous step if they are not specified.
+type EtcdClientCert struct {
+	CACert     string `json:"caCert,omitempty"`
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
+}
+
+func (stepper *EtcdClientCert) InstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	bytes, err := json.Marshal(stepper)
+	if err != nil {
+		return nil, err
+	}
+	return []v1.Step{
+		{
+			ID:         strutil.GetUUID(),
+			Name:       "configEtcdClientCert",
+			Timeout:    metav1.Duration{Duration: 1 * time.Minute},
+			ErrIgnore:  false,
+			RetryTimes: 1,
+			Nodes:      nodes,
+			Action:     v1.ActionInstall,
+			Commands: []v1.Command{
+				{
+					Type:          v1.CommandCustom,
+					Identity:      fmt.Sprintf(component.RegisterStepKeyFormat, etcdClientCert, version, component.TypeStep),
+					CustomCommand: bytes,
+				},
+			},
+		},
+	}, nil
+}
+
+func (stepper *EtcdClientCert) NewInstance() component.ObjectMeta {
+	return &EtcdClientCert{}
+}
+
+func (stepper *EtcdClientCert) Install(ctx context.Context, opts component.Options) ([]byte, error) {
+	if stepper.CACert == "" {
+		v := component.GetExtraData(ctx)
+		if v == nil {
+			return nil, fmt.Errorf("no etcd client certs received")
+		}
+		certs := EtcdCerts{}
+		if err := json.Unmarshal(v, &certs); err != nil {
+			return nil, err
+		}
+		stepper.CACert, stepper.ClientCert, stepper.ClientKey = certs.CACert, certs.ClientCert, certs.ClientKey
+	}
+	if opts.DryRun {
+		return nil, nil
+	}
+	return nil, writeEtcdCerts(map[string]string{
+		ExternalEtcdCAFile:   stepper.CACert,
+		ExternalEtcdCertFile: stepper.ClientCert,
+		ExternalEtcdKeyFile:  stepper.ClientKey,
+	})
+}
+
+func (stepper *EtcdClientCert) Uninstall(ctx context.Context, opts component.Options) ([]byte, error) {
+	return nil, nil
+}
+
+func readEtcdCerts(caCert, caKey, clientCert, clientKey string) (*EtcdCerts, error) {
+	files := []string{caCert, caKey, clientCert, clientKey}
+	contents := make([]string, len(files))
+	for i, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		contents[i] = string(data)
+	}
+	return &EtcdCerts{CACert: contents[0], CAKey: contents[1], ClientCert: contents[2], ClientKey: contents[3]}, nil
+}
+
+func writeEtcdCerts(files map[string]string) error {
+	for file, content := range files {
+		if content == "" {
+			return fmt.Errorf("the content of %s is empty", file)
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package k8s
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func TestKubeadmConfig_renderExternalEtcd(t *testing.T) {
+	stepper := &KubeadmConfig{
+		ClusterConfigAPIVersion: "v1beta3",
+		Etcd: v1.Etcd{
+			DataDir: "/var/lib/etcd",
+			External: &v1.ExternalEtcd{
+				Endpoints: []string{"https://192.168.10.1:2379", "https://192.168.10.2:2379"},
+			},
+		},
+		Networking: v1.Networking{
+			Services: v1.NetworkRanges{CIDRBlocks: []string{"10.96.0.0/16"}},
+			Pods:     v1.NetworkRanges{CIDRBlocks: []string{"172.25.0.0/16"}},
+		},
+		KubernetesVersion: "v1.23.6",
+	}
+	w := &bytes.Buffer{}
+	if err := stepper.renderTo(w); err != nil {
+		t.Fatalf("renderTo() error = %v", err)
+	}
+	want := `etcd:
+  external:
+    endpoints:
+    - https://192.168.10.1:2379
+    - https://192.168.10.2:2379
+    caFile: /etc/kubernetes/pki/etcd/ca.crt
+    certFile: /etc/kubernetes/pki/apiserver-etcd-client.crt
+    keyFile: /etc/kubernetes/pki/apiserver-etcd-client.key
+networking:`
+	if !strings.Contains(w.String(), want) {
+		t.Errorf("renderTo() got = %s, want contains %s", w.String(), want)
+	}
+	if strings.Contains(w.String(), "local:") {
+		t.Errorf("renderTo() got = %s, the local etcd must not be rendered", w.String())
+	}
+}
+
+func TestEtcdInstallSteps(t *testing.T) {
+	metadata := &component.ExtraMetadata{
+		Masters: []component.Node{{ID: "master-1", IPv4: "192.168.1.1", Hostname: "master-1"}},
+		EtcdNodes: []component.Node{
+			{ID: "etcd-1", IPv4: "192.168.10.1", Hostname: "etcd-1"},
+			{ID: "etcd-2", IPv4: "192.168.10.2", Hostname: "etcd-2"},
+			{ID: "etcd-3", IPv4: "192.168.10.3", Hostname: "etcd-3"},
+		},
+	}
+	tests := []struct {
+		name      string
+		etcd      v1.Etcd
+		wantSteps []string
+	}{
+		{
+			name: "stacked etcd",
+			etcd: v1.Etcd{DataDir: "/var/lib/etcd"},
+		},
+		{
+			name: "existing external etcd",
+			etcd: v1.Etcd{External: &v1.ExternalEtcd{
+				Endpoints: []string{"https://192.168.10.1:2379"},
+				CACert:    "ca", ClientCert: "cert", ClientKey: "key",
+			}},
+			wantSteps: []string{"configEtcdClientCert"},
+		},
+		{
+			name: "managed external etcd",
+			etcd: v1.Etcd{DataDir: "/var/lib/etcd", External: &v1.ExternalEtcd{
+				Nodes: v1.WorkerNodeList{{ID: "etcd-1"}, {ID: "etcd-2"}, {ID: "etcd-3"}},
+			}},
+			wantSteps: []string{"generateEtcdCA", "installEtcdMember", "configEtcdClientCert"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &v1.Cluster{KubernetesVersion: "v1.23.6", Etcd: tt.etcd}
+			c.ContainerRuntime.Type = v1.CRIContainerd
+			steps, err := EtcdInstallSteps(c, metadata)
+			if err != nil {
+				t.Fatalf("EtcdInstallSteps() error = %v", err)
+			}
+			var names []string
+			for _, step := range steps {
+				names = append(names, step.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantSteps, ",") {
+				t.Errorf("EtcdInstallSteps() got = %v, want %v", names, tt.wantSteps)
+			}
+			if tt.etcd.IsManaged() && len(steps[0].Nodes) != 1 {
+				t.Errorf("the etcd ca must be generated on one node")
+			}
+		})
+	}
+}
+
+func TestEtcdServer_renderTo(t *testing.T) {
+	stepper := &EtcdServer{
+		ClusterConfigAPIVersion: "v1beta3",
+		KubernetesVersion:       "v1.23.6",
+		DataDir:                 "/var/lib/etcd",
+		Peers:                   []EtcdPeer{{Name: "etcd-1", IP: "192.168.10.1"}, {Name: "etcd-2", IP: "192.168.10.2"}},
+		Name:                    "etcd-2",
+		IP:                      "192.168.10.2",
+	}
+	w := &bytes.Buffer{}
+	if err := stepper.renderTo(w); err != nil {
+		t.Fatalf("renderTo() error = %v", err)
+	}
+	for _, want := range []string{
+		"initial-cluster: etcd-1=https://192.168.10.1:2380,etcd-2=https://192.168.10.2:2380",
+		"name: etcd-2",
+		"listen-client-urls: https://192.168.10.2:2379",
+	} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("renderTo() got = %s, want contains %s", w.String(), want)
+		}
+	}
+}
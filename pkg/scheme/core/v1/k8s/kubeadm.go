@@ -26,6 +26,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -39,6 +40,7 @@ import (
 	"github.com/kubeclipper/kubeclipper/pkg/component/utils"
 	"github.com/kubeclipper/kubeclipper/pkg/logger"
 	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/cri"
 	"github.com/kubeclipper/kubeclipper/pkg/simple/downloader"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/cmdutil"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/fileutil"
@@ -102,21 +104,23 @@ type KubeadmConfig struct {
 	ClusterConfigAPIVersion string `json:"clusterConfigAPIVersion"`
 	// If both Docker and containerd are detected, Docker takes precedence,so we must specify cri.
 	// https://v1-20.docs.kubernetes.io/docs/setup/production-environment/tools/kubeadm/install-kubeadm/#installing-runtime
-	ContainerRuntime     string        `json:"containerRuntime"`
-	Etcd                 v1.Etcd       `json:"etcd"`
-	Networking           v1.Networking `json:"networking"`
-	KubeProxy            v1.KubeProxy  `json:"kubeProxy"`
-	Kubelet              v1.Kubelet    `json:"kubelet"`
-	ClusterName          string        `json:"clusterName"`
-	KubernetesVersion    string        `json:"kubernetesVersion"`
-	ControlPlaneEndpoint string        `json:"controlPlaneEndpoint"`
-	CertSANs             []string      `json:"certSANs"`
-	LocalRegistry        string        `json:"localRegistry"`
-	Offline              bool          `json:"offline"`
-	IsControlPlane       bool          `json:"isControlPlane,omitempty"`
-	CACertHashes         string        `json:"caCertHashes,omitempty"`
-	BootstrapToken       string        `json:"bootstrapToken,omitempty"`
-	CertificateKey       string        `json:"certificateKey,omitempty"`
+	ContainerRuntime     string                    `json:"containerRuntime"`
+	Etcd                 v1.Etcd                   `json:"etcd"`
+	Networking           v1.Networking             `json:"networking"`
+	KubeProxy            v1.KubeProxy              `json:"kubeProxy"`
+	Kubelet              v1.Kubelet                `json:"kubelet"`
+	ControlPlane         v1.ControlPlaneComponents `json:"controlPlane"`
+	FeatureGates         map[string]bool           `json:"featureGates,omitempty"`
+	ClusterName          string                    `json:"clusterName"`
+	KubernetesVersion    string                    `json:"kubernetesVersion"`
+	ControlPlaneEndpoint string                    `json:"controlPlaneEndpoint"`
+	CertSANs             []string                  `json:"certSANs"`
+	LocalRegistry        string                    `json:"localRegistry"`
+	Offline              bool                      `json:"offline"`
+	IsControlPlane       bool                      `json:"isControlPlane,omitempty"`
+	CACertHashes         string                    `json:"caCertHashes,omitempty"`
+	BootstrapToken       string                    `json:"bootstrapToken,omitempty"`
+	CertificateKey       string                    `json:"certificateKey,omitempty"`
 }
 
 type ControlPlane struct {
@@ -126,6 +130,10 @@ type ControlPlane struct {
 	ContainerRuntime    string
 	ExternalCaCert      string
 	ExternalCaKey       string
+	// SkipKubeProxy do not deploy kube-proxy addon, the cni takes over service load balancing.
+	SkipKubeProxy bool
+	// VIP the control plane vip which must be ready before kubeadm init.
+	VIP *APIServerVIP `json:"vip,omitempty"`
 }
 
 type ClusterNode struct {
@@ -336,6 +344,23 @@ func (stepper *KubeadmConfig) renderTo(w io.Writer) error {
 	return err
 }
 
+// ExtraArgs returns the extra flags of a control plane component, the feature gates are appended if not set explicitly.
+func (stepper *KubeadmConfig) ExtraArgs(component v1.ControlPlaneComponent) map[string]string {
+	args := make(map[string]string, len(component.ExtraArgs)+1)
+	for k, v := range component.ExtraArgs {
+		args[k] = v
+	}
+	if _, ok := args["feature-gates"]; !ok && len(stepper.FeatureGates) > 0 {
+		gates := make([]string, 0, len(stepper.FeatureGates))
+		for name, enabled := range stepper.FeatureGates {
+			gates = append(gates, fmt.Sprintf("%s=%t", name, enabled))
+		}
+		sort.Strings(gates)
+		args["feature-gates"] = strings.Join(gates, ",")
+	}
+	return args
+}
+
 func (stepper *KubeadmConfig) renderJoin(w io.Writer) error {
 	at := tmplutil.New()
 	_, err := at.RenderTo(w, KubeadmJoinTemplate, stepper)
@@ -405,6 +430,11 @@ func (stepper *ControlPlane) Install(ctx context.Context, opts component.Options
 	if err := hosts.Save(); err != nil {
 		return nil, err
 	}
+	if stepper.VIP != nil {
+		if _, err = stepper.VIP.Install(ctx, opts); err != nil {
+			return nil, err
+		}
+	}
 
 	// before run 'kubeadm init', clean the processes 'kube-controller, kube-apiserver, kube-proxy, kube-scheduler, containerd-shim, etcd'
 	pids, err := getProcessID(ctx, opts.DryRun)
@@ -421,7 +451,7 @@ func (stepper *ControlPlane) Install(ctx context.Context, opts component.Options
 		}
 	}
 
-	ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "kubeadm", "init", "--config", "/tmp/.k8s/kubeadm.yaml", "--upload-certs")
+	ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "kubeadm", stepper.initArgs()...)
 	if err != nil {
 		logger.Error("run kubeadm init error", zap.Error(err))
 		return nil, err
@@ -439,6 +469,19 @@ func (stepper *ControlPlane) Install(ctx context.Context, opts component.Options
 	return []byte(fmt.Sprintf("%s,%s", joinControlPlaneCMD, joinWorkerCMD)), nil
 }
 
+// initArgs the vip static pods are rendered before kubeadm init, because kubeadm waits for the control plane
+// through the vip, so the preflight check of the empty manifests dir is ignored.
+func (stepper *ControlPlane) initArgs() []string {
+	initArgs := []string{"init", "--config", "/tmp/.k8s/kubeadm.yaml", "--upload-certs"}
+	if stepper.SkipKubeProxy {
+		initArgs = append(initArgs, "--skip-phases=addon/kube-proxy")
+	}
+	if stepper.VIP != nil {
+		initArgs = append(initArgs, "--ignore-preflight-errors=DirAvailable--etc-kubernetes-manifests")
+	}
+	return initArgs
+}
+
 func (stepper *ControlPlane) externalCa(ctx context.Context, opts component.Options) error {
 	if stepper.ExternalCaCert != "" && stepper.ExternalCaKey != "" {
 		certBytes, err := base64.StdEncoding.DecodeString(stepper.ExternalCaCert)
@@ -847,6 +890,12 @@ func (stepper *Container) Uninstall(ctx context.Context, opts component.Options)
 		if err != nil {
 			logger.Warnf("delete containerd container error: %s", err.Error())
 		}
+	case "crio":
+		// stop and remove all pod sandboxes and their containers
+		_, err = cmdutil.RunCmdWithContext(ctx, opts.DryRun, "crictl", "--runtime-endpoint", cri.CrioSocket, "rmp", "-af")
+		if err != nil {
+			logger.Warnf("delete cri-o pods error: %s", err.Error())
+		}
 	case "docker":
 	// TODO
 	default:
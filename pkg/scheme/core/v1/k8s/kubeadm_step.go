@@ -36,6 +36,7 @@ import (
 	"github.com/kubeclipper/kubeclipper/pkg/service"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/strutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 const (
@@ -94,6 +95,10 @@ func (runnable *Runnable) Validate() error {
 		}
 	}
 
+	if runnable.Networking.ProxyMode == v1.ProxyModeEBPF && !cni.SupportKubeProxyReplacement(runnable.CNI.Type) {
+		return fmt.Errorf("proxy mode ebpf is not supported by cni %s", runnable.CNI.Type)
+	}
+
 	return nil
 }
 
@@ -135,16 +140,25 @@ func (runnable *Runnable) makeInstallSteps(metadata *component.ExtraMetadata) ([
 	c := v1.Cluster(*runnable)
 	nodes := utils.UnwrapNodeList(metadata.GetAllNodes())
 	masters := utils.UnwrapNodeList(metadata.Masters)
+	// the managed etcd nodes run etcd by kubelet as well.
+	hosts := append(nodes, utils.UnwrapNodeList(metadata.EtcdNodes)...)
 
 	var installSteps []v1.Step
-	steps, err := EnvSetupSteps(nodes)
+	steps, err := EnvSetupSteps(hosts)
 	if err != nil {
 		return nil, err
 	}
 	installSteps = append(installSteps, steps...)
+	envSetup := len(installSteps)
 
 	pack := Package{}
-	steps, err = pack.InitStepper(&c).InstallSteps(nodes)
+	steps, err = pack.InitStepper(&c).InstallSteps(hosts)
+	if err != nil {
+		return nil, err
+	}
+	installSteps = append(installSteps, steps...)
+
+	steps, err = EtcdInstallSteps(&c, metadata)
 	if err != nil {
 		return nil, err
 	}
@@ -157,7 +171,7 @@ func (runnable *Runnable) makeInstallSteps(metadata *component.ExtraMetadata) ([
 	}
 	installSteps = append(installSteps, steps...)
 
-	controlPlane := ControlPlane{}
+	controlPlane := ControlPlane{VIP: NewAPIServerVIP(&c, metadata)}
 	steps, err = controlPlane.InitStepper(&c).InstallSteps([]v1.StepNode{masters[0]})
 	if err != nil {
 		return nil, err
@@ -188,6 +202,13 @@ func (runnable *Runnable) makeInstallSteps(metadata *component.ExtraMetadata) ([
 			return nil, err
 		}
 		installSteps = append(installSteps, steps...)
+		if vip := NewAPIServerVIP(&c, metadata); vip != nil {
+			steps, err = vip.InstallSteps(utils.UnwrapNodeList(metadata.Masters)[1:])
+			if err != nil {
+				return nil, err
+			}
+			installSteps = append(installSteps, steps...)
+		}
 	}
 	if len(runnable.Workers) > 0 {
 		cluNode := ClusterNode{}
@@ -236,15 +257,44 @@ func (runnable *Runnable) makeInstallSteps(metadata *component.ExtraMetadata) ([
 		return nil, err
 	}
 	installSteps = append(installSteps, steps...)
+	// the node environment is set up without waiting for any step, see InstallAfterRuntime.
+	v1.ChainSteps(installSteps[:envSetup])
+	v1.ChainSteps(installSteps[envSetup:], installSteps[envSetup-1].ID)
 	return installSteps, nil
 }
 
+// InstallAfterRuntime makes the install steps of kubernetes run after the install steps of container runtime,
+// except the steps setting up the node environment, which run along with the container runtime installation.
+// The packages are installed after the container runtime, the images are loaded into it in offline mode.
+func InstallAfterRuntime(steps, runtimeSteps []v1.Step) {
+	v1.ChainSteps(runtimeSteps)
+	if len(runtimeSteps) == 0 {
+		return
+	}
+	installed := runtimeSteps[len(runtimeSteps)-1].ID
+	roots := sets.NewString()
+	for _, step := range steps {
+		if len(step.DependsOn) == 0 {
+			roots.Insert(step.ID)
+		}
+	}
+	for i := range steps {
+		for _, id := range steps[i].DependsOn {
+			if roots.Has(id) {
+				steps[i].DependsOn = append(steps[i].DependsOn, installed)
+				break
+			}
+		}
+	}
+}
+
 func (runnable *Runnable) makeUninstallSteps(metadata *component.ExtraMetadata) ([]v1.Step, error) {
 	// TODO: need refactor
 
 	c := v1.Cluster(*runnable)
 	nodes := utils.UnwrapNodeList(metadata.GetAllNodes())
 	masters := utils.UnwrapNodeList(metadata.Masters)
+	hosts := append(nodes, utils.UnwrapNodeList(metadata.EtcdNodes)...)
 
 	var uninstallSteps []v1.Step
 
@@ -257,7 +307,7 @@ func (runnable *Runnable) makeUninstallSteps(metadata *component.ExtraMetadata)
 	uninstallSteps = append(uninstallSteps, steps...)
 
 	// exec kubeadm reset
-	steps, err = KubeadmReset(nodes)
+	steps, err = KubeadmReset(hosts)
 	if err != nil {
 		return nil, err
 	}
@@ -265,7 +315,7 @@ func (runnable *Runnable) makeUninstallSteps(metadata *component.ExtraMetadata)
 
 	// NOTE: clean container must after kubeadm reset,see #122450
 	container := Container{}
-	steps, err = container.InitStepper(c.ContainerRuntime.Type).UninstallSteps(nodes)
+	steps, err = container.InitStepper(c.ContainerRuntime.Type).UninstallSteps(hosts)
 	if err != nil {
 		return nil, err
 	}
@@ -278,9 +328,15 @@ func (runnable *Runnable) makeUninstallSteps(metadata *component.ExtraMetadata)
 	}
 	uninstallSteps = append(uninstallSteps, steps...)
 
+	steps, err = EtcdUninstallSteps(&c, metadata)
+	if err != nil {
+		return nil, err
+	}
+	uninstallSteps = append(uninstallSteps, steps...)
+
 	// remove configuration files and rpm packages already installed
 	pack := Package{}
-	steps, err = pack.InitStepper(&c).UninstallSteps(nodes)
+	steps, err = pack.InitStepper(&c).UninstallSteps(hosts)
 	if err != nil {
 		return nil, err
 	}
@@ -380,8 +436,7 @@ func (stepper *Package) UninstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
 }
 
 func (stepper *KubeadmConfig) InitStepper(c *v1.Cluster, metadata *component.ExtraMetadata) *KubeadmConfig {
-	apiServerDomain := APIServerDomainPrefix + strutil.StringDefaultIfEmpty("cluster.local", c.Networking.DNSDomain)
-	cpEndpoint := fmt.Sprintf("%s:6443", apiServerDomain)
+	cpEndpoint := ControlPlaneEndpoint(c)
 	if _, ok := c.Labels[common.LabelClusterProviderName]; ok {
 		cpEndpoint = fmt.Sprintf("%s:6443", metadata.Masters[0].IPv4)
 	}
@@ -392,10 +447,12 @@ func (stepper *KubeadmConfig) InitStepper(c *v1.Cluster, metadata *component.Ext
 	stepper.Networking = c.Networking
 	stepper.KubeProxy = c.KubeProxy
 	stepper.Kubelet = c.Kubelet
+	stepper.ControlPlane = c.ControlPlane
+	stepper.FeatureGates = c.FeatureGates
 	stepper.ClusterName = metadata.ClusterName
 	stepper.KubernetesVersion = c.KubernetesVersion
 	stepper.ControlPlaneEndpoint = cpEndpoint
-	stepper.CertSANs = c.CertSANs
+	stepper.CertSANs = certSANs(c)
 	stepper.LocalRegistry = c.LocalRegistry
 	stepper.Offline = metadata.Offline
 
@@ -436,7 +493,7 @@ func (stepper *KubeadmConfig) JoinSteps(isControlPlane bool, nodes []v1.StepNode
 	}
 	step := v1.Step{
 		ID:         strutil.GetUUID(),
-		Name:       "renderMasterJoinConfig",
+		Name:       "renderWorkerJoinConfig",
 		Timeout:    metav1.Duration{Duration: 1 * time.Minute},
 		ErrIgnore:  false,
 		RetryTimes: 1,
@@ -452,7 +509,7 @@ func (stepper *KubeadmConfig) JoinSteps(isControlPlane bool, nodes []v1.StepNode
 		},
 	}
 	if isControlPlane {
-		step.Name = "renderWorkerJoinConfig"
+		step.Name = "renderMasterJoinConfig"
 	}
 	return []v1.Step{step}, nil
 
@@ -471,6 +528,7 @@ func (stepper *ControlPlane) InitStepper(c *v1.Cluster) *ControlPlane {
 	stepper.ContainerRuntime = c.ContainerRuntime.Type
 	stepper.ExternalCaCert = c.ExternalCaCert
 	stepper.ExternalCaKey = c.ExternalCaKey
+	stepper.SkipKubeProxy = c.Networking.ProxyMode == v1.ProxyModeEBPF
 
 	return stepper
 }
@@ -541,7 +599,8 @@ func (stepper *ClusterNode) InitStepper(c *v1.Cluster, metadata *component.Extra
 	return stepper
 }
 
-func GetKubeConfig(ctx context.Context, name string, node component.Node, deliveryCmd service.CmdDelivery) (string, error) {
+// GetKubeConfig the server of kubeconfig is the control plane vip if set, otherwise it is the node.
+func GetKubeConfig(ctx context.Context, name string, node component.Node, vip *v1.ControlPlaneVIP, deliveryCmd service.CmdDelivery) (string, error) {
 	content, err := deliveryCmd.DeliverCmd(ctx, node.ID, []string{"cat", "/etc/kubernetes/admin.conf"}, 3*time.Minute)
 	if err != nil {
 		logger.Errorf(" cat kubeConfig error: %s", err.Error())
@@ -558,6 +617,9 @@ func GetKubeConfig(ctx context.Context, name string, node component.Node, delive
 	}
 
 	kubeConfig.Clusters[name].Server = fmt.Sprintf("https://%s:6443", node.IPv4)
+	if vip != nil {
+		kubeConfig.Clusters[name].Server = "https://" + vip.Endpoint()
+	}
 	config, err := clientcmd.Write(kubeConfig)
 	if err != nil {
 		return "", err
@@ -20,6 +20,7 @@ package k8s
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/kubeclipper/kubeclipper/pkg/constatns"
@@ -245,3 +246,69 @@ func TestKubeadmConfig_renderJoin(t *testing.T) {
 		})
 	}
 }
+
+func TestKubeadmConfig_ExtraArgs(t *testing.T) {
+	stepper := &KubeadmConfig{FeatureGates: map[string]bool{"B": false, "A": true}}
+	args := stepper.ExtraArgs(v1.ControlPlaneComponent{ExtraArgs: map[string]string{"v": "2"}})
+	if args["v"] != "2" || args["feature-gates"] != "A=true,B=false" {
+		t.Errorf("ExtraArgs() = %v", args)
+	}
+	// the feature gates set explicitly are not overridden.
+	args = stepper.ExtraArgs(v1.ControlPlaneComponent{ExtraArgs: map[string]string{"feature-gates": "C=true"}})
+	if args["feature-gates"] != "C=true" {
+		t.Errorf("ExtraArgs() = %v", args)
+	}
+}
+
+func TestKubeadmConfig_JoinSteps(t *testing.T) {
+	for isControlPlane, want := range map[bool]string{true: "renderMasterJoinConfig", false: "renderWorkerJoinConfig"} {
+		steps, err := (&KubeadmConfig{}).JoinSteps(isControlPlane, nil)
+		if err != nil {
+			t.Fatalf("JoinSteps() error: %v", err)
+		}
+		if steps[0].Name != want {
+			t.Errorf("JoinSteps(%v) step name = %s, want %s", isControlPlane, steps[0].Name, want)
+		}
+	}
+}
+
+func TestKubeadmConfig_renderComponentConfig(t *testing.T) {
+	maxPerCore := int32(0)
+	stepper := &KubeadmConfig{
+		ClusterConfigAPIVersion: "v1beta3",
+		ContainerRuntime:        "containerd",
+		Networking:              v1.Networking{ProxyMode: "ipvs"},
+		KubeProxy: v1.KubeProxy{
+			IPVSScheduler: "lc",
+			Conntrack:     v1.KubeProxyConntrack{MaxPerCore: &maxPerCore},
+		},
+		Kubelet: v1.Kubelet{
+			RootDir:        "/var/lib/kubelet",
+			ExtraArgs:      map[string]string{"v": "2"},
+			MaxPods:        200,
+			SystemReserved: map[string]string{"cpu": "500m"},
+		},
+		ControlPlane: v1.ControlPlaneComponents{
+			APIServer: v1.ControlPlaneComponent{ExtraArgs: map[string]string{"audit-log-maxage": "30"}},
+		},
+		FeatureGates: map[string]bool{"EphemeralContainers": true},
+	}
+	w := &bytes.Buffer{}
+	if err := stepper.renderTo(w); err != nil {
+		t.Fatalf("renderTo() error = %v", err)
+	}
+	for _, want := range []string{
+		"apiServer:\n  extraArgs:\n    audit-log-maxage: \"30\"\n    feature-gates: \"EphemeralContainers=true\"\n",
+		"scheduler:\n  extraArgs:\n    feature-gates: \"EphemeralContainers=true\"\n",
+		"ipvs:\n  scheduler: lc\n",
+		"conntrack:\n  maxPerCore: 0\n",
+		"maxPods: 200\n",
+		"systemReserved:\n  cpu: \"500m\"\n",
+		"featureGates:\n  EphemeralContainers: true\n",
+		"root-dir: /var/lib/kubelet\n    v: \"2\"\n",
+	} {
+		if !strings.Contains(w.String(), want) {
+			t.Errorf("renderTo() missing %q in:\n%s", want, w.String())
+		}
+	}
+}
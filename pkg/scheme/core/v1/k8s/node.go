@@ -22,6 +22,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -30,8 +33,13 @@ import (
 	"github.com/kubeclipper/kubeclipper/pkg/logger"
 	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
 	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/cni"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/cri"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/cmdutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/fileutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/ipvsutil"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/strutil"
+	tmplutil "github.com/kubeclipper/kubeclipper/pkg/utils/template"
+	"github.com/txn2/txeh"
 	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -39,11 +47,15 @@ import (
 var (
 	_ component.StepRunnable = (*JoinCmd)(nil)
 	_ component.StepRunnable = (*Drain)(nil)
+	_ component.StepRunnable = (*EtcdMember)(nil)
+	_ component.StepRunnable = (*IPVSRules)(nil)
 )
 
 const (
 	joinNodeCmd = "joinNodeCmd"
 	drain       = "drain"
+	etcdMember  = "etcdMember"
+	ipvsRules   = "ipvsRules"
 )
 
 func init() {
@@ -53,6 +65,12 @@ func init() {
 	if err := component.RegisterAgentStep(fmt.Sprintf(component.RegisterStepKeyFormat, drain, version, component.TypeStep), &Drain{}); err != nil {
 		panic(err)
 	}
+	if err := component.RegisterAgentStep(fmt.Sprintf(component.RegisterStepKeyFormat, etcdMember, version, component.TypeStep), &EtcdMember{}); err != nil {
+		panic(err)
+	}
+	if err := component.RegisterAgentStep(fmt.Sprintf(component.RegisterStepKeyFormat, ipvsRules, version, component.TypeStep), &IPVSRules{}); err != nil {
+		panic(err)
+	}
 }
 
 type GenNode struct {
@@ -64,6 +82,8 @@ type GenNode struct {
 
 type JoinCmd struct {
 	ContainerRuntime string `json:"containerRuntime"`
+	// IsControlPlane upload control plane certificates and return the control plane join command as well.
+	IsControlPlane bool `json:"isControlPlane,omitempty"`
 }
 
 type KubeadmJoinUtil struct {
@@ -78,6 +98,21 @@ type Drain struct {
 	ExtraArgs []string `json:"extraArgs"`
 }
 
+// EtcdMember remove the stacked etcd members of control plane nodes, it must be run on a remaining control plane node.
+type EtcdMember struct {
+	PeerIPs []string `json:"peerIPs"`
+}
+
+// IPVSRules refresh the apiserver route of worker nodes after the control plane nodes changed.
+type IPVSRules struct {
+	WorkerNodeVIP string `json:"workerNodeVIP"`
+	// master ip
+	Masters             map[string]string `json:"masters"`
+	LocalRegistry       string            `json:"localRegistry"`
+	APIServerDomainName string            `json:"apiServerDomainName"`
+	JoinMasterIP        string            `json:"joinMasterIP"`
+}
+
 func (stepper *GenNode) Validate() error {
 	if stepper == nil {
 		return fmt.Errorf("GenNode object is empty")
@@ -116,53 +151,22 @@ func (stepper *GenNode) MakeInstallSteps(metadata *component.ExtraMetadata, patc
 		}
 		stepper.installSteps = append(stepper.installSteps, steps...)
 
-		if metadata.Offline {
-			cf, err := cni.Load(stepper.Cluster.CNI.Type)
-			if err != nil {
-				return err
-			}
-			cniStepper := cf.Create().InitStep(metadata, &stepper.Cluster.CNI, &stepper.Cluster.Networking)
-			steps, err = cniStepper.LoadImage(patchNodes)
-			if err != nil {
-				return err
-			}
-			stepper.installSteps = append(stepper.installSteps, steps...)
-
-			for _, addon := range metadata.Addons {
-				ad, ok := component.Load(fmt.Sprintf(component.RegisterFormat, addon.Name, addon.Version))
-				if !ok {
-					continue
-				}
-				compMeta := ad.NewInstance()
-				if err := json.Unmarshal(addon.Config.Raw, compMeta); err != nil {
-					return err
-				}
-				newComp, _ := compMeta.(component.Interface)
-				if err := newComp.InitSteps(component.WithExtraMetadata(context.TODO(), *metadata)); err != nil {
-					return err
-				}
-				stepList := newComp.GetInstallSteps()
-				for _, st := range stepList {
-					// TODO: Temporarily use hardcode to adjust, and subsequently optimize the step code for image download and load
-					if strings.Contains(st.Name, "imageLoad") {
-						st.Nodes = patchNodes
-						stepper.installSteps = append(stepper.installSteps, st)
-						break
-					}
-				}
-			}
+		steps, err = LoadOfflineImageSteps(metadata, stepper.Cluster, patchNodes)
+		if err != nil {
+			return err
 		}
+		stepper.installSteps = append(stepper.installSteps, steps...)
 
+		isControlPlane := role == NodeRoleMaster
 		joinCmd := JoinCmd{}
-		steps, err = joinCmd.InitStepper(stepper.Cluster.ContainerRuntime.Type).InstallSteps([]v1.StepNode{masters[0]})
+		steps, err = joinCmd.InitStepper(stepper.Cluster.ContainerRuntime.Type, isControlPlane).InstallSteps([]v1.StepNode{masters[0]})
 		if err != nil {
 			return err
 		}
 		stepper.installSteps = append(stepper.installSteps, steps...)
 
-		// Notice: only support join worker node now.
 		kubeadmConf := KubeadmConfig{}
-		steps, err = kubeadmConf.InitStepper(stepper.Cluster, metadata).JoinSteps(false, patchNodes)
+		steps, err = kubeadmConf.InitStepper(stepper.Cluster, metadata).JoinSteps(isControlPlane, patchNodes)
 		if err != nil {
 			return err
 		}
@@ -174,18 +178,84 @@ func (stepper *GenNode) MakeInstallSteps(metadata *component.ExtraMetadata, patc
 			return err
 		}
 		stepper.installSteps = append(stepper.installSteps, steps...)
+
+		// the etcd member of new control plane node is added by kubeadm join,
+		// the workers need to route apiserver traffic to the new control plane node.
+		if isControlPlane && len(metadata.Workers) > 0 {
+			rules := IPVSRules{}
+			steps, err = rules.InitStepper(stepper.Cluster, metadata).InstallSteps(utils.UnwrapNodeList(metadata.Workers))
+			if err != nil {
+				return err
+			}
+			stepper.installSteps = append(stepper.installSteps, steps...)
+		}
+		// the vip holders on every control plane node must know all of the control plane nodes.
+		if vip := NewAPIServerVIP(stepper.Cluster, metadata); isControlPlane && vip != nil {
+			steps, err = vip.InstallSteps(masters)
+			if err != nil {
+				return err
+			}
+			stepper.installSteps = append(stepper.installSteps, steps...)
+		}
 	}
 
 	return nil
 }
 
-// MakeUninstallSteps make uninstall-steps
-func (stepper *GenNode) MakeUninstallSteps(metadata *component.ExtraMetadata, patchNodes []v1.StepNode) error {
+// LoadOfflineImageSteps load the images of cni and addons into the container runtime of nodes in offline mode,
+// the container runtime is decided by metadata.CRI.
+func LoadOfflineImageSteps(metadata *component.ExtraMetadata, cluster *v1.Cluster, nodes []v1.StepNode) ([]v1.Step, error) {
+	if !metadata.Offline {
+		return nil, nil
+	}
+	cf, err := cni.Load(cluster.CNI.Type)
+	if err != nil {
+		return nil, err
+	}
+	cniStepper := cf.Create().InitStep(metadata, &cluster.CNI, &cluster.Networking)
+	steps, err := cniStepper.LoadImage(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addon := range metadata.Addons {
+		ad, ok := component.Load(fmt.Sprintf(component.RegisterFormat, addon.Name, addon.Version))
+		if !ok {
+			continue
+		}
+		compMeta := ad.NewInstance()
+		if err := json.Unmarshal(addon.Config.Raw, compMeta); err != nil {
+			return nil, err
+		}
+		newComp, _ := compMeta.(component.Interface)
+		if err := newComp.InitSteps(component.WithExtraMetadata(context.TODO(), *metadata)); err != nil {
+			return nil, err
+		}
+		stepList := newComp.GetInstallSteps()
+		for _, st := range stepList {
+			// TODO: Temporarily use hardcode to adjust, and subsequently optimize the step code for image download and load
+			if strings.Contains(st.Name, "imageLoad") {
+				st.Nodes = nodes
+				steps = append(steps, st)
+				break
+			}
+		}
+	}
+	return steps, nil
+}
+
+// MakeUninstallSteps make uninstall-steps.
+// When removing control plane nodes, metadata.Masters must only contain the remaining control plane nodes.
+func (stepper *GenNode) MakeUninstallSteps(metadata *component.ExtraMetadata, patchNodes []v1.StepNode, role string) error {
 	err := stepper.Validate()
 	if err != nil {
 		return err
 	}
+	if len(metadata.Masters) == 0 {
+		return fmt.Errorf("at least one control plane node must be kept")
+	}
 	masters := utils.UnwrapNodeList(metadata.Masters)
+	isControlPlane := role == NodeRoleMaster
 	if len(stepper.uninstallSteps) == 0 {
 		args := []string{"--ignore-daemonsets", "--delete-local-data"}
 		for _, node := range patchNodes {
@@ -197,16 +267,44 @@ func (stepper *GenNode) MakeUninstallSteps(metadata *component.ExtraMetadata, pa
 			stepper.uninstallSteps = append(stepper.uninstallSteps, steps...)
 		}
 
+		// remove the etcd members through a remaining control plane node before kubeadm reset,
+		// so the etcd cluster will not lose its quorum while the removed nodes are shutting down.
+		// The external etcd is not affected by the control plane nodes.
+		if isControlPlane && !stepper.Cluster.Etcd.IsExternal() {
+			member := EtcdMember{}
+			steps, err := member.InitStepper(patchNodes).UninstallSteps([]v1.StepNode{masters[0]})
+			if err != nil {
+				return err
+			}
+			stepper.uninstallSteps = append(stepper.uninstallSteps, steps...)
+		}
+
 		steps, err := KubeadmReset(patchNodes)
 		if err != nil {
 			return err
 		}
 		stepper.uninstallSteps = append(stepper.uninstallSteps, steps...)
-		// worker nodes don't need to remove etcd data dir
-		stepper.uninstallSteps = append(stepper.uninstallSteps,
-			doCommandRemoveStep("removeKubeletDataDir", patchNodes, KubeletDefaultDataDir),
-			doCommandRemoveStep("removeDockershimDataDir", patchNodes, DockershimDefaultDataDir),
-		)
+		if isControlPlane {
+			kubeletDataDir := strutil.StringDefaultIfEmpty(KubeletDefaultDataDir, stepper.Cluster.Kubelet.RootDir)
+			stepper.uninstallSteps = append(stepper.uninstallSteps,
+				doCommandRemoveStep("clearDatabase", patchNodes,
+					strutil.StringDefaultIfEmpty(EtcdDefaultDataDir, stepper.Cluster.Etcd.DataDir)),
+				doCommandRemoveStep("removeKubeletDataDir", patchNodes, kubeletDataDir),
+				doCommandRemoveStep("removeDockershimDataDir", patchNodes, DockershimDefaultDataDir),
+			)
+			ctl := Kubectl{}
+			steps, err = ctl.InitStepper().UninstallSteps(patchNodes)
+			if err != nil {
+				return err
+			}
+			stepper.uninstallSteps = append(stepper.uninstallSteps, steps...)
+		} else {
+			// worker nodes don't need to remove etcd data dir
+			stepper.uninstallSteps = append(stepper.uninstallSteps,
+				doCommandRemoveStep("removeKubeletDataDir", patchNodes, KubeletDefaultDataDir),
+				doCommandRemoveStep("removeDockershimDataDir", patchNodes, DockershimDefaultDataDir),
+			)
+		}
 		heal := Health{}
 		steps, err = heal.InitStepper().UninstallSteps(&stepper.Cluster.Networking, patchNodes...)
 		if err != nil {
@@ -244,6 +342,22 @@ func (stepper *GenNode) MakeUninstallSteps(metadata *component.ExtraMetadata, pa
 			},
 		})
 
+		// the workers must stop routing apiserver traffic to the removed control plane nodes.
+		if isControlPlane && len(metadata.Workers) > 0 {
+			rules := IPVSRules{}
+			steps, err = rules.InitStepper(stepper.Cluster, metadata).InstallSteps(utils.UnwrapNodeList(metadata.Workers))
+			if err != nil {
+				return err
+			}
+			stepper.uninstallSteps = append(stepper.uninstallSteps, steps...)
+		}
+		if vip := NewAPIServerVIP(stepper.Cluster, metadata); isControlPlane && vip != nil {
+			steps, err = vip.InstallSteps(masters)
+			if err != nil {
+				return err
+			}
+			stepper.uninstallSteps = append(stepper.uninstallSteps, steps...)
+		}
 	}
 
 	return nil
@@ -259,8 +373,9 @@ func (stepper *GenNode) GetSteps(action v1.StepAction) []v1.Step {
 	return nil
 }
 
-func (stepper *JoinCmd) InitStepper(criType string) *JoinCmd {
+func (stepper *JoinCmd) InitStepper(criType string, isControlPlane bool) *JoinCmd {
 	stepper.ContainerRuntime = criType
+	stepper.IsControlPlane = isControlPlane
 	return stepper
 }
 
@@ -366,7 +481,22 @@ func (stepper JoinCmd) Install(ctx context.Context, opts component.Options) ([]b
 	// bytes, err = json.Marshal(cmd)
 	// format: ${master node join command};${worker node join command}
 	// Work around to split out the worker node join command.
-	return []byte("," + strings.Join(cmd.GetCmd(), " ")), nil
+	if !stepper.IsControlPlane {
+		return []byte("," + strings.Join(cmd.GetCmd(), " ")), nil
+	}
+
+	// kubeadm init phase upload-certs --upload-certs
+	// the uploaded certificates will be deleted in two hours, so they need to be uploaded before every control plane join.
+	ec, err = cmdutil.RunCmdWithContext(ctx, opts.DryRun, "kubeadm", "init", "phase", "upload-certs", "--upload-certs")
+	if err != nil {
+		logger.Error("run kubeadm init phase upload-certs error", zap.Error(err))
+		return nil, err
+	}
+	certificateKey := getLastLine(ec.StdOut())
+	if certificateKey == "" {
+		return nil, fmt.Errorf("get control plane certificate key failed")
+	}
+	return []byte(strings.Join(cmd.GetControlPlaneCmd(certificateKey), " ") + "," + strings.Join(cmd.GetCmd(), " ")), nil
 }
 
 func (stepper JoinCmd) Uninstall(ctx context.Context, opts component.Options) ([]byte, error) {
@@ -428,11 +558,213 @@ func (stepper *Drain) Uninstall(ctx context.Context, opts component.Options) (by
 	return
 }
 
+// GetControlPlaneCmd the field order must be kept, KubeadmConfig parses the control plane join command by index.
+func (stepper *KubeadmJoinUtil) GetControlPlaneCmd(certificateKey string) []string {
+	cmd := fmt.Sprintf("kubeadm join %s --token %s --discovery-token-ca-cert-hash %s --control-plane --certificate-key %s",
+		stepper.ControlPlaneEndpoint, stepper.Token, stepper.DiscoveryHash, certificateKey)
+	return strings.Split(cmd, " ")
+}
+
 func (stepper *KubeadmJoinUtil) GetCmd() []string {
 	cmd := fmt.Sprintf("kubeadm join %s --token %s --discovery-token-ca-cert-hash %s",
 		stepper.ControlPlaneEndpoint, stepper.Token, stepper.DiscoveryHash)
-	if stepper.ContainerRuntime == "containerd" {
-		cmd += " --cri-socket /run/containerd/containerd.sock"
+	switch stepper.ContainerRuntime {
+	case "containerd":
+		cmd += " --cri-socket " + ContainerdSocket
+	case "crio":
+		cmd += " --cri-socket " + cri.CrioSocket
 	}
 	return strings.Split(cmd, " ")
 }
+
+func (stepper *EtcdMember) InitStepper(nodes []v1.StepNode) *EtcdMember {
+	for _, node := range nodes {
+		stepper.PeerIPs = append(stepper.PeerIPs, node.IPv4)
+	}
+	return stepper
+}
+
+func (stepper *EtcdMember) InstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	return nil, fmt.Errorf("EtcdMember dose not support install steps")
+}
+
+func (stepper *EtcdMember) UninstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	bytes, err := json.Marshal(stepper)
+	if err != nil {
+		return nil, err
+	}
+	return []v1.Step{
+		{
+			ID:         strutil.GetUUID(),
+			Name:       "removeEtcdMember",
+			Timeout:    metav1.Duration{Duration: 1 * time.Minute},
+			ErrIgnore:  false,
+			RetryTimes: 1,
+			Nodes:      nodes,
+			Action:     v1.ActionUninstall,
+			Commands: []v1.Command{
+				{
+					Type:          v1.CommandCustom,
+					Identity:      fmt.Sprintf(component.RegisterStepKeyFormat, etcdMember, version, component.TypeStep),
+					CustomCommand: bytes,
+				},
+			},
+		},
+	}, nil
+}
+
+func (stepper *EtcdMember) NewInstance() component.ObjectMeta {
+	return &EtcdMember{}
+}
+
+func (stepper *EtcdMember) Install(ctx context.Context, opts component.Options) ([]byte, error) {
+	return nil, fmt.Errorf("EtcdMember dose not support install")
+}
+
+func (stepper *EtcdMember) Uninstall(ctx context.Context, opts component.Options) ([]byte, error) {
+	// etcdctl member list
+	// 8e9e05c52164694d, started, node1, https://192.168.10.1:2380, https://192.168.10.1:2379, false
+	ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "etcdctl", stepper.etcdctlArgs("member", "list")...)
+	if err != nil {
+		logger.Error("etcdctl member list error", zap.Error(err))
+		return nil, err
+	}
+	members := make(map[string]string)
+	for _, line := range strings.Split(ec.StdOut(), "\n") {
+		fields := strings.Split(line, ",")
+		if len(fields) < 4 {
+			continue
+		}
+		members[strings.TrimSpace(fields[3])] = strings.TrimSpace(fields[0])
+	}
+	for _, ip := range stepper.PeerIPs {
+		id, ok := members[fmt.Sprintf("https://%s:2380", ip)]
+		if !ok {
+			logger.Infof("etcd member of %s is not found, it may have been removed", ip)
+			continue
+		}
+		if len(members) <= 1 {
+			return nil, fmt.Errorf("refuse to remove the last etcd member %s", ip)
+		}
+		if _, err = cmdutil.RunCmdWithContext(ctx, opts.DryRun, "etcdctl", stepper.etcdctlArgs("member", "remove", id)...); err != nil {
+			logger.Error("etcdctl member remove error", zap.String("member", id), zap.Error(err))
+			return nil, err
+		}
+		delete(members, fmt.Sprintf("https://%s:2380", ip))
+		logger.Infof("etcd member %s(%s) removed", id, ip)
+	}
+	return nil, nil
+}
+
+func (stepper *EtcdMember) etcdctlArgs(args ...string) []string {
+	return append([]string{
+		"--endpoints=https://127.0.0.1:2379",
+		"--cacert=/etc/kubernetes/pki/etcd/ca.crt",
+		"--cert=/etc/kubernetes/pki/etcd/server.crt",
+		"--key=/etc/kubernetes/pki/etcd/server.key",
+	}, args...)
+}
+
+func (stepper *IPVSRules) InitStepper(c *v1.Cluster, metadata *component.ExtraMetadata) *IPVSRules {
+	apiServerDomain := APIServerDomainPrefix +
+		strutil.StringDefaultIfEmpty("cluster.local", c.Networking.DNSDomain)
+
+	stepper.WorkerNodeVIP = c.Networking.WorkerNodeVip
+	stepper.Masters = metadata.GetMasterNodeIP()
+	stepper.LocalRegistry = c.LocalRegistry
+	stepper.APIServerDomainName = apiServerDomain
+	stepper.JoinMasterIP = metadata.Masters[0].IPv4
+	return stepper
+}
+
+func (stepper *IPVSRules) InstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	bytes, err := json.Marshal(stepper)
+	if err != nil {
+		return nil, err
+	}
+	return []v1.Step{
+		{
+			ID:         strutil.GetUUID(),
+			Name:       "refreshIPVSRules",
+			Timeout:    metav1.Duration{Duration: 1 * time.Minute},
+			ErrIgnore:  false,
+			RetryTimes: 1,
+			Nodes:      nodes,
+			Action:     v1.ActionInstall,
+			Commands: []v1.Command{
+				{
+					Type:          v1.CommandCustom,
+					Identity:      fmt.Sprintf(component.RegisterStepKeyFormat, ipvsRules, version, component.TypeStep),
+					CustomCommand: bytes,
+				},
+			},
+		},
+	}, nil
+}
+
+func (stepper *IPVSRules) UninstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	return nil, fmt.Errorf("IPVSRules dose not support uninstall steps")
+}
+
+func (stepper *IPVSRules) NewInstance() component.ObjectMeta {
+	return &IPVSRules{}
+}
+
+// Install keep the same apiserver route as ClusterNode does when a worker joins the cluster.
+func (stepper *IPVSRules) Install(ctx context.Context, opts component.Options) ([]byte, error) {
+	ha := len(stepper.Masters) > 1 && stepper.WorkerNodeVIP != ""
+	hosts, err := txeh.NewHostsDefault()
+	if err != nil {
+		return nil, err
+	}
+	if ha {
+		hosts.AddHost(stepper.WorkerNodeVIP, stepper.APIServerDomainName)
+	} else {
+		hosts.AddHost(stepper.JoinMasterIP, stepper.APIServerDomainName)
+	}
+	if err = hosts.Save(); err != nil {
+		return nil, err
+	}
+
+	if stepper.WorkerNodeVIP == "" {
+		return nil, nil
+	}
+	// only delete the apiserver virtual server, the other rules belong to kube-proxy.
+	vs := ipvsutil.VirtualServer{
+		Address: stepper.WorkerNodeVIP,
+		Port:    6443,
+	}
+	if err = ipvsutil.DeleteIPVS(&vs, opts.DryRun); err != nil {
+		logger.Warnf("ipvs delete apiserver service error info: %v", err)
+	}
+	manifestFile := filepath.Join(KubeManifestsDir, "kube-lvscare.yaml")
+	if !ha {
+		if err = os.RemoveAll(manifestFile); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	for _, ip := range stepper.Masters {
+		vs.RealServers = append(vs.RealServers, ipvsutil.RealServer{
+			Address: ip,
+			Port:    6443,
+		})
+	}
+	if err = ipvsutil.CreateIPVS(&vs, opts.DryRun); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(KubeManifestsDir, 0755); err != nil {
+		return nil, err
+	}
+	return nil, fileutil.WriteFileWithContext(ctx, manifestFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644,
+		stepper.renderIPVSCarePod, opts.DryRun)
+}
+
+func (stepper *IPVSRules) Uninstall(ctx context.Context, opts component.Options) ([]byte, error) {
+	return nil, fmt.Errorf("IPVSRules dose not support uninstall")
+}
+
+func (stepper *IPVSRules) renderIPVSCarePod(w io.Writer) error {
+	_, err := tmplutil.New().RenderTo(w, lvscareV111, stepper)
+	return err
+}
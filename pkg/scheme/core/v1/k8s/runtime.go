@@ -0,0 +1,318 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/logger"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/cri"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/cmdutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/strutil"
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var (
+	_ component.StepRunnable = (*Cordon)(nil)
+	_ component.StepRunnable = (*KubeletRuntime)(nil)
+)
+
+const (
+	cordon         = "cordon"
+	kubeletRuntime = "kubeletRuntime"
+
+	// DockershimSocket the cri socket of kubelet built-in docker shim.
+	DockershimSocket = "/var/run/dockershim.sock"
+	// ContainerdSocket the cri socket of containerd.
+	ContainerdSocket = "/run/containerd/containerd.sock"
+
+	kubeadmFlagsEnv     = "kubeadm-flags.env"
+	kubeadmFlagsArgsKey = "KUBELET_KUBEADM_ARGS="
+	// AnnotationCRISocket kubeadm records the cri socket of every node in this annotation.
+	AnnotationCRISocket = "kubeadm.alpha.kubernetes.io/cri-socket"
+)
+
+func init() {
+	if err := component.RegisterAgentStep(fmt.Sprintf(component.RegisterStepKeyFormat, cordon, version, component.TypeStep), &Cordon{}); err != nil {
+		panic(err)
+	}
+	if err := component.RegisterAgentStep(fmt.Sprintf(component.RegisterStepKeyFormat, kubeletRuntime, version, component.TypeStep), &KubeletRuntime{}); err != nil {
+		panic(err)
+	}
+}
+
+// CRISocket return the cri socket which kubelet uses to connect to the container runtime.
+func CRISocket(criType string) string {
+	switch criType {
+	case v1.CRIContainerd:
+		return ContainerdSocket
+	case v1.CRICrio:
+		return cri.CrioSocket
+	}
+	return DockershimSocket
+}
+
+// Cordon keep the node out of scheduling while its components are being replaced in place.
+// Unlike Drain, the node object is kept, so it must be run on a control plane node.
+type Cordon struct {
+	Hostname  string   `json:"hostname"`
+	ExtraArgs []string `json:"extraArgs"`
+	// CRISocket update the cri socket annotation of the node before uncordon it, it is ignored if empty.
+	CRISocket string `json:"criSocket,omitempty"`
+}
+
+func (stepper *Cordon) InitStepper(hostname string, extraArgs []string, criSocket string) *Cordon {
+	stepper.Hostname = hostname
+	stepper.ExtraArgs = extraArgs
+	stepper.CRISocket = criSocket
+	return stepper
+}
+
+// InstallSteps cordon and drain the node.
+func (stepper *Cordon) InstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	bytes, err := json.Marshal(stepper)
+	if err != nil {
+		return nil, err
+	}
+	return []v1.Step{
+		{
+			ID:         strutil.GetUUID(),
+			Name:       fmt.Sprintf("cordonNode-%s", stepper.Hostname),
+			Timeout:    metav1.Duration{Duration: 30 * time.Minute},
+			ErrIgnore:  false,
+			RetryTimes: 1,
+			Nodes:      nodes,
+			Action:     v1.ActionInstall,
+			Commands: []v1.Command{
+				{
+					Type:          v1.CommandCustom,
+					Identity:      fmt.Sprintf(component.RegisterStepKeyFormat, cordon, version, component.TypeStep),
+					CustomCommand: bytes,
+				},
+			},
+		},
+	}, nil
+}
+
+// UninstallSteps wait for the node to be ready and uncordon it.
+func (stepper *Cordon) UninstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	bytes, err := json.Marshal(stepper)
+	if err != nil {
+		return nil, err
+	}
+	return []v1.Step{
+		{
+			ID:         strutil.GetUUID(),
+			Name:       fmt.Sprintf("uncordonNode-%s", stepper.Hostname),
+			Timeout:    metav1.Duration{Duration: 10 * time.Minute},
+			ErrIgnore:  false,
+			RetryTimes: 1,
+			Nodes:      nodes,
+			Action:     v1.ActionUninstall,
+			Commands: []v1.Command{
+				{
+					Type:          v1.CommandCustom,
+					Identity:      fmt.Sprintf(component.RegisterStepKeyFormat, cordon, version, component.TypeStep),
+					CustomCommand: bytes,
+				},
+			},
+		},
+	}, nil
+}
+
+func (stepper *Cordon) NewInstance() component.ObjectMeta {
+	return &Cordon{}
+}
+
+func (stepper *Cordon) Install(ctx context.Context, opts component.Options) ([]byte, error) {
+	// kubectl cordon ${node_name}
+	ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "kubectl", "cordon", stepper.Hostname)
+	if err != nil {
+		logger.Error("kubectl cordon node error", zap.String("node", stepper.Hostname), zap.String("stderr", ec.StdErr()))
+		return nil, err
+	}
+	// kubectl drain ${node_name} --ignore-daemonsets --delete-local-data
+	args := append([]string{"drain", stepper.Hostname}, stepper.ExtraArgs...)
+	ec, err = cmdutil.RunCmdWithContext(ctx, opts.DryRun, "kubectl", args...)
+	if err != nil {
+		logger.Error("kubectl drain node error", zap.String("node", stepper.Hostname), zap.String("stderr", ec.StdErr()))
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (stepper *Cordon) Uninstall(ctx context.Context, opts component.Options) ([]byte, error) {
+	// the apiserver may be restarting if the node is the only control plane node, so keep polling until it is back.
+	err := wait.PollImmediateUntil(5*time.Second, func() (bool, error) {
+		ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "kubectl", "get", "node", stepper.Hostname,
+			"-o", `jsonpath={.status.conditions[?(@.type=="Ready")].status}`)
+		if err != nil {
+			logger.Debug("get node status failed, retry later", zap.String("node", stepper.Hostname), zap.Error(err))
+			return false, nil
+		}
+		return opts.DryRun || strings.TrimSpace(ec.StdOut()) == "True", nil
+	}, ctx.Done())
+	if err != nil {
+		return nil, fmt.Errorf("wait for node %s to be ready failed: %v", stepper.Hostname, err)
+	}
+	if stepper.CRISocket != "" {
+		// kubectl annotate node ${node_name} kubeadm.alpha.kubernetes.io/cri-socket=${socket} --overwrite
+		ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "kubectl", "annotate", "node", stepper.Hostname,
+			fmt.Sprintf("%s=%s", AnnotationCRISocket, stepper.CRISocket), "--overwrite")
+		if err != nil {
+			logger.Error("kubectl annotate node error", zap.String("node", stepper.Hostname), zap.String("stderr", ec.StdErr()))
+			return nil, err
+		}
+	}
+	// kubectl uncordon ${node_name}
+	ec, err := cmdutil.RunCmdWithContext(ctx, opts.DryRun, "kubectl", "uncordon", stepper.Hostname)
+	if err != nil {
+		logger.Error("kubectl uncordon node error", zap.String("node", stepper.Hostname), zap.String("stderr", ec.StdErr()))
+		return nil, err
+	}
+	return nil, nil
+}
+
+// StopKubelet stop the kubelet before the container runtime is replaced, it is started again by Package.
+func StopKubelet(nodes []v1.StepNode) v1.Step {
+	return v1.Step{
+		ID:         strutil.GetUUID(),
+		Name:       "stopKubelet",
+		Timeout:    metav1.Duration{Duration: 1 * time.Minute},
+		ErrIgnore:  false,
+		RetryTimes: 1,
+		Nodes:      nodes,
+		Action:     v1.ActionInstall,
+		Commands: []v1.Command{
+			{
+				Type:         v1.CommandShell,
+				ShellCommand: []string{"systemctl", "stop", "kubelet"},
+			},
+		},
+	}
+}
+
+// KubeletRuntime point the kubelet of the node to another container runtime.
+type KubeletRuntime struct {
+	CRISocket string `json:"criSocket"`
+}
+
+func (stepper *KubeletRuntime) InitStepper(criType string) *KubeletRuntime {
+	stepper.CRISocket = CRISocket(criType)
+	return stepper
+}
+
+func (stepper *KubeletRuntime) InstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	bytes, err := json.Marshal(stepper)
+	if err != nil {
+		return nil, err
+	}
+	return []v1.Step{
+		{
+			ID:         strutil.GetUUID(),
+			Name:       "configKubeletRuntime",
+			Timeout:    metav1.Duration{Duration: 10 * time.Second},
+			ErrIgnore:  false,
+			RetryTimes: 1,
+			Nodes:      nodes,
+			Action:     v1.ActionInstall,
+			Commands: []v1.Command{
+				{
+					Type:          v1.CommandCustom,
+					Identity:      fmt.Sprintf(component.RegisterStepKeyFormat, kubeletRuntime, version, component.TypeStep),
+					CustomCommand: bytes,
+				},
+			},
+		},
+	}, nil
+}
+
+func (stepper *KubeletRuntime) UninstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	return nil, fmt.Errorf("KubeletRuntime dose not support uninstall steps")
+}
+
+func (stepper *KubeletRuntime) NewInstance() component.ObjectMeta {
+	return &KubeletRuntime{}
+}
+
+// Install rewrite the runtime flags in kubeadm-flags.env, the kubelet must be restarted to take effect.
+func (stepper *KubeletRuntime) Install(ctx context.Context, opts component.Options) ([]byte, error) {
+	// kubeadm always writes the flags file into the default kubelet dir, whatever the kubelet root dir is.
+	flagsFile := filepath.Join(KubeletDefaultDataDir, kubeadmFlagsEnv)
+	content, err := os.ReadFile(flagsFile)
+	if err != nil {
+		return nil, err
+	}
+	flags, err := rewriteKubeletFlags(string(content), stepper.CRISocket)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun {
+		return nil, nil
+	}
+	return nil, os.WriteFile(flagsFile, []byte(flags), 0644)
+}
+
+func (stepper *KubeletRuntime) Uninstall(ctx context.Context, opts component.Options) ([]byte, error) {
+	return nil, fmt.Errorf("KubeletRuntime dose not support uninstall")
+}
+
+// rewriteKubeletFlags replace the runtime flags of KUBELET_KUBEADM_ARGS, e.g.
+// KUBELET_KUBEADM_ARGS="--network-plugin=cni --pod-infra-container-image=k8s.gcr.io/pause:3.6"
+// is rewritten to
+// KUBELET_KUBEADM_ARGS="--pod-infra-container-image=k8s.gcr.io/pause:3.6 --container-runtime=remote --container-runtime-endpoint=/run/containerd/containerd.sock"
+func rewriteKubeletFlags(content, criSocket string) (string, error) {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, kubeadmFlagsArgsKey) {
+			continue
+		}
+		var (
+			flags []string
+			// the kubelet before v1.24 only talks to cri socket in remote mode, the flag is removed since then.
+			remote bool
+		)
+		for _, arg := range strings.Fields(strings.Trim(strings.TrimPrefix(line, kubeadmFlagsArgsKey), `"`)) {
+			switch strings.SplitN(arg, "=", 2)[0] {
+			case "--container-runtime", "--network-plugin":
+				remote = true
+			case "--container-runtime-endpoint":
+			default:
+				flags = append(flags, arg)
+			}
+		}
+		if remote {
+			flags = append(flags, "--container-runtime=remote")
+		}
+		flags = append(flags, "--container-runtime-endpoint="+criSocket)
+		lines[i] = fmt.Sprintf(`%s"%s"`, kubeadmFlagsArgsKey, strings.Join(flags, " "))
+		return strings.Join(lines, "\n"), nil
+	}
+	return "", fmt.Errorf("%s not found in %s", strings.TrimSuffix(kubeadmFlagsArgsKey, "="), kubeadmFlagsEnv)
+}
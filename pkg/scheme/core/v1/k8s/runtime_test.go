@@ -0,0 +1,52 @@
+package k8s
+
+import (
+	"testing"
+)
+
+func Test_rewriteKubeletFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		socket  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "docker to containerd",
+			content: "KUBELET_KUBEADM_ARGS=\"--network-plugin=cni --pod-infra-container-image=k8s.gcr.io/pause:3.6\"\n",
+			socket:  ContainerdSocket,
+			want:    "KUBELET_KUBEADM_ARGS=\"--pod-infra-container-image=k8s.gcr.io/pause:3.6 --container-runtime=remote --container-runtime-endpoint=/run/containerd/containerd.sock\"\n",
+		},
+		{
+			name:    "containerd to crio",
+			content: "KUBELET_KUBEADM_ARGS=\"--container-runtime=remote --container-runtime-endpoint=/run/containerd/containerd.sock --pod-infra-container-image=k8s.gcr.io/pause:3.6\"",
+			socket:  "unix:///var/run/crio/crio.sock",
+			want:    "KUBELET_KUBEADM_ARGS=\"--pod-infra-container-image=k8s.gcr.io/pause:3.6 --container-runtime=remote --container-runtime-endpoint=unix:///var/run/crio/crio.sock\"",
+		},
+		{
+			name:    "without container runtime flag",
+			content: "KUBELET_KUBEADM_ARGS=\"--container-runtime-endpoint=unix:///var/run/containerd/containerd.sock --pod-infra-container-image=registry.k8s.io/pause:3.9\"",
+			socket:  "unix:///var/run/crio/crio.sock",
+			want:    "KUBELET_KUBEADM_ARGS=\"--pod-infra-container-image=registry.k8s.io/pause:3.9 --container-runtime-endpoint=unix:///var/run/crio/crio.sock\"",
+		},
+		{
+			name:    "args not found",
+			content: "KUBELET_EXTRA_ARGS=\"\"",
+			socket:  ContainerdSocket,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rewriteKubeletFlags(tt.content, tt.socket)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("rewriteKubeletFlags() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("rewriteKubeletFlags() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -22,6 +22,14 @@ const kubeadmTemplate = `
 kind: ClusterConfiguration
 apiVersion: kubeadm.k8s.io/{{.ClusterConfigAPIVersion}}
 etcd:
+{{- if .Etcd.External}}
+  external:
+    endpoints:{{range .Etcd.External.Endpoints}}
+    - {{.}}{{end}}
+    caFile: ` + ExternalEtcdCAFile + `
+    certFile: ` + ExternalEtcdCertFile + `
+    keyFile: ` + ExternalEtcdKeyFile + `
+{{- else}}
   local:
 {{with .Etcd.DataDir}}    dataDir: "{{.}}"{{end}}
     extraArgs:
@@ -30,6 +38,7 @@ etcd:
       heartbeat-interval: '300'
       quota-backend-bytes: '8589934592'
       snapshot-count: '5000'
+{{- end}}
 networking:
   serviceSubnet: {{ range .Networking.Services.CIDRBlocks }}{{ . }}{{- end }}
   podSubnet: {{ range .Networking.Pods.CIDRBlocks }}{{ . }}{{- end }}
@@ -37,6 +46,10 @@ networking:
 kubernetesVersion: {{.KubernetesVersion}}
 controlPlaneEndpoint: {{.ControlPlaneEndpoint}}
 apiServer:
+{{- with $.ExtraArgs .ControlPlane.APIServer}}
+  extraArgs:{{range $k, $v := .}}
+    {{$k}}: {{quote $v}}{{end}}
+{{- end}}
   extraVolumes:
   - name: localtime
     hostPath: "/etc/localtime"
@@ -46,6 +59,10 @@ apiServer:
   certSANs:{{range .CertSANs}}
   - {{.}}{{end}}
 controllerManager:
+{{- with $.ExtraArgs .ControlPlane.ControllerManager}}
+  extraArgs:{{range $k, $v := .}}
+    {{$k}}: {{quote $v}}{{end}}
+{{- end}}
   extraVolumes:
   - name: localtime
     hostPath: "/etc/localtime"
@@ -53,6 +70,10 @@ controllerManager:
     readOnly: true
     pathType: File
 scheduler:
+{{- with $.ExtraArgs .ControlPlane.Scheduler}}
+  extraArgs:{{range $k, $v := .}}
+    {{$k}}: {{quote $v}}{{end}}
+{{- end}}
   extraVolumes:
   - name: localtime
     hostPath: "/etc/localtime"
@@ -65,9 +86,35 @@ scheduler:
 kind: KubeProxyConfiguration
 apiVersion: kubeproxy.config.k8s.io/v1alpha1
 mode: {{if eq .Networking.ProxyMode "ipvs"}}ipvs{{else}}iptables{{end}}
-{{if eq .Networking.ProxyMode "ipvs"}}{{if .Networking.WorkerNodeVip}}ipvs:
+{{- if eq .Networking.ProxyMode "ipvs"}}{{if or .KubeProxy.IPVSScheduler .Networking.WorkerNodeVip}}
+ipvs:
+{{- with .KubeProxy.IPVSScheduler}}
+  scheduler: {{.}}
+{{- end}}
+{{- with .Networking.WorkerNodeVip}}
   excludeCIDRs:
-  - "{{.Networking.WorkerNodeVip}}/32"{{end}}{{end}}
+  - "{{.}}/32"
+{{- end}}
+{{- end}}{{end}}
+{{- with .KubeProxy.Conntrack}}{{if or .MaxPerCore .Min .TCPEstablishedTimeout .TCPCloseWaitTimeout}}
+conntrack:
+{{- with .MaxPerCore}}
+  maxPerCore: {{.}}
+{{- end}}
+{{- with .Min}}
+  min: {{.}}
+{{- end}}
+{{- with .TCPEstablishedTimeout}}
+  tcpEstablishedTimeout: {{.Duration}}
+{{- end}}
+{{- with .TCPCloseWaitTimeout}}
+  tcpCloseWaitTimeout: {{.Duration}}
+{{- end}}
+{{- end}}{{end}}
+{{- with .FeatureGates}}
+featureGates:{{range $k, $v := .}}
+  {{$k}}: {{$v}}{{end}}
+{{- end}}
 ---
 apiVersion: kubelet.config.k8s.io/v1beta1
 authentication:
@@ -87,15 +134,45 @@ staticPodPath: /etc/kubernetes/manifests
 streamingConnectionIdleTimeout: 0s
 syncFrequency: 3s
 volumeStatsAggPeriod: 1m
+{{- with .Kubelet.MaxPods}}
+maxPods: {{.}}
+{{- end}}
+{{- with .Kubelet.EvictionHard}}
+evictionHard:{{range $k, $v := .}}
+  {{$k}}: {{quote $v}}{{end}}
+{{- end}}
+{{- with .Kubelet.EvictionSoft}}
+evictionSoft:{{range $k, $v := .}}
+  {{$k}}: {{quote $v}}{{end}}
+{{- end}}
+{{- with .Kubelet.EvictionSoftGracePeriod}}
+evictionSoftGracePeriod:{{range $k, $v := .}}
+  {{$k}}: {{quote $v}}{{end}}
+{{- end}}
+{{- with .Kubelet.SystemReserved}}
+systemReserved:{{range $k, $v := .}}
+  {{$k}}: {{quote $v}}{{end}}
+{{- end}}
+{{- with .Kubelet.KubeReserved}}
+kubeReserved:{{range $k, $v := .}}
+  {{$k}}: {{quote $v}}{{end}}
+{{- end}}
+{{- with .FeatureGates}}
+featureGates:{{range $k, $v := .}}
+  {{$k}}: {{$v}}{{end}}
+{{- end}}
 ---
 apiVersion: kubeadm.k8s.io/{{.ClusterConfigAPIVersion}}
 kind: InitConfiguration
 nodeRegistration:
 {{- if eq .ContainerRuntime  "containerd"}}
   criSocket: /run/containerd/containerd.sock
+{{else if eq .ContainerRuntime  "crio"}}
+  criSocket: unix:///var/run/crio/crio.sock
 {{end}}
   kubeletExtraArgs:
-    root-dir: {{.Kubelet.RootDir}}
+    root-dir: {{.Kubelet.RootDir}}{{range $k, $v := .Kubelet.ExtraArgs}}
+    {{$k}}: {{quote $v}}{{end}}
 `
 
 const KubeadmJoinTemplate = `apiVersion: kubeadm.k8s.io/{{.ClusterConfigAPIVersion}}
@@ -113,9 +190,12 @@ controlPlane:
 nodeRegistration:
 {{- if eq .ContainerRuntime  "containerd"}}
   criSocket: /run/containerd/containerd.sock
+{{else if eq .ContainerRuntime  "crio"}}
+  criSocket: unix:///var/run/crio/crio.sock
 {{end}}
   kubeletExtraArgs:
-    root-dir: {{.Kubelet.RootDir}}`
+    root-dir: {{.Kubelet.RootDir}}{{range $k, $v := .Kubelet.ExtraArgs}}
+    {{$k}}: {{quote $v}}{{end}}`
 
 const lvscareV111 = `
 apiVersion: v1
@@ -162,6 +242,199 @@ spec:
 status: {}
 `
 
+const kubeVIPPod = `
+apiVersion: v1
+kind: Pod
+metadata:
+  creationTimestamp: null
+  labels:
+    component: kube-vip
+    tier: control-plane
+  name: kube-vip
+  namespace: kube-system
+spec:
+  containers:
+  - args:
+    - manager
+    env:
+    - name: vip_arp
+      value: "true"
+    - name: port
+      value: "{{.Port}}"
+    - name: vip_interface
+      value: {{.Interface}}
+    - name: cp_enable
+      value: "true"
+    - name: cp_namespace
+      value: kube-system
+    - name: vip_leaderelection
+      value: "true"
+    - name: vip_leasename
+      value: plndr-cp-lock
+    - name: address
+      value: {{.Address}}
+    image: {{with .LocalRegistry}}{{.}}/{{end}}plndr/kube-vip:v0.5.0
+    imagePullPolicy: IfNotPresent
+    name: kube-vip
+    resources: {}
+    securityContext:
+      capabilities:
+        add:
+        - NET_ADMIN
+        - NET_RAW
+    volumeMounts:
+    - mountPath: /etc/kubernetes/admin.conf
+      name: kubeconfig
+  hostAliases:
+  - hostnames:
+    - kubernetes
+    ip: 127.0.0.1
+  hostNetwork: true
+  priorityClassName: system-cluster-critical
+  volumes:
+  - hostPath:
+      path: /etc/kubernetes/admin.conf
+    name: kubeconfig
+status: {}
+`
+
+const keepalivedConf = `
+global_defs {
+  router_id LVS_KUBECLIPPER
+  script_user root
+  enable_script_security
+}
+
+vrrp_script check_apiserver {
+  script "/usr/bin/nc -z 127.0.0.1 {{.Port}}"
+  interval 3
+  weight -2
+  fall 10
+  rise 2
+}
+
+vrrp_instance VI_APISERVER {
+  state BACKUP
+  interface {{.Interface}}
+  virtual_router_id {{.RouterID}}
+  priority {{.Priority}}
+  advert_int 1
+  unicast_src_ip {{.LocalIP}}
+  unicast_peer {
+{{- range .Peers}}
+    {{.}}
+{{- end}}
+  }
+  authentication {
+    auth_type PASS
+    auth_pass kc{{.RouterID}}
+  }
+  virtual_ipaddress {
+    {{.Address}}
+  }
+  track_script {
+    check_apiserver
+  }
+}
+`
+
+const keepalivedPod = `
+apiVersion: v1
+kind: Pod
+metadata:
+  annotations:
+    kubeclipper.io/config-hash: {{.ConfigHash}}
+  creationTimestamp: null
+  labels:
+    component: keepalived
+    tier: control-plane
+  name: keepalived
+  namespace: kube-system
+spec:
+  containers:
+  - args:
+    - --copy-service
+    image: {{with .LocalRegistry}}{{.}}/{{end}}osixia/keepalived:2.0.20
+    imagePullPolicy: IfNotPresent
+    name: keepalived
+    resources: {}
+    securityContext:
+      capabilities:
+        add:
+        - NET_ADMIN
+        - NET_BROADCAST
+        - NET_RAW
+    volumeMounts:
+    - mountPath: /container/service/keepalived/assets/keepalived.conf
+      name: config
+      readOnly: true
+  hostNetwork: true
+  priorityClassName: system-cluster-critical
+  volumes:
+  - hostPath:
+      path: {{.ConfigDir}}/keepalived.conf
+      type: File
+    name: config
+status: {}
+`
+
+const haproxyConf = `
+global
+  log stdout format raw local0
+  maxconn 4000
+
+defaults
+  log global
+  mode tcp
+  option tcplog
+  timeout connect 10s
+  timeout client 1h
+  timeout server 1h
+
+frontend kube-apiserver
+  bind *:{{.Port}}
+  default_backend kube-apiserver
+
+backend kube-apiserver
+  balance roundrobin
+  option tcp-check
+{{- range $i, $ip := .Masters}}
+  server master-{{$i}} {{$ip}}:6443 check inter 3s fall 3 rise 2
+{{- end}}
+`
+
+const haproxyPod = `
+apiVersion: v1
+kind: Pod
+metadata:
+  annotations:
+    kubeclipper.io/config-hash: {{.ConfigHash}}
+  creationTimestamp: null
+  labels:
+    component: haproxy
+    tier: control-plane
+  name: haproxy
+  namespace: kube-system
+spec:
+  containers:
+  - image: {{with .LocalRegistry}}{{.}}/{{end}}haproxy:2.6
+    imagePullPolicy: IfNotPresent
+    name: haproxy
+    resources: {}
+    volumeMounts:
+    - mountPath: /usr/local/etc/haproxy/haproxy.cfg
+      name: config
+      readOnly: true
+  hostNetwork: true
+  priorityClassName: system-cluster-critical
+  volumes:
+  - hostPath:
+      path: {{.ConfigDir}}/haproxy.cfg
+      type: File
+    name: config
+status: {}
+`
+
 // KubectlPodTemplate kubectl terminal service yaml template
 const KubectlPodTemplate = `
 apiVersion: apps/v1
@@ -238,3 +511,43 @@ subjects:
     name: kc-kubectl
     namespace: kube-system
 `
+
+const etcdServerTemplate = `
+apiVersion: kubeadm.k8s.io/{{.Member.ClusterConfigAPIVersion}}
+kind: InitConfiguration
+nodeRegistration:
+  name: {{.Member.Name}}
+localAPIEndpoint:
+  advertiseAddress: {{.Member.IP}}
+---
+apiVersion: kubeadm.k8s.io/{{.Member.ClusterConfigAPIVersion}}
+kind: ClusterConfiguration
+kubernetesVersion: {{.Member.KubernetesVersion}}
+{{with .Member.LocalRegistry}}imageRepository: {{.}}{{end}}
+etcd:
+  local:
+{{with .Member.DataDir}}    dataDir: "{{.}}"{{end}}
+    serverCertSANs:
+    - "{{.Member.IP}}"
+    peerCertSANs:
+    - "{{.Member.IP}}"
+    extraArgs:
+      initial-cluster: {{.InitialCluster}}
+      initial-cluster-state: new
+      name: {{.Member.Name}}
+      listen-peer-urls: https://{{.Member.IP}}:2380
+      listen-client-urls: https://{{.Member.IP}}:2379
+      advertise-client-urls: https://{{.Member.IP}}:2379
+      initial-advertise-peer-urls: https://{{.Member.IP}}:2380
+      auto-compaction-retention: '1'
+      election-timeout: '1500'
+      heartbeat-interval: '300'
+      quota-backend-bytes: '8589934592'
+      snapshot-count: '5000'
+`
+
+const etcdKubeletServiceTemplate = `[Service]
+ExecStart=
+ExecStart=/usr/bin/kubelet --address=127.0.0.1 --pod-manifest-path=/etc/kubernetes/manifests --cgroup-driver=systemd{{with .CRISocket}} --container-runtime=remote --container-runtime-endpoint={{.}}{{end}}
+Restart=always
+`
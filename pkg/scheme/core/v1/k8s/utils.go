@@ -48,6 +48,12 @@ func getJoinCmdFromStdOut(output string, cutBegin string) string {
 	return strings.Replace(result, "\\\n", "", -1)
 }
 
+// getLastLine get the last non-empty line of the command output.
+func getLastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
 func generateKubeConfig(ctx context.Context) error {
 	if err := fileutil.CreateDirIfNotExists("/root/.kube", 0755); err != nil {
 		return err
@@ -0,0 +1,237 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package k8s
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeclipper/kubeclipper/pkg/agent/config"
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/fileutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/netutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sliceutil"
+	"github.com/kubeclipper/kubeclipper/pkg/utils/strutil"
+	tmplutil "github.com/kubeclipper/kubeclipper/pkg/utils/template"
+)
+
+const (
+	apiServerVIP = "apiServerVIP"
+	// VIPConfigDir the config files of keepalived and haproxy, the static pods mount them from the host.
+	VIPConfigDir = "/etc/kubernetes/vip"
+)
+
+var _ component.StepRunnable = (*APIServerVIP)(nil)
+
+func init() {
+	if err := component.RegisterAgentStep(fmt.Sprintf(component.RegisterStepKeyFormat, apiServerVIP, version, component.TypeStep), &APIServerVIP{}); err != nil {
+		panic(err)
+	}
+}
+
+// APIServerVIP run the holders of control plane vip as static pods on the control plane nodes.
+type APIServerVIP struct {
+	Mode      string `json:"mode"`
+	Address   string `json:"address"`
+	Interface string `json:"interface"`
+	Port      int    `json:"port"`
+	// Masters the ips of all control plane nodes, the first one has the highest keepalived priority.
+	Masters       []string `json:"masters"`
+	LocalRegistry string   `json:"localRegistry"`
+}
+
+// vipNode the node specific values of APIServerVIP which are decided on the node.
+type vipNode struct {
+	*APIServerVIP
+	LocalIP    string
+	Peers      []string
+	Priority   int
+	RouterID   int
+	ConfigDir  string
+	ConfigHash string
+}
+
+// NewAPIServerVIP returns nil if the cluster has no control plane vip.
+func NewAPIServerVIP(c *v1.Cluster, metadata *component.ExtraMetadata) *APIServerVIP {
+	if c.ControlPlaneVIP == nil {
+		return nil
+	}
+	stepper := &APIServerVIP{
+		Mode:          c.ControlPlaneVIP.Mode,
+		Address:       c.ControlPlaneVIP.Address,
+		Interface:     c.ControlPlaneVIP.Interface,
+		Port:          c.ControlPlaneVIP.GetPort(),
+		LocalRegistry: c.LocalRegistry,
+	}
+	for _, node := range metadata.Masters {
+		stepper.Masters = append(stepper.Masters, node.IPv4)
+	}
+	return stepper
+}
+
+// ControlPlaneEndpoint the address of kube-apiserver used by nodes and clients,
+// the control plane vip is preferred over the apiserver domain name.
+func ControlPlaneEndpoint(c *v1.Cluster) string {
+	if c.ControlPlaneVIP != nil {
+		return c.ControlPlaneVIP.Endpoint()
+	}
+	apiServerDomain := APIServerDomainPrefix + strutil.StringDefaultIfEmpty("cluster.local", c.Networking.DNSDomain)
+	return fmt.Sprintf("%s:6443", apiServerDomain)
+}
+
+// certSANs the control plane vip must be in the SANs of kube-apiserver certificate.
+func certSANs(c *v1.Cluster) []string {
+	if c.ControlPlaneVIP == nil || sliceutil.HasString(c.CertSANs, c.ControlPlaneVIP.Address) {
+		return c.CertSANs
+	}
+	return append(append([]string{}, c.CertSANs...), c.ControlPlaneVIP.Address)
+}
+
+func (stepper *APIServerVIP) InstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	bytes, err := json.Marshal(stepper)
+	if err != nil {
+		return nil, err
+	}
+	return []v1.Step{
+		{
+			ID:         strutil.GetUUID(),
+			Name:       "renderAPIServerVIP",
+			Timeout:    metav1.Duration{Duration: 1 * time.Minute},
+			ErrIgnore:  false,
+			RetryTimes: 1,
+			Nodes:      nodes,
+			Action:     v1.ActionInstall,
+			Commands: []v1.Command{
+				{
+					Type:          v1.CommandCustom,
+					Identity:      fmt.Sprintf(component.RegisterStepKeyFormat, apiServerVIP, version, component.TypeStep),
+					CustomCommand: bytes,
+				},
+			},
+		},
+	}, nil
+}
+
+func (stepper *APIServerVIP) UninstallSteps(nodes []v1.StepNode) ([]v1.Step, error) {
+	return nil, fmt.Errorf("APIServerVIP does not support uninstall steps")
+}
+
+func (stepper *APIServerVIP) NewInstance() component.ObjectMeta {
+	return &APIServerVIP{}
+}
+
+// Install render the static pods of vip holders, the kubelet restarts them once their configs changed.
+func (stepper *APIServerVIP) Install(ctx context.Context, opts component.Options) ([]byte, error) {
+	agentConfig, err := config.TryLoadFromDisk()
+	if err != nil {
+		return nil, errors.WithMessage(err, "load agent config")
+	}
+	ip, err := netutil.GetDefaultIP(true, agentConfig.IPDetect)
+	if err != nil {
+		return nil, err
+	}
+	node, err := stepper.nodeValues(ip)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(KubeManifestsDir, 0755); err != nil {
+		return nil, err
+	}
+
+	if stepper.Mode == v1.VIPModeKubeVIP {
+		return nil, stepper.writeFile(ctx, filepath.Join(KubeManifestsDir, "kube-vip.yaml"), kubeVIPPod, node, opts.DryRun)
+	}
+
+	if err = os.MkdirAll(VIPConfigDir, 0755); err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		conf, confTmpl, pod, podTmpl string
+	}{
+		{conf: "keepalived.conf", confTmpl: keepalivedConf, pod: "keepalived.yaml", podTmpl: keepalivedPod},
+		{conf: "haproxy.cfg", confTmpl: haproxyConf, pod: "haproxy.yaml", podTmpl: haproxyPod},
+	} {
+		conf, err := tmplutil.New().Render(f.confTmpl, node)
+		if err != nil {
+			return nil, err
+		}
+		// the manifest changes with the config, so the kubelet restarts the static pod.
+		sum := sha256.Sum256([]byte(conf))
+		node.ConfigHash = hex.EncodeToString(sum[:])[:16]
+		if err = stepper.writeFile(ctx, filepath.Join(VIPConfigDir, f.conf), f.confTmpl, node, opts.DryRun); err != nil {
+			return nil, err
+		}
+		if err = stepper.writeFile(ctx, filepath.Join(KubeManifestsDir, f.pod), f.podTmpl, node, opts.DryRun); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (stepper *APIServerVIP) Uninstall(ctx context.Context, opts component.Options) ([]byte, error) {
+	return nil, fmt.Errorf("APIServerVIP does not support uninstall")
+}
+
+// nodeValues the keepalived priority decreases in the order of control plane nodes,
+// so the vip stays on the first control plane node which runs kubeadm init.
+func (stepper *APIServerVIP) nodeValues(ip net.IP) (*vipNode, error) {
+	node := &vipNode{
+		APIServerVIP: stepper,
+		LocalIP:      ip.String(),
+		Priority:     150,
+		RouterID:     int(net.ParseIP(stepper.Address).To4()[3])%255 + 1,
+		ConfigDir:    VIPConfigDir,
+	}
+	for i, master := range stepper.Masters {
+		if master == node.LocalIP {
+			node.Priority = 150 - i
+			continue
+		}
+		node.Peers = append(node.Peers, master)
+	}
+	if node.Interface == "" {
+		iface, err := netutil.GetInterfaceByIP(ip)
+		if err != nil {
+			return nil, err
+		}
+		vip := *stepper
+		vip.Interface = iface
+		node.APIServerVIP = &vip
+	}
+	return node, nil
+}
+
+func (stepper *APIServerVIP) writeFile(ctx context.Context, path, tmpl string, node *vipNode, dryRun bool) error {
+	return fileutil.WriteFileWithContext(ctx, path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644, func(w io.Writer) error {
+		_, err := tmplutil.New().RenderTo(w, tmpl, node)
+		return err
+	}, dryRun)
+}
@@ -0,0 +1,156 @@
+package k8s
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	tmplutil "github.com/kubeclipper/kubeclipper/pkg/utils/template"
+)
+
+func TestControlPlaneEndpoint(t *testing.T) {
+	c := &v1.Cluster{CertSANs: []string{"10.0.0.1"}}
+	if got := ControlPlaneEndpoint(c); got != "apiserver.cluster.local:6443" {
+		t.Errorf("ControlPlaneEndpoint() = %s, want apiserver.cluster.local:6443", got)
+	}
+	if got := certSANs(c); !reflect.DeepEqual(got, []string{"10.0.0.1"}) {
+		t.Errorf("certSANs() = %v, want [10.0.0.1]", got)
+	}
+
+	c.ControlPlaneVIP = &v1.ControlPlaneVIP{Mode: v1.VIPModeKeepalived, Address: "10.0.0.100"}
+	if got := ControlPlaneEndpoint(c); got != "10.0.0.100:8443" {
+		t.Errorf("ControlPlaneEndpoint() = %s, want 10.0.0.100:8443", got)
+	}
+	if got := certSANs(c); !reflect.DeepEqual(got, []string{"10.0.0.1", "10.0.0.100"}) {
+		t.Errorf("certSANs() = %v, want [10.0.0.1 10.0.0.100]", got)
+	}
+	if len(c.CertSANs) != 1 {
+		t.Errorf("certSANs() must not modify the cluster")
+	}
+}
+
+func TestAPIServerVIP_nodeValues(t *testing.T) {
+	vip := &APIServerVIP{
+		Mode:      v1.VIPModeKeepalived,
+		Address:   "10.0.0.100",
+		Interface: "eth0",
+		Port:      8443,
+		Masters:   []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+	}
+	node, err := vip.nodeValues(net.ParseIP("10.0.0.2"))
+	if err != nil {
+		t.Fatalf("nodeValues() error: %v", err)
+	}
+	if node.Priority != 149 {
+		t.Errorf("Priority = %d, want 149", node.Priority)
+	}
+	if !reflect.DeepEqual(node.Peers, []string{"10.0.0.1", "10.0.0.3"}) {
+		t.Errorf("Peers = %v, want [10.0.0.1 10.0.0.3]", node.Peers)
+	}
+	if node.RouterID != 101 {
+		t.Errorf("RouterID = %d, want 101", node.RouterID)
+	}
+
+	conf, err := tmplutil.New().Render(keepalivedConf, node)
+	if err != nil {
+		t.Fatalf("render keepalived config error: %v", err)
+	}
+	for _, want := range []string{"interface eth0", "unicast_src_ip 10.0.0.2", "    10.0.0.1\n    10.0.0.3\n", "nc -z 127.0.0.1 8443"} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("keepalived config does not contain %q:\n%s", want, conf)
+		}
+	}
+	conf, err = tmplutil.New().Render(haproxyConf, node)
+	if err != nil {
+		t.Fatalf("render haproxy config error: %v", err)
+	}
+	for _, want := range []string{"bind *:8443", "server master-2 10.0.0.3:6443"} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("haproxy config does not contain %q:\n%s", want, conf)
+		}
+	}
+}
+
+func TestControlPlane_initArgs(t *testing.T) {
+	stepper := &ControlPlane{}
+	if got := strings.Join(stepper.initArgs(), " "); strings.Contains(got, "--ignore-preflight-errors") {
+		t.Errorf("initArgs() = %s, must not ignore preflight errors without vip", got)
+	}
+	stepper.VIP = &APIServerVIP{Mode: v1.VIPModeKeepalived, Address: "10.0.0.100"}
+	stepper.SkipKubeProxy = true
+	want := "init --config /tmp/.k8s/kubeadm.yaml --upload-certs --skip-phases=addon/kube-proxy --ignore-preflight-errors=DirAvailable--etc-kubernetes-manifests"
+	if got := strings.Join(stepper.initArgs(), " "); got != want {
+		t.Errorf("initArgs() = %s, want %s", got, want)
+	}
+}
+
+func TestRunnable_makeInstallStepsWithVIP(t *testing.T) {
+	metadata := &component.ExtraMetadata{
+		ClusterName: "test",
+		KubeVersion: "v1.23.6",
+		Masters: []component.Node{
+			{ID: "m1", IPv4: "10.0.0.1", Hostname: "master-1"},
+			{ID: "m2", IPv4: "10.0.0.2", Hostname: "master-2"},
+			{ID: "m3", IPv4: "10.0.0.3", Hostname: "master-3"},
+		},
+		Workers: []component.Node{
+			{ID: "w1", IPv4: "10.0.0.4", Hostname: "worker-1"},
+		},
+	}
+	runnable := &Runnable{
+		Masters:           v1.WorkerNodeList{{ID: "m1"}, {ID: "m2"}, {ID: "m3"}},
+		Workers:           v1.WorkerNodeList{{ID: "w1"}},
+		KubernetesVersion: "v1.23.6",
+		ContainerRuntime:  v1.ContainerRuntime{Type: v1.CRIContainerd},
+		CNI:               v1.CNI{Type: "calico"},
+		Networking: v1.Networking{
+			IPFamily: v1.IPFamilyIPv4,
+			Pods:     v1.NetworkRanges{CIDRBlocks: []string{"172.25.0.0/16"}},
+		},
+		ControlPlaneVIP: &v1.ControlPlaneVIP{Mode: v1.VIPModeKeepalived, Address: "10.0.0.100"},
+	}
+	steps, err := runnable.makeInstallSteps(metadata)
+	if err != nil {
+		t.Fatalf("makeInstallSteps() error: %v", err)
+	}
+
+	index := func(name string, node string) int {
+		for i, step := range steps {
+			if step.Name != name {
+				continue
+			}
+			for _, n := range step.Nodes {
+				if n.ID == node {
+					return i
+				}
+			}
+		}
+		return -1
+	}
+	// the vip is rendered by the init step on the first control plane node,
+	// and on the other control plane nodes after they joined.
+	initIdx := index("initControlPlane", "m1")
+	if initIdx < 0 {
+		t.Fatalf("initControlPlane step is not found")
+	}
+	cp := &ControlPlane{}
+	if err = json.Unmarshal(steps[initIdx].Commands[0].CustomCommand, cp); err != nil {
+		t.Fatalf("unmarshal initControlPlane error: %v", err)
+	}
+	if cp.VIP == nil || !strings.Contains(strings.Join(cp.initArgs(), " "), "DirAvailable--etc-kubernetes-manifests") {
+		t.Errorf("initControlPlane must render the vip and ignore the manifests dir preflight check")
+	}
+	if index("renderAPIServerVIP", "m1") >= 0 {
+		t.Errorf("the vip of the first control plane node must be rendered by initControlPlane")
+	}
+	for _, node := range []string{"m2", "m3"} {
+		joinIdx, vipIdx := index("joinNode", node), index("renderAPIServerVIP", node)
+		if !(initIdx < joinIdx && joinIdx < vipIdx) {
+			t.Errorf("steps of %s: initControlPlane %d, joinNode %d, renderAPIServerVIP %d, want ascending", node, initIdx, joinIdx, vipIdx)
+		}
+	}
+}
@@ -20,8 +20,11 @@ package v1
 
 import (
 	"errors"
+	"sort"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
 )
 
 // +genclient
@@ -36,6 +39,16 @@ type Operation struct {
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	Steps             []Step          `json:"steps,omitempty"`
 	Status            OperationStatus `json:"status,omitempty"`
+	// Rollbacks the steps which undo the succeeded steps when the operation fails.
+	// +optional
+	Rollbacks []OperationRollback `json:"rollbacks,omitempty"`
+}
+
+// OperationRollback the steps which undo the step StepID and the steps after it in the same group,
+// they run only if the step StepID succeeded.
+type OperationRollback struct {
+	StepID string `json:"stepID"`
+	Steps  []Step `json:"steps"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -57,6 +70,57 @@ func (op *Operation) GetStep(stepID string) (step Step, ok bool) {
 	return
 }
 
+// ChainSteps makes every step depend on the step before it, the first step depends on the given steps.
+func ChainSteps(steps []Step, dependsOn ...string) {
+	for i := range steps {
+		if i > 0 {
+			dependsOn = []string{steps[i-1].ID}
+		}
+		steps[i].DependsOn = append([]string(nil), dependsOn...)
+	}
+}
+
+// HasStepDependencies whether the steps of operation run as a graph by their dependencies.
+func (op *Operation) HasStepDependencies() bool {
+	for _, s := range op.Steps {
+		if len(s.DependsOn) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// AddRollback registers rollback as the steps which undo steps, they run when the operation fails
+// after the first of steps succeeded.
+func (op *Operation) AddRollback(steps, rollback []Step) {
+	if len(steps) == 0 || len(rollback) == 0 {
+		return
+	}
+	op.Rollbacks = append(op.Rollbacks, OperationRollback{StepID: steps[0].ID, Steps: rollback})
+}
+
+// IsRollbackStep whether the step is one of the rollback steps of operation.
+func (op *Operation) IsRollbackStep(stepID string) bool {
+	for _, r := range op.Rollbacks {
+		for _, s := range r.Steps {
+			if s.ID == stepID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsRolledBack whether the operation has run its rollback steps.
+func (op *Operation) IsRolledBack() bool {
+	return len(op.Status.RollbackConditions) > 0
+}
+
+// IsDropped whether the operation is dropped from the queue of cluster without starting.
+func (op *Operation) IsDropped() bool {
+	return op.Annotations[common.AnnotationDropReason] != ""
+}
+
 // default operation timeout is 90 min
 
 const DefaultOperationTimeoutSecs = "5400"
@@ -69,11 +133,45 @@ const (
 	OperationStatusFailed     OperationStatusType = "failed"
 	OperationStatusUnknown    OperationStatusType = "unknown"
 	OperationStatusSuccessful OperationStatusType = "successful"
+	// OperationStatusPaused the operation stops before its next step until it is resumed,
+	// the paused time does not count towards the operation timeout.
+	OperationStatusPaused OperationStatusType = "paused"
+	// OperationStatusCancelled the operation is stopped by user, the running commands are killed.
+	OperationStatusCancelled OperationStatusType = "cancelled"
 )
 
 type OperationStatus struct {
 	Status     OperationStatusType  `json:"status,omitempty"`
 	Conditions []OperationCondition `json:"conditions,omitempty"`
+	// Nodes the progress of every node, only operations that handle nodes one batch after another record it.
+	// +optional
+	Nodes []OperationNodeStatus `json:"nodes,omitempty"`
+	// RollbackConditions the conditions of the rollback steps run after the operation failed.
+	// +optional
+	RollbackConditions []OperationCondition `json:"rollbackConditions,omitempty"`
+}
+
+type OperationNodePhase string
+
+const (
+	OperationNodePending    OperationNodePhase = "pending"
+	OperationNodeInProgress OperationNodePhase = "inProgress"
+	OperationNodeDone       OperationNodePhase = "done"
+)
+
+// OperationNodeStatus the progress of a node in the operation.
+type OperationNodeStatus struct {
+	ID       string             `json:"id,omitempty"`
+	Hostname string             `json:"hostname,omitempty"`
+	Phase    OperationNodePhase `json:"phase,omitempty"`
+}
+
+// OperationNodeSteps the nodes whose progress changes with a step.
+type OperationNodeSteps struct {
+	// Start the nodes enter in progress when the step starts.
+	Start []string `json:"start,omitempty"`
+	// Done the nodes are done when the step ends.
+	Done []string `json:"done,omitempty"`
 }
 
 type StepAction string
@@ -97,7 +195,13 @@ const (
 	OperationRecoverCluster      = "RecoveryCluster"
 	OperationInstallComponents   = "InstallComponents"
 	OperationUninstallComponents = "UninstallComponents"
+	// OperationUpgradeComponents upgrade the installed components to other versions in place.
+	OperationUpgradeComponents   = "UpgradeComponents"
 	OperationUpdateCertification = "UpdateCertifications"
+	// OperationMigrateContainerRuntime replace the container runtime of all nodes in place, one node at a time.
+	OperationMigrateContainerRuntime = "MigrateContainerRuntime"
+	// OperationUpdateClusterConfig update the configuration of the kubernetes components, one node at a time.
+	OperationUpdateClusterConfig = "UpdateClusterConfig"
 )
 
 // Step TODO: add commands struct instead of string
@@ -113,6 +217,11 @@ type Step struct {
 	AfterRunCommands  []Command       `json:"afterRunCommands,omitempty"`
 	RetryTimes        int32           `json:"retryTimes,omitempty"`
 	AutomaticRetry    bool            `json:"automaticRetry"`
+	// DependsOn the ids of the steps which must be done before the step runs.
+	// The steps of operation run one after another unless any step declares its dependencies,
+	// then the steps whose dependencies are done run in parallel.
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
 }
 
 type StepNode struct {
@@ -176,3 +285,25 @@ type PendingOperation struct {
 	ClusterResourceVersion string `json:"clusterResourceVersion"`
 	ExtraData              []byte `json:"extraData,omitempty"`
 }
+
+// QueuedOperation the pending operation waits in the queue of cluster before it starts, while other operations
+// are running or queued. The operations adding or removing nodes change the nodes of cluster when they start.
+type QueuedOperation struct {
+	PendingOperation `json:",inline"`
+	// Priority the operation of higher priority starts earlier, defaults to 0.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+	// QueuedAt the operations of the same priority start in the order they are queued.
+	QueuedAt metav1.Time `json:"queuedAt"`
+}
+
+// SortQueuedOperations sorts the queued operations by priority from high to low,
+// the operations of the same priority keep the order they are queued.
+func SortQueuedOperations(ops []QueuedOperation) {
+	sort.SliceStable(ops, func(i, j int) bool {
+		if ops[i].Priority != ops[j].Priority {
+			return ops[i].Priority > ops[j].Priority
+		}
+		return ops[i].QueuedAt.Before(&ops[j].QueuedAt)
+	})
+}
@@ -19,8 +19,11 @@
 package v1
 
 import (
+	"fmt"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 // +genclient
@@ -34,6 +37,78 @@ type Template struct {
 	// +optional
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	Config            runtime.RawExtension `json:"config"`
+	// Parameters the typed parameters of config, a string value "${name}" in config is replaced by the parameter value,
+	// and "${name}" inside a longer string value is replaced by the text of parameter value.
+	Parameters []TemplateParameter `json:"parameters,omitempty" optional:"true"`
+	// Revision starts from 1 and increases every time the config or parameters change.
+	Revision int64 `json:"revision,omitempty" optional:"true"`
+	// History the previous revisions of template, the oldest one comes first.
+	History []TemplateRevision `json:"history,omitempty" optional:"true"`
+}
+
+const (
+	TemplateParameterString  = "string"
+	TemplateParameterInteger = "integer"
+	TemplateParameterBoolean = "boolean"
+	TemplateParameterArray   = "array"
+	// TemplateParameterNodes the node ids rendered as a node list of cluster, e.g. the masters.
+	TemplateParameterNodes = "nodes"
+
+	// TemplateRevisionHistoryLimit the number of previous revisions kept in a template.
+	TemplateRevisionHistoryLimit = 10
+)
+
+var AllowedTemplateParameterType = sets.NewString(TemplateParameterString, TemplateParameterInteger,
+	TemplateParameterBoolean, TemplateParameterArray, TemplateParameterNodes)
+
+type TemplateParameter struct {
+	Name        string `json:"name"`
+	Type        string `json:"type" enum:"string|integer|boolean|array|nodes"`
+	Description string `json:"description,omitempty" optional:"true"`
+	// Required the value must be given if the parameter has no default value.
+	Required bool                  `json:"required,omitempty" optional:"true"`
+	Default  *runtime.RawExtension `json:"default,omitempty" optional:"true"`
+	// Enum the allowed values of string parameter.
+	Enum []string `json:"enum,omitempty" optional:"true"`
+	// Pattern the regular expression matched by the string parameter or every item of array parameter.
+	Pattern string `json:"pattern,omitempty" optional:"true"`
+}
+
+// TemplateRevision the config and parameters of template at a revision.
+type TemplateRevision struct {
+	Revision   int64                `json:"revision"`
+	Config     runtime.RawExtension `json:"config"`
+	Parameters []TemplateParameter  `json:"parameters,omitempty" optional:"true"`
+}
+
+// TemplateReference the template and parameter values which a cluster is rendered from.
+type TemplateReference struct {
+	Name string `json:"name"`
+	// Revision defaults to the latest revision of template.
+	Revision int64                           `json:"revision,omitempty" optional:"true"`
+	Values   map[string]runtime.RawExtension `json:"values,omitempty" optional:"true"`
+}
+
+// GetRevision returns the latest revision if revision is 0.
+// The templates created before revisions were introduced are at revision 1.
+func (t *Template) GetRevision(revision int64) (*TemplateRevision, error) {
+	latest := t.Revision
+	if latest == 0 {
+		latest = 1
+	}
+	if revision == 0 || revision == latest {
+		return &TemplateRevision{
+			Revision:   latest,
+			Config:     t.Config,
+			Parameters: t.Parameters,
+		}, nil
+	}
+	for i := range t.History {
+		if t.History[i].Revision == revision {
+			return &t.History[i], nil
+		}
+	}
+	return nil, fmt.Errorf("revision %d of template %s does not exist", revision, t.Name)
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -0,0 +1,53 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package validation
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	corev1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+func ValidateCronBackupRetention(p *corev1.RetentionPolicy, fldPath *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if p == nil {
+		return allErrs
+	}
+	counts := []struct {
+		name  string
+		value int
+	}{
+		{"keepLast", p.KeepLast},
+		{"keepDaily", p.KeepDaily},
+		{"keepWeekly", p.KeepWeekly},
+		{"keepMonthly", p.KeepMonthly},
+		{"keepYearly", p.KeepYearly},
+	}
+	total := 0
+	for _, c := range counts {
+		if c.value < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child(c.name), c.value, "must be greater than or equal to 0"))
+		}
+		total += c.value
+	}
+	if len(allErrs) == 0 && total == 0 {
+		allErrs = append(allErrs, field.Required(fldPath, "at least one of keepLast, keepDaily, keepWeekly, keepMonthly and keepYearly must be set"))
+	}
+	return allErrs
+}
@@ -421,7 +421,7 @@ var Roles = []iamv1.GlobalRole{
 		Rules: []rbacv1.PolicyRule{
 			{
 				APIGroups: []string{"core.kubeclipper.io"},
-				Resources: []string{"clusters", "nodes", "regions", "operations", "logs", "clusters/upgrade", "nodes/terminal"},
+				Resources: []string{"clusters", "nodes", "regions", "operations", "logs", "clusters/upgrade", "clusters/operations", "nodes/terminal"},
 				Verbs:     []string{"get", "list", "watch"},
 			},
 			{
@@ -457,7 +457,7 @@ var Roles = []iamv1.GlobalRole{
 		Rules: []rbacv1.PolicyRule{
 			{
 				APIGroups: []string{"core.kubeclipper.io"},
-				Resources: []string{"clusters", "nodes", "regions", "operations/retry", "clusters/upgrade"},
+				Resources: []string{"clusters", "nodes", "regions", "operations/retry", "operations/pause", "operations/resume", "operations/cancel", "clusters/upgrade"},
 				Verbs:     []string{"create"},
 			},
 			{
@@ -498,7 +498,7 @@ var Roles = []iamv1.GlobalRole{
 			},
 			{
 				APIGroups: []string{"core.kubeclipper.io"},
-				Resources: []string{"clusters/plugins", "clusters/join", "clusters/nodes", "clusters/backups", "clusters/cronbackups", "clusters/certification", "clusters/kubeconfig"},
+				Resources: []string{"clusters/plugins", "clusters/join", "clusters/nodes", "clusters/backups", "clusters/cronbackups", "clusters/certification", "clusters/runtime", "clusters/config", "clusters/kubeconfig", "clusters/operations"},
 				Verbs:     []string{"*"},
 			},
 			{
@@ -1451,7 +1451,7 @@ var ProjectRolesTemplate = []iamv1.ProjectRole{
 		Rules: []rbacv1.PolicyRule{
 			{
 				APIGroups: []string{"core.kubeclipper.io"},
-				Resources: []string{"clusters", "nodes", "regions", "operations", "logs", "clusters/upgrade", "nodes/terminal"},
+				Resources: []string{"clusters", "nodes", "regions", "operations", "logs", "clusters/upgrade", "clusters/operations", "nodes/terminal"},
 				Verbs:     []string{"get", "list", "watch"},
 			},
 			{
@@ -1487,7 +1487,7 @@ var ProjectRolesTemplate = []iamv1.ProjectRole{
 		Rules: []rbacv1.PolicyRule{
 			{
 				APIGroups: []string{"core.kubeclipper.io"},
-				Resources: []string{"clusters", "nodes", "regions", "operations/retry", "clusters/upgrade"},
+				Resources: []string{"clusters", "nodes", "regions", "operations/retry", "operations/pause", "operations/resume", "operations/cancel", "clusters/upgrade"},
 				Verbs:     []string{"create"},
 			},
 			{
@@ -1528,7 +1528,7 @@ var ProjectRolesTemplate = []iamv1.ProjectRole{
 			},
 			{
 				APIGroups: []string{"core.kubeclipper.io"},
-				Resources: []string{"clusters/plugins", "clusters/nodes", "clusters/backups", "clusters/cronbackups", "clusters/certification", "clusters/kubeconfig"},
+				Resources: []string{"clusters/plugins", "clusters/nodes", "clusters/backups", "clusters/cronbackups", "clusters/certification", "clusters/runtime", "clusters/config", "clusters/kubeconfig", "clusters/operations"},
 				Verbs:     []string{"*"},
 			},
 			{
@@ -25,6 +25,10 @@ import (
 	auditoptions "github.com/kubeclipper/kubeclipper/pkg/auditing/option"
 
 	authoptions "github.com/kubeclipper/kubeclipper/pkg/authentication/options"
+	"github.com/kubeclipper/kubeclipper/pkg/controller/backuppointcontroller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller/backupverifycontroller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller/certcontroller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller/operationgccontroller"
 	bs "github.com/kubeclipper/kubeclipper/pkg/simple/backupstore"
 	"github.com/kubeclipper/kubeclipper/pkg/simple/client/cache"
 
@@ -59,6 +63,10 @@ type Config struct {
 	LogOptions              *logger.Options                    `json:"log,omitempty" yaml:"log,omitempty" mapstructure:"log"`
 	AuthenticationOptions   *authoptions.AuthenticationOptions `json:"authentication,omitempty" yaml:"authentication,omitempty" mapstructure:"authentication"`
 	AuditOptions            *auditoptions.AuditOptions         `json:"audit,omitempty" yaml:"audit,omitempty" mapstructure:"audit"`
+	CertRenewalOptions      *certcontroller.Options            `json:"certRenewal,omitempty" yaml:"certRenewal,omitempty" mapstructure:"certRenewal"`
+	OperationGCOptions      *operationgccontroller.Options     `json:"operationRetention,omitempty" yaml:"operationRetention,omitempty" mapstructure:"operationRetention"`
+	BackupVerifyOptions     *backupverifycontroller.Options    `json:"backupVerify,omitempty" yaml:"backupVerify,omitempty" mapstructure:"backupVerify"`
+	BackupPointOptions      *backuppointcontroller.Options     `json:"backupPoint,omitempty" yaml:"backupPoint,omitempty" mapstructure:"backupPoint"`
 }
 
 func New() *Config {
@@ -71,6 +79,10 @@ func New() *Config {
 		LogOptions:              logger.NewLogOptions(),
 		AuthenticationOptions:   authoptions.NewAuthenticateOptions(),
 		AuditOptions:            auditoptions.NewAuditOptions(),
+		CertRenewalOptions:      certcontroller.NewOptions(),
+		OperationGCOptions:      operationgccontroller.NewOptions(),
+		BackupVerifyOptions:     backupverifycontroller.NewOptions(),
+		BackupPointOptions:      backuppointcontroller.NewOptions(),
 	}
 }
 
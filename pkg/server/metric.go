@@ -21,6 +21,7 @@ package server
 import (
 	compbasemetrics "k8s.io/component-base/metrics"
 
+	"github.com/kubeclipper/kubeclipper/pkg/controller/certcontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/utils/metrics"
 )
 
@@ -51,6 +52,8 @@ var (
 	metricsList = []compbasemetrics.Registerable{
 		RequestCounter,
 		RequestLatencies,
+		certcontroller.CertExpiration,
+		certcontroller.CertRenewalFailed,
 	}
 )
 
@@ -2,6 +2,7 @@ package configmap
 
 import (
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apiserver/pkg/registry/generic"
 	genericregistry "k8s.io/apiserver/pkg/registry/generic/registry"
 	"k8s.io/apiserver/pkg/registry/rest"
@@ -10,6 +11,16 @@ import (
 )
 
 func NewStorage(scheme *runtime.Scheme, optsGetter generic.RESTOptionsGetter) (rest.StandardStorage, error) {
+	return newStorage(scheme, optsGetter, v1.Resource("configmaps"))
+}
+
+// NewBackupKeyStorage returns the storage of platform-managed keys of backup encryption, the keys are saved
+// as config maps apart from the ones of configmaps storage, and no API serves them.
+func NewBackupKeyStorage(scheme *runtime.Scheme, optsGetter generic.RESTOptionsGetter) (rest.StandardStorage, error) {
+	return newStorage(scheme, optsGetter, v1.Resource("backupkeys"))
+}
+
+func newStorage(scheme *runtime.Scheme, optsGetter generic.RESTOptionsGetter, resource schema.GroupResource) (rest.StandardStorage, error) {
 	strategy := NewStrategy(scheme)
 
 	store := &genericregistry.Store{
@@ -19,7 +30,7 @@ func NewStorage(scheme *runtime.Scheme, optsGetter generic.RESTOptionsGetter) (r
 		NewListFunc: func() runtime.Object {
 			return &v1.ConfigMapList{}
 		},
-		DefaultQualifiedResource: v1.Resource("configmaps"),
+		DefaultQualifiedResource: resource,
 		KeyRootFunc:              nil,
 		KeyFunc:                  nil,
 		ObjectNameFunc:           nil,
@@ -38,7 +49,7 @@ func NewStorage(scheme *runtime.Scheme, optsGetter generic.RESTOptionsGetter) (r
 		AfterDelete:              nil,
 		ReturnDeletedObject:      false,
 		ShouldDeleteDuringUpdate: nil,
-		TableConvertor:           rest.NewDefaultTableConvertor(v1.Resource("configmaps")),
+		TableConvertor:           rest.NewDefaultTableConvertor(resource),
 		ResetFieldsStrategy:      nil,
 		Storage:                  genericregistry.DryRunnableStorage{},
 		StorageVersioner:         nil,
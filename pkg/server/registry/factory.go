@@ -85,6 +85,7 @@ type SharedStorageFactory interface {
 	DNSDomains() rest.StandardStorage
 	Template() rest.StandardStorage
 	ConfigMaps() rest.StandardStorage
+	BackupKeys() rest.StandardStorage
 	CloudProvider() rest.StandardStorage
 	Registry() rest.StandardStorage
 	Project() rest.StandardStorage
@@ -207,6 +208,14 @@ func (s *sharedStorageFactory) Template() rest.StandardStorage {
 func (s *sharedStorageFactory) ConfigMaps() rest.StandardStorage {
 	return s.StorageFor(&corev1.ConfigMap{}, configmap.NewStorage)
 }
+
+// backupKeys distinguishes the storage of backup keys from the configmaps storage, both of them keep config maps.
+type backupKeys struct{}
+
+func (s *sharedStorageFactory) BackupKeys() rest.StandardStorage {
+	return s.StorageFor(&backupKeys{}, configmap.NewBackupKeyStorage)
+}
+
 func (s *sharedStorageFactory) CloudProvider() rest.StandardStorage {
 	return s.StorageFor(&corev1.CloudProvider{}, cloudprovider.NewStorage)
 }
@@ -54,15 +54,20 @@ import (
 	"github.com/kubeclipper/kubeclipper/pkg/authorization/rbac"
 	"github.com/kubeclipper/kubeclipper/pkg/client/clientrest"
 	"github.com/kubeclipper/kubeclipper/pkg/client/informers"
+	"github.com/kubeclipper/kubeclipper/pkg/component/bundle"
 	"github.com/kubeclipper/kubeclipper/pkg/controller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller-runtime/manager"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/backupcontroller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller/backuppointcontroller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller/backupverifycontroller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller/certcontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/cloudprovidercontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/clustercontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/cronbackupcontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/dnscontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/nodecontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/operationcontroller"
+	"github.com/kubeclipper/kubeclipper/pkg/controller/operationgccontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/projectcontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/regioncontroller"
 	"github.com/kubeclipper/kubeclipper/pkg/controller/tokencontroller"
@@ -129,6 +134,15 @@ func (s *APIServer) PrepareRun(stopCh <-chan struct{}) error {
 		return err
 	}
 
+	// the bundles which fail to be loaded are reported, they do not stop the server.
+	loaded, err := bundle.LoadAll(s.Config.StaticServerOptions.Path)
+	if err != nil {
+		logger.Error("load component bundles failed", zap.Error(err))
+	}
+	if len(loaded) > 0 {
+		logger.Info("component bundles loaded", zap.Strings("components", loaded))
+	}
+
 	s.container = restful.NewContainer()
 	s.container.DoNotRecover(false)
 	s.container.Filter(filters.LogRequestAndResponse)
@@ -255,14 +269,14 @@ func (s *APIServer) installAPIs(stopCh <-chan struct{}) error {
 		s.storageFactory.CloudProvider(),
 		s.storageFactory.Registry(),
 	)
-	coreOperator := core.NewOperator(s.storageFactory.ConfigMaps())
+	coreOperator := core.NewOperator(s.storageFactory.ConfigMaps(), s.storageFactory.BackupKeys())
 	leaseOperator := lease.NewLeaseOperator(s.storageFactory.Leases())
 	opOperator := operation.NewOperationOperator(s.storageFactory.Operations())
 	iamOperator := iam.NewOperator(s.storageFactory.Users(), s.storageFactory.GlobalRoles(),
 		s.storageFactory.GlobalRoleBindings(), s.storageFactory.Tokens(), s.storageFactory.LoginRecords(), s.storageFactory.ProjectRole(), s.storageFactory.ProjectRoleBinding())
 	s.rbacAuthorizer = rbac.NewAuthorizer(iamOperator, clusterOperator)
 
-	deliverySvc := delivery.NewService(s.Config.MQOptions, clusterOperator, leaseOperator, opOperator)
+	deliverySvc := delivery.NewService(s.Config.MQOptions, clusterOperator, coreOperator, leaseOperator, opOperator)
 	s.Services = append(s.Services, deliverySvc)
 
 	platformOperator := platform.NewPlatformOperator(s.storageFactory.PlatformSettings(), s.storageFactory.Events())
@@ -325,7 +339,7 @@ func (s *APIServer) installAPIs(stopCh <-chan struct{}) error {
 
 	if err = corev1.AddToContainer(s.container, clusterOperator, opOperator, platformOperator,
 		leaseOperator, coreOperator, deliverySvc, tokenOperator, tenantOperator,
-		s.Config.GenericServerRunOptions); err != nil {
+		s.Config.GenericServerRunOptions, s.Config.StaticServerOptions); err != nil {
 		return err
 	}
 	if err = proxy.AddToContainer(s.container, clusterOperator); err != nil {
@@ -431,7 +445,7 @@ func (s *APIServer) SetupController(mgr manager.Manager, informerFactory informe
 		storageFactory.CloudProvider(),
 		storageFactory.Registry(),
 	)
-	coreOperator := core.NewOperator(storageFactory.ConfigMaps())
+	coreOperator := core.NewOperator(storageFactory.ConfigMaps(), storageFactory.BackupKeys())
 	opOperator := operation.NewOperationOperator(storageFactory.Operations())
 	iamOperator := iam.NewOperator(storageFactory.Users(), storageFactory.GlobalRoles(), storageFactory.GlobalRoleBindings(),
 		storageFactory.Tokens(), storageFactory.LoginRecords(), s.storageFactory.ProjectRole(), s.storageFactory.ProjectRoleBinding())
@@ -481,10 +495,22 @@ func (s *APIServer) SetupController(mgr manager.Manager, informerFactory informe
 		CronBackupLister:  informerFactory.Core().V1().CronBackups().Lister(),
 		BackupPointLister: informerFactory.Core().V1().BackupPoints().Lister(),
 		OperationWriter:   opOperator,
+		OperationOperator: opOperator,
 		ClusterWriter:     clusterOperator,
 		CronBackupWriter:  clusterOperator,
 		BackupWriter:      clusterOperator,
 		Now:               time.Now,
+		CoreOperator:      coreOperator,
+	}).SetupWithManager(mgr, informerFactory); err != nil {
+		return err
+	}
+	if err = (&certcontroller.CertRenewalReconciler{
+		ClusterLister:   informerFactory.Core().V1().Clusters().Lister(),
+		ClusterWriter:   clusterOperator,
+		OperationLister: informerFactory.Core().V1().Operations().Lister(),
+		EventWriter:     platform.NewPlatformOperator(storageFactory.Operations(), storageFactory.Events()),
+		Options:         s.Config.CertRenewalOptions,
+		Now:             time.Now,
 	}).SetupWithManager(mgr, informerFactory); err != nil {
 		return err
 	}
@@ -505,6 +531,36 @@ func (s *APIServer) SetupController(mgr manager.Manager, informerFactory informe
 	}).SetupWithManager(mgr, informerFactory); err != nil {
 		return err
 	}
+	if err = (&operationgccontroller.OperationGCReconciler{
+		CmdDelivery:     mgr.GetCmdDelivery(),
+		OperationLister: informerFactory.Core().V1().Operations().Lister(),
+		BackupLister:    informerFactory.Core().V1().Backups().Lister(),
+		OperationWriter: opOperator,
+		Options:         s.Config.OperationGCOptions,
+		Now:             time.Now,
+	}).SetupWithManager(mgr, informerFactory); err != nil {
+		return err
+	}
+	if err = (&backupverifycontroller.BackupVerifyReconciler{
+		BackupLister:      informerFactory.Core().V1().Backups().Lister(),
+		BackupPointLister: informerFactory.Core().V1().BackupPoints().Lister(),
+		BackupWriter:      clusterOperator,
+		CoreOperator:      coreOperator,
+		Options:           s.Config.BackupVerifyOptions,
+		Now:               time.Now,
+	}).SetupWithManager(mgr, informerFactory); err != nil {
+		return err
+	}
+	if err = (&backuppointcontroller.BackupPointReconciler{
+		BackupLister:      informerFactory.Core().V1().Backups().Lister(),
+		BackupPointLister: informerFactory.Core().V1().BackupPoints().Lister(),
+		BackupWriter:      clusterOperator,
+		BackupPointWriter: clusterOperator,
+		Options:           s.Config.BackupPointOptions,
+		Now:               time.Now,
+	}).SetupWithManager(mgr, informerFactory); err != nil {
+		return err
+	}
 	if err = (&tokencontroller.TokenReconciler{
 		TokenLister: informerFactory.Iam().V1().Tokens().Lister(),
 		TokenWriter: iamOperator,
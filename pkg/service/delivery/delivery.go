@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -33,14 +34,17 @@ import (
 
 	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
 
+	"github.com/kubeclipper/kubeclipper/pkg/clusteroperation"
 	"github.com/kubeclipper/kubeclipper/pkg/component"
 
 	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/kubeclipper/kubeclipper/pkg/logger"
 	"github.com/kubeclipper/kubeclipper/pkg/models/cluster"
+	"github.com/kubeclipper/kubeclipper/pkg/models/core"
 	"github.com/kubeclipper/kubeclipper/pkg/models/lease"
 	"github.com/kubeclipper/kubeclipper/pkg/models/operation"
 	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
@@ -52,9 +56,12 @@ var _ service.Interface = (*Service)(nil)
 var _ service.IDelivery = (*Service)(nil)
 
 const (
-	updateOperationStatusRetry = 10
+	updateOperationStatusRetry   = 10
+	pausedOperationCheckInterval = 5 * time.Second
 )
 
+var errOperationCancelled = errors.New("operation is cancelled")
+
 type stepStatus struct {
 	OperationIdentity  string
 	OperationCondition v1.OperationCondition
@@ -68,12 +75,15 @@ type Service struct {
 	subjectSuffix     string
 	client            natsio.Interface
 	clusterOperator   cluster.Operator
+	coreOperator      core.Operator
 	leaseOperator     lease.Operator
 	opOperator        operation.Operator
 	stepStatusChan    chan stepStatus
+	// cancels the cancel functions of the operations being delivered, keyed by operation name.
+	cancels sync.Map
 }
 
-func NewService(opts *natsio.NatsOptions, clusterOperator cluster.Operator, leaseOperator lease.Operator, opOperator operation.Operator) *Service {
+func NewService(opts *natsio.NatsOptions, clusterOperator cluster.Operator, coreOperator core.Operator, leaseOperator lease.Operator, opOperator operation.Operator) *Service {
 	s := &Service{
 		external:          opts.External,
 		client:            natsio.NewNats(opts),
@@ -81,6 +91,7 @@ func NewService(opts *natsio.NatsOptions, clusterOperator cluster.Operator, leas
 		nodeReportSubject: opts.Client.NodeReportSubject,
 		queueGroup:        opts.Client.QueueGroupName,
 		clusterOperator:   clusterOperator,
+		coreOperator:      coreOperator,
 		leaseOperator:     leaseOperator,
 		opOperator:        opOperator,
 		stepStatusChan:    make(chan stepStatus, 256),
@@ -170,7 +181,9 @@ func (s *Service) stepStatusChannelController() {
 			}
 
 			stepLen := len(o.Status.Conditions)
-			if stepLen > 0 && status.OperationCondition.StepID == o.Status.Conditions[stepLen-1].StepID {
+			if o.IsRollbackStep(status.OperationCondition.StepID) {
+				setRollbackCondition(o, status.OperationCondition)
+			} else if stepLen > 0 && status.OperationCondition.StepID == o.Status.Conditions[stepLen-1].StepID {
 				o.Status.Conditions[stepLen-1].Status = append(o.Status.Conditions[stepLen-1].Status, status.OperationCondition.Status...)
 			} else {
 				o.Status.Conditions = append(o.Status.Conditions, status.OperationCondition)
@@ -199,6 +212,11 @@ func (s *Service) updateOperationStatus(op string, status v1.OperationStatusType
 			logger.Error("update operation status type failed", zap.String("op", op), zap.String("status", string(status)), zap.Error(err))
 			continue
 		}
+		if o.Status.Status == v1.OperationStatusCancelled {
+			// the cluster condition has been synced when the operation is cancelled.
+			logger.Info("operation is cancelled, keep its status", zap.String("op", op), zap.String("status", string(status)))
+			return
+		}
 		o.Status.Status = status
 		if o, err = s.opOperator.UpdateOperation(context.TODO(), o); err != nil {
 			logger.Error("update operation status type failed", zap.String("op", op), zap.String("status", string(status)), zap.Error(err))
@@ -209,6 +227,152 @@ func (s *Service) updateOperationStatus(op string, status v1.OperationStatusType
 	}
 }
 
+// beforeStep waits until the operation is resumed if it is paused, then marks the nodes handled by the step in progress.
+// The cancelled operation does not run its following steps.
+func (s *Service) beforeStep(ctx context.Context, op *v1.Operation, stepID string, dryRun bool) error {
+	if dryRun {
+		return nil
+	}
+	if err := s.waitOperationResumed(ctx, op.Name); err != nil {
+		return err
+	}
+	s.syncOperationNodes(op, stepID, v1.OperationNodeInProgress)
+	return nil
+}
+
+// afterStep records the progress of cluster and nodes when the step is done, and pauses the operation if required.
+func (s *Service) afterStep(op *v1.Operation, stepID string, dryRun bool) {
+	if dryRun {
+		return
+	}
+	s.syncUpgradeHop(op, stepID)
+	s.syncOperationNodes(op, stepID, v1.OperationNodeDone)
+	if sets.NewString(strings.Split(op.Annotations[common.AnnotationPauseAfterSteps], ",")...).Has(stepID) {
+		s.pauseOperation(op.Name)
+	}
+}
+
+// waitOperationResumed waits until the operation is resumed, the clock of operation timeout stops while it is paused.
+func (s *Service) waitOperationResumed(ctx context.Context, opName string) error {
+	ticker := time.NewTicker(pausedOperationCheckInterval)
+	defer ticker.Stop()
+	timeout, paused := operationTimeoutFrom(ctx), false
+	defer func() {
+		if paused {
+			timeout.resume()
+		}
+	}()
+	for {
+		o, err := s.opOperator.GetOperation(ctx, opName)
+		if err != nil {
+			logger.Error("get operation failed", zap.String("op", opName), zap.Error(err))
+		} else if o.Status.Status == v1.OperationStatusCancelled {
+			return errOperationCancelled
+		} else if o.Status.Status != v1.OperationStatusPaused {
+			return nil
+		} else if !paused {
+			paused = true
+			timeout.pause()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) pauseOperation(opName string) {
+	for i := 0; i < updateOperationStatusRetry; i++ {
+		o, err := s.opOperator.GetOperation(context.TODO(), opName)
+		if err != nil {
+			logger.Error("get operation failed", zap.String("op", opName), zap.Error(err))
+			continue
+		}
+		o.Status.Status = v1.OperationStatusPaused
+		if _, err = s.opOperator.UpdateOperation(context.TODO(), o); err != nil {
+			logger.Error("pause operation failed", zap.String("op", opName), zap.Error(err))
+			continue
+		}
+		logger.Info("operation is paused", zap.String("op", opName))
+		return
+	}
+}
+
+// syncOperationNodes updates the progress of nodes which are started or done by the step.
+func (s *Service) syncOperationNodes(op *v1.Operation, stepID string, phase v1.OperationNodePhase) {
+	nodeSteps := make(map[string]v1.OperationNodeSteps)
+	if err := json.Unmarshal([]byte(op.Annotations[common.AnnotationNodeSteps]), &nodeSteps); err != nil {
+		return
+	}
+	var nodes sets.String
+	switch phase {
+	case v1.OperationNodeInProgress:
+		nodes = sets.NewString(nodeSteps[stepID].Start...)
+	case v1.OperationNodeDone:
+		nodes = sets.NewString(nodeSteps[stepID].Done...)
+	}
+	if nodes.Len() == 0 {
+		return
+	}
+	for i := 0; i < updateOperationStatusRetry; i++ {
+		o, err := s.opOperator.GetOperation(context.TODO(), op.Name)
+		if err != nil {
+			logger.Error("get operation failed", zap.String("op", op.Name), zap.Error(err))
+			continue
+		}
+		for j := range o.Status.Nodes {
+			if nodes.Has(o.Status.Nodes[j].ID) {
+				o.Status.Nodes[j].Phase = phase
+			}
+		}
+		if _, err = s.opOperator.UpdateOperation(context.TODO(), o); err != nil {
+			logger.Error("update operation nodes failed", zap.String("op", op.Name), zap.Error(err))
+			continue
+		}
+		return
+	}
+}
+
+// syncUpgradeHop records the version of cluster when a hop of the multi-minor-version upgrade is done,
+// so the cluster versions are correct even if the following hops failed.
+func (s *Service) syncUpgradeHop(op *v1.Operation, stepID string) {
+	if op.Labels[common.LabelOperationAction] != v1.OperationUpgradeCluster {
+		return
+	}
+	hops := make(map[string]string)
+	if err := json.Unmarshal([]byte(op.Annotations[common.AnnotationUpgradeHops]), &hops); err != nil {
+		return
+	}
+	ver, ok := hops[stepID]
+	if !ok {
+		return
+	}
+	for i := 0; i < updateOperationStatusRetry; i++ {
+		clu, err := s.clusterOperator.GetClusterEx(context.TODO(), op.Labels[common.LabelClusterName], "0")
+		if err != nil {
+			logger.Error("get cluster failed", zap.String("operation", op.Name), zap.Error(err))
+			continue
+		}
+		clu.KubernetesVersion = ver
+		setClusterVersions(clu, ver)
+		if _, err = s.clusterOperator.UpdateCluster(context.TODO(), clu); err != nil {
+			logger.Error("update cluster versions failed", zap.String("name", clu.Name), zap.String("version", ver), zap.Error(err))
+			continue
+		}
+		return
+	}
+}
+
+func setClusterVersions(clu *v1.Cluster, ver string) {
+	clu.Status.Versions = v1.ClusterVersionsStatus{
+		ControlPlane:      ver,
+		Apiserver:         ver,
+		ControllerManager: ver,
+		Scheduler:         ver,
+	}
+}
+
 func (s *Service) SyncClusterCondition(op *v1.Operation) {
 	defer service.HandlerCrash()
 	for i := 0; i < updateOperationStatusRetry; i++ {
@@ -237,6 +401,9 @@ func (s *Service) syncClusterCondition(op *v1.Operation, clu *v1.Cluster) error
 		}
 		return nil
 	case v1.OperationAddNodes:
+		if op.Status.Status != v1.OperationStatusSuccessful && op.IsRolledBack() {
+			return s.removeRolledBackNodes(op, clu)
+		}
 		if op.Status.Status == v1.OperationStatusSuccessful {
 			clu.Status.Phase = v1.ClusterRunning
 		} else {
@@ -277,9 +444,11 @@ func (s *Service) syncClusterCondition(op *v1.Operation, clu *v1.Cluster) error
 					Taints: nil,
 				}
 			}
-			removed = clu.Workers.Intersect(removed...)
-			if len(removed) > 0 {
-				clu.Workers = clu.Workers.Complement(removed...)
+			if workers := clu.Workers.Intersect(removed...); len(workers) > 0 {
+				clu.Workers = clu.Workers.Complement(workers...)
+			}
+			if masters := clu.Masters.Intersect(removed...); len(masters) > 0 {
+				clu.Masters = clu.Masters.Complement(masters...)
 			}
 			clu.Status.Phase = v1.ClusterRunning
 			if _, err := s.clusterOperator.UpdateCluster(context.TODO(), clu); err != nil {
@@ -303,11 +472,36 @@ func (s *Service) syncClusterCondition(op *v1.Operation, clu *v1.Cluster) error
 		if op.Status.Status == v1.OperationStatusSuccessful {
 			clu.Status.Phase = v1.ClusterRunning
 			clu.KubernetesVersion = op.Labels[common.LabelUpgradeVersion]
+			setClusterVersions(clu, clu.KubernetesVersion)
 		} else {
 			clu.Status.Phase = v1.ClusterUpgradeFailed
 		}
 		_, err := s.clusterOperator.UpdateCluster(context.TODO(), clu)
 		return err
+	case v1.OperationMigrateContainerRuntime:
+		if op.Status.Status == v1.OperationStatusSuccessful {
+			clu.Status.Phase = v1.ClusterRunning
+			clu.ContainerRuntime.Type = op.Labels[common.LabelRuntimeType]
+			clu.ContainerRuntime.Version = op.Labels[common.LabelRuntimeVersion]
+			clu.ContainerRuntime.DataRootDir = ""
+		} else {
+			clu.Status.Phase = v1.ClusterUpdateFailed
+		}
+		_, err := s.clusterOperator.UpdateCluster(context.TODO(), clu)
+		return err
+	case v1.OperationUpdateClusterConfig:
+		clu.Status.Phase = v1.ClusterUpdateFailed
+		if op.Status.Status == v1.OperationStatusSuccessful {
+			var config v1.ClusterConfig
+			if err := json.Unmarshal([]byte(op.Annotations[common.AnnotationClusterConfig]), &config); err != nil {
+				logger.Error("unmarshal cluster config failed", zap.String("operation", op.Name), zap.Error(err))
+			} else {
+				clu.Status.Phase = v1.ClusterRunning
+				clu.SetConfig(config)
+			}
+		}
+		_, err := s.clusterOperator.UpdateCluster(context.TODO(), clu)
+		return err
 	case v1.OperationBackupCluster:
 		clu.Status.Phase = v1.ClusterRunning
 		_, err := s.clusterOperator.UpdateCluster(context.TODO(), clu)
@@ -320,12 +514,19 @@ func (s *Service) syncClusterCondition(op *v1.Operation, clu *v1.Cluster) error
 		}
 		_, err := s.clusterOperator.UpdateCluster(context.TODO(), clu)
 		return err
-	case v1.OperationInstallComponents, v1.OperationUninstallComponents:
-		if op.Status.Status == v1.OperationStatusSuccessful {
+	case v1.OperationInstallComponents, v1.OperationUninstallComponents, v1.OperationUpgradeComponents:
+		// the rolled back components are not added to the cluster.
+		if op.Status.Status == v1.OperationStatusSuccessful || op.IsRolledBack() {
 			clu.Status.Phase = v1.ClusterRunning
 		} else {
 			clu.Status.Phase = v1.ClusterUpdateFailed
 		}
+		if !op.IsRolledBack() {
+			if err := clusteroperation.ApplyComponents(clu, op); err != nil {
+				logger.Error("update the components of cluster failed", zap.String("operation", op.Name),
+					zap.String("cluster", clu.Name), zap.Error(err))
+			}
+		}
 		if _, err := s.clusterOperator.UpdateCluster(context.TODO(), clu); err != nil {
 			return err
 		}
@@ -394,15 +595,24 @@ func (s *Service) DeliverTaskOperation(ctx context.Context, operation *v1.Operat
 	}
 	timeoutSecs := operation.Labels[common.LabelTimeoutSeconds]
 	secs, _ := strconv.Atoi(timeoutSecs)
-	ctx, cancelFn := context.WithTimeout(ctx, time.Duration(secs)*time.Second)
+	ctx, cancelFn := context.WithCancel(ctx)
 	defer cancelFn()
+	// the operation times out when it runs longer than timeout, the time it is paused is not counted.
+	timeout := newOperationTimeout(time.Duration(secs)*time.Second, cancelFn)
+	defer timeout.stop()
 	// new empty context, pass retry value
-	stepCtx, stepCtxCancel := context.WithCancel(component.WithRetry(context.TODO(), component.GetRetry(ctx)))
+	stepCtx, stepCtxCancel := context.WithCancel(withOperationTimeout(component.WithRetry(context.TODO(), component.GetRetry(ctx)), timeout))
 	defer stepCtxCancel()
+	if !opts.DryRun {
+		s.cancels.Store(operation.Name, stepCtxCancel)
+		defer s.cancels.Delete(operation.Name)
+	}
 	doneChan := make(chan struct{}, 1)
 	defer close(doneChan)
 	errChan := make(chan error, 1)
 	defer close(errChan)
+	// the conditions of the steps done before retry
+	done := operation.Status.Conditions
 	operation.Status.Conditions = make([]v1.OperationCondition, len(operation.Steps))
 	go func() {
 		for {
@@ -424,43 +634,59 @@ func (s *Service) DeliverTaskOperation(ctx context.Context, operation *v1.Operat
 		}
 	}()
 	var err error
-	for i, step := range operation.Steps {
-		// TODO: add retry steps
-		// TODO: refactor
-		// Notice: 目前只针对 CUSTOM 命令有用，下一步骤依赖上一步骤的输出，比如 K8S 安装时初始化一个 K8S 控制节点后得到 kubeadm join 命令，需要传给其他节点进行执行
-		// len(steps) > 0
-		if i-1 > 0 {
-			// Steps will not be run when nodes field is empty,
-			// so there is no running status.
-			// May be out of list range here.
-			if len(operation.Status.Conditions[i-1].Status) < 1 {
-				if !opts.ForceSkipError {
-					return errors.New("unexpected error, steps node field must be valid")
+	if operation.HasStepDependencies() {
+		err = s.deliverStepGraph(ctx, stepCtx, operation, done, opts)
+	} else {
+		for i, step := range operation.Steps {
+			if err = s.beforeStep(stepCtx, operation, step.ID, opts.DryRun); err != nil {
+				logger.Error("operation stops before step", zap.Error(err), zap.String("step", step.Name))
+				break
+			}
+			// TODO: add retry steps
+			// TODO: refactor
+			// Notice: 目前只针对 CUSTOM 命令有用，下一步骤依赖上一步骤的输出，比如 K8S 安装时初始化一个 K8S 控制节点后得到 kubeadm join 命令，需要传给其他节点进行执行
+			// len(steps) > 0
+			if i-1 > 0 {
+				// Steps will not be run when nodes field is empty,
+				// so there is no running status.
+				// May be out of list range here.
+				if len(operation.Status.Conditions[i-1].Status) < 1 {
+					if !opts.ForceSkipError {
+						return errors.New("unexpected error, steps node field must be valid")
+					}
+					err = s.deliveryTaskStep(stepCtx, operation.Name, &operation.Steps[i],
+						nil, &operation.Status.Conditions[i], opts.DryRun)
+				} else {
+					logger.Info("last response", zap.ByteString("response", operation.Status.Conditions[i-1].Status[0].Response))
+					err = s.deliveryTaskStep(stepCtx, operation.Name, &operation.Steps[i],
+						operation.Status.Conditions[i-1].Status[0].Response, &operation.Status.Conditions[i], opts.DryRun)
 				}
-				err = s.deliveryTaskStep(stepCtx, operation.Name, &operation.Steps[i],
-					nil, &operation.Status.Conditions[i], opts.DryRun)
 			} else {
-				logger.Info("last response", zap.ByteString("response", operation.Status.Conditions[i-1].Status[0].Response))
 				err = s.deliveryTaskStep(stepCtx, operation.Name, &operation.Steps[i],
-					operation.Status.Conditions[i-1].Status[0].Response, &operation.Status.Conditions[i], opts.DryRun)
+					component.GetExtraData(ctx), &operation.Status.Conditions[i], opts.DryRun)
 			}
-		} else {
-			err = s.deliveryTaskStep(stepCtx, operation.Name, &operation.Steps[i],
-				component.GetExtraData(ctx), &operation.Status.Conditions[i], opts.DryRun)
-		}
-		logger.Debug("after delivery task step", zap.Error(err))
-		if err != nil {
-			logger.Error("delivery task step error", zap.Error(err), zap.String("step", step.Name))
-			if step.ErrIgnore || opts.ForceSkipError {
-				logger.Debug("delivery task step, ignore the error", zap.Error(err), zap.String("step", step.Name))
-				// reset error
-				err = nil
-				continue
+			logger.Debug("after delivery task step", zap.Error(err))
+			if err != nil {
+				logger.Error("delivery task step error", zap.Error(err), zap.String("step", step.Name))
+				if step.ErrIgnore || opts.ForceSkipError {
+					logger.Debug("delivery task step, ignore the error", zap.Error(err), zap.String("step", step.Name))
+					// reset error
+					err = nil
+					s.afterStep(operation, step.ID, opts.DryRun)
+					continue
+				}
+				break
 			}
-			break
+			s.afterStep(operation, step.ID, opts.DryRun)
 		}
 	}
 	if err != nil {
+		// the cancelled or timeout operation is not rolled back.
+		if stepCtx.Err() == nil && len(operation.Rollbacks) > 0 && s.rollbackOperation(stepCtx, operation, done, opts.DryRun) {
+			errChan <- err
+			// the caller must not record the changes of the rolled back operation.
+			return fmt.Errorf("operation %s is rolled back: %w", operation.Name, err)
+		}
 		errChan <- err
 	} else {
 		doneChan <- struct{}{}
@@ -468,6 +694,55 @@ func (s *Service) DeliverTaskOperation(ctx context.Context, operation *v1.Operat
 	return nil
 }
 
+// CancelOperation stops delivering the following steps of operation, sends the cancel message to its nodes
+// to kill the running commands, and sets the cluster back to the phase of the failed operation.
+// The operation must be marked cancelled before.
+func (s *Service) CancelOperation(ctx context.Context, operation *v1.Operation) error {
+	if cancel, ok := s.cancels.Load(operation.Name); ok {
+		cancel.(context.CancelFunc)()
+	}
+	// the offline node does not run the steps either, so the error is ignored.
+	if err := s.publishToStepNodes(operation, service.OperationCancelTask); err != nil {
+		return err
+	}
+	if operation.Labels[common.LabelClusterName] != "" {
+		s.SyncClusterCondition(operation)
+	}
+	return nil
+}
+
+// DeleteOperationLogs sends the message to the nodes of operation to delete its step logs, no reply is waited.
+// The offline node keeps the logs unless the retention of its agent is set.
+func (s *Service) DeleteOperationLogs(ctx context.Context, operation *v1.Operation) error {
+	return s.publishToStepNodes(operation, service.OperationDeleteLogs)
+}
+
+// publishToStepNodes sends the message of operation to every node which runs its steps,
+// the error of sending to a node is only logged.
+func (s *Service) publishToStepNodes(operation *v1.Operation, op service.Operation) error {
+	payload, err := initPayload(operation.Name, op, nil, nil, nil, false, false)
+	if err != nil {
+		return err
+	}
+	nodes := sets.NewString()
+	for _, step := range operation.Steps {
+		for _, node := range step.Nodes {
+			nodes.Insert(node.ID)
+		}
+	}
+	for _, node := range nodes.List() {
+		msg := &natsio.Msg{
+			Subject: fmt.Sprintf(service.MsgSubjectFormat, node, s.subjectSuffix),
+			Data:    payload,
+		}
+		if err = s.client.Publish(msg); err != nil {
+			logger.Error("send message to node failed", zap.String("op", operation.Name),
+				zap.String("node", node), zap.Int32("msg", int32(op)), zap.Error(err))
+		}
+	}
+	return nil
+}
+
 func (s *Service) DeliverLogRequest(ctx context.Context, operation *service.LogOperation) (opResp oplog.LogContentResponse, err error) {
 	pb, err := initPayload(operation.OperationIdentity, operation.Op, nil, nil, nil, false, component.GetRetry(ctx))
 	if err != nil {
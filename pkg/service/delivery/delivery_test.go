@@ -0,0 +1,76 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	mock_cluster "github.com/kubeclipper/kubeclipper/pkg/models/cluster/mock"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/service"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/client/natsio"
+	mock_natsio "github.com/kubeclipper/kubeclipper/pkg/simple/client/natsio/mock"
+)
+
+func TestService_CancelOperation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	natsMock := mock_natsio.NewMockInterface(ctrl)
+	clusterMock := mock_cluster.NewMockOperator(ctrl)
+	s := &Service{client: natsMock, clusterOperator: clusterMock, subjectSuffix: "subj"}
+
+	op := &v1.Operation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "op-test",
+			Labels: map[string]string{
+				common.LabelClusterName:     "test",
+				common.LabelOperationAction: v1.OperationUpgradeCluster,
+			},
+		},
+		Steps: []v1.Step{
+			{Nodes: []v1.StepNode{{ID: "node1"}, {ID: "node2"}}},
+			{Nodes: []v1.StepNode{{ID: "node1"}}},
+		},
+		Status: v1.OperationStatus{Status: v1.OperationStatusCancelled},
+	}
+
+	dispatching, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	s.cancels.Store(op.Name, cancel)
+
+	subjects := make(map[string]bool)
+	natsMock.EXPECT().Publish(gomock.Any()).DoAndReturn(func(msg *natsio.Msg) error {
+		payload := service.MsgPayload{}
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			t.Fatalf("unmarshal payload error: %v", err)
+		}
+		if payload.Op != service.OperationCancelTask || payload.OperationIdentity != op.Name {
+			t.Errorf("unexpected payload: %+v", payload)
+		}
+		subjects[msg.Subject] = true
+		return nil
+	}).Times(2)
+	clusterMock.EXPECT().GetClusterEx(gomock.Any(), "test", "0").
+		Return(&v1.Cluster{Status: v1.ClusterStatus{Phase: v1.ClusterUpgrading}}, nil)
+	clusterMock.EXPECT().UpdateCluster(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, c *v1.Cluster) (*v1.Cluster, error) {
+		if c.Status.Phase != v1.ClusterUpgradeFailed {
+			t.Errorf("cluster phase = %s, want %s", c.Status.Phase, v1.ClusterUpgradeFailed)
+		}
+		return c, nil
+	})
+
+	if err := s.CancelOperation(context.TODO(), op); err != nil {
+		t.Fatalf("CancelOperation() error: %v", err)
+	}
+	if dispatching.Err() == nil {
+		t.Errorf("the delivery of operation is not cancelled")
+	}
+	if !subjects["node1.subj"] || !subjects["node2.subj"] {
+		t.Errorf("cancel message is not sent to all nodes: %v", subjects)
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package delivery
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/logger"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/service"
+)
+
+// maxConcurrentSteps the maximum number of steps of an operation run at the same time.
+const maxConcurrentSteps = 5
+
+type stepResult struct {
+	index int
+	err   error
+	// stopped the step is not run because the operation is cancelled or timeout.
+	stopped bool
+}
+
+// stepDependencies returns the indexes of the steps which every step depends on.
+// The dependencies out of steps are ignored, they are done before the operation is retried.
+func stepDependencies(steps []v1.Step) ([][]int, error) {
+	index := make(map[string]int, len(steps))
+	for i, step := range steps {
+		if _, ok := index[step.ID]; ok {
+			return nil, fmt.Errorf("duplicated step id %s", step.ID)
+		}
+		index[step.ID] = i
+	}
+	deps := make([][]int, len(steps))
+	for i, step := range steps {
+		for _, id := range step.DependsOn {
+			if j, ok := index[id]; ok {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+	if err := checkStepCycle(steps, deps); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+func checkStepCycle(steps []v1.Step, deps [][]int) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(steps))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("step %s(%s) depends on itself", steps[i].Name, steps[i].ID)
+		case visited:
+			return nil
+		}
+		state[i] = visiting
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		return nil
+	}
+	for i := range steps {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deliverStepGraph runs every step once its dependencies are done, the independent steps run in parallel
+// and at most maxConcurrentSteps steps run at the same time.
+// No more step is started after a step fails, the error is returned when the running steps end.
+func (s *Service) deliverStepGraph(ctx, stepCtx context.Context, operation *v1.Operation,
+	done []v1.OperationCondition, opts *service.Options) error {
+	deps, err := stepDependencies(operation.Steps)
+	if err != nil {
+		return err
+	}
+	// the replies of steps, the step receives the reply of its last dependency which replies.
+	replies := make(map[string][]byte, len(operation.Steps))
+	for _, cond := range done {
+		if len(cond.Status) > 0 && cond.Status[0].Response != nil {
+			replies[cond.StepID] = cond.Status[0].Response
+		}
+	}
+	waiting := make([]int, len(operation.Steps))
+	dependents := make([][]int, len(operation.Steps))
+	var ready []int
+	for i := range deps {
+		waiting[i] = len(deps[i])
+		for _, j := range deps[i] {
+			dependents[j] = append(dependents[j], i)
+		}
+		if waiting[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	results := make(chan stepResult, len(operation.Steps))
+	running := 0
+	for len(ready) > 0 || running > 0 {
+		for err == nil && len(ready) > 0 && running < maxConcurrentSteps {
+			i := ready[0]
+			ready = ready[1:]
+			running++
+			go func(i int, lastStepReply []byte) {
+				results <- s.deliverGraphStep(stepCtx, operation, i, lastStepReply, opts.DryRun)
+			}(i, graphStepReply(ctx, &operation.Steps[i], replies))
+		}
+		if running == 0 {
+			break
+		}
+		r := <-results
+		running--
+		step := &operation.Steps[r.index]
+		if r.err != nil {
+			if r.stopped {
+				logger.Error("operation stops before step", zap.Error(r.err), zap.String("step", step.Name))
+			} else {
+				logger.Error("delivery task step error", zap.Error(r.err), zap.String("step", step.Name))
+			}
+			if r.stopped || !(step.ErrIgnore || opts.ForceSkipError) {
+				if err == nil {
+					err = r.err
+				}
+				continue
+			}
+			logger.Debug("delivery task step, ignore the error", zap.Error(r.err), zap.String("step", step.Name))
+		}
+		s.afterStep(operation, step.ID, opts.DryRun)
+		if cond := operation.Status.Conditions[r.index]; len(cond.Status) > 0 && cond.Status[0].Response != nil {
+			replies[step.ID] = cond.Status[0].Response
+		}
+		for _, j := range dependents[r.index] {
+			if waiting[j]--; waiting[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+	return err
+}
+
+func (s *Service) deliverGraphStep(ctx context.Context, operation *v1.Operation, i int, lastStepReply []byte, dryRun bool) stepResult {
+	step := &operation.Steps[i]
+	if err := s.beforeStep(ctx, operation, step.ID, dryRun); err != nil {
+		return stepResult{index: i, err: err, stopped: true}
+	}
+	err := s.deliveryTaskStep(ctx, operation.Name, step, lastStepReply, &operation.Status.Conditions[i], dryRun)
+	logger.Debug("after delivery task step", zap.Error(err), zap.String("step", step.Name))
+	return stepResult{index: i, err: err}
+}
+
+// graphStepReply returns the reply of the last dependency which replies, the step without dependency
+// receives the extra data of operation as the first step of the sequential operation does.
+func graphStepReply(ctx context.Context, step *v1.Step, replies map[string][]byte) []byte {
+	if len(step.DependsOn) == 0 {
+		return component.GetExtraData(ctx)
+	}
+	var reply []byte
+	for _, id := range step.DependsOn {
+		if r, ok := replies[id]; ok {
+			reply = r
+		}
+	}
+	return reply
+}
@@ -0,0 +1,236 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+
+	"github.com/kubeclipper/kubeclipper/pkg/component"
+	"github.com/kubeclipper/kubeclipper/pkg/component/utils"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/cri"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1/k8s"
+	"github.com/kubeclipper/kubeclipper/pkg/service"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/client/natsio"
+	mock_natsio "github.com/kubeclipper/kubeclipper/pkg/simple/client/natsio/mock"
+)
+
+func graphStep(id string, dependsOn ...string) v1.Step {
+	return v1.Step{ID: id, Name: id, Nodes: []v1.StepNode{{ID: "node1"}}, DependsOn: dependsOn}
+}
+
+func Test_stepDependencies(t *testing.T) {
+	deps, err := stepDependencies([]v1.Step{graphStep("a"), graphStep("b", "a", "done"), graphStep("c", "a", "b")})
+	if err != nil {
+		t.Fatalf("stepDependencies() error: %v", err)
+	}
+	if len(deps[0]) != 0 || len(deps[1]) != 1 || len(deps[2]) != 2 {
+		t.Errorf("unexpected dependencies: %v", deps)
+	}
+	if _, err = stepDependencies([]v1.Step{graphStep("a", "c"), graphStep("b", "a"), graphStep("c", "b")}); err == nil {
+		t.Errorf("stepDependencies() must fail with cycle")
+	}
+	if _, err = stepDependencies([]v1.Step{graphStep("a"), graphStep("a")}); err == nil {
+		t.Errorf("stepDependencies() must fail with duplicated step id")
+	}
+}
+
+func TestService_deliverStepGraph(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	natsMock := mock_natsio.NewMockInterface(ctrl)
+	s := &Service{client: natsMock, stepStatusChan: make(chan stepStatus, 16)}
+
+	// b and c wait for each other, they must run at the same time.
+	started := map[string]chan struct{}{"b": make(chan struct{}), "c": make(chan struct{})}
+	var (
+		mu        sync.Mutex
+		order     []string
+		lastReply = make(map[string]string)
+	)
+	natsMock.EXPECT().Request(gomock.Any(), gomock.Any()).DoAndReturn(func(msg *natsio.Msg, _ natsio.TimeoutHandler) ([]byte, error) {
+		payload := service.MsgPayload{}
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			return nil, err
+		}
+		id := payload.Step.ID
+		mu.Lock()
+		order = append(order, id)
+		lastReply[id] = string(payload.LastTaskReply)
+		mu.Unlock()
+		if ch, ok := started[id]; ok {
+			close(ch)
+			other := map[string]string{"b": "c", "c": "b"}[id]
+			select {
+			case <-started[other]:
+			case <-time.After(5 * time.Second):
+				return nil, errors.New("steps b and c are not run in parallel")
+			}
+		}
+		return json.Marshal(service.CommonReply{Data: []byte("reply-" + id)})
+	}).Times(5)
+
+	op := &v1.Operation{Steps: []v1.Step{
+		graphStep("a"),
+		graphStep("b", "a"),
+		graphStep("c", "a"),
+		graphStep("d", "c", "b"),
+		graphStep("e", "d"),
+	}}
+	op.Status.Conditions = make([]v1.OperationCondition, len(op.Steps))
+	err := s.deliverStepGraph(context.TODO(), context.TODO(), op, nil, &service.Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("deliverStepGraph() error: %v", err)
+	}
+	if order[0] != "a" || order[3] != "d" || order[4] != "e" {
+		t.Errorf("unexpected step order: %v", order)
+	}
+	if lastReply["b"] != "reply-a" || lastReply["d"] != "reply-b" {
+		t.Errorf("unexpected last step replies: %v", lastReply)
+	}
+}
+
+func TestService_deliverStepGraphFailed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	natsMock := mock_natsio.NewMockInterface(ctrl)
+	s := &Service{client: natsMock, stepStatusChan: make(chan stepStatus, 16)}
+
+	var (
+		mu  sync.Mutex
+		ran = make(map[string]bool)
+	)
+	natsMock.EXPECT().Request(gomock.Any(), gomock.Any()).DoAndReturn(func(msg *natsio.Msg, _ natsio.TimeoutHandler) ([]byte, error) {
+		payload := service.MsgPayload{}
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		ran[payload.Step.ID] = true
+		mu.Unlock()
+		if id := payload.Step.ID; id == "b" || id == "c" {
+			return nil, errors.New("step failed")
+		}
+		return json.Marshal(service.CommonReply{})
+	}).AnyTimes()
+
+	ignored := graphStep("c", "a")
+	ignored.ErrIgnore = true
+	op := &v1.Operation{Steps: []v1.Step{graphStep("a"), graphStep("b", "a"), ignored, graphStep("d", "b")}}
+	op.Status.Conditions = make([]v1.OperationCondition, len(op.Steps))
+	if err := s.deliverStepGraph(context.TODO(), context.TODO(), op, nil, &service.Options{DryRun: true}); err == nil {
+		t.Fatalf("deliverStepGraph() must fail")
+	}
+	if !ran["a"] || !ran["b"] || !ran["c"] || ran["d"] {
+		t.Errorf("unexpected steps run: %v", ran)
+	}
+}
+
+// createClusterSteps makes the install steps of cluster the same as the create cluster operation.
+func createClusterSteps(t *testing.T) []v1.Step {
+	metadata := component.ExtraMetadata{
+		ClusterName: "test",
+		KubeVersion: "v1.23.6",
+		Masters:     []component.Node{{ID: "m1", IPv4: "10.0.0.1", Hostname: "master-1"}},
+		Workers: []component.Node{
+			{ID: "w1", IPv4: "10.0.0.2", Hostname: "worker-1"},
+			{ID: "w2", IPv4: "10.0.0.3", Hostname: "worker-2"},
+		},
+	}
+	c := &v1.Cluster{
+		Masters:           v1.WorkerNodeList{{ID: "m1"}},
+		Workers:           v1.WorkerNodeList{{ID: "w1"}, {ID: "w2"}},
+		KubernetesVersion: "v1.23.6",
+		ContainerRuntime:  v1.ContainerRuntime{Type: v1.CRIContainerd, Version: "1.6.4"},
+		CNI:               v1.CNI{Type: "calico"},
+		Networking: v1.Networking{
+			IPFamily: v1.IPFamilyIPv4,
+			Pods:     v1.NetworkRanges{CIDRBlocks: []string{"172.25.0.0/16"}},
+		},
+	}
+	ctx := component.WithExtraMetadata(context.TODO(), metadata)
+	runtime := cri.ContainerdRunnable{}
+	if err := runtime.InitStep(ctx, c, utils.UnwrapNodeList(metadata.GetAllNodes())); err != nil {
+		t.Fatalf("init container runtime steps error: %v", err)
+	}
+	runtimeSteps := runtime.GetActionSteps(v1.ActionInstall)
+	runnable := k8s.Runnable(*c)
+	steps, err := runnable.GetStep(ctx, v1.ActionInstall)
+	if err != nil {
+		t.Fatalf("get kubernetes install steps error: %v", err)
+	}
+	k8s.InstallAfterRuntime(steps, runtimeSteps)
+	return append(runtimeSteps, steps...)
+}
+
+func TestService_deliverCreateClusterGraph(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	natsMock := mock_natsio.NewMockInterface(ctrl)
+	s := &Service{client: natsMock, stepStatusChan: make(chan stepStatus, 64)}
+
+	op := &v1.Operation{Steps: createClusterSteps(t)}
+	if !op.HasStepDependencies() {
+		t.Fatalf("the steps of create cluster operation must run as a graph")
+	}
+	// the container runtime and the node environment wait for each other, they must run at the same time.
+	started := map[string]chan struct{}{"installRuntime": make(chan struct{}), "nodeEnvSetup": make(chan struct{})}
+	var (
+		mu    sync.Mutex
+		order []string
+		seen  = make(map[string]bool)
+	)
+	natsMock.EXPECT().Request(gomock.Any(), gomock.Any()).DoAndReturn(func(msg *natsio.Msg, _ natsio.TimeoutHandler) ([]byte, error) {
+		payload := service.MsgPayload{}
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			return nil, err
+		}
+		name := payload.Step.Name
+		mu.Lock()
+		first := !seen[payload.Step.ID]
+		if first {
+			seen[payload.Step.ID] = true
+			order = append(order, name)
+		}
+		mu.Unlock()
+		if ch, ok := started[name]; ok {
+			if first {
+				close(ch)
+			}
+			other := map[string]string{"installRuntime": "nodeEnvSetup", "nodeEnvSetup": "installRuntime"}[name]
+			select {
+			case <-started[other]:
+			case <-time.After(5 * time.Second):
+				return nil, errors.New("the container runtime is not installed along with the node environment setup")
+			}
+		}
+		return json.Marshal(service.CommonReply{})
+	}).AnyTimes()
+
+	op.Status.Conditions = make([]v1.OperationCondition, len(op.Steps))
+	if err := s.deliverStepGraph(context.TODO(), context.TODO(), op, nil, &service.Options{DryRun: true}); err != nil {
+		t.Fatalf("deliverStepGraph() error: %v", err)
+	}
+	if len(order) != len(op.Steps) {
+		t.Fatalf("unexpected steps run: %v", order)
+	}
+	// the other steps run one after another in the order of operation.
+	var want []string
+	for _, step := range op.Steps {
+		if _, ok := started[step.Name]; !ok {
+			want = append(want, step.Name)
+		}
+	}
+	if got := order[2:]; strings.Join(got, ",") != strings.Join(want, ",") || got[0] != "installPackages" {
+		t.Errorf("unexpected step order: %v, want %v", got, want)
+	}
+}
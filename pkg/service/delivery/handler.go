@@ -30,6 +30,7 @@ import (
 
 	"github.com/kubeclipper/kubeclipper/pkg/errors"
 	"github.com/kubeclipper/kubeclipper/pkg/logger"
+	"github.com/kubeclipper/kubeclipper/pkg/models/core"
 	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
 	"github.com/kubeclipper/kubeclipper/pkg/service"
 )
@@ -103,6 +104,17 @@ func (s *Service) nodeStateReportInHandler(msg *nats.Msg) {
 			logger.Error("failed to reply message to notify server", zap.Error(err))
 			return
 		}
+	case service.OperationGetBackupKey:
+		resp := s.getBackupKeyOperation(msg, payload.NodeName, payload.Data)
+		respBytes, err := json.Marshal(resp)
+		if err != nil {
+			logger.Error("failed to marshal backup key reply", zap.Error(err))
+			return
+		}
+		if err := msg.Respond(respBytes); err != nil {
+			logger.Error("failed to reply message to notify server", zap.Error(err))
+			return
+		}
 	}
 }
 
@@ -469,3 +481,44 @@ func (s *Service) createNodeLeaseOperation(msg *nats.Msg, data []byte) *service.
 	}
 	return resp
 }
+
+// getBackupKeyOperation resolves the key of backup encryption for the agent which saves or downloads the backup,
+// the keys are kept by the server rather than the steps.
+func (s *Service) getBackupKeyOperation(msg *nats.Msg, nodeName string, data []byte) *service.CommonReply {
+	resp := &service.CommonReply{}
+	payload := &service.BackupKeyPayload{}
+	if err := json.Unmarshal(data, payload); err != nil {
+		resp.Error = backupKeyStatusError(msg, "unmarshal backup key payload error",
+			errors.StatusReasonUnexpected, errors.Unmarshal, 400, err)
+		return resp
+	}
+	bp, err := s.clusterOperator.GetBackupPoint(context.TODO(), payload.BackupPoint, "0")
+	if err == nil {
+		resp.Data, err = core.BackupDecryptionKey(context.TODO(), s.coreOperator, bp, payload.KeyID)
+	}
+	if err != nil {
+		logger.Error("failed to get backup encryption key", zap.String("node", nodeName),
+			zap.String("backup_point", payload.BackupPoint), zap.String("key_id", payload.KeyID), zap.Error(err))
+		resp.Error = backupKeyStatusError(msg, "get backup encryption key error",
+			errors.StatusReasonStorageMethodCall, errors.StorageMethodCall, 500, err)
+	}
+	return resp
+}
+
+func backupKeyStatusError(msg *nats.Msg, message string, reason errors.StatusReason, cause errors.CauseType, code int32, err error) *errors.StatusError {
+	return &errors.StatusError{
+		Message: message,
+		Reason:  reason,
+		Details: &errors.StatusDetails{
+			Subject:   msg.Subject,
+			Operation: int32(service.OperationGetBackupKey),
+			Causes: []errors.StatusCause{
+				{
+					Type:    cause,
+					Message: err.Error(),
+				},
+			},
+		},
+		Code: code,
+	}
+}
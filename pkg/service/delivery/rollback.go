@@ -0,0 +1,146 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package delivery
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/kubeclipper/kubeclipper/pkg/logger"
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+)
+
+// rollbackOperation runs the rollback steps of the succeeded steps in the reverse order after the operation failed,
+// done is the conditions of the steps done before the operation is retried. It returns false if there is nothing to roll back.
+// The errors of rollback steps are ignored to undo as many steps as possible.
+func (s *Service) rollbackOperation(ctx context.Context, operation *v1.Operation, done []v1.OperationCondition, dryRun bool) bool {
+	steps := rollbackSteps(operation, done)
+	if len(steps) == 0 {
+		return false
+	}
+	operation.Status.RollbackConditions = make([]v1.OperationCondition, len(steps))
+	for i := range steps {
+		operation.Status.RollbackConditions[i].StepID = steps[i].ID
+	}
+	// record the rollback conditions before running any rollback step, so that the operation is known
+	// to be rolled back once it fails.
+	if !dryRun {
+		s.initRollbackConditions(operation.Name, operation.Status.RollbackConditions)
+	}
+	for i := range steps {
+		if err := ctx.Err(); err != nil {
+			logger.Error("operation stops before rollback step", zap.Error(err), zap.String("step", steps[i].Name))
+			break
+		}
+		err := s.deliveryTaskStep(ctx, operation.Name, &steps[i], nil, &operation.Status.RollbackConditions[i], dryRun)
+		if err != nil {
+			logger.Error("delivery rollback step error, ignore the error", zap.Error(err), zap.String("step", steps[i].Name))
+		}
+	}
+	return true
+}
+
+// rollbackSteps returns the rollback steps of the succeeded steps, the later registered rollback runs first.
+func rollbackSteps(operation *v1.Operation, done []v1.OperationCondition) []v1.Step {
+	succeeded := sets.NewString()
+	for _, conditions := range [][]v1.OperationCondition{done, operation.Status.Conditions} {
+		for _, cond := range conditions {
+			if conditionSucceeded(cond) {
+				succeeded.Insert(cond.StepID)
+			}
+		}
+	}
+	var steps []v1.Step
+	for i := len(operation.Rollbacks) - 1; i >= 0; i-- {
+		if r := operation.Rollbacks[i]; succeeded.Has(r.StepID) {
+			steps = append(steps, r.Steps...)
+		}
+	}
+	return steps
+}
+
+func conditionSucceeded(cond v1.OperationCondition) bool {
+	if cond.StepID == "" || len(cond.Status) == 0 {
+		return false
+	}
+	for _, status := range cond.Status {
+		if status.Status != v1.StepStatusSuccessful {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Service) initRollbackConditions(opName string, conditions []v1.OperationCondition) {
+	for i := 0; i < updateOperationStatusRetry; i++ {
+		o, err := s.opOperator.GetOperation(context.TODO(), opName)
+		if err != nil {
+			logger.Error("get operation failed", zap.String("op", opName), zap.Error(err))
+			continue
+		}
+		o.Status.RollbackConditions = conditions
+		if _, err = s.opOperator.UpdateOperation(context.TODO(), o); err != nil {
+			logger.Error("update operation rollback conditions failed", zap.String("op", opName), zap.Error(err))
+			continue
+		}
+		return
+	}
+}
+
+// setRollbackCondition appends the step status of the rollback step to its condition.
+func setRollbackCondition(op *v1.Operation, cond v1.OperationCondition) {
+	for i := range op.Status.RollbackConditions {
+		if op.Status.RollbackConditions[i].StepID == cond.StepID {
+			op.Status.RollbackConditions[i].Status = append(op.Status.RollbackConditions[i].Status, cond.Status...)
+			return
+		}
+	}
+	op.Status.RollbackConditions = append(op.Status.RollbackConditions, cond)
+}
+
+// removeRolledBackNodes removes the nodes added by the rolled back operation from the cluster,
+// the cluster is back to the state before the operation.
+func (s *Service) removeRolledBackNodes(op *v1.Operation, clu *v1.Cluster) error {
+	var removed []v1.WorkerNode
+	if v := op.Annotations[common.AnnotationAddedNodes]; v != "" {
+		for _, id := range strings.Split(v, ",") {
+			removed = append(removed, v1.WorkerNode{ID: id})
+		}
+	}
+	if workers := clu.Workers.Intersect(removed...); len(workers) > 0 {
+		clu.Workers = clu.Workers.Complement(workers...)
+	}
+	if masters := clu.Masters.Intersect(removed...); len(masters) > 0 {
+		clu.Masters = clu.Masters.Complement(masters...)
+	}
+	clu.Status.Phase = v1.ClusterRunning
+	if _, err := s.clusterOperator.UpdateCluster(context.TODO(), clu); err != nil {
+		return err
+	}
+	for _, n := range removed {
+		if err := s.updateNodeRoleLabel(clu.Name, n.ID, common.NodeRoleWorker, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
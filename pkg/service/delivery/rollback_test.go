@@ -0,0 +1,103 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kubeclipper/kubeclipper/pkg/scheme/common"
+	v1 "github.com/kubeclipper/kubeclipper/pkg/scheme/core/v1"
+	"github.com/kubeclipper/kubeclipper/pkg/service"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/client/natsio"
+	mock_natsio "github.com/kubeclipper/kubeclipper/pkg/simple/client/natsio/mock"
+)
+
+func rollbackStep(id string) v1.Step {
+	return v1.Step{ID: id, Name: id, Action: v1.ActionUninstall, Nodes: []v1.StepNode{{ID: "node1"}}}
+}
+
+func TestService_DeliverTaskOperationRollback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	natsMock := mock_natsio.NewMockInterface(ctrl)
+	s := &Service{client: natsMock, stepStatusChan: make(chan stepStatus, 16)}
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	natsMock.EXPECT().Request(gomock.Any(), gomock.Any()).DoAndReturn(func(msg *natsio.Msg, _ natsio.TimeoutHandler) ([]byte, error) {
+		payload := service.MsgPayload{}
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		order = append(order, payload.Step.ID)
+		mu.Unlock()
+		if id := payload.Step.ID; id == "c" || id == "rollback-b" {
+			return nil, errors.New("step failed")
+		}
+		return json.Marshal(service.CommonReply{})
+	}).Times(5)
+
+	op := &v1.Operation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "op-test",
+			Labels: map[string]string{common.LabelTimeoutSeconds: "60"},
+		},
+		Steps: []v1.Step{graphStep("a"), graphStep("b"), graphStep("c")},
+	}
+	op.AddRollback(op.Steps[0:1], []v1.Step{rollbackStep("rollback-a")})
+	op.AddRollback(op.Steps[1:2], []v1.Step{rollbackStep("rollback-b")})
+	op.AddRollback(op.Steps[2:3], []v1.Step{rollbackStep("rollback-c")})
+
+	if err := s.DeliverTaskOperation(context.TODO(), op, &service.Options{DryRun: true}); err == nil {
+		t.Fatalf("DeliverTaskOperation() must fail when the operation is rolled back")
+	}
+	// the failed rollback step does not stop the rollback, and the failed step is not rolled back.
+	want := []string{"a", "b", "c", "rollback-b", "rollback-a"}
+	if len(order) != len(want) {
+		t.Fatalf("unexpected steps run: %v", order)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("unexpected steps run: %v", order)
+		}
+	}
+	if len(op.Status.RollbackConditions) != 2 || op.Status.RollbackConditions[0].StepID != "rollback-b" {
+		t.Errorf("unexpected rollback conditions: %v", op.Status.RollbackConditions)
+	}
+}
+
+func Test_rollbackSteps(t *testing.T) {
+	op := &v1.Operation{Steps: []v1.Step{graphStep("a"), graphStep("b"), graphStep("c")}}
+	op.AddRollback(op.Steps[0:2], []v1.Step{rollbackStep("rollback-a")})
+	op.AddRollback(op.Steps[2:3], []v1.Step{rollbackStep("rollback-c")})
+	succeeded := []v1.StepStatus{{Node: "node1", Status: v1.StepStatusSuccessful}}
+	// a is done before the operation is retried.
+	done := []v1.OperationCondition{{StepID: "a", Status: succeeded}}
+	op.Status.Conditions = []v1.OperationCondition{
+		{StepID: "b", Status: succeeded},
+		{StepID: "c", Status: []v1.StepStatus{{Node: "node1", Status: v1.StepStatusFailed}}},
+	}
+	steps := rollbackSteps(op, done)
+	if len(steps) != 1 || steps[0].ID != "rollback-a" {
+		t.Errorf("unexpected rollback steps: %v", steps)
+	}
+}
+
+func Test_setRollbackCondition(t *testing.T) {
+	op := &v1.Operation{Status: v1.OperationStatus{
+		RollbackConditions: []v1.OperationCondition{{StepID: "rollback-b"}, {StepID: "rollback-a"}},
+	}}
+	setRollbackCondition(op, v1.OperationCondition{StepID: "rollback-a", Status: []v1.StepStatus{{Node: "node1"}}})
+	if len(op.Status.RollbackConditions) != 2 || len(op.Status.RollbackConditions[1].Status) != 1 {
+		t.Errorf("unexpected rollback conditions: %v", op.Status.RollbackConditions)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package delivery
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type operationTimeoutKey struct{}
+
+// operationTimeout cancels the operation when it runs out of its timeout, the time the operation is paused is not counted.
+type operationTimeout struct {
+	mu       sync.Mutex
+	timer    *time.Timer
+	deadline time.Time
+	// paused the number of steps waiting for the operation to be resumed.
+	paused   int
+	pausedAt time.Time
+}
+
+func newOperationTimeout(timeout time.Duration, cancel context.CancelFunc) *operationTimeout {
+	return &operationTimeout{
+		timer:    time.AfterFunc(timeout, cancel),
+		deadline: time.Now().Add(timeout),
+	}
+}
+
+func withOperationTimeout(ctx context.Context, t *operationTimeout) context.Context {
+	return context.WithValue(ctx, operationTimeoutKey{}, t)
+}
+
+func operationTimeoutFrom(ctx context.Context) *operationTimeout {
+	t, _ := ctx.Value(operationTimeoutKey{}).(*operationTimeout)
+	return t
+}
+
+// pause stops the clock of operation.
+func (t *operationTimeout) pause() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.paused++; t.paused == 1 {
+		t.timer.Stop()
+		t.pausedAt = time.Now()
+	}
+}
+
+// resume extends the deadline of operation by the paused time and restarts the clock.
+func (t *operationTimeout) resume() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.paused--; t.paused == 0 {
+		t.deadline = t.deadline.Add(time.Since(t.pausedAt))
+		t.timer.Reset(time.Until(t.deadline))
+	}
+}
+
+func (t *operationTimeout) stop() {
+	t.timer.Stop()
+}
@@ -0,0 +1,35 @@
+package delivery
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOperationTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	timeout := newOperationTimeout(100*time.Millisecond, cancel)
+	defer timeout.stop()
+	ctx = withOperationTimeout(ctx, timeout)
+
+	// two steps wait for the paused operation, the clock restarts when both of them go on.
+	operationTimeoutFrom(ctx).pause()
+	operationTimeoutFrom(ctx).pause()
+	time.Sleep(150 * time.Millisecond)
+	operationTimeoutFrom(ctx).resume()
+	time.Sleep(20 * time.Millisecond)
+	operationTimeoutFrom(ctx).resume()
+	if ctx.Err() != nil {
+		t.Fatalf("the paused time must not count towards the operation timeout")
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Errorf("the operation does not time out after it is resumed")
+	}
+
+	// the operation without timeout is never paused.
+	operationTimeoutFrom(context.TODO()).pause()
+	operationTimeoutFrom(context.TODO()).resume()
+}
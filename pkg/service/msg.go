@@ -41,6 +41,9 @@ const (
 	OperationRecovery
 	OperationRunCmd
 	OperationRunStep
+	OperationCancelTask
+	OperationDeleteLogs
+	OperationGetBackupKey
 )
 
 const (
@@ -59,6 +62,12 @@ type NodeStatusPayload struct {
 	Data     []byte    `json:"data,omitempty"`
 }
 
+// BackupKeyPayload the agent resolves the key of backup encryption by the backup point and the key id.
+type BackupKeyPayload struct {
+	BackupPoint string `json:"backupPoint"`
+	KeyID       string `json:"keyID"`
+}
+
 type CommonReply struct {
 	Error *errors.StatusError `json:"error,omitempty"`
 	Data  []byte              `json:"data,omitempty"`
@@ -42,6 +42,7 @@ type Interface interface {
 
 type IDelivery interface {
 	DeliverLogRequest(ctx context.Context, operation *LogOperation) (oplog.LogContentResponse, error) // request & response synchronously.
+	CancelOperation(ctx context.Context, operation *v1.Operation) error
 	CmdDelivery
 }
 
@@ -49,6 +50,7 @@ type CmdDelivery interface {
 	DeliverTaskOperation(ctx context.Context, operation *v1.Operation, opts *Options) error
 	DeliverStep(ctx context.Context, operation *v1.Step, opts *Options) error
 	DeliverCmd(ctx context.Context, toNode string, cmds []string, timeout time.Duration) ([]byte, error)
+	DeleteOperationLogs(ctx context.Context, operation *v1.Operation) error
 }
 
 func HandlerCrash() {
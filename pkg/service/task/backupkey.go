@@ -0,0 +1,62 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/kubeclipper/kubeclipper/pkg/service"
+	"github.com/kubeclipper/kubeclipper/pkg/simple/client/natsio"
+)
+
+// getBackupKey requests the key of backup encryption from the server, the keys are not carried by the steps.
+func (s *Service) getBackupKey(ctx context.Context, backupPoint, keyID string) ([]byte, error) {
+	data, err := json.Marshal(service.BackupKeyPayload{BackupPoint: backupPoint, KeyID: keyID})
+	if err != nil {
+		return nil, err
+	}
+	payloadBytes, err := json.Marshal(&service.NodeStatusPayload{
+		Op:       service.OperationGetBackupKey,
+		NodeName: s.AgentID,
+		Data:     data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg := &natsio.Msg{
+		Subject: s.NodeReportSubject,
+		From:    s.AgentID,
+		Timeout: 3 * time.Second,
+		Data:    payloadBytes,
+	}
+	msgResp, err := s.mqClient.Request(msg, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp := &service.CommonReply{}
+	if err = json.Unmarshal(msgResp, resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+	return resp.Data, nil
+}
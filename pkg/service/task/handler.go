@@ -44,6 +44,7 @@ func (s *Service) runTaskStep(ctx context.Context, payload *service.MsgPayload,
 	ctx = component.WithStepID(ctx, stepKey)                        // put step ID into context
 	ctx = component.WithOplog(ctx, s.oplog)                         // put operation log object into context
 	ctx = component.WithRepoMirror(ctx, s.repoMirror)
+	ctx = component.WithBackupKeyResolver(ctx, s.getBackupKey)
 
 	var entry string
 	// truncate step log file
@@ -108,6 +109,7 @@ func (s *Service) runStep(ctx context.Context, payload *service.MsgPayload, subj
 	ctx = component.WithStepID(ctx, stepKey) // put step ID into context
 	ctx = component.WithOplog(ctx, s.oplog)  // put operation log object into context
 	ctx = component.WithRepoMirror(ctx, s.repoMirror)
+	ctx = component.WithBackupKeyResolver(ctx, s.getBackupKey)
 
 	cmds := make([]v1.Command, len(payload.Step.BeforeRunCommands)+len(payload.Step.Commands)+len(payload.Step.AfterRunCommands))
 	cmds = append(cmds, payload.Step.BeforeRunCommands...)
@@ -201,6 +203,11 @@ func (s *Service) taskHandler(msg *nats.Msg) {
 		}
 	case service.OperationRunTask:
 		var replyData []byte
+		ctx, cancelTask := context.WithCancel(ctx)
+		defer cancelTask()
+		key := taskKey{operation: payload.OperationIdentity, step: payload.Step.ID}
+		s.runningTasks.Store(key, cancelTask)
+		defer s.runningTasks.Delete(key)
 		for i := 0; i <= int(payload.Step.RetryTimes); i++ {
 			// reset retry field
 			if i > 0 {
@@ -210,9 +217,27 @@ func (s *Service) taskHandler(msg *nats.Msg) {
 			if statusError == nil {
 				break
 			}
+			if ctx.Err() == context.Canceled {
+				statusError = doStatusError("run task step error", "operation is cancelled", errors.ShellCommand, 500, statusError)
+				break
+			}
 			logger.Debug("run task step failed", zap.String("step", payload.Step.Name), zap.Int("retry", i), zap.Int32("maxRetry", payload.Step.RetryTimes))
 		}
 		responseMessage(msg, replyData, statusError)
+	case service.OperationCancelTask:
+		// kill the running commands of operation by cancelling their context, no reply is required.
+		s.runningTasks.Range(func(k, cancel interface{}) bool {
+			if k.(taskKey).operation == payload.OperationIdentity {
+				logger.Info("cancel task step", zap.String("operation", payload.OperationIdentity), zap.String("step", k.(taskKey).step))
+				cancel.(context.CancelFunc)()
+			}
+			return true
+		})
+	case service.OperationDeleteLogs:
+		// the operation is pruned by the server, no reply is required.
+		if err := s.oplog.DeleteOperationDir(payload.OperationIdentity); err != nil {
+			logger.Error("delete operation logs error", zap.String("operation", payload.OperationIdentity), zap.Error(err))
+		}
 	case service.OperationRunStep:
 		var replyData []byte
 		for i := 0; i <= int(payload.Step.RetryTimes); i++ {
@@ -50,6 +50,7 @@ var _ service.Interface = (*Service)(nil)
 
 const (
 	nodeStatusUpdateRetry = 5
+	oplogPruneInterval    = time.Hour
 )
 
 type Service struct {
@@ -99,6 +100,13 @@ type Service struct {
 	oplog       component.OperationLogFile
 	backupStore bs.BackupStore
 	repoMirror  string
+	// runningTasks the cancel functions of the running task steps, keyed by taskKey.
+	runningTasks sync.Map
+}
+
+type taskKey struct {
+	operation string
+	step      string
 }
 
 type ServiceOption func(*Service)
@@ -191,9 +199,19 @@ func (s *Service) Run(stopCh <-chan struct{}) error {
 	// start syncing lease
 	// TODO: disable node lease provisional
 	go wait.Until(s.syncNodeLease, s.leaseRenewInterval, stopCh)
+	if s.oplog != nil {
+		go wait.Until(s.pruneOperationLogs, oplogPruneInterval, stopCh)
+	}
 	return nil
 }
 
+// pruneOperationLogs deletes the operation logs by the retention policy of agent.
+func (s *Service) pruneOperationLogs() {
+	if err := s.oplog.PruneOperationDirs(); err != nil {
+		logger.Error("prune operation logs error", zap.Error(err))
+	}
+}
+
 func (s *Service) PrepareRun(stopCh <-chan struct{}) error {
 	return s.mqClient.InitConn(stopCh)
 }
@@ -0,0 +1,80 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package backupstore
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"hash"
+)
+
+// ErrChecksumMismatch the size or md5 of backup is not the same as it was recorded when the backup was created.
+var ErrChecksumMismatch = errors.New("backup checksum mismatch")
+
+// Checksum computes the size and md5 of the backup written to it.
+type Checksum struct {
+	size int64
+	hash hash.Hash
+}
+
+func NewChecksum() *Checksum {
+	return &Checksum{hash: md5.New()}
+}
+
+func (c *Checksum) Write(p []byte) (int, error) {
+	c.size += int64(len(p))
+	return c.hash.Write(p)
+}
+
+// Size returns the size of backup written.
+func (c *Checksum) Size() int64 {
+	return c.size
+}
+
+// MD5 returns the hex encoded md5 of backup written.
+func (c *Checksum) MD5() string {
+	return fmt.Sprintf("%x", c.hash.Sum(nil))
+}
+
+// Check returns ErrChecksumMismatch if the backup written is not the same as the size and md5,
+// the size and md5 which were not recorded are not checked.
+func (c *Checksum) Check(size int64, md5sum string) error {
+	if size != 0 && c.size != size {
+		return fmt.Errorf("%w: size is %d, expected %d", ErrChecksumMismatch, c.size, size)
+	}
+	if sum := c.MD5(); md5sum != "" && sum != md5sum {
+		return fmt.Errorf("%w: md5 is %s, expected %s", ErrChecksumMismatch, sum, md5sum)
+	}
+	return nil
+}
+
+// Verify downloads the backup from the store and checks it by the size and md5, ErrNotFound is returned
+// if the backup does not exist.
+func Verify(ctx context.Context, store BackupStore, fileName string, size int64, md5sum string) error {
+	if _, err := store.Stat(ctx, fileName); err != nil {
+		return err
+	}
+	c := NewChecksum()
+	if err := store.Download(ctx, fileName, c); err != nil {
+		return err
+	}
+	return c.Check(size, md5sum)
+}
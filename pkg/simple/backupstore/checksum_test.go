@@ -0,0 +1,64 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package backupstore
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	fs := &FilesystemStore{RootDir: t.TempDir()}
+	snapshot := []byte("etcd snapshot")
+	if err := os.WriteFile(filepath.Join(fs.RootDir, "snapshot"), snapshot, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := fmt.Sprintf("%x", md5.Sum(snapshot))
+
+	tests := []struct {
+		name     string
+		fileName string
+		size     int64
+		md5      string
+		wantErr  error
+	}{
+		{name: "intact", fileName: "snapshot", size: int64(len(snapshot)), md5: sum},
+		{name: "not recorded", fileName: "snapshot"},
+		{name: "size mismatch", fileName: "snapshot", size: 1, md5: sum, wantErr: ErrChecksumMismatch},
+		{name: "md5 mismatch", fileName: "snapshot", size: int64(len(snapshot)), md5: "0123", wantErr: ErrChecksumMismatch},
+		{name: "missing", fileName: "missing", size: int64(len(snapshot)), md5: sum, wantErr: ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(context.TODO(), fs, tt.fileName, tt.size, tt.md5)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	// the missing backup is not created by the verification.
+	if _, err := os.Stat(filepath.Join(fs.RootDir, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing backup is created: %v", err)
+	}
+}
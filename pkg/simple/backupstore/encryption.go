@@ -0,0 +1,300 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package backupstore
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// EncryptionKeySize the size of the key encrypting backups, AES-256 is used.
+const EncryptionKeySize = 32
+
+const (
+	encryptionMagic   = "KCBE"
+	encryptionVersion = 1
+	// encryptionChunkSize the backup is encrypted in chunks, it is never held in memory as a whole.
+	encryptionChunkSize = 64 << 10
+)
+
+var (
+	ErrNotEncrypted       = errors.New("backup is not encrypted")
+	ErrEncryptionKeyID    = errors.New("backup is encrypted by another key")
+	ErrBackupTruncated    = errors.New("encrypted backup is truncated")
+	ErrBackupCorrupted    = errors.New("encrypted backup is corrupted")
+	errInvalidKeySize     = fmt.Errorf("backup encryption key must be %d bytes", EncryptionKeySize)
+	errInvalidChunkLength = fmt.Errorf("%w: invalid chunk length", ErrBackupCorrupted)
+)
+
+var _ BackupStore = (*EncryptedStore)(nil)
+
+// EncryptedStore encrypts the backups by AES-GCM before they are saved into the store, and decrypts them when they
+// are downloaded. Every backup is encrypted by its own data key, the data key is encrypted by the key of store
+// and saved in the header of backup with the key id:
+//
+//	magic | version | key id length | key id | nonce | encrypted data key | chunks...
+//
+// Every chunk is its length followed by the data sealed with the chunk index as nonce, the last chunk is marked
+// in the additional data so that a truncated backup is detected.
+type EncryptedStore struct {
+	BackupStore
+	keyID string
+	key   cipher.AEAD
+}
+
+// NewEncryptedStore wraps the store with the key of id.
+func NewEncryptedStore(store BackupStore, keyID string, key []byte) (*EncryptedStore, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyID) > 0xffff {
+		return nil, fmt.Errorf("backup encryption key id is too long")
+	}
+	return &EncryptedStore{BackupStore: store, keyID: keyID, key: aead}, nil
+}
+
+// GenerateEncryptionKey returns a random key for backup encryption.
+func GenerateEncryptionKey() ([]byte, error) {
+	key := make([]byte, EncryptionKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *EncryptedStore) Save(ctx context.Context, r io.Reader, fileName string) error {
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(s.encrypt(r, pw))
+	}()
+	err := s.BackupStore.Save(ctx, pr, fileName)
+	// the encryption stops if the store does not read all of the backup.
+	_ = pr.CloseWithError(err)
+	return err
+}
+
+func (s *EncryptedStore) Download(ctx context.Context, fileName string, w io.Writer) error {
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := s.BackupStore.Download(ctx, fileName, pw)
+		_ = pw.CloseWithError(err)
+		done <- err
+	}()
+	err := s.decrypt(pr, w)
+	// the download stops if the backup can not be decrypted.
+	_ = pr.CloseWithError(err)
+	if dErr := <-done; dErr != nil {
+		return dErr
+	}
+	return err
+}
+
+func (s *EncryptedStore) header() []byte {
+	h := make([]byte, 0, len(encryptionMagic)+3+len(s.keyID))
+	h = append(h, encryptionMagic...)
+	h = append(h, encryptionVersion)
+	h = binary.BigEndian.AppendUint16(h, uint16(len(s.keyID)))
+	return append(h, s.keyID...)
+}
+
+func (s *EncryptedStore) encrypt(r io.Reader, w io.Writer) error {
+	dataKey, err := GenerateEncryptionKey()
+	if err != nil {
+		return err
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	header := s.header()
+	nonce := make([]byte, s.key.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	if _, err = bw.Write(header); err != nil {
+		return err
+	}
+	if _, err = bw.Write(nonce); err != nil {
+		return err
+	}
+	if _, err = bw.Write(s.key.Seal(nil, nonce, dataKey, header)); err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(r, encryptionChunkSize)
+	buf := make([]byte, encryptionChunkSize)
+	for index := uint64(0); ; index++ {
+		n, err := io.ReadFull(br, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		last := false
+		if err != nil {
+			last = true
+		} else if _, pErr := br.Peek(1); pErr == io.EOF {
+			last = true
+		}
+		sealed := data.Seal(nil, chunkNonce(data, index), buf[:n], chunkAdditionalData(last))
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(sealed)))
+		if _, err = bw.Write(length[:]); err != nil {
+			return err
+		}
+		if _, err = bw.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return bw.Flush()
+		}
+	}
+}
+
+// EncryptionKeyID returns the id of key which the backup saved in the store is encrypted by, only the header of
+// backup is downloaded. ErrNotEncrypted is returned if the backup is not encrypted.
+func EncryptionKeyID(ctx context.Context, store BackupStore, fileName string) (string, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(store.Download(ctx, fileName, pw))
+	}()
+	keyID, err := readKeyID(pr)
+	// the download stops once the header is read.
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	return keyID, err
+}
+
+func readKeyID(r io.Reader) (string, error) {
+	prefix := make([]byte, len(encryptionMagic)+3)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return "", ErrNotEncrypted
+		}
+		return "", err
+	}
+	if string(prefix[:len(encryptionMagic)]) != encryptionMagic {
+		return "", ErrNotEncrypted
+	}
+	if v := prefix[len(encryptionMagic)]; v != encryptionVersion {
+		return "", fmt.Errorf("unsupported backup encryption version %d", v)
+	}
+	keyID := make([]byte, binary.BigEndian.Uint16(prefix[len(encryptionMagic)+1:]))
+	if _, err := io.ReadFull(r, keyID); err != nil {
+		return "", ErrBackupTruncated
+	}
+	return string(keyID), nil
+}
+
+func (s *EncryptedStore) decrypt(r io.Reader, w io.Writer) error {
+	br := bufio.NewReader(r)
+	keyID, err := readKeyID(br)
+	if err != nil {
+		return err
+	}
+	if keyID != s.keyID {
+		return fmt.Errorf("%w %s", ErrEncryptionKeyID, keyID)
+	}
+	nonce := make([]byte, s.key.NonceSize())
+	sealedKey := make([]byte, EncryptionKeySize+s.key.Overhead())
+	if _, err := io.ReadFull(br, nonce); err != nil {
+		return ErrBackupTruncated
+	}
+	if _, err := io.ReadFull(br, sealedKey); err != nil {
+		return ErrBackupTruncated
+	}
+	dataKey, err := s.key.Open(nil, nonce, sealedKey, s.header())
+	if err != nil {
+		return fmt.Errorf("decrypt backup data key: %w", err)
+	}
+	data, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	var length [4]byte
+	for index := uint64(0); ; index++ {
+		if _, err = io.ReadFull(br, length[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return ErrBackupTruncated
+			}
+			return err
+		}
+		n := binary.BigEndian.Uint32(length[:])
+		if n < uint32(data.Overhead()) || n > encryptionChunkSize+uint32(data.Overhead()) {
+			return errInvalidChunkLength
+		}
+		sealed := make([]byte, n)
+		if _, err = io.ReadFull(br, sealed); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return ErrBackupTruncated
+			}
+			return err
+		}
+		last := false
+		plain, err := data.Open(nil, chunkNonce(data, index), sealed, chunkAdditionalData(false))
+		if err != nil {
+			if plain, err = data.Open(nil, chunkNonce(data, index), sealed, chunkAdditionalData(true)); err != nil {
+				return fmt.Errorf("%w: decrypt chunk %d: %v", ErrBackupCorrupted, index, err)
+			}
+			last = true
+		}
+		if _, err = bw.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			if _, err = br.Peek(1); err != io.EOF {
+				return fmt.Errorf("%w: unexpected data after the last chunk", ErrBackupCorrupted)
+			}
+			return bw.Flush()
+		}
+	}
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, errInvalidKeySize
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(aead cipher.AEAD, index uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], index)
+	return nonce
+}
+
+func chunkAdditionalData(last bool) []byte {
+	if last {
+		return []byte{1}
+	}
+	return []byte{0}
+}
@@ -0,0 +1,101 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package backupstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	key, err := GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := &FilesystemStore{RootDir: t.TempDir()}
+	store, err := NewEncryptedStore(fs, "default", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, size := range []int{0, 10, encryptionChunkSize, 3*encryptionChunkSize + 7} {
+		snapshot := make([]byte, size)
+		_, _ = rand.Read(snapshot)
+		if err = store.Save(context.TODO(), bytes.NewReader(snapshot), "snapshot"); err != nil {
+			t.Fatalf("Save() error: %v", err)
+		}
+		saved, err := os.ReadFile(filepath.Join(fs.RootDir, "snapshot"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size > 0 && bytes.Contains(saved, snapshot) {
+			t.Errorf("backup of %d bytes is saved in plaintext", size)
+		}
+		got := &bytes.Buffer{}
+		if err = store.Download(context.TODO(), "snapshot", got); err != nil {
+			t.Fatalf("Download() error: %v", err)
+		}
+		if !bytes.Equal(got.Bytes(), snapshot) {
+			t.Errorf("downloaded backup of %d bytes is different from the snapshot", size)
+		}
+	}
+
+	// the backup is truncated at the boundary of chunks.
+	snapshot := make([]byte, 2*encryptionChunkSize)
+	if err = store.Save(context.TODO(), bytes.NewReader(snapshot), "snapshot"); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := os.ReadFile(filepath.Join(fs.RootDir, "snapshot"))
+	chunk := 4 + encryptionChunkSize + store.key.Overhead()
+	if err = os.WriteFile(filepath.Join(fs.RootDir, "truncated"), saved[:len(saved)-chunk], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Download(context.TODO(), "truncated", &bytes.Buffer{}); !errors.Is(err, ErrBackupTruncated) {
+		t.Errorf("Download() error = %v, want %v", err, ErrBackupTruncated)
+	}
+
+	other, _ := NewEncryptedStore(fs, "rotated", key)
+	if err = other.Download(context.TODO(), "snapshot", &bytes.Buffer{}); !errors.Is(err, ErrEncryptionKeyID) {
+		t.Errorf("Download() error = %v, want %v", err, ErrEncryptionKeyID)
+	}
+	wrongKey, _ := GenerateEncryptionKey()
+	wrong, _ := NewEncryptedStore(fs, "default", wrongKey)
+	if err = wrong.Download(context.TODO(), "snapshot", &bytes.Buffer{}); err == nil {
+		t.Errorf("Download() must fail with the wrong key")
+	}
+
+	if err = fs.Save(context.TODO(), bytes.NewReader([]byte("plain snapshot")), "plain"); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Download(context.TODO(), "plain", &bytes.Buffer{}); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("Download() error = %v, want %v", err, ErrNotEncrypted)
+	}
+
+	if keyID, err := EncryptionKeyID(context.TODO(), fs, "snapshot"); err != nil || keyID != "default" {
+		t.Errorf("EncryptionKeyID() = %q, %v, want %q", keyID, err, "default")
+	}
+	if _, err = EncryptionKeyID(context.TODO(), fs, "plain"); !errors.Is(err, ErrNotEncrypted) {
+		t.Errorf("EncryptionKeyID() error = %v, want %v", err, ErrNotEncrypted)
+	}
+}
@@ -82,10 +82,11 @@ func (fs *FilesystemStore) Delete(ctx context.Context, fileName string) (err err
 
 func (fs *FilesystemStore) Download(ctx context.Context, fileName string, w io.Writer) (err error) {
 	defer logProbe(ctx, fmt.Sprintf("download backup from %s", filepath.Join(fs.RootDir, fileName)), err)
-	f, err := os.OpenFile(filepath.Join(fs.RootDir, fileName), os.O_CREATE|os.O_RDWR, os.ModePerm)
+	f, err := os.Open(filepath.Join(fs.RootDir, fileName))
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
 	write := bufio.NewWriter(w)
 
@@ -95,3 +96,34 @@ func (fs *FilesystemStore) Download(ctx context.Context, fileName string, w io.W
 	}
 	return write.Flush()
 }
+
+func (fs *FilesystemStore) Stat(ctx context.Context, fileName string) (*FileInfo, error) {
+	fi, err := os.Stat(filepath.Join(fs.RootDir, fileName))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, fileName)
+		}
+		return nil, err
+	}
+	return &FileInfo{Name: fileName, Size: fi.Size(), ModTime: fi.ModTime()}, nil
+}
+
+func (fs *FilesystemStore) List(ctx context.Context) ([]FileInfo, error) {
+	entries, err := os.ReadDir(fs.RootDir)
+	if err != nil {
+		return nil, err
+	}
+	var files []FileInfo
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			// the file is deleted after the directory is read.
+			continue
+		}
+		files = append(files, FileInfo{Name: fi.Name(), Size: fi.Size(), ModTime: fi.ModTime()})
+	}
+	return files, nil
+}
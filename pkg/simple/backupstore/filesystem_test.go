@@ -22,6 +22,9 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -179,3 +182,29 @@ func TestFilesystemStore_Delete(t *testing.T) {
 		})
 	}
 }
+
+func TestFilesystemStore_List(t *testing.T) {
+	fs := &FilesystemStore{RootDir: t.TempDir()}
+	for _, name := range []string{"backup-1", "backup-2"} {
+		if err := fs.Save(context.TODO(), bytes.NewReader([]byte(name)), name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(fs.RootDir, "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	files, err := fs.List(context.TODO())
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+		if f.Size != int64(len(f.Name)) {
+			t.Errorf("List() size of %s = %d, want %d", f.Name, f.Size, len(f.Name))
+		}
+	}
+	if want := []string{"backup-1", "backup-2"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("List() = %v, want %v", names, want)
+	}
+}
@@ -118,3 +118,25 @@ func (receiver *ObjectStore) Download(ctx context.Context, fileName string, w io
 	_, err = bufio.NewReader(obj).WriteTo(w)
 	return err
 }
+
+func (receiver *ObjectStore) Stat(ctx context.Context, fileName string) (*FileInfo, error) {
+	obj, err := receiver.Client.StatObject(ctx, receiver.Bucket, fileName, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, fmt.Errorf("%w: %s/%s", ErrNotFound, receiver.Bucket, fileName)
+		}
+		return nil, err
+	}
+	return &FileInfo{Name: fileName, Size: obj.Size, ModTime: obj.LastModified}, nil
+}
+
+func (receiver *ObjectStore) List(ctx context.Context) ([]FileInfo, error) {
+	var files []FileInfo
+	for obj := range receiver.Client.ListObjects(ctx, receiver.Bucket, minio.ListObjectsOptions{}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		files = append(files, FileInfo{Name: obj.Key, Size: obj.Size, ModTime: obj.LastModified})
+	}
+	return files, nil
+}
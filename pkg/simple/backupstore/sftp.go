@@ -0,0 +1,193 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package backupstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/sftp"
+
+	"github.com/kubeclipper/kubeclipper/pkg/utils/sshutils"
+)
+
+func init() {
+	RegisterProvider(&SftpStore{})
+}
+
+var _ BackupStore = (*SftpStore)(nil)
+
+var (
+	errEmptySftpHost = errors.New("sftp host must be provided")
+	errEmptySftpUser = errors.New("sftp user must be provided")
+	errEmptySftpAuth = errors.New("either sftp password or private key must be provided")
+)
+
+// SftpStore saves the backup files on the host over sftp, a connection is made for every operation.
+type SftpStore struct {
+	Host       string `json:"host" yaml:"host"`
+	Port       int    `json:"port" yaml:"port"`
+	User       string `json:"user" yaml:"user"`
+	Password   string `json:"password" yaml:"password"`
+	PrivateKey string `json:"privateKey" yaml:"privateKey"`
+	PkPassword string `json:"pkPassword" yaml:"pkPassword"`
+	RootDir    string `json:"rootDir,omitempty" yaml:"rootDir,omitempty"` // root directory for storing backup files
+}
+
+func (s *SftpStore) Type() string {
+	return SFTPStorage
+}
+
+func (s *SftpStore) Create() (BackupStore, error) {
+	if s.Host == "" {
+		return nil, errEmptySftpHost
+	}
+	if s.User == "" {
+		return nil, errEmptySftpUser
+	}
+	if s.Password == "" && s.PrivateKey == "" {
+		return nil, errEmptySftpAuth
+	}
+	store := *s
+	if store.Port == 0 {
+		store.Port = 22
+	}
+	if store.RootDir == "" {
+		store.RootDir = defaultRootDir
+	}
+	return &store, nil
+}
+
+func (s *SftpStore) Save(ctx context.Context, r io.Reader, fileName string) (err error) {
+	defer func() { logProbe(ctx, fmt.Sprintf("save backup to %s:%s", s.Host, s.remotePath(fileName)), err) }()
+	return s.do(func(client *sftp.Client) error {
+		if err := client.MkdirAll(s.RootDir); err != nil {
+			return err
+		}
+		// the backup file is written to a temporary file first, a broken transfer never leaves a partial backup.
+		tmp := s.remotePath(fileName) + ".tmp"
+		w, err := client.Create(tmp)
+		if err != nil {
+			return err
+		}
+		if _, err = io.Copy(w, r); err != nil {
+			_ = w.Close()
+			_ = client.Remove(tmp)
+			return err
+		}
+		if err = w.Close(); err != nil {
+			_ = client.Remove(tmp)
+			return err
+		}
+		return client.PosixRename(tmp, s.remotePath(fileName))
+	})
+}
+
+func (s *SftpStore) Delete(ctx context.Context, fileName string) (err error) {
+	defer func() { logProbe(ctx, fmt.Sprintf("delete backup from %s:%s", s.Host, s.remotePath(fileName)), err) }()
+	return s.do(func(client *sftp.Client) error {
+		err := client.Remove(s.remotePath(fileName))
+		if err != nil && errors.Is(err, os.ErrNotExist) {
+			// The target file is already deleted.
+			return nil
+		}
+		return err
+	})
+}
+
+func (s *SftpStore) Download(ctx context.Context, fileName string, w io.Writer) (err error) {
+	defer func() { logProbe(ctx, fmt.Sprintf("download backup from %s:%s", s.Host, s.remotePath(fileName)), err) }()
+	return s.do(func(client *sftp.Client) error {
+		f, err := client.Open(s.remotePath(fileName))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = f.WriteTo(w)
+		return err
+	})
+}
+
+func (s *SftpStore) Stat(ctx context.Context, fileName string) (info *FileInfo, err error) {
+	err = s.do(func(client *sftp.Client) error {
+		fi, err := client.Stat(s.remotePath(fileName))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("%w: %s:%s", ErrNotFound, s.Host, s.remotePath(fileName))
+			}
+			return err
+		}
+		info = &FileInfo{Name: fileName, Size: fi.Size(), ModTime: fi.ModTime()}
+		return nil
+	})
+	return info, err
+}
+
+func (s *SftpStore) List(ctx context.Context) (files []FileInfo, err error) {
+	err = s.do(func(client *sftp.Client) error {
+		entries, err := client.ReadDir(s.RootDir)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// the root directory is created when the first backup is saved.
+				return nil
+			}
+			return err
+		}
+		for _, fi := range entries {
+			// the temporary file is being saved.
+			if !fi.Mode().IsRegular() || strings.HasSuffix(fi.Name(), ".tmp") {
+				continue
+			}
+			files = append(files, FileInfo{Name: fi.Name(), Size: fi.Size(), ModTime: fi.ModTime()})
+		}
+		return nil
+	})
+	return files, err
+}
+
+// remotePath the backup file is saved in the root directory by its base name.
+func (s *SftpStore) remotePath(fileName string) string {
+	return path.Join(s.RootDir, path.Base(fileName))
+}
+
+func (s *SftpStore) do(fn func(client *sftp.Client) error) error {
+	ss := &sshutils.SSH{
+		User:       s.User,
+		Password:   s.Password,
+		Port:       s.Port,
+		PrivateKey: s.PrivateKey,
+		PkPassword: s.PkPassword,
+	}
+	sshClient, err := ss.NewClient(s.Host)
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return fn(client)
+}
@@ -0,0 +1,70 @@
+/*
+ *
+ *  * Copyright 2021 KubeClipper Authors.
+ *  *
+ *  * Licensed under the Apache License, Version 2.0 (the "License");
+ *  * you may not use this file except in compliance with the License.
+ *  * You may obtain a copy of the License at
+ *  *
+ *  *     http://www.apache.org/licenses/LICENSE-2.0
+ *  *
+ *  * Unless required by applicable law or agreed to in writing, software
+ *  * distributed under the License is distributed on an "AS IS" BASIS,
+ *  * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *  * See the License for the specific language governing permissions and
+ *  * limitations under the License.
+ *
+ */
+
+package backupstore
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSftpStore_Create(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   SftpStore
+		wantErr error
+	}{
+		{
+			name:    "empty host",
+			store:   SftpStore{User: "root", Password: "pwd"},
+			wantErr: errEmptySftpHost,
+		},
+		{
+			name:    "empty user",
+			store:   SftpStore{Host: "10.0.0.1", Password: "pwd"},
+			wantErr: errEmptySftpUser,
+		},
+		{
+			name:    "empty auth",
+			store:   SftpStore{Host: "10.0.0.1", User: "root"},
+			wantErr: errEmptySftpAuth,
+		},
+		{
+			name:  "defaults",
+			store: SftpStore{Host: "10.0.0.1", User: "root", PrivateKey: "key"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.store.Create()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			s := got.(*SftpStore)
+			if s.Port != 22 || s.RootDir != defaultRootDir {
+				t.Errorf("unexpected defaults port %d root dir %s", s.Port, s.RootDir)
+			}
+			if p := s.remotePath("/tmp/etcd-snapshot.db"); p != defaultRootDir+"/etcd-snapshot.db" {
+				t.Errorf("unexpected remote path %s", p)
+			}
+		})
+	}
+}
@@ -20,6 +20,7 @@ package backupstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -32,8 +33,9 @@ import (
 )
 
 const (
-	FSStorage = "fs"
-	S3Storage = "s3"
+	FSStorage   = "fs"
+	S3Storage   = "s3"
+	SFTPStorage = "sftp"
 )
 
 var providerFactories = make(map[string]ProviderFactory)
@@ -51,6 +53,20 @@ type BackupStore interface {
 	Save(ctx context.Context, r io.Reader, fileName string) error
 	Delete(ctx context.Context, fileName string) error
 	Download(ctx context.Context, fileName string, w io.Writer) error
+	// Stat returns ErrNotFound if the file does not exist in the store.
+	Stat(ctx context.Context, fileName string) (*FileInfo, error)
+	// List returns the backup files saved in the store.
+	List(ctx context.Context) ([]FileInfo, error)
+}
+
+// ErrNotFound the backup file does not exist in the store.
+var ErrNotFound = errors.New("backup file not found")
+
+// FileInfo describes the backup file saved in the store, the size is of the file as it is saved.
+type FileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
 }
 
 func GetProviderFactoryType() []string {